@@ -26,15 +26,18 @@ import (
 	configv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
 	"github.com/nebuly-ai/nos/pkg/gpu/nvml"
 	"github.com/nebuly-ai/nos/pkg/gpu/slicing"
 	"github.com/nebuly-ai/nos/pkg/resource"
 	"github.com/nebuly-ai/nos/pkg/util"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"os"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"time"
@@ -102,13 +105,19 @@ func main() {
 	nvmlClient := nvml.NewClient(ctrl.Log.WithName("NvmlClient"))
 	gpuClient := slicing.NewClient(resourceClient, nvmlClient)
 
-	// Check if any of the GPUs of the node has MIG mode enabled
+	// Check if any of the GPUs of the node has MIG mode enabled. On nodes with hybrid partitioning,
+	// the MIG-enabled GPUs are managed by the MIG agent, so they are allowed.
+	var node v1.Node
+	if err = mgr.GetAPIReader().Get(ctx, client.ObjectKey{Name: nodeName}, &node); err != nil {
+		setupLog.Error(err, "unable to fetch node", "node", nodeName)
+		os.Exit(1)
+	}
 	anyMigEnabledGpu, err := AnyMigEnabledGpu(nvmlClient)
 	if err != nil {
 		setupLog.Error(err, "unable to fetch GPUs")
 		os.Exit(1)
 	}
-	if anyMigEnabledGpu {
+	if anyMigEnabledGpu && !gpu.IsHybridPartitioningEnabled(node) {
 		setupLog.Error(errors.New("cannot run on a node with MIG enabled GPUs"), "exiting")
 		os.Exit(1)
 	}
@@ -19,39 +19,37 @@ package main
 import (
 	"context"
 	"flag"
-	"fmt"
 	"github.com/nebuly-ai/nos/internal/controllers/gpupartitioner"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/hybrid"
 	"github.com/nebuly-ai/nos/internal/partitioning/mig"
 	"github.com/nebuly-ai/nos/internal/partitioning/mps"
 	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	nosscheduler "github.com/nebuly-ai/nos/internal/scheduler"
 	configv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/api/scheduler"
 	schedulerv1beta3 "github.com/nebuly-ai/nos/pkg/api/scheduler/v1beta3"
 	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
 	gpumig "github.com/nebuly-ai/nos/pkg/gpu/mig"
-	"github.com/nebuly-ai/nos/pkg/scheduler/plugins/capacityscheduling"
 	testutil "github.com/nebuly-ai/nos/pkg/test/util"
 	"github.com/nebuly-ai/nos/pkg/util"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
-	schedulerconfig "k8s.io/kubernetes/pkg/scheduler/apis/config"
-	latestschedulerconfig "k8s.io/kubernetes/pkg/scheduler/apis/config/latest"
-	schedulerscheme "k8s.io/kubernetes/pkg/scheduler/apis/config/scheme"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
-	schedulerplugins "k8s.io/kubernetes/pkg/scheduler/framework/plugins"
 	schedulerruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
 	"os"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sync"
 	"time"
 	// Ensure scheduler package is initialized.
 	_ "github.com/nebuly-ai/nos/pkg/api/scheduler"
@@ -122,7 +120,7 @@ func main() {
 
 	// Setup known MIG geometries
 	if config.KnownMigGeometriesFile != "" {
-		knownGeometries, err := loadKnownMigGeometriesFromFile(config.KnownMigGeometriesFile)
+		knownGeometries, err := gpumig.LoadAllowedMigGeometriesFromFile(config.KnownMigGeometriesFile)
 		if err != nil {
 			setupLog.Error(err, "unable to load known MIG geometries")
 			os.Exit(1)
@@ -152,10 +150,15 @@ func main() {
 	clusterState := state.NewEmptyClusterState()
 
 	// Setup state controllers
+	migNodeInitializer := mig.NewNodeInitializer(mgr.GetClient())
+	if config.DryRun {
+		recorder := mgr.GetEventRecorderFor(constant.GpuPartitionerEventRecorderName)
+		migNodeInitializer = mig.NewDryRunNodeInitializer(recorder)
+	}
 	nodeController := gpupartitioner.NewNodeController(
 		mgr.GetClient(),
 		mgr.GetScheme(),
-		mig.NewNodeInitializer(mgr.GetClient()),
+		migNodeInitializer,
 		clusterState,
 	)
 	if err = nodeController.SetupWithManager(mgr, constant.ClusterStateNodeControllerName); err != nil {
@@ -211,13 +214,143 @@ func main() {
 		}
 	}()
 
+	// Init planners
+	tenants := core.NewElasticQuotaTenantGetter(mgr.GetClient())
+	var migPlanner, mpsPlanner, hybridPlanner core.Planner
+	switch config.Planner {
+	case configv1alpha1.PlannerKindOptimal:
+		timeout := config.OptimalPlannerTimeoutSeconds * time.Second
+		setupLog.Info("using optimal planner", "timeout", timeout.String())
+		migPlanner = mig.NewOptimalPlanner(schedulerFramework, config.PodSorter, tenants, timeout)
+		mpsPlanner = mps.NewOptimalPlanner(schedulerFramework, config.PodSorter, tenants, timeout)
+		hybridPlanner = hybrid.NewOptimalPlanner(schedulerFramework, config.PodSorter, tenants, timeout)
+	default:
+		setupLog.Info("using greedy planner")
+		migPlanner = mig.NewPlanner(schedulerFramework, config.PodSorter, tenants)
+		mpsPlanner = mps.NewPlanner(schedulerFramework, config.PodSorter, tenants)
+		hybridPlanner = hybrid.NewPlanner(schedulerFramework, config.PodSorter, tenants)
+	}
+	if config.PreemptionEnabled {
+		setupLog.Info("preemption enabled, MIG plans can evict over-quota pods")
+		migPlanner = mig.NewPreemptionPlanner(migPlanner, schedulerFramework, config.PodSorter, tenants)
+	}
+
+	// Init actuators
+	recorder := mgr.GetEventRecorderFor(constant.GpuPartitionerEventRecorderName)
+	var migActuator, mpsActuator, hybridActuator core.Actuator
+	if config.DryRun {
+		setupLog.Info("dry run enabled, partitioning plans will only be recorded as events on the nodes")
+		migActuator = core.NewDryRunActuator(mgr.GetClient(), recorder)
+		mpsActuator = core.NewDryRunActuator(mgr.GetClient(), recorder)
+		hybridActuator = core.NewDryRunActuator(mgr.GetClient(), recorder)
+	} else {
+		devicePluginDelay := config.DevicePluginDelaySeconds * time.Second
+		migActuator = mig.NewActuator(mgr.GetClient())
+		mpsActuator = mps.NewActuator(mgr.GetClient(), devicePluginCM, devicePluginDelay)
+		hybridActuator = hybrid.NewActuator(mgr.GetClient(), devicePluginCM, devicePluginDelay)
+		migActuator = core.NewEventRecordingActuator(mgr.GetClient(), recorder, migActuator)
+		mpsActuator = core.NewEventRecordingActuator(mgr.GetClient(), recorder, mpsActuator)
+		hybridActuator = core.NewEventRecordingActuator(mgr.GetClient(), recorder, hybridActuator)
+		if config.PreemptionEnabled {
+			migActuator = core.NewEvictingActuator(k8sClient, migActuator)
+		}
+	}
+
+	// Changes to the partitioning of the nodes of each kind are serialized across the
+	// partitioner controllers, the plan controllers and the compaction loop
+	var migPartitioningMtx, mpsPartitioningMtx, hybridPartitioningMtx sync.Mutex
+
+	// Setup partitioning plan controllers, which apply the approved plans and track their status
+	planApprovalTimeout := config.PlanApprovalTimeoutSeconds * time.Second
+	planControllers := map[string]gpupartitioner.PlanController{
+		constant.MigPlanControllerName: mig.NewPlanController(
+			mgr.GetClient(),
+			clusterState,
+			migActuator,
+			planApprovalTimeout,
+			&migPartitioningMtx,
+		),
+		constant.MpsPlanControllerName: mps.NewPlanController(
+			mgr.GetClient(),
+			clusterState,
+			mpsActuator,
+			planApprovalTimeout,
+			&mpsPartitioningMtx,
+		),
+		constant.HybridPlanControllerName: hybrid.NewPlanController(
+			mgr.GetClient(),
+			clusterState,
+			hybridActuator,
+			planApprovalTimeout,
+			&hybridPartitioningMtx,
+		),
+	}
+	for name, planController := range planControllers {
+		planController := planController
+		if err = planController.SetupWithManager(mgr, name); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", name)
+			os.Exit(1)
+		}
+	}
+
+	// Store partitioning plans as PartitioningPlan resources before applying them
+	setupLog.Info(
+		"partitioning plans approval",
+		"policy",
+		config.PlanApprovalPolicy,
+		"historyLimit",
+		config.GetPlanHistoryLimit(),
+		"approvalTimeout",
+		planApprovalTimeout.String(),
+	)
+	recordActuator := func(kind gpu.PartitioningKind, actuator core.Actuator) core.Actuator {
+		return core.NewRecordingActuator(
+			mgr.GetClient(),
+			kind,
+			actuator,
+			config.PlanApprovalPolicy,
+			config.GetPlanHistoryLimit(),
+		)
+	}
+	migActuator = recordActuator(gpu.PartitioningKindMig, migActuator)
+	mpsActuator = recordActuator(gpu.PartitioningKindMps, mpsActuator)
+	hybridActuator = recordActuator(gpu.PartitioningKindHybrid, hybridActuator)
+
+	// Setup MIG compaction loop
+	if config.MigCompactionEnabled {
+		compactionInterval := config.MigCompactionIntervalSeconds * time.Second
+		compactionQuietPeriod := config.MigCompactionQuietPeriodSeconds * time.Second
+		setupLog.Info(
+			"MIG compaction enabled",
+			"interval",
+			compactionInterval.String(),
+			"quietPeriod",
+			compactionQuietPeriod.String(),
+		)
+		compactionLoop := mig.NewCompactionLoop(
+			mgr.GetClient(),
+			clusterState,
+			migActuator,
+			compactionInterval,
+			compactionQuietPeriod,
+			&migPartitioningMtx,
+		)
+		if err = mgr.Add(compactionLoop); err != nil {
+			setupLog.Error(err, "unable to add MIG compaction loop to manager")
+			os.Exit(1)
+		}
+	}
+
 	// Setup MIG controller
 	migController := mig.NewController(
 		mgr.GetScheme(),
 		mgr.GetClient(),
 		podBatcher,
 		clusterState,
-		schedulerFramework,
+		migPlanner,
+		migActuator,
+		recorder,
+		&migPartitioningMtx,
 	)
 	if err = migController.SetupWithManager(mgr, constant.MigPartitionerControllerName); err != nil {
 		setupLog.Error(
@@ -235,9 +368,10 @@ func main() {
 		mgr.GetClient(),
 		podBatcher,
 		clusterState,
-		schedulerFramework,
-		devicePluginCM,
-		config.DevicePluginDelaySeconds*time.Second,
+		mpsPlanner,
+		mpsActuator,
+		recorder,
+		&mpsPartitioningMtx,
 	)
 	if err = mpsSlicingController.SetupWithManager(mgr, constant.MpsPartitionerControllerName); err != nil {
 		setupLog.Error(
@@ -249,6 +383,27 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Setup hybrid controller
+	hybridController := hybrid.NewController(
+		mgr.GetScheme(),
+		mgr.GetClient(),
+		podBatcher,
+		clusterState,
+		hybridPlanner,
+		hybridActuator,
+		recorder,
+		&hybridPartitioningMtx,
+	)
+	if err = hybridController.SetupWithManager(mgr, constant.HybridPartitionerControllerName); err != nil {
+		setupLog.Error(
+			err,
+			"unable to create controller",
+			"controller",
+			constant.HybridPartitionerControllerName,
+		)
+		os.Exit(1)
+	}
+
 	// Setup health checks
 	if err = mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -295,86 +450,22 @@ func newSchedulerFramework(ctx context.Context, config configv1alpha1.GpuPartiti
 	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
 
 	// Configure scheduler profile
-	profile, err := getSchedulerProfile(config)
+	profile, err := nosscheduler.GetProfile(config.SchedulerConfigFile)
 	if err != nil {
 		return nil, err
 	}
-	setupLog.V(1).Info("scheduler profile", "profile", profile)
-
-	// Register capacity scheduling plugin
-	var registry = schedulerplugins.NewInTreeRegistry()
-	if err = registry.Register(capacityscheduling.Name, capacityscheduling.New); err != nil {
-		return nil, fmt.Errorf("couldn't register Capacity Scheduling plugin: %v", err)
+	if config.SchedulerConfigFile == "" {
+		setupLog.Info("scheduler configured with default profile")
+	} else {
+		setupLog.Info("scheduler configured with custom profile")
 	}
+	setupLog.V(1).Info("scheduler profile", "profile", profile)
 
-	return schedulerruntime.NewFramework(
-		registry,
-		&profile,
-		ctx.Done(),
+	return nosscheduler.NewFramework(
+		ctx,
+		profile,
 		schedulerruntime.WithInformerFactory(informerFactory),
 		schedulerruntime.WithKubeConfig(ctrl.GetConfigOrDie()),
 		schedulerruntime.WithSnapshotSharedLister(testutil.NewFakeSharedLister(make([]*v1.Pod, 0), make([]*v1.Node, 0))),
 	)
 }
-
-func getSchedulerProfile(config configv1alpha1.GpuPartitionerConfig) (schedulerconfig.KubeSchedulerProfile, error) {
-	// If scheduler config is not provided, use default scheduler config
-	if config.SchedulerConfigFile == "" {
-		defaultSchedulerConfig, err := latestschedulerconfig.Default()
-		if err != nil {
-			return schedulerconfig.KubeSchedulerProfile{}, fmt.Errorf("couldn't create scheduler config: %v", err)
-		}
-		if len(defaultSchedulerConfig.Profiles) != 1 || defaultSchedulerConfig.Profiles[0].SchedulerName != v1.DefaultSchedulerName {
-			return schedulerconfig.KubeSchedulerProfile{}, fmt.Errorf(
-				"unexpected scheduler config: expected default scheduler profile only (found %d profiles)",
-				len(defaultSchedulerConfig.Profiles),
-			)
-		}
-		setupLog.Info("scheduler configured with default profile")
-		return defaultSchedulerConfig.Profiles[0], nil
-	}
-
-	// Otherwise, use the scheduler config provided in the GpuPartitionerConfig
-	schedulerConfig, err := loadSchedulerConfigFromFile(config.SchedulerConfigFile)
-	if err != nil {
-		return schedulerconfig.KubeSchedulerProfile{}, fmt.Errorf(
-			"couldn't load scheduler config: %v",
-			err,
-		)
-	}
-	profile := schedulerConfig.Profiles[0]
-	setupLog.Info("scheduler configured with custom profile")
-	return profile, nil
-}
-
-func loadSchedulerConfigFromFile(file string) (*schedulerconfig.KubeSchedulerConfiguration, error) {
-	data, err := os.ReadFile(file)
-	if err != nil {
-		return nil, err
-	}
-	return decodeSchedulerConfig(data)
-}
-
-func decodeSchedulerConfig(data []byte) (*schedulerconfig.KubeSchedulerConfiguration, error) {
-	// The UniversalDecoder runs defaulting and returns the internal type by default.
-	obj, gvk, err := schedulerscheme.Codecs.UniversalDecoder().Decode(data, nil, nil)
-	if err != nil {
-		return nil, err
-	}
-	if cfgObj, ok := obj.(*schedulerconfig.KubeSchedulerConfiguration); ok {
-		return cfgObj, nil
-	}
-	return nil, fmt.Errorf("couldn't decode as KubeSchedulerConfiguration, got %s: ", gvk)
-}
-
-func loadKnownMigGeometriesFromFile(file string) (gpumig.AllowedMigGeometriesList, error) {
-	data, err := os.ReadFile(file)
-	if err != nil {
-		return nil, err
-	}
-	var allowedGeometries = make(gpumig.AllowedMigGeometriesList, 0)
-	if err = yaml.Unmarshal(data, &allowedGeometries); err != nil {
-		return nil, err
-	}
-	return allowedGeometries, nil
-}
@@ -26,6 +26,7 @@ import (
 	configv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
 	"github.com/nebuly-ai/nos/pkg/gpu/mig"
 	"github.com/nebuly-ai/nos/pkg/gpu/nvml"
 	"github.com/nebuly-ai/nos/pkg/resource"
@@ -56,10 +57,17 @@ func init() {
 func main() {
 	// Setup CLI args
 	var configFile string
+	var fakeGpuModel string
+	var fakeGpuCount int
 	flag.StringVar(&configFile, "config", "",
 		"The controller will load its initial configuration from this file. "+
 			"Omit this flag to use the default configuration values. "+
 			"Command-line flags override configuration from this file.")
+	flag.StringVar(&fakeGpuModel, "fake-gpu-model", "",
+		"If set, the agent simulates in memory MIG-enabled GPUs of this model instead of using NVML. "+
+			"Intended for testing the agent on nodes without GPUs.")
+	flag.IntVar(&fakeGpuCount, "fake-gpu-count", 1,
+		"The number of GPUs simulated when --fake-gpu-model is set.")
 	opts := zap.Options{}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
@@ -106,16 +114,19 @@ func main() {
 	var sharedState = migagent.NewSharedState()
 
 	// Init MIG client
-	lister, err := resource.NewPodResourcesListerClient(
-		constant.DefaultPodResourcesTimeout,
-		constant.DefaultPodResourcesMaxMsgSize,
-	)
-	resourceClient := resource.NewClient(lister)
-	setupLog.Info("Initializing NVML client")
-	nvmlClient := nvml.NewClient(ctrl.Log.WithName("NvmlClient"))
+	nvmlClient, resourceClient, devicePluginClient, err := newClients(mgr.GetClient(), fakeGpuModel, fakeGpuCount)
+	if err != nil {
+		setupLog.Error(err, "unable to initialize clients")
+		os.Exit(1)
+	}
 	migClient := mig.NewClient(resourceClient, nvmlClient)
 
-	if err = initAgent(ctx, nvmlClient, migClient); err != nil {
+	hybridNode, err := isHybridNode(ctx, mgr.GetAPIReader(), nodeName)
+	if err != nil {
+		setupLog.Error(err, "unable to fetch node", "node", nodeName)
+		os.Exit(1)
+	}
+	if err = initAgent(ctx, nvmlClient, migClient, hybridNode); err != nil {
 		setupLog.Error(err, "unable to initialize agent")
 		os.Exit(1)
 	}
@@ -126,6 +137,7 @@ func main() {
 		migClient,
 		sharedState,
 		migAgentConfig.ReportConfigIntervalSeconds*time.Second,
+		migAgentConfig.DiscoverAllowedMigGeometries,
 	)
 	if err = migReporter.SetupWithManager(mgr, "reporter", nodeName); err != nil {
 		setupLog.Error(err, "unable to create MIG Reporter")
@@ -136,6 +148,7 @@ func main() {
 	migActuator := migagent.NewActuator(
 		mgr.GetClient(),
 		migClient,
+		devicePluginClient,
 		sharedState,
 		nodeName,
 	)
@@ -162,10 +175,41 @@ func main() {
 	}
 }
 
-func initAgent(ctx context.Context, nvmlClient nvml.Client, migClient mig.Client) error {
-	setupLog.Info("Checking MIG-enabled GPUs")
-	if err := checkAtLeastOneMigGpu(nvmlClient); err != nil {
-		return err
+// newClients returns the clients used by the agent for managing the GPUs of the node. If fakeGpuModel
+// is set, the clients simulate in memory the GPUs instead of using NVML, the Kubelet and the NVIDIA device plugin.
+func newClients(
+	k8sClient client.Client,
+	fakeGpuModel string,
+	fakeGpuCount int,
+) (nvml.Client, resource.Client, gpu.DevicePluginClient, error) {
+	if fakeGpuModel == "" {
+		setupLog.Info("Initializing NVML client")
+		nvmlClient := nvml.NewClient(ctrl.Log.WithName("NvmlClient"))
+		lister, err := resource.NewPodResourcesListerClient(
+			constant.DefaultPodResourcesTimeout,
+			constant.DefaultPodResourcesMaxMsgSize,
+		)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("unable to initialize pod resources lister client: %w", err)
+		}
+		return nvmlClient, resource.NewClient(lister), gpu.NewDevicePluginClient(k8sClient), nil
+	}
+	setupLog.Info("Initializing fake NVML client", "model", fakeGpuModel, "count", fakeGpuCount)
+	nvmlClient, err := nvml.NewFakeClient(gpu.Model(fakeGpuModel), fakeGpuCount)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return nvmlClient, mig.NewFakeResourceClient(nvmlClient), gpu.NewNoopDevicePluginClient(), nil
+}
+
+func initAgent(ctx context.Context, nvmlClient nvml.Client, migClient mig.Client, hybridNode bool) error {
+	// On nodes with hybrid partitioning the GPUs without MIG mode are partitioned with MPS,
+	// so MIG mode could be disabled on all of them
+	if !hybridNode {
+		setupLog.Info("Checking MIG-enabled GPUs")
+		if err := checkAtLeastOneMigGpu(nvmlClient); err != nil {
+			return err
+		}
 	}
 
 	setupLog.Info("Cleaning up unused MIG resources")
@@ -176,6 +220,15 @@ func initAgent(ctx context.Context, nvmlClient nvml.Client, migClient mig.Client
 	return nil
 }
 
+// isHybridNode returns true if the node with the name provided as argument has hybrid GPU partitioning enabled
+func isHybridNode(ctx context.Context, reader client.Reader, nodeName string) (bool, error) {
+	var node v1.Node
+	if err := reader.Get(ctx, client.ObjectKey{Name: nodeName}, &node); err != nil {
+		return false, err
+	}
+	return gpu.IsHybridPartitioningEnabled(node), nil
+}
+
 func checkAtLeastOneMigGpu(nvmlClient nvml.Client) error {
 	migGpus, err := nvmlClient.GetMigEnabledGPUs()
 	if err != nil {
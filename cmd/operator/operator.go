@@ -30,6 +30,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -72,6 +73,8 @@ func main() {
 		}
 	}
 	setupLog.Info(fmt.Sprintf("using nvidiaGpuResourceMemoryGB=%d", controllerConfig.NvidiaGpuResourceMemoryGB))
+	setupLog.Info(fmt.Sprintf("using nvidiaGpuModelsMemoryGB=%v", controllerConfig.NvidiaGpuModelsMemoryGB))
+	setupLog.Info(fmt.Sprintf("using rejectOvercommittingQuotas=%t", controllerConfig.RejectOvercommittingQuotas))
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
@@ -79,17 +82,39 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Setup overcommit guard
+	overcommitGuard := elasticquota.NewOvercommitGuard(
+		mgr.GetClient(),
+		controllerConfig.NvidiaGpuResourceMemoryGB,
+		controllerConfig.NvidiaGpuModelsMemoryGB,
+	)
+	if err = mgr.Add(overcommitGuard); err != nil {
+		setupLog.Error(err, "unable to add overcommit guard")
+		os.Exit(1)
+	}
+	var overcommitChecker v1alpha1.OvercommitChecker
+	if controllerConfig.RejectOvercommittingQuotas {
+		overcommitChecker = overcommitGuard
+	}
+
 	// Setup ElasticQuota
+	kubeClient := kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie())
 	elasticQuotaReconciler := elasticquota.NewElasticQuotaReconciler(
 		mgr.GetClient(),
+		kubeClient,
 		mgr.GetScheme(),
 		controllerConfig.NvidiaGpuResourceMemoryGB,
+		controllerConfig.NvidiaGpuModelsMemoryGB,
 	)
 	if err = elasticQuotaReconciler.SetupWithManager(mgr, constant.ElasticQuotaControllerName); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ElasticQuota")
 		os.Exit(1)
 	}
-	if err = (&v1alpha1.ElasticQuota{}).SetupWebhookWithManager(mgr); err != nil {
+	if err = (&v1alpha1.ElasticQuota{}).SetupWebhookWithManager(
+		mgr,
+		controllerConfig.NvidiaGpuResourceMemoryGB,
+		overcommitChecker,
+	); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "ElasticQuota")
 		os.Exit(1)
 	}
@@ -97,18 +122,33 @@ func main() {
 	// Setup CompositeElasticQuota
 	compositeElasticQuotaReconciler := elasticquota.NewCompositeElasticQuotaReconciler(
 		mgr.GetClient(),
+		kubeClient,
 		mgr.GetScheme(),
 		controllerConfig.NvidiaGpuResourceMemoryGB,
+		controllerConfig.NvidiaGpuModelsMemoryGB,
 	)
 	if err = compositeElasticQuotaReconciler.SetupWithManager(mgr, constant.CompositeElasticQuotaControllerName); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CompositeElasticQuota")
 		os.Exit(1)
 	}
-	if err = (&v1alpha1.CompositeElasticQuota{}).SetupWebhookWithManager(mgr); err != nil {
+	if err = (&v1alpha1.CompositeElasticQuota{}).SetupWebhookWithManager(
+		mgr,
+		controllerConfig.NvidiaGpuResourceMemoryGB,
+		overcommitChecker,
+	); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "CompositeElasticQuota")
 		os.Exit(1)
 	}
 
+	// Setup usage report server
+	if controllerConfig.UsageReportBindAddress != "" {
+		usageReportServer := elasticquota.NewUsageReportServer(mgr.GetClient(), controllerConfig.UsageReportBindAddress)
+		if err = mgr.Add(usageReportServer); err != nil {
+			setupLog.Error(err, "unable to add usage report server")
+			os.Exit(1)
+		}
+	}
+
 	if err = mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -0,0 +1,234 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/hybrid"
+	"github.com/nebuly-ai/nos/internal/partitioning/mig"
+	"github.com/nebuly-ai/nos/internal/partitioning/mps"
+	"github.com/nebuly-ai/nos/internal/partitioning/whatif"
+	nosscheduler "github.com/nebuly-ai/nos/internal/scheduler"
+	configv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	gpumig "github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/scheduler/plugins/capacityscheduling"
+	testutil "github.com/nebuly-ai/nos/pkg/test/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	schedulerruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	"os"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"time"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var setupLog = ctrl.Log.WithName("setup")
+
+func main() {
+	// Setup CLI args
+	var dumpFile string
+	var partitioningKind string
+	var plannerKind string
+	var optimalPlannerTimeout time.Duration
+	var podSorter string
+	var schedulerConfigFile string
+	var knownMigGeometriesFile string
+	var output string
+	flag.StringVar(&dumpFile, "dump", "",
+		"Path to the YAML or JSON file containing the nodes and the pods of the cluster, "+
+			"for instance the output of \"kubectl get nodes,pods -A -o yaml\". "+
+			"Only the running pods are considered as using the resources of their nodes, while the pending pods "+
+			"are considered by the GPU partitioner only if the scheduler marked them as unschedulable.")
+	flag.StringVar(&partitioningKind, "partitioning", gpu.PartitioningKindMig.String(),
+		"The kind of GPU partitioning to simulate: \"mig\", \"mps\" or \"hybrid\".")
+	flag.StringVar(&plannerKind, "planner", string(configv1alpha1.PlannerKindGreedy),
+		"The planner used for computing the partitioning plan: \"greedy\" or \"optimal\".")
+	flag.DurationVar(&optimalPlannerTimeout, "optimal-planner-timeout", 5*time.Second,
+		"Time budget of the optimal planner.")
+	flag.StringVar(&podSorter, "pod-sorter", string(configv1alpha1.PodSorterKindPriority),
+		"The strategy used for sorting the pending pods: \"priority\", \"fifo\", \"largest-first\", \"fair-share\" or \"gang\".")
+	flag.StringVar(&schedulerConfigFile, "scheduler-config", "",
+		"Optional path to the k8s scheduler configuration file. If not provided, the default scheduler profile is used.")
+	flag.StringVar(&knownMigGeometriesFile, "known-mig-geometries", "",
+		"Optional path to the file containing the possible MIG geometries of each known GPU model.")
+	flag.StringVar(&output, "output", outputText,
+		"Output format of the report: \"text\" or \"json\".")
+	opts := zap.Options{}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts), zap.WriteTo(os.Stderr)))
+
+	if dumpFile == "" {
+		setupLog.Error(fmt.Errorf("flag \"dump\" is required"), "invalid arguments")
+		os.Exit(1)
+	}
+	if output != outputText && output != outputJSON {
+		setupLog.Error(fmt.Errorf("invalid output format %q", output), "invalid arguments")
+		os.Exit(1)
+	}
+
+	// Setup known MIG geometries
+	if knownMigGeometriesFile != "" {
+		knownGeometries, err := gpumig.LoadAllowedMigGeometriesFromFile(knownMigGeometriesFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load known MIG geometries")
+			os.Exit(1)
+		}
+		if err = gpumig.SetKnownGeometries(knownGeometries.GroupByModel()); err != nil {
+			setupLog.Error(err, "unable to set known MIG geometries")
+			os.Exit(1)
+		}
+	}
+
+	// Load cluster dump
+	dump, err := whatif.LoadClusterDump(dumpFile)
+	if err != nil {
+		setupLog.Error(err, "unable to load cluster dump")
+		os.Exit(1)
+	}
+	setupLog.Info("loaded cluster dump", "nodes", len(dump.Nodes), "pods", len(dump.Pods))
+
+	// Init scheduler
+	ctx, cancel := context.WithCancel(ctrl.LoggerInto(context.Background(), ctrl.Log))
+	defer cancel()
+	schedulerFramework, err := newSchedulerFramework(ctx, schedulerConfigFile, dump)
+	if err != nil {
+		setupLog.Error(err, "unable to init k8s scheduler framework")
+		os.Exit(1)
+	}
+
+	// Init snapshot taker and planner
+	snapshotTaker, planner, err := newSnapshotTakerAndPlanner(
+		partitioningKind,
+		configv1alpha1.PlannerKind(plannerKind),
+		configv1alpha1.PodSorterKind(podSorter),
+		optimalPlannerTimeout,
+		schedulerFramework,
+	)
+	if err != nil {
+		setupLog.Error(err, "invalid arguments")
+		os.Exit(1)
+	}
+
+	// Run simulation
+	report, err := whatif.Simulate(ctx, dump, snapshotTaker, planner, schedulerFramework)
+	if err != nil {
+		setupLog.Error(err, "simulation failed")
+		os.Exit(1)
+	}
+	if output == outputJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		setupLog.Error(err, "unable to write report")
+		os.Exit(1)
+	}
+}
+
+func newSnapshotTakerAndPlanner(
+	partitioningKind string,
+	plannerKind configv1alpha1.PlannerKind,
+	sorterKind configv1alpha1.PodSorterKind,
+	optimalPlannerTimeout time.Duration,
+	schedulerFramework framework.Framework,
+) (core.SnapshotTaker, core.Planner, error) {
+	if plannerKind != configv1alpha1.PlannerKindGreedy && plannerKind != configv1alpha1.PlannerKindOptimal {
+		return nil, nil, fmt.Errorf("invalid planner %q", plannerKind)
+	}
+	switch sorterKind {
+	case configv1alpha1.PodSorterKindPriority,
+		configv1alpha1.PodSorterKindFifo,
+		configv1alpha1.PodSorterKindLargestFirst,
+		configv1alpha1.PodSorterKindFairShare,
+		configv1alpha1.PodSorterKindGang:
+	default:
+		return nil, nil, fmt.Errorf("invalid pod sorter %q", sorterKind)
+	}
+	optimal := plannerKind == configv1alpha1.PlannerKindOptimal
+	// The dump does not include the quotas, so the fair-share sorter considers the namespaces as tenants
+	var tenants core.TenantGetter
+
+	switch partitioningKind {
+	case gpu.PartitioningKindMig.String():
+		if optimal {
+			return mig.NewSnapshotTaker(), mig.NewOptimalPlanner(schedulerFramework, sorterKind, tenants, optimalPlannerTimeout), nil
+		}
+		return mig.NewSnapshotTaker(), mig.NewPlanner(schedulerFramework, sorterKind, tenants), nil
+	case gpu.PartitioningKindMps.String():
+		if optimal {
+			return mps.NewSnapshotTaker(), mps.NewOptimalPlanner(schedulerFramework, sorterKind, tenants, optimalPlannerTimeout), nil
+		}
+		return mps.NewSnapshotTaker(), mps.NewPlanner(schedulerFramework, sorterKind, tenants), nil
+	case gpu.PartitioningKindHybrid.String():
+		if optimal {
+			return hybrid.NewSnapshotTaker(), hybrid.NewOptimalPlanner(schedulerFramework, sorterKind, tenants, optimalPlannerTimeout), nil
+		}
+		return hybrid.NewSnapshotTaker(), hybrid.NewPlanner(schedulerFramework, sorterKind, tenants), nil
+	default:
+		return nil, nil, fmt.Errorf("invalid partitioning kind %q", partitioningKind)
+	}
+}
+
+// newSchedulerFramework returns a scheduler framework set up like the one of the GPU partitioner,
+// but backed by a fake client containing only the objects of the cluster dump.
+func newSchedulerFramework(ctx context.Context, schedulerConfigFile string, dump whatif.ClusterDump) (framework.Framework, error) {
+	profile, err := nosscheduler.GetProfile(schedulerConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	// Capacity Scheduling needs to watch the ElasticQuotas of a live cluster
+	if nosscheduler.DisablePlugin(&profile, capacityscheduling.Name) {
+		setupLog.Info("plugin disabled since it requires a live cluster, elastic quotas are not taken into account", "plugin", capacityscheduling.Name)
+	}
+
+	objects := make([]runtime.Object, 0, len(dump.Nodes)+len(dump.Pods))
+	nodes := make([]*v1.Node, 0, len(dump.Nodes))
+	pods := make([]*v1.Pod, 0, len(dump.Pods))
+	for i := range dump.Nodes {
+		objects = append(objects, &dump.Nodes[i])
+		nodes = append(nodes, &dump.Nodes[i])
+	}
+	for i := range dump.Pods {
+		objects = append(objects, &dump.Pods[i])
+		if dump.Pods[i].Spec.NodeName != "" {
+			pods = append(pods, &dump.Pods[i])
+		}
+	}
+	kubeClient := fake.NewSimpleClientset(objects...)
+
+	return nosscheduler.NewFramework(
+		ctx,
+		profile,
+		schedulerruntime.WithClientSet(kubeClient),
+		schedulerruntime.WithInformerFactory(informers.NewSharedInformerFactory(kubeClient, 0)),
+		schedulerruntime.WithSnapshotSharedLister(testutil.NewFakeSharedLister(pods, nodes)),
+	)
+}
@@ -25,8 +25,11 @@ import (
 	"github.com/nebuly-ai/nos/pkg/util"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -36,6 +39,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"time"
 )
 
 // CompositeElasticQuotaReconciler reconciles a CompositeElasticQuota object
@@ -46,9 +50,17 @@ type CompositeElasticQuotaReconciler struct {
 	podsReconciler     *elasticQuotaPodsReconciler
 }
 
-func NewCompositeElasticQuotaReconciler(client client.Client, scheme *runtime.Scheme, nvidiaGpuResourceMemoryGB int64) CompositeElasticQuotaReconciler {
+func NewCompositeElasticQuotaReconciler(
+	client client.Client,
+	kubeClient kubernetes.Interface,
+	scheme *runtime.Scheme,
+	nvidiaGpuResourceMemoryGB int64,
+	nvidiaGpuModelsMemoryGB map[string]int64,
+) CompositeElasticQuotaReconciler {
 	resourceCalculator := gpu_util.ResourceCalculator{
 		NvidiaGPUDeviceMemoryGB: nvidiaGpuResourceMemoryGB,
+		NvidiaGPUModelsMemoryGB: nvidiaGpuModelsMemoryGB,
+		Nodes:                   gpu_util.NewClientNodeGetter(client),
 	}
 	return CompositeElasticQuotaReconciler{
 		Client:             client,
@@ -56,6 +68,7 @@ func NewCompositeElasticQuotaReconciler(client client.Client, scheme *runtime.Sc
 		resourceCalculator: &resourceCalculator,
 		podsReconciler: &elasticQuotaPodsReconciler{
 			c:                  client,
+			kubeClient:         kubeClient,
 			resourceCalculator: &resourceCalculator,
 		},
 	}
@@ -65,7 +78,9 @@ func NewCompositeElasticQuotaReconciler(client client.Client, scheme *runtime.Sc
 //+kubebuilder:rbac:groups=nos.nebuly.com,resources=elasticquotas,verbs=list;delete
 //+kubebuilder:rbac:groups=nos.nebuly.com,resources=compositeelasticquotas/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=nos.nebuly.com,resources=compositeelasticquotas/finalizers,verbs=update
-//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
 
 func (r *CompositeElasticQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -73,6 +88,9 @@ func (r *CompositeElasticQuotaReconciler) Reconcile(ctx context.Context, req ctr
 	// Fetch CEQ instance
 	var instance v1alpha1.CompositeElasticQuota
 	if err := r.Client.Get(ctx, req.NamespacedName, &instance); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.podsReconciler.ForgetBlockedEvictions(req.NamespacedName.String())
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
@@ -88,23 +106,50 @@ func (r *CompositeElasticQuotaReconciler) Reconcile(ctx context.Context, req ctr
 		return ctrl.Result{}, err
 	}
 
+	// Compute the limits in effect according to the time windows of the CompositeElasticQuota
+	now := time.Now().Truncate(time.Second)
+	limits, err := instance.Spec.GetLimits(now)
+	if err != nil {
+		logger.Error(err, "ignoring invalid time windows")
+	}
+
+	// Evict the pods exceeding a Max lowered by a time window since the last reconciliation
+	// or whose eviction has been previously blocked by a disruption budget
+	quotaKey := req.NamespacedName.String()
+	if limits.Max == nil {
+		r.podsReconciler.ForgetBlockedEvictions(quotaKey)
+	} else if r.podsReconciler.HasBlockedEvictions(quotaKey) || isMaxDecreasedSince(instance.Spec, instance.Status.LastAccountingTime, limits.Max) {
+		if pods, err = r.podsReconciler.EvictPodsOverMax(ctx, quotaKey, pods, limits.Max); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Update pods in EQ namespaces and compute used quota
 	used, err := r.podsReconciler.PatchPodsAndComputeUsedQuota(
 		ctx,
 		pods,
-		instance.Spec.Min,
-		instance.Spec.Max,
+		limits.Min,
+		limits.Max,
 	)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	// Account the GPU resources consumed by the running pods
+	instance.Status.Usage = r.podsReconciler.AccountUsage(
+		instance.Status.Usage,
+		pods,
+		instance.Status.LastAccountingTime,
+		now,
+	)
+	instance.Status.LastAccountingTime = &metav1.Time{Time: now}
+
 	// Update status
 	instance.Status.Used = used
 	if err = r.updateStatus(ctx, instance); err != nil {
 		return ctrl.Result{}, err
 	}
-	return ctrl.Result{}, nil
+	return newReconcileResult(limits, len(pods), r.podsReconciler.HasBlockedEvictions(quotaKey), now), nil
 }
 
 // deleteOverlappingElasticQuotas deletes any ElasticQuota existing in one of the namespaces specified by the
@@ -182,7 +227,8 @@ func (r *CompositeElasticQuotaReconciler) updateStatus(ctx context.Context, inst
 // SetupWithManager sets up the controller with the Manager.
 func (r *CompositeElasticQuotaReconciler) SetupWithManager(mgr ctrl.Manager, name string) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.CompositeElasticQuota{}).
+		// Status updates are ignored, since the status is updated at every reconciliation
+		For(&v1alpha1.CompositeElasticQuota{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Named(name).
 		Watches(
 			&source.Kind{Type: &v1.Pod{}},
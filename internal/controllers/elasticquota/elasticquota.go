@@ -23,16 +23,34 @@ import (
 	"github.com/nebuly-ai/nos/pkg/constant"
 	"github.com/nebuly-ai/nos/pkg/resource"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	quota "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sort"
+	"sync"
+	"time"
 )
 
+// blockedEvictionRetryInterval is the interval after which the eviction of the pods
+// exceeding the Max of a quota is retried if it has been blocked by a PodDisruptionBudget
+const blockedEvictionRetryInterval = 10 * time.Second
+
 type elasticQuotaPodsReconciler struct {
 	c                  client.Client
+	kubeClient         kubernetes.Interface
 	resourceCalculator resource.Calculator
+
+	// blockedEvictions contains the keys of the quotas with Pods exceeding the Max
+	// whose eviction has been blocked by a PodDisruptionBudget
+	blockedEvictions    sets.String
+	blockedEvictionsMtx sync.Mutex
 }
 
 func (r *elasticQuotaPodsReconciler) PatchPodsAndComputeUsedQuota(ctx context.Context,
@@ -45,8 +63,9 @@ func (r *elasticQuotaPodsReconciler) PatchPodsAndComputeUsedQuota(ctx context.Co
 
 	used := newZeroUsed(quotaMin, quotaMax)
 	var err error
-	for _, pod := range pods {
-		request := r.resourceCalculator.ComputePodRequest(pod)
+	for i := range pods {
+		pod := &pods[i]
+		request := r.resourceCalculator.ComputePodRequest(*pod)
 		used = quota.Add(used, request)
 
 		var desiredCapacityInfo constant.CapacityInfo
@@ -56,7 +75,7 @@ func (r *elasticQuotaPodsReconciler) PatchPodsAndComputeUsedQuota(ctx context.Co
 			desiredCapacityInfo = constant.CapacityInfoOverQuota
 		}
 
-		if _, err = r.patchCapacityInfoIfDifferent(ctx, &pod, desiredCapacityInfo); err != nil {
+		if _, err = r.patchCapacityInfoIfDifferent(ctx, pod, desiredCapacityInfo); err != nil {
 			return nil, err
 		}
 	}
@@ -71,6 +90,114 @@ func (r *elasticQuotaPodsReconciler) PatchPodsAndComputeUsedQuota(ctx context.Co
 	return used, nil
 }
 
+// EvictPodsOverMax evicts the over-quota Pods that, following the same order used for finding over-quota Pods,
+// would make the used quota exceed the max provided as argument, and returns the remaining Pods.
+//
+// This is needed when the Max of a quota is lowered by a time window, since the scheduler
+// enforces the Max only on the Pods that are still to be scheduled. In-quota Pods are never evicted,
+// since the resources they use are guaranteed by the quota Min. Pods are evicted through the Eviction API,
+// so that their PodDisruptionBudgets are honored.
+//
+// Pods that cannot be evicted yet because of a PodDisruptionBudget are kept among the remaining Pods,
+// and the quota identified by the key provided as argument is marked as having blocked evictions,
+// so that the eviction can be retried later (see HasBlockedEvictions).
+func (r *elasticQuotaPodsReconciler) EvictPodsOverMax(ctx context.Context,
+	quotaKey string,
+	pods []v1.Pod,
+	quotaMax v1.ResourceList) ([]v1.Pod, error) {
+
+	logger := log.FromContext(ctx)
+	r.sortPodListForFindingOverQuotaPods(pods)
+
+	var remaining = make([]v1.Pod, 0, len(pods))
+	var blocked bool
+	used := newZeroUsed(nil, quotaMax)
+	for _, pod := range pods {
+		usedWithPod := quota.Add(used, r.resourceCalculator.ComputePodRequest(pod))
+		lessOrEqual, _ := quota.LessThanOrEqual(usedWithPod, quotaMax)
+		if lessOrEqual || pod.Labels[v1alpha1.LabelCapacityInfo] != string(constant.CapacityInfoOverQuota) {
+			used = usedWithPod
+			remaining = append(remaining, pod)
+			continue
+		}
+		logger.Info("evicting over-quota Pod exceeding the quota max", "pod", pod.Name, "namespace", pod.Namespace)
+		eviction := policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+		}
+		err := r.kubeClient.CoreV1().Pods(pod.Namespace).EvictV1(ctx, &eviction)
+		if apierrors.IsTooManyRequests(err) {
+			logger.Info("eviction of Pod exceeding the quota max blocked by a disruption budget, retrying later", "pod", pod.Name, "namespace", pod.Namespace)
+			blocked = true
+			used = usedWithPod
+			remaining = append(remaining, pod)
+			continue
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			logger.Error(err, "unable to evict Pod exceeding the quota max", "pod", pod.Name, "namespace", pod.Namespace)
+			return nil, err
+		}
+	}
+
+	r.setBlockedEvictions(quotaKey, blocked)
+	return remaining, nil
+}
+
+// HasBlockedEvictions returns true if the last call to EvictPodsOverMax for the quota identified by
+// the key provided as argument could not evict some Pods because of their PodDisruptionBudgets
+func (r *elasticQuotaPodsReconciler) HasBlockedEvictions(quotaKey string) bool {
+	r.blockedEvictionsMtx.Lock()
+	defer r.blockedEvictionsMtx.Unlock()
+	return r.blockedEvictions.Has(quotaKey)
+}
+
+// ForgetBlockedEvictions marks the quota identified by the key provided as argument as not having blocked evictions
+func (r *elasticQuotaPodsReconciler) ForgetBlockedEvictions(quotaKey string) {
+	r.setBlockedEvictions(quotaKey, false)
+}
+
+func (r *elasticQuotaPodsReconciler) setBlockedEvictions(quotaKey string, blocked bool) {
+	r.blockedEvictionsMtx.Lock()
+	defer r.blockedEvictionsMtx.Unlock()
+	if r.blockedEvictions == nil {
+		r.blockedEvictions = sets.NewString()
+	}
+	if blocked {
+		r.blockedEvictions.Insert(quotaKey)
+		return
+	}
+	r.blockedEvictions.Delete(quotaKey)
+}
+
+// limitsGetter is implemented by the specs of ElasticQuotas and CompositeElasticQuotas
+type limitsGetter interface {
+	GetLimits(t time.Time) (v1alpha1.ElasticQuotaLimits, error)
+}
+
+// isMaxDecreasedSince returns true if the current max is lower than the one that was in effect
+// at the last accounting time of the quota. It returns false if the quota has never been accounted.
+func isMaxDecreasedSince(spec limitsGetter, lastAccountingTime *metav1.Time, current v1.ResourceList) bool {
+	if lastAccountingTime == nil {
+		return false
+	}
+	previousLimits, _ := spec.GetLimits(lastAccountingTime.Time)
+	return isMaxDecreased(previousLimits.Max, current)
+}
+
+// isMaxDecreased returns true if the current max limits at least one resource more than the previous one,
+// either because the resource has a lower limit or because it was not limited before.
+func isMaxDecreased(previous v1.ResourceList, current v1.ResourceList) bool {
+	for r, currentQuantity := range current {
+		previousQuantity, ok := previous[r]
+		if !ok || currentQuantity.Cmp(previousQuantity) < 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // sortPodListForFindingOverQuotaPods sorts the input list so that it can be used for finding the Pods that are
 // "over-quota" (e.g. they are borrowing quotas from another namespace) and the ones that are "in-quota" (e.g.
 // in their respective ElasticQuota used <= min)
@@ -103,6 +230,24 @@ func (r *elasticQuotaPodsReconciler) sortPodListForFindingOverQuotaPods(pods []v
 	})
 }
 
+// newReconcileResult returns the result of a reconciliation that enforced the limits provided as argument.
+// The quota is requeued when its limits are going to change because a time window starts or ends,
+// periodically while it has running pods, so that their consumption is accounted, and shortly if
+// the eviction of some of its pods exceeding the Max has been blocked, so that it is retried.
+func newReconcileResult(limits v1alpha1.ElasticQuotaLimits, nRunningPods int, blockedEvictions bool, now time.Time) ctrl.Result {
+	var requeueAfter time.Duration
+	if !limits.NextTransition.IsZero() {
+		requeueAfter = limits.NextTransition.Sub(now)
+	}
+	if nRunningPods > 0 && (requeueAfter == 0 || requeueAfter > usageAccountingInterval) {
+		requeueAfter = usageAccountingInterval
+	}
+	if blockedEvictions && (requeueAfter == 0 || requeueAfter > blockedEvictionRetryInterval) {
+		requeueAfter = blockedEvictionRetryInterval
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}
+}
+
 // newZeroUsed will return the zero value of the union of min and max
 func newZeroUsed(min v1.ResourceList, max v1.ResourceList) v1.ResourceList {
 	minResources := quota.ResourceNames(min)
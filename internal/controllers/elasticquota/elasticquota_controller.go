@@ -24,8 +24,11 @@ import (
 	"github.com/nebuly-ai/nos/pkg/resource"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -35,6 +38,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"time"
 )
 
 // ElasticQuotaReconciler reconciles a ElasticQuota object
@@ -45,14 +49,25 @@ type ElasticQuotaReconciler struct {
 	podsReconciler     *elasticQuotaPodsReconciler
 }
 
-func NewElasticQuotaReconciler(client client.Client, scheme *runtime.Scheme, nvidiaGpuResourceMemoryGB int64) ElasticQuotaReconciler {
-	resourceCalculator := gpu_util.ResourceCalculator{NvidiaGPUDeviceMemoryGB: nvidiaGpuResourceMemoryGB}
+func NewElasticQuotaReconciler(
+	client client.Client,
+	kubeClient kubernetes.Interface,
+	scheme *runtime.Scheme,
+	nvidiaGpuResourceMemoryGB int64,
+	nvidiaGpuModelsMemoryGB map[string]int64,
+) ElasticQuotaReconciler {
+	resourceCalculator := gpu_util.ResourceCalculator{
+		NvidiaGPUDeviceMemoryGB: nvidiaGpuResourceMemoryGB,
+		NvidiaGPUModelsMemoryGB: nvidiaGpuModelsMemoryGB,
+		Nodes:                   gpu_util.NewClientNodeGetter(client),
+	}
 	return ElasticQuotaReconciler{
 		Client:             client,
 		Scheme:             scheme,
 		resourceCalculator: resourceCalculator,
 		podsReconciler: &elasticQuotaPodsReconciler{
 			c:                  client,
+			kubeClient:         kubeClient,
 			resourceCalculator: &resourceCalculator,
 		},
 	}
@@ -61,7 +76,9 @@ func NewElasticQuotaReconciler(client client.Client, scheme *runtime.Scheme, nvi
 //+kubebuilder:rbac:groups=nos.nebuly.com,resources=elasticquotas,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=nos.nebuly.com,resources=elasticquotas/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=nos.nebuly.com,resources=elasticquotas/finalizers,verbs=update
-//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
 
 func (r *ElasticQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -69,6 +86,9 @@ func (r *ElasticQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	// Fetch EQ instance
 	var instance v1alpha1.ElasticQuota
 	if err := r.Client.Get(ctx, req.NamespacedName, &instance); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.podsReconciler.ForgetBlockedEvictions(req.NamespacedName.String())
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
@@ -83,23 +103,51 @@ func (r *ElasticQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 
+	// Compute the limits in effect according to the time windows of the ElasticQuota
+	now := time.Now().Truncate(time.Second)
+	limits, err := instance.Spec.GetLimits(now)
+	if err != nil {
+		logger.Error(err, "ignoring invalid time windows")
+	}
+
+	// Evict the pods exceeding a Max lowered by a time window since the last reconciliation
+	// or whose eviction has been previously blocked by a disruption budget
+	pods := runningPodList.Items
+	quotaKey := req.NamespacedName.String()
+	if limits.Max == nil {
+		r.podsReconciler.ForgetBlockedEvictions(quotaKey)
+	} else if r.podsReconciler.HasBlockedEvictions(quotaKey) || isMaxDecreasedSince(instance.Spec, instance.Status.LastAccountingTime, limits.Max) {
+		if pods, err = r.podsReconciler.EvictPodsOverMax(ctx, quotaKey, pods, limits.Max); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Update pods in EQ namespaces and compute used quota
 	used, err := r.podsReconciler.PatchPodsAndComputeUsedQuota(
 		ctx,
-		runningPodList.Items,
-		instance.Spec.Min,
-		instance.Spec.Max,
+		pods,
+		limits.Min,
+		limits.Max,
 	)
 	if err != nil {
 		return ctrl.Result{}, nil
 	}
 
+	// Account the GPU resources consumed by the running pods
+	instance.Status.Usage = r.podsReconciler.AccountUsage(
+		instance.Status.Usage,
+		pods,
+		instance.Status.LastAccountingTime,
+		now,
+	)
+	instance.Status.LastAccountingTime = &metav1.Time{Time: now}
+
 	// Update EQ status
 	instance.Status.Used = used
 	if err = r.updateStatus(ctx, instance); err != nil {
 		return ctrl.Result{}, err
 	}
-	return ctrl.Result{}, nil
+	return newReconcileResult(limits, len(pods), r.podsReconciler.HasBlockedEvictions(quotaKey), now), nil
 }
 
 func (r *ElasticQuotaReconciler) updateStatus(ctx context.Context, instance v1alpha1.ElasticQuota) error {
@@ -135,7 +183,8 @@ func (r *ElasticQuotaReconciler) SetupWithManager(mgr ctrl.Manager, name string)
 	}
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.ElasticQuota{}).
+		// Status updates are ignored, since the status is updated at every reconciliation
+		For(&v1alpha1.ElasticQuota{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Named(name).
 		Watches(
 			&source.Kind{Type: &v1.Pod{}},
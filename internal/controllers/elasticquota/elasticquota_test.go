@@ -17,12 +17,22 @@
 package elasticquota
 
 import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/constant"
 	"github.com/nebuly-ai/nos/pkg/gpu/util"
 	"github.com/nebuly-ai/nos/pkg/test/factory"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"testing"
 	"time"
 )
@@ -131,3 +141,167 @@ func TestElasticQuotaPodsReconciler_sortPodListForFindingOverQuotaPods(t *testin
 		})
 	}
 }
+
+func TestElasticQuotaPodsReconciler_EvictPodsOverMax(t *testing.T) {
+	newPod := func(name string, year int, gpus int64, capacityInfo constant.CapacityInfo) v1.Pod {
+		return factory.BuildPod("ns-1", name).
+			WithCreationTimestamp(metav1.NewTime(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC))).
+			WithPriority(int32(0)).
+			WithLabel(v1alpha1.LabelCapacityInfo, string(capacityInfo)).
+			WithContainer(factory.BuildContainer("c1", "test").WithNvidiaGPURequest(gpus).Get()).
+			Get()
+	}
+	newPods := func() []v1.Pod {
+		return []v1.Pod{
+			newPod("pd-3", 2002, 1, constant.CapacityInfoOverQuota),
+			newPod("pd-1", 2000, 1, constant.CapacityInfoInQuota),
+			newPod("pd-5", 2004, 1, constant.CapacityInfoInQuota),
+			newPod("pd-4", 2003, 2, constant.CapacityInfoOverQuota),
+			newPod("pd-2", 2001, 1, constant.CapacityInfoInQuota),
+		}
+	}
+	quotaMax := v1.ResourceList{v1alpha1.ResourceGPUMemory: resource.MustParse("30")}
+
+	testCases := []struct {
+		name                     string
+		evictionErr              error
+		errExpected              bool
+		expectedRemaining        []string
+		expectedEvictions        []string
+		expectedBlockedEvictions bool
+	}{
+		{
+			name:              "Only over-quota pods exceeding the max are evicted",
+			expectedRemaining: []string{"pd-1", "pd-2", "pd-3", "pd-5"},
+			expectedEvictions: []string{"pd-4"},
+		},
+		{
+			name:              "Pods not found are considered as already evicted",
+			evictionErr:       apierrors.NewNotFound(v1.Resource("pods"), "pd-4"),
+			expectedRemaining: []string{"pd-1", "pd-2", "pd-3", "pd-5"},
+			expectedEvictions: []string{"pd-4"},
+		},
+		{
+			name:                     "Pods whose eviction is blocked by a disruption budget are kept and retried later",
+			evictionErr:              apierrors.NewTooManyRequests("disruption budget", 10),
+			expectedRemaining:        []string{"pd-1", "pd-2", "pd-3", "pd-4", "pd-5"},
+			expectedEvictions:        []string{"pd-4"},
+			expectedBlockedEvictions: true,
+		},
+		{
+			name:              "Eviction error",
+			evictionErr:       apierrors.NewInternalError(fmt.Errorf("error")),
+			errExpected:       true,
+			expectedEvictions: []string{"pd-4"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var evictions []string
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+				evictions = append(evictions, eviction.Name)
+				return true, nil, tt.evictionErr
+			})
+			r := elasticQuotaPodsReconciler{
+				kubeClient:         kubeClient,
+				resourceCalculator: &util.ResourceCalculator{NvidiaGPUDeviceMemoryGB: 10},
+			}
+
+			remaining, err := r.EvictPodsOverMax(context.Background(), "ns-1/eq-1", newPods(), quotaMax)
+			if tt.errExpected {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				remainingNames := make([]string, len(remaining))
+				for i, pod := range remaining {
+					remainingNames[i] = pod.Name
+				}
+				assert.Equal(t, tt.expectedRemaining, remainingNames)
+				assert.Equal(t, tt.expectedBlockedEvictions, r.HasBlockedEvictions("ns-1/eq-1"))
+			}
+			assert.Equal(t, tt.expectedEvictions, evictions)
+		})
+	}
+}
+
+func TestIsMaxDecreased(t *testing.T) {
+	testCases := []struct {
+		name     string
+		previous v1.ResourceList
+		current  v1.ResourceList
+		expected bool
+	}{
+		{
+			name:     "Same max",
+			previous: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+			current:  v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+			expected: false,
+		},
+		{
+			name:     "Increased max",
+			previous: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+			current:  v1.ResourceList{v1.ResourceCPU: resource.MustParse("4")},
+			expected: false,
+		},
+		{
+			name:     "Resource not limited anymore",
+			previous: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("1Gi")},
+			current:  v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+			expected: false,
+		},
+		{
+			name:     "Decreased max",
+			previous: v1.ResourceList{v1.ResourceCPU: resource.MustParse("4"), v1.ResourceMemory: resource.MustParse("1Gi")},
+			current:  v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("2Gi")},
+			expected: true,
+		},
+		{
+			name:     "Resource limited only by the current max",
+			previous: nil,
+			current:  v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+			expected: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isMaxDecreased(tt.previous, tt.current))
+		})
+	}
+}
+
+func TestNewReconcileResult(t *testing.T) {
+	now := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, ctrl.Result{}, newReconcileResult(v1alpha1.ElasticQuotaLimits{}, 0, false, now))
+	assert.Equal(
+		t,
+		ctrl.Result{RequeueAfter: usageAccountingInterval},
+		newReconcileResult(v1alpha1.ElasticQuotaLimits{}, 1, false, now),
+	)
+	assert.Equal(
+		t,
+		ctrl.Result{RequeueAfter: 2 * time.Hour},
+		newReconcileResult(v1alpha1.ElasticQuotaLimits{NextTransition: now.Add(2 * time.Hour)}, 0, false, now),
+	)
+	assert.Equal(
+		t,
+		ctrl.Result{RequeueAfter: usageAccountingInterval},
+		newReconcileResult(v1alpha1.ElasticQuotaLimits{NextTransition: now.Add(2 * time.Hour)}, 1, false, now),
+	)
+	assert.Equal(
+		t,
+		ctrl.Result{RequeueAfter: 10 * time.Second},
+		newReconcileResult(v1alpha1.ElasticQuotaLimits{NextTransition: now.Add(10 * time.Second)}, 1, false, now),
+	)
+	assert.Equal(
+		t,
+		ctrl.Result{RequeueAfter: blockedEvictionRetryInterval},
+		newReconcileResult(v1alpha1.ElasticQuotaLimits{NextTransition: now.Add(2 * time.Hour)}, 1, true, now),
+	)
+}
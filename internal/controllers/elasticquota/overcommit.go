@@ -0,0 +1,338 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package elasticquota
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	gpu_util "github.com/nebuly-ai/nos/pkg/gpu/util"
+	"github.com/nebuly-ai/nos/pkg/resource"
+	"github.com/nebuly-ai/nos/pkg/scheduler/plugins/capacityscheduling"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const overcommitCheckInterval = time.Minute
+
+// OvercommitGuard periodically compares the aggregated Min of the quotas with the allocatable resources
+// of the schedulable nodes of the cluster, and sets the Overcommitted condition of each quota accordingly.
+//
+// When the aggregated Min exceeds the capacity of the cluster, the over-quotas guaranteed to the quotas
+// cannot be provided, since they are computed assuming that the Min of every quota can be satisfied.
+type OvercommitGuard struct {
+	client.Client
+	resourceCalculator *gpu_util.ResourceCalculator
+	interval           time.Duration
+}
+
+func NewOvercommitGuard(
+	client client.Client,
+	nvidiaGpuResourceMemoryGB int64,
+	nvidiaGpuModelsMemoryGB map[string]int64,
+) *OvercommitGuard {
+	return &OvercommitGuard{
+		Client: client,
+		resourceCalculator: &gpu_util.ResourceCalculator{
+			NvidiaGPUDeviceMemoryGB: nvidiaGpuResourceMemoryGB,
+			NvidiaGPUModelsMemoryGB: nvidiaGpuModelsMemoryGB,
+			Nodes:                   gpu_util.NewClientNodeGetter(client),
+		},
+		interval: overcommitCheckInterval,
+	}
+}
+
+// Start checks the quotas periodically until the context is cancelled
+func (g *OvercommitGuard) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("overcommit-guard")
+	ctx = log.IntoContext(ctx, logger)
+	logger.Info("starting overcommit guard", "interval", g.interval.String())
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		if err := g.RunOnce(ctx); err != nil {
+			logger.Error(err, "unable to check quotas overcommitment")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so that only
+// the leader updates the status of the quotas
+func (g *OvercommitGuard) NeedLeaderElection() bool {
+	return true
+}
+
+// RunOnce sets the Overcommitted condition of each quota, which is true if the aggregated Min
+// exceeds the capacity of the cluster for any of the resources of the Min of the quota
+func (g *OvercommitGuard) RunOnce(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	eqs, compositeEqs, nodes, err := g.fetchState(ctx)
+	if err != nil {
+		return err
+	}
+	overcommitted := g.computeOvercommittedResources(eqs, compositeEqs, nodes)
+	if len(overcommitted) > 0 {
+		logger.Info("aggregated min of the quotas exceeds the capacity of the cluster", "excess", formatResourceList(overcommitted))
+	}
+
+	now := time.Now()
+	for i := range eqs {
+		eq := &eqs[i]
+		original := eq.DeepCopy()
+		limits, _ := eq.Spec.GetLimits(now)
+		if !setOvercommittedCondition(&eq.Status.Conditions, eq.Generation, limits.Min, overcommitted) {
+			continue
+		}
+		if err = g.Status().Patch(ctx, eq, client.MergeFrom(original)); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to update status of ElasticQuota %s/%s: %w", eq.Namespace, eq.Name, err)
+		}
+	}
+	for i := range compositeEqs {
+		compositeEq := &compositeEqs[i]
+		original := compositeEq.DeepCopy()
+		limits, _ := compositeEq.Spec.GetLimits(now)
+		if !setOvercommittedCondition(&compositeEq.Status.Conditions, compositeEq.Generation, limits.Min, overcommitted) {
+			continue
+		}
+		if err = g.Status().Patch(ctx, compositeEq, client.MergeFrom(original)); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to update status of CompositeElasticQuota %s/%s: %w", compositeEq.Namespace, compositeEq.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// CheckOvercommit implements v1alpha1.OvercommitChecker: it returns an error if the quota provided as argument
+// would make the aggregated Min of the quotas exceed the capacity of the cluster, or increase it for
+// resources that already exceed the capacity.
+//
+// Since the Min of the quotas changes over time according to their time windows, the check considers
+// the largest Min that each quota can have in effect.
+func (g *OvercommitGuard) CheckOvercommit(ctx context.Context, quota client.Object) error {
+	logger := log.FromContext(ctx)
+
+	eqs, compositeEqs, nodes, err := g.fetchState(ctx)
+	if err != nil {
+		logger.Error(err, "unable to check quota overcommitment")
+		return fmt.Errorf(constant.InternalErrorMsg)
+	}
+	for i := range eqs {
+		eqs[i] = withLargestMin(eqs[i])
+	}
+	for i := range compositeEqs {
+		compositeEqs[i] = withLargestMinComposite(compositeEqs[i])
+	}
+	before := g.computeOvercommittedResources(eqs, compositeEqs, nodes)
+
+	namespace, name := quota.GetNamespace(), quota.GetName()
+	switch q := quota.(type) {
+	case *v1alpha1.ElasticQuota:
+		eqs = replaceQuota(eqs, withLargestMin(*q), func(eq v1alpha1.ElasticQuota) bool {
+			return eq.Namespace == namespace && eq.Name == name
+		})
+	case *v1alpha1.CompositeElasticQuota:
+		compositeEqs = replaceQuota(compositeEqs, withLargestMinComposite(*q), func(compositeEq v1alpha1.CompositeElasticQuota) bool {
+			return compositeEq.Namespace == namespace && compositeEq.Name == name
+		})
+	default:
+		return fmt.Errorf("unsupported quota type %T", quota)
+	}
+	after := g.computeOvercommittedResources(eqs, compositeEqs, nodes)
+
+	var increased = make(v1.ResourceList)
+	for r, excess := range after {
+		if previous, ok := before[r]; !ok || excess.Cmp(previous) > 0 {
+			increased[r] = excess
+		}
+	}
+	if len(increased) > 0 {
+		return fmt.Errorf(
+			"the quota would make the aggregated min of the quotas exceed the capacity of the cluster by: %s",
+			formatResourceList(increased),
+		)
+	}
+	return nil
+}
+
+func (g *OvercommitGuard) fetchState(ctx context.Context) ([]v1alpha1.ElasticQuota, []v1alpha1.CompositeElasticQuota, []v1.Node, error) {
+	var eqList v1alpha1.ElasticQuotaList
+	if err := g.List(ctx, &eqList); err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to list ElasticQuotas: %w", err)
+	}
+	var compositeEqList v1alpha1.CompositeElasticQuotaList
+	if err := g.List(ctx, &compositeEqList); err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to list CompositeElasticQuotas: %w", err)
+	}
+	var nodeList v1.NodeList
+	if err := g.List(ctx, &nodeList); err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to list nodes: %w", err)
+	}
+	return eqList.Items, compositeEqList.Items, nodeList.Items, nil
+}
+
+// computeOvercommittedResources returns, for each resource whose aggregated Min exceeds the
+// allocatable resources of the schedulable nodes, the amount by which it exceeds them
+func (g *OvercommitGuard) computeOvercommittedResources(
+	eqs []v1alpha1.ElasticQuota,
+	compositeEqs []v1alpha1.CompositeElasticQuota,
+	nodes []v1.Node,
+) v1.ResourceList {
+	eqInfos := capacityscheduling.NewElasticQuotaInfos()
+	for _, eq := range eqs {
+		eqInfos.Add(capacityscheduling.NewElasticQuotaInfoFromElasticQuota(eq, g.resourceCalculator))
+	}
+	for _, compositeEq := range compositeEqs {
+		eqInfos.Add(capacityscheduling.NewElasticQuotaInfoFromCompositeElasticQuota(compositeEq, g.resourceCalculator))
+	}
+
+	var capacity framework.Resource
+	for _, node := range nodes {
+		if !isSchedulable(node) {
+			continue
+		}
+		capacity = resource.Sum(capacity, resource.FromListToFramework(g.resourceCalculator.ComputeNodeAllocatable(node)))
+	}
+	capacityList := resource.FromFrameworkToList(capacity)
+
+	var res = make(v1.ResourceList)
+	for r, min := range resource.FromFrameworkToList(*eqInfos.GetAggregatedMin()) {
+		available := capacityList[r]
+		if min.Cmp(available) > 0 {
+			excess := min.DeepCopy()
+			excess.Sub(available)
+			res[r] = excess
+		}
+	}
+	return res
+}
+
+// setOvercommittedCondition sets the Overcommitted condition considering the resources of the Min
+// provided as argument, and returns true if the conditions have changed
+func setOvercommittedCondition(
+	conditions *[]metav1.Condition,
+	generation int64,
+	min v1.ResourceList,
+	overcommitted v1.ResourceList,
+) bool {
+	var affected = make(v1.ResourceList)
+	for r, excess := range overcommitted {
+		if quantity, ok := min[r]; ok && !quantity.IsZero() {
+			affected[r] = excess
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               v1alpha1.ConditionTypeOvercommitted,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             v1alpha1.ReasonMinWithinCapacity,
+		Message:            "The aggregated min of the quotas fits the capacity of the cluster",
+	}
+	if len(affected) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = v1alpha1.ReasonMinExceedsCapacity
+		condition.Message = fmt.Sprintf(
+			"The aggregated min of the quotas exceeds the capacity of the cluster by: %s",
+			formatResourceList(affected),
+		)
+	}
+
+	current := meta.FindStatusCondition(*conditions, condition.Type)
+	if current != nil &&
+		current.Status == condition.Status &&
+		current.Reason == condition.Reason &&
+		current.Message == condition.Message &&
+		current.ObservedGeneration == condition.ObservedGeneration {
+		return false
+	}
+	meta.SetStatusCondition(conditions, condition)
+	return true
+}
+
+// withLargestMin returns a copy of the ElasticQuota whose Min is the largest one it can have in effect,
+// without time windows so that the Min does not depend on the current time
+func withLargestMin(eq v1alpha1.ElasticQuota) v1alpha1.ElasticQuota {
+	res := *eq.DeepCopy()
+	res.Spec.Min = eq.Spec.GetLargestMin()
+	res.Spec.TimeWindows = nil
+	return res
+}
+
+// withLargestMinComposite returns a copy of the CompositeElasticQuota whose Min is the largest one it can have
+// in effect, without time windows so that the Min does not depend on the current time
+func withLargestMinComposite(compositeEq v1alpha1.CompositeElasticQuota) v1alpha1.CompositeElasticQuota {
+	res := *compositeEq.DeepCopy()
+	res.Spec.Min = compositeEq.Spec.GetLargestMin()
+	res.Spec.TimeWindows = nil
+	return res
+}
+
+// replaceQuota returns the quotas provided as argument with the first quota matching the
+// function provided as argument replaced by the new quota. If no quota matches, the new quota is appended.
+func replaceQuota[T any](quotas []T, newQuota T, matches func(T) bool) []T {
+	res := make([]T, 0, len(quotas)+1)
+	var replaced bool
+	for _, q := range quotas {
+		if !replaced && matches(q) {
+			res = append(res, newQuota)
+			replaced = true
+			continue
+		}
+		res = append(res, q)
+	}
+	if !replaced {
+		res = append(res, newQuota)
+	}
+	return res
+}
+
+// isSchedulable returns true if the node is ready and not cordoned
+func isSchedulable(node v1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, c := range node.Status.Conditions {
+		if c.Type == v1.NodeReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func formatResourceList(resources v1.ResourceList) string {
+	var res = make([]string, 0, len(resources))
+	for r, quantity := range resources {
+		res = append(res, fmt.Sprintf("%s=%s", r, quantity.String()))
+	}
+	sort.Strings(res)
+	return strings.Join(res, ", ")
+}
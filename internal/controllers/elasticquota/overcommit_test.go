@@ -0,0 +1,202 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package elasticquota
+
+import (
+	"context"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+func newOvercommitTestNode(name string, nGPUs int64, ready bool, unschedulable bool) *v1.Node {
+	readyStatus := v1.ConditionFalse
+	if ready {
+		readyStatus = v1.ConditionTrue
+	}
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       v1.NodeSpec{Unschedulable: unschedulable},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:             *resource.NewMilliQuantity(4000, resource.DecimalSI),
+				constant.ResourceNvidiaGPU: *resource.NewQuantity(nGPUs, resource.DecimalSI),
+			},
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: readyStatus},
+			},
+		},
+	}
+}
+
+func newOvercommitTestGuard(t *testing.T, objs ...client.Object) *OvercommitGuard {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return NewOvercommitGuard(c, 10, nil)
+}
+
+func TestOvercommitGuard_RunOnce(t *testing.T) {
+	// Capacity: 2 schedulable nodes with 2 GPUs of 10 GB each, the other nodes are not schedulable
+	nodes := []client.Object{
+		newOvercommitTestNode("node-1", 2, true, false),
+		newOvercommitTestNode("node-2", 2, true, false),
+		newOvercommitTestNode("node-3", 2, false, false),
+		newOvercommitTestNode("node-4", 2, true, true),
+	}
+
+	t.Run("Aggregated min within capacity", func(t *testing.T) {
+		eq := v1alpha1.BuildEq("ns-1", "eq-1").WithMinGPUMemory(20).Get()
+		compositeEq := v1alpha1.BuildCompositeEq("ns-2", "ceq-1").WithNamespaces("ns-2").WithMinGPUMemory(20).Get()
+		guard := newOvercommitTestGuard(t, append(nodes, &eq, &compositeEq)...)
+		assert.NoError(t, guard.RunOnce(context.Background()))
+
+		assertOvercommittedCondition(t, guard, &v1alpha1.ElasticQuota{}, eq.Namespace, eq.Name, metav1.ConditionFalse)
+		assertOvercommittedCondition(t, guard, &v1alpha1.CompositeElasticQuota{}, compositeEq.Namespace, compositeEq.Name, metav1.ConditionFalse)
+	})
+
+	t.Run("Aggregated min exceeds capacity, only quotas specifying the exceeding resources are affected", func(t *testing.T) {
+		eq := v1alpha1.BuildEq("ns-1", "eq-1").WithMinGPUMemory(30).Get()
+		compositeEq := v1alpha1.BuildCompositeEq("ns-2", "ceq-1").WithNamespaces("ns-2").WithMinGPUMemory(20).Get()
+		cpuOnlyEq := v1alpha1.BuildEq("ns-3", "eq-3").WithMinCPUMilli(1000).Get()
+		guard := newOvercommitTestGuard(t, append(nodes, &eq, &compositeEq, &cpuOnlyEq)...)
+		assert.NoError(t, guard.RunOnce(context.Background()))
+
+		assertOvercommittedCondition(t, guard, &v1alpha1.ElasticQuota{}, eq.Namespace, eq.Name, metav1.ConditionTrue)
+		assertOvercommittedCondition(t, guard, &v1alpha1.CompositeElasticQuota{}, compositeEq.Namespace, compositeEq.Name, metav1.ConditionTrue)
+		assertOvercommittedCondition(t, guard, &v1alpha1.ElasticQuota{}, cpuOnlyEq.Namespace, cpuOnlyEq.Name, metav1.ConditionFalse)
+	})
+
+	t.Run("Min of quotas with a parent is part of the min of the parent", func(t *testing.T) {
+		parent := v1alpha1.BuildCompositeEq("ns-1", "parent").WithNamespaces("ns-1").WithMinGPUMemory(40).Get()
+		child := v1alpha1.BuildEq("ns-2", "child").
+			WithMinGPUMemory(40).
+			WithParent(v1alpha1.KindCompositeElasticQuota, "ns-1", "parent").
+			Get()
+		guard := newOvercommitTestGuard(t, append(nodes, &parent, &child)...)
+		assert.NoError(t, guard.RunOnce(context.Background()))
+
+		assertOvercommittedCondition(t, guard, &v1alpha1.CompositeElasticQuota{}, parent.Namespace, parent.Name, metav1.ConditionFalse)
+		assertOvercommittedCondition(t, guard, &v1alpha1.ElasticQuota{}, child.Namespace, child.Name, metav1.ConditionFalse)
+	})
+}
+
+func assertOvercommittedCondition(
+	t *testing.T,
+	guard *OvercommitGuard,
+	quota client.Object,
+	namespace, name string,
+	expected metav1.ConditionStatus,
+) {
+	assert.NoError(t, guard.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: name}, quota))
+	var conditions []metav1.Condition
+	switch q := quota.(type) {
+	case *v1alpha1.ElasticQuota:
+		conditions = q.Status.Conditions
+	case *v1alpha1.CompositeElasticQuota:
+		conditions = q.Status.Conditions
+	}
+	condition := meta.FindStatusCondition(conditions, v1alpha1.ConditionTypeOvercommitted)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, expected, condition.Status)
+	}
+}
+
+func TestOvercommitGuard_CheckOvercommit(t *testing.T) {
+	node := newOvercommitTestNode("node-1", 4, true, false)
+	existing := v1alpha1.BuildEq("ns-1", "eq-1").WithMinGPUMemory(30).Get()
+
+	testCases := []struct {
+		name        string
+		quota       client.Object
+		expectedErr bool
+	}{
+		{
+			name:        "New quota fits the capacity",
+			quota:       ptr(v1alpha1.BuildEq("ns-2", "eq-2").WithMinGPUMemory(10).Get()),
+			expectedErr: false,
+		},
+		{
+			name:        "New quota exceeds the capacity",
+			quota:       ptr(v1alpha1.BuildEq("ns-2", "eq-2").WithMinGPUMemory(20).Get()),
+			expectedErr: true,
+		},
+		{
+			name: "New CompositeElasticQuota exceeds the capacity",
+			quota: ptr(v1alpha1.BuildCompositeEq("ns-2", "ceq-2").
+				WithNamespaces("ns-2", "ns-3").
+				WithMinGPUMemory(20).
+				Get()),
+			expectedErr: true,
+		},
+		{
+			name:        "Updated quota replaces the existing one",
+			quota:       ptr(v1alpha1.BuildEq("ns-1", "eq-1").WithMinGPUMemory(40).Get()),
+			expectedErr: false,
+		},
+		{
+			name: "Updated quota exceeds the capacity during a time window",
+			quota: ptr(v1alpha1.BuildEq("ns-1", "eq-1").
+				WithMinGPUMemory(30).
+				WithTimeWindows(v1alpha1.ElasticQuotaTimeWindow{
+					Name:     "business-hours",
+					Schedule: "0 8 * * mon-fri",
+					Duration: metav1.Duration{Duration: 10 * time.Hour},
+					Min: v1.ResourceList{
+						v1alpha1.ResourceGPUMemory: *resource.NewQuantity(50, resource.DecimalSI),
+					},
+				}).
+				Get()),
+			expectedErr: true,
+		},
+		{
+			name: "New child quota does not increase the aggregated min",
+			quota: ptr(v1alpha1.BuildEq("ns-2", "eq-2").
+				WithMinGPUMemory(30).
+				WithParent(v1alpha1.KindElasticQuota, "ns-1", "eq-1").
+				Get()),
+			expectedErr: false,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := newOvercommitTestGuard(t, node, &existing)
+			err := guard.CheckOvercommit(context.Background(), tt.quota)
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
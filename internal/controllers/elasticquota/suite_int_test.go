@@ -25,6 +25,7 @@ import (
 	"github.com/nebuly-ai/nos/pkg/constant"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"path/filepath"
@@ -82,8 +83,10 @@ var _ = BeforeSuite(func() {
 	// Setup ElasticQuota controller
 	eqReconciler := NewElasticQuotaReconciler(
 		k8sManager.GetClient(),
+		kubernetes.NewForConfigOrDie(cfg),
 		k8sManager.GetScheme(),
 		constant.DefaultNvidiaGPUResourceMemory,
+		nil,
 	)
 	err = eqReconciler.SetupWithManager(k8sManager, constant.ElasticQuotaControllerName)
 	Expect(err).ToNot(HaveOccurred())
@@ -91,8 +94,10 @@ var _ = BeforeSuite(func() {
 	// Setup CompositeElasticQuota controller
 	ceqReconciler := NewCompositeElasticQuotaReconciler(
 		k8sManager.GetClient(),
+		kubernetes.NewForConfigOrDie(cfg),
 		k8sManager.GetScheme(),
 		constant.DefaultNvidiaGPUResourceMemory,
+		nil,
 	)
 	err = ceqReconciler.SetupWithManager(k8sManager, constant.CompositeElasticQuotaControllerName)
 	Expect(err).ToNot(HaveOccurred())
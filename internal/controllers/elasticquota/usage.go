@@ -0,0 +1,134 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package elasticquota
+
+import (
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"time"
+)
+
+const (
+	// usageAccountingInterval is the maximum interval between two consecutive accountings
+	// of the consumption of the running pods of a quota
+	usageAccountingInterval = time.Minute
+	// usageRetention is how long the usage records are kept in the status of the quotas
+	usageRetention = 90 * 24 * time.Hour
+)
+
+// AccountUsage adds to the usage records provided as argument the GPU resources consumed by the running pods
+// from the last accounting time (or from their start, if more recent) up to now, and removes the records older
+// than the usage retention. The consumption is split into in-quota and over-quota according to the capacity info
+// label of the pods, and into daily records according to the UTC time.
+//
+// The pods terminated between two consecutive accountings are not accounted for the time they were
+// running after the last accounting.
+func (r *elasticQuotaPodsReconciler) AccountUsage(records []v1alpha1.ElasticQuotaUsageRecord,
+	pods []v1.Pod,
+	lastAccountingTime *metav1.Time,
+	now time.Time) []v1alpha1.ElasticQuotaUsageRecord {
+
+	var recordsByKey = make(map[string]*v1alpha1.ElasticQuotaUsageRecord, len(records))
+	var result = make([]*v1alpha1.ElasticQuotaUsageRecord, 0, len(records))
+	getRecord := func(namespace string, date string) *v1alpha1.ElasticQuotaUsageRecord {
+		key := namespace + "/" + date
+		if record, ok := recordsByKey[key]; ok {
+			return record
+		}
+		record := &v1alpha1.ElasticQuotaUsageRecord{Namespace: namespace, Date: date}
+		recordsByKey[key] = record
+		result = append(result, record)
+		return record
+	}
+	for i := range records {
+		record := records[i]
+		*getRecord(record.Namespace, record.Date) = record
+	}
+
+	now = now.UTC()
+	for _, pod := range pods {
+		var from time.Time
+		if lastAccountingTime != nil {
+			from = lastAccountingTime.UTC()
+		}
+		if pod.Status.StartTime != nil && pod.Status.StartTime.UTC().After(from) {
+			from = pod.Status.StartTime.UTC()
+		}
+		if from.IsZero() || !from.Before(now) {
+			continue
+		}
+
+		gpuMemory, migSlices := r.computeGPURequest(pod)
+		if gpuMemory == 0 && migSlices == 0 {
+			continue
+		}
+		overQuota := pod.Labels[v1alpha1.LabelCapacityInfo] == string(constant.CapacityInfoOverQuota)
+
+		// Split the consumption across the days between from and now
+		for from.Before(now) {
+			to := time.Date(from.Year(), from.Month(), from.Day()+1, 0, 0, 0, 0, time.UTC)
+			if to.After(now) {
+				to = now
+			}
+			seconds := int64(to.Sub(from).Seconds())
+			usage := v1alpha1.GPUUsage{
+				GPUMemoryGBSeconds: gpuMemory * seconds,
+				MigSliceSeconds:    migSlices * seconds,
+			}
+			record := getRecord(pod.Namespace, from.Format(v1alpha1.UsageRecordDateFormat))
+			if overQuota {
+				record.OverQuota = record.OverQuota.Add(usage)
+			} else {
+				record.InQuota = record.InQuota.Add(usage)
+			}
+			from = to
+		}
+	}
+
+	// Remove old records and sort the remaining ones by date and namespace
+	oldestDate := now.Add(-usageRetention).Format(v1alpha1.UsageRecordDateFormat)
+	var res = make([]v1alpha1.ElasticQuotaUsageRecord, 0, len(result))
+	for _, record := range result {
+		if record.Date >= oldestDate {
+			res = append(res, *record)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Date != res[j].Date {
+			return res[i].Date < res[j].Date
+		}
+		return res[i].Namespace < res[j].Namespace
+	})
+	return res
+}
+
+// computeGPURequest returns the GPU memory (in GB) and the number of MIG slices requested by the pod
+func (r *elasticQuotaPodsReconciler) computeGPURequest(pod v1.Pod) (int64, int64) {
+	request := r.resourceCalculator.ComputePodRequest(pod)
+	gpuMemory := request[v1alpha1.ResourceGPUMemory]
+	var migSlices int64
+	for resourceName, quantity := range request {
+		if mig.IsNvidiaMigDevice(resourceName) {
+			migSlices += quantity.Value()
+		}
+	}
+	return gpuMemory.Value(), migSlices
+}
@@ -0,0 +1,273 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package elasticquota
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// UsageReportPath is the path at which the UsageReportServer serves the usage reports
+	UsageReportPath = "/usage"
+
+	usageReportFormatJSON = "json"
+	usageReportFormatCSV  = "csv"
+)
+
+// UsageReport is the consumption of GPU resources of the pods subject to each
+// ElasticQuota and CompositeElasticQuota within a range of days
+type UsageReport struct {
+	// From is the first day of the report, empty if the report has no lower bound
+	From string `json:"from,omitempty"`
+	// To is the last day of the report, empty if the report has no upper bound
+	To    string            `json:"to,omitempty"`
+	Items []UsageReportItem `json:"items"`
+}
+
+// UsageReportItem is the consumption of GPU resources of the pods of a namespace subject to a quota
+type UsageReportItem struct {
+	Kind           string         `json:"kind"`
+	QuotaNamespace string         `json:"quotaNamespace"`
+	QuotaName      string         `json:"quotaName"`
+	Namespace      string         `json:"namespace"`
+	InQuota        GPUUsageReport `json:"inQuota"`
+	OverQuota      GPUUsageReport `json:"overQuota"`
+}
+
+// GPUUsageReport is an amount of GPU resources consumed over time, expressed in hours
+type GPUUsageReport struct {
+	GPUMemoryGBHours float64 `json:"gpuMemoryGBHours"`
+	MigSliceHours    float64 `json:"migSliceHours"`
+}
+
+func newGPUUsageReport(usage v1alpha1.GPUUsage) GPUUsageReport {
+	return GPUUsageReport{
+		GPUMemoryGBHours: usage.GPUMemoryGBHours(),
+		MigSliceHours:    usage.MigSliceHours(),
+	}
+}
+
+// UsageReportServer is a read-only HTTP server that returns the usage accounted in the status of the
+// ElasticQuotas and CompositeElasticQuotas, in JSON or CSV format.
+//
+// The report is served at UsageReportPath, and it accepts the following query parameters:
+//   - from: the first day (YYYY-MM-DD) included in the report
+//   - to: the last day (YYYY-MM-DD) included in the report
+//   - format: either "json" (default) or "csv"
+type UsageReportServer struct {
+	client      client.Reader
+	bindAddress string
+}
+
+func NewUsageReportServer(client client.Reader, bindAddress string) *UsageReportServer {
+	return &UsageReportServer{
+		client:      client,
+		bindAddress: bindAddress,
+	}
+}
+
+// Start serves the usage reports until the context is cancelled
+func (s *UsageReportServer) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("usage-report")
+	mux := http.NewServeMux()
+	mux.Handle(UsageReportPath, s)
+	server := &http.Server{
+		Addr:              s.bindAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("serving usage reports", "address", s.bindAddress, "path", UsageReportPath)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so that every
+// replica can serve the reports
+func (s *UsageReportServer) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *UsageReportServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := req.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	for _, date := range []string{from, to} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(v1alpha1.UsageRecordDateFormat, date); err != nil {
+			http.Error(w, fmt.Sprintf("invalid date %q, expected format YYYY-MM-DD", date), http.StatusBadRequest)
+			return
+		}
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = usageReportFormatJSON
+	}
+	if format != usageReportFormatJSON && format != usageReportFormatCSV {
+		http.Error(w, fmt.Sprintf("invalid format %q, expected either json or csv", format), http.StatusBadRequest)
+		return
+	}
+
+	report, err := s.GetReport(req.Context(), from, to)
+	if err != nil {
+		log.FromContext(req.Context()).Error(err, "unable to compute usage report")
+		http.Error(w, "unable to compute usage report", http.StatusInternalServerError)
+		return
+	}
+
+	if format == usageReportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+		err = writeUsageReportCSV(w, report)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(report)
+	}
+	if err != nil {
+		log.FromContext(req.Context()).Error(err, "unable to write usage report")
+	}
+}
+
+// GetReport returns the report of the usage recorded between the days provided as argument, included.
+// An empty day means that the report has no lower or upper bound.
+func (s *UsageReportServer) GetReport(ctx context.Context, from, to string) (UsageReport, error) {
+	var items = make([]UsageReportItem, 0)
+
+	var eqList v1alpha1.ElasticQuotaList
+	if err := s.client.List(ctx, &eqList); err != nil {
+		return UsageReport{}, err
+	}
+	for _, eq := range eqList.Items {
+		items = append(items, sumUsageRecords(v1alpha1.KindElasticQuota, eq.Namespace, eq.Name, eq.Status.Usage, from, to)...)
+	}
+
+	var compositeEqList v1alpha1.CompositeElasticQuotaList
+	if err := s.client.List(ctx, &compositeEqList); err != nil {
+		return UsageReport{}, err
+	}
+	for _, eq := range compositeEqList.Items {
+		items = append(items, sumUsageRecords(v1alpha1.KindCompositeElasticQuota, eq.Namespace, eq.Name, eq.Status.Usage, from, to)...)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Namespace != items[j].Namespace {
+			return items[i].Namespace < items[j].Namespace
+		}
+		return items[i].Kind < items[j].Kind
+	})
+	return UsageReport{From: from, To: to, Items: items}, nil
+}
+
+// sumUsageRecords sums, for each namespace, the records of a quota whose date is between from and to, included
+func sumUsageRecords(kind, quotaNamespace, quotaName string, records []v1alpha1.ElasticQuotaUsageRecord, from, to string) []UsageReportItem {
+	var inQuota = make(map[string]v1alpha1.GPUUsage)
+	var overQuota = make(map[string]v1alpha1.GPUUsage)
+	var namespaces = make([]string, 0)
+	for _, record := range records {
+		if from != "" && record.Date < from {
+			continue
+		}
+		if to != "" && record.Date > to {
+			continue
+		}
+		if _, ok := inQuota[record.Namespace]; !ok {
+			namespaces = append(namespaces, record.Namespace)
+		}
+		inQuota[record.Namespace] = inQuota[record.Namespace].Add(record.InQuota)
+		overQuota[record.Namespace] = overQuota[record.Namespace].Add(record.OverQuota)
+	}
+
+	var res = make([]UsageReportItem, 0, len(namespaces))
+	for _, ns := range namespaces {
+		res = append(res, UsageReportItem{
+			Kind:           kind,
+			QuotaNamespace: quotaNamespace,
+			QuotaName:      quotaName,
+			Namespace:      ns,
+			InQuota:        newGPUUsageReport(inQuota[ns]),
+			OverQuota:      newGPUUsageReport(overQuota[ns]),
+		})
+	}
+	return res
+}
+
+func writeUsageReportCSV(w http.ResponseWriter, report UsageReport) error {
+	formatHours := func(hours float64) string {
+		return strconv.FormatFloat(hours, 'f', 4, 64)
+	}
+	writer := csv.NewWriter(w)
+	header := []string{
+		"kind",
+		"quota_namespace",
+		"quota_name",
+		"namespace",
+		"in_quota_gpu_memory_gb_hours",
+		"over_quota_gpu_memory_gb_hours",
+		"in_quota_mig_slice_hours",
+		"over_quota_mig_slice_hours",
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, item := range report.Items {
+		row := []string{
+			item.Kind,
+			item.QuotaNamespace,
+			item.QuotaName,
+			item.Namespace,
+			formatHours(item.InQuota.GPUMemoryGBHours),
+			formatHours(item.OverQuota.GPUMemoryGBHours),
+			formatHours(item.InQuota.MigSliceHours),
+			formatHours(item.OverQuota.MigSliceHours),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package elasticquota
+
+import (
+	"encoding/json"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"net/http"
+	"net/http/httptest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strings"
+	"testing"
+)
+
+func newUsageReportTestServer(t *testing.T) *UsageReportServer {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	eq := v1alpha1.BuildEq("ns-1", "eq-1").Get()
+	eq.Status.Usage = []v1alpha1.ElasticQuotaUsageRecord{
+		{
+			Namespace: "ns-1",
+			Date:      "2023-01-01",
+			InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 3600 * 10, MigSliceSeconds: 3600},
+			OverQuota: v1alpha1.GPUUsage{GPUMemoryGBSeconds: 1800 * 10},
+		},
+		{
+			Namespace: "ns-1",
+			Date:      "2023-01-02",
+			InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 3600 * 10},
+		},
+		{
+			Namespace: "ns-1",
+			Date:      "2023-01-03",
+			InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 3600 * 10},
+		},
+	}
+	compositeEq := v1alpha1.BuildCompositeEq("ns-2", "ceq-1").WithNamespaces("ns-2", "ns-3").Get()
+	compositeEq.Status.Usage = []v1alpha1.ElasticQuotaUsageRecord{
+		{
+			Namespace: "ns-2",
+			Date:      "2023-01-02",
+			OverQuota: v1alpha1.GPUUsage{MigSliceSeconds: 7200},
+		},
+		{
+			Namespace: "ns-3",
+			Date:      "2023-01-02",
+			InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 3600},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&eq, &compositeEq).Build()
+	return NewUsageReportServer(c, ":0")
+}
+
+func TestUsageReportServer_JSON(t *testing.T) {
+	server := newUsageReportTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, UsageReportPath+"?from=2023-01-01&to=2023-01-02", nil)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var report UsageReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, "2023-01-01", report.From)
+	assert.Equal(t, "2023-01-02", report.To)
+	assert.Equal(t, []UsageReportItem{
+		{
+			Kind:           v1alpha1.KindElasticQuota,
+			QuotaNamespace: "ns-1",
+			QuotaName:      "eq-1",
+			Namespace:      "ns-1",
+			InQuota:        GPUUsageReport{GPUMemoryGBHours: 20, MigSliceHours: 1},
+			OverQuota:      GPUUsageReport{GPUMemoryGBHours: 5},
+		},
+		{
+			Kind:           v1alpha1.KindCompositeElasticQuota,
+			QuotaNamespace: "ns-2",
+			QuotaName:      "ceq-1",
+			Namespace:      "ns-2",
+			OverQuota:      GPUUsageReport{MigSliceHours: 2},
+		},
+		{
+			Kind:           v1alpha1.KindCompositeElasticQuota,
+			QuotaNamespace: "ns-2",
+			QuotaName:      "ceq-1",
+			Namespace:      "ns-3",
+			InQuota:        GPUUsageReport{GPUMemoryGBHours: 1},
+		},
+	}, report.Items)
+}
+
+func TestUsageReportServer_CSV(t *testing.T) {
+	server := newUsageReportTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, UsageReportPath+"?from=2023-01-03&format=csv", nil)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	assert.Equal(t, []string{
+		"kind,quota_namespace,quota_name,namespace,in_quota_gpu_memory_gb_hours,over_quota_gpu_memory_gb_hours,in_quota_mig_slice_hours,over_quota_mig_slice_hours",
+		"ElasticQuota,ns-1,eq-1,ns-1,10.0000,0.0000,0.0000,0.0000",
+	}, lines)
+}
+
+func TestUsageReportServer_InvalidRequests(t *testing.T) {
+	server := newUsageReportTestServer(t)
+	testCases := []struct {
+		name     string
+		method   string
+		url      string
+		expected int
+	}{
+		{
+			name:     "Invalid date",
+			method:   http.MethodGet,
+			url:      UsageReportPath + "?from=01-01-2023",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid format",
+			method:   http.MethodGet,
+			url:      UsageReportPath + "?format=xml",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid method",
+			method:   http.MethodPost,
+			url:      UsageReportPath,
+			expected: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+			assert.Equal(t, tt.expected, rec.Code)
+		})
+	}
+}
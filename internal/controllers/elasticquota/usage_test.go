@@ -0,0 +1,156 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package elasticquota
+
+import (
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu/util"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestElasticQuotaPodsReconciler_AccountUsage(t *testing.T) {
+	now := time.Date(2023, 1, 2, 1, 0, 0, 0, time.UTC)
+	newPod := func(name string, capacityInfo constant.CapacityInfo, startTime time.Time, container v1.Container) v1.Pod {
+		pod := factory.BuildPod("ns-1", name).
+			WithLabel(v1alpha1.LabelCapacityInfo, string(capacityInfo)).
+			WithContainer(container).
+			Get()
+		pod.Status.StartTime = &metav1.Time{Time: startTime}
+		return pod
+	}
+	gpuContainer := factory.BuildContainer("c1", "test").WithNvidiaGPURequest(1).Get()
+	migContainer := factory.BuildContainer("c1", "test").WithScalarResourceRequest("nvidia.com/mig-1g.10gb", 2).Get()
+	cpuContainer := factory.BuildContainer("c1", "test").WithCPUMilliRequest(1000).Get()
+
+	tests := []struct {
+		name               string
+		records            []v1alpha1.ElasticQuotaUsageRecord
+		pods               []v1.Pod
+		lastAccountingTime *metav1.Time
+		expected           []v1alpha1.ElasticQuotaUsageRecord
+	}{
+		{
+			name:     "No records, no pods",
+			expected: []v1alpha1.ElasticQuotaUsageRecord{},
+		},
+		{
+			name: "Pods started after last accounting time are accounted from their start",
+			pods: []v1.Pod{
+				newPod("pd-1", constant.CapacityInfoInQuota, now.Add(-30*time.Minute), gpuContainer),
+				newPod("pd-2", constant.CapacityInfoOverQuota, now.Add(-30*time.Minute), migContainer),
+				newPod("pd-3", constant.CapacityInfoInQuota, now.Add(-30*time.Minute), cpuContainer),
+			},
+			lastAccountingTime: &metav1.Time{Time: now.Add(-time.Hour)},
+			expected: []v1alpha1.ElasticQuotaUsageRecord{
+				{
+					Namespace: "ns-1",
+					Date:      "2023-01-02",
+					InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 10 * 1800},
+					OverQuota: v1alpha1.GPUUsage{GPUMemoryGBSeconds: 20 * 1800, MigSliceSeconds: 2 * 1800},
+				},
+			},
+		},
+		{
+			name: "Consumption is split across days and added to existing records",
+			records: []v1alpha1.ElasticQuotaUsageRecord{
+				{
+					Namespace: "ns-1",
+					Date:      "2023-01-01",
+					InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 100},
+				},
+				{
+					Namespace: "ns-2",
+					Date:      "2023-01-01",
+					InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 100},
+				},
+			},
+			pods: []v1.Pod{
+				newPod("pd-1", constant.CapacityInfoInQuota, now.Add(-24*time.Hour), gpuContainer),
+			},
+			lastAccountingTime: &metav1.Time{Time: now.Add(-2 * time.Hour)},
+			expected: []v1alpha1.ElasticQuotaUsageRecord{
+				{
+					Namespace: "ns-1",
+					Date:      "2023-01-01",
+					InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 100 + 10*3600},
+				},
+				{
+					Namespace: "ns-2",
+					Date:      "2023-01-01",
+					InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 100},
+				},
+				{
+					Namespace: "ns-1",
+					Date:      "2023-01-02",
+					InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 10 * 3600},
+				},
+			},
+		},
+		{
+			name: "Records older than the retention are removed",
+			records: []v1alpha1.ElasticQuotaUsageRecord{
+				{
+					Namespace: "ns-1",
+					Date:      now.Add(-usageRetention - 24*time.Hour).Format(v1alpha1.UsageRecordDateFormat),
+					InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 100},
+				},
+				{
+					Namespace: "ns-1",
+					Date:      "2023-01-02",
+					InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 100},
+				},
+			},
+			lastAccountingTime: &metav1.Time{Time: now.Add(-time.Hour)},
+			expected: []v1alpha1.ElasticQuotaUsageRecord{
+				{
+					Namespace: "ns-1",
+					Date:      "2023-01-02",
+					InQuota:   v1alpha1.GPUUsage{GPUMemoryGBSeconds: 100},
+				},
+			},
+		},
+		{
+			name: "Without last accounting time pods are accounted from their start",
+			pods: []v1.Pod{
+				newPod("pd-1", constant.CapacityInfoOverQuota, now.Add(-time.Minute), gpuContainer),
+			},
+			expected: []v1alpha1.ElasticQuotaUsageRecord{
+				{
+					Namespace: "ns-1",
+					Date:      "2023-01-02",
+					OverQuota: v1alpha1.GPUUsage{GPUMemoryGBSeconds: 10 * 60},
+				},
+			},
+		},
+	}
+
+	r := elasticQuotaPodsReconciler{
+		resourceCalculator: &util.ResourceCalculator{NvidiaGPUDeviceMemoryGB: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := r.AccountUsage(tt.records, tt.pods, tt.lastAccountingTime, now)
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+}
@@ -18,8 +18,10 @@ package gpuagent
 
 import (
 	"context"
+	"github.com/nebuly-ai/nos/internal/metrics"
 	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
 	"github.com/nebuly-ai/nos/pkg/gpu/slicing"
 	"github.com/nebuly-ai/nos/pkg/util/predicate"
 	v1 "k8s.io/api/core/v1"
@@ -55,7 +57,12 @@ func (r *Reporter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result
 	if err := r.Client.Get(ctx, client.ObjectKey{Name: req.Name, Namespace: req.Namespace}, &instance); err != nil {
 		return ctrl.Result{}, err
 	}
+	// On nodes with hybrid partitioning, the status annotations of the MIG GPUs are
+	// reported by the MIG agent, so they must be ignored.
 	lastStatusAnnotations, _ := gpu.ParseNodeAnnotations(instance)
+	lastStatusAnnotations = lastStatusAnnotations.Filter(func(a gpu.StatusAnnotation) bool {
+		return !isMigStatusAnnotation(a)
+	})
 
 	// Fetch GPUs
 	devices, err := r.gpuClient.GetDevices(ctx)
@@ -66,6 +73,8 @@ func (r *Reporter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result
 
 	// Check if status changed
 	currentStatusAnnotations := devices.AsStatusAnnotation(slicing.ExtractProfileNameStr)
+	gpuModel, _ := gpu.GetModel(instance)
+	metrics.SetGpuSlices(instance.Name, gpuModel, currentStatusAnnotations)
 	logger.Info("computed annotations", "current", currentStatusAnnotations, "last", lastStatusAnnotations, "devices", devices)
 	if currentStatusAnnotations.Equal(lastStatusAnnotations) {
 		logger.Info("current status is equal to last reported status, nothing to do")
@@ -78,10 +87,14 @@ func (r *Reporter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result
 	if updated.Annotations == nil {
 		updated.Annotations = make(map[string]string)
 	}
-	for k := range updated.Annotations {
-		if strings.HasPrefix(k, v1alpha1.AnnotationGpuStatusPrefix) {
-			delete(updated.Annotations, k)
+	for k, v := range updated.Annotations {
+		if !strings.HasPrefix(k, v1alpha1.AnnotationGpuStatusPrefix) {
+			continue
 		}
+		if a, err := gpu.ParseStatusAnnotation(k, v); err == nil && isMigStatusAnnotation(a) {
+			continue
+		}
+		delete(updated.Annotations, k)
 	}
 	for _, a := range currentStatusAnnotations {
 		updated.Annotations[a.String()] = a.GetValue()
@@ -95,6 +108,11 @@ func (r *Reporter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result
 	return ctrl.Result{RequeueAfter: r.refreshInterval}, nil
 }
 
+// isMigStatusAnnotation returns true if the annotation refers to a MIG device
+func isMigStatusAnnotation(a gpu.StatusAnnotation) bool {
+	return mig.ProfileName(a.ProfileName).IsValid()
+}
+
 func (r *Reporter) SetupWithManager(mgr ctrl.Manager, controllerName string, nodeName string) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gpupartitioner
+
+import (
+	"context"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/util/pod"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sync"
+	"time"
+)
+
+// CompactionLoop periodically compacts the GPU partitioning of the nodes during quiet periods,
+// namely when no pending pod could be helped by extra GPU resources for at least the configured
+// amount of time. Compaction merges the free capacity of the GPUs into larger slices, so that large
+// slices are already available when new pods request them.
+//
+// The loop shares the partitioning mutex with the controllers of the same partitioning kind, so that
+// compaction plans are never computed or applied while another plan is being applied to the nodes.
+type CompactionLoop struct {
+	client.Client
+	clusterState  *state.ClusterState
+	kind          gpu.PartitioningKind
+	compactor     core.Compactor
+	actuator      core.Actuator
+	snapshotTaker core.SnapshotTaker
+	interval      time.Duration
+	quietPeriod   time.Duration
+
+	partitioningMtx *sync.Mutex
+	lastActivity    time.Time
+}
+
+func NewCompactionLoop(
+	client client.Client,
+	clusterState *state.ClusterState,
+	kind gpu.PartitioningKind,
+	compactor core.Compactor,
+	actuator core.Actuator,
+	snapshotTaker core.SnapshotTaker,
+	interval time.Duration,
+	quietPeriod time.Duration,
+	partitioningMtx *sync.Mutex,
+) *CompactionLoop {
+	return &CompactionLoop{
+		Client:          client,
+		clusterState:    clusterState,
+		kind:            kind,
+		compactor:       compactor,
+		actuator:        actuator,
+		snapshotTaker:   snapshotTaker,
+		interval:        interval,
+		quietPeriod:     quietPeriod,
+		partitioningMtx: partitioningMtx,
+		lastActivity:    time.Now(),
+	}
+}
+
+// Start runs the compaction loop until the context is cancelled
+func (l *CompactionLoop) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("compaction").WithValues("kind", l.kind)
+	ctx = log.IntoContext(ctx, logger)
+	logger.Info("starting compaction loop", "interval", l.interval.String(), "quietPeriod", l.quietPeriod.String())
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := l.RunOnce(ctx); err != nil {
+				logger.Error(err, "unable to compact GPU partitioning")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so that only
+// the leader changes the partitioning of the nodes
+func (l *CompactionLoop) NeedLeaderElection() bool {
+	return true
+}
+
+// RunOnce compacts the GPU partitioning of the nodes if the cluster is in a quiet period.
+// It returns true if a compaction plan has been applied.
+func (l *CompactionLoop) RunOnce(ctx context.Context) (bool, error) {
+	l.partitioningMtx.Lock()
+	defer l.partitioningMtx.Unlock()
+	logger := log.FromContext(ctx)
+
+	if !l.clusterState.IsPartitioningEnabled(l.kind) {
+		return false, nil
+	}
+
+	// Any pending pod that could be helped by extra resources resets the quiet period
+	pendingPods, err := fetchPendingPods(ctx, l.Client)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range pendingPods {
+		if pod.ExtraResourcesCouldHelpScheduling(p) {
+			logger.V(1).Info("found pending pods that could be helped by extra resources, skipping compaction")
+			l.lastActivity = time.Now()
+			return false, nil
+		}
+	}
+	if quietFor := time.Since(l.lastActivity); quietFor < l.quietPeriod {
+		logger.V(1).Info("quiet period not elapsed yet, skipping compaction", "quietFor", quietFor.String())
+		return false, nil
+	}
+	if waitingAnyNodeToReportPlan(l.clusterState) {
+		logger.V(1).Info("last partitioning plan has not been reported by all nodes yet, skipping compaction")
+		return false, nil
+	}
+
+	snapshot, err := l.snapshotTaker.TakeSnapshot(l.clusterState)
+	if err != nil {
+		return false, err
+	}
+	plan, err := l.compactor.Compact(ctx, snapshot.Clone())
+	if err != nil {
+		return false, err
+	}
+	if snapshot.GetPartitioningState().Equal(plan.DesiredState) {
+		logger.V(1).Info("GPU partitioning is already compact, nothing to do")
+		return false, nil
+	}
+
+	logger.Info("applying compaction plan", "partitioning", plan)
+	return l.actuator.Apply(ctx, snapshot, plan)
+}
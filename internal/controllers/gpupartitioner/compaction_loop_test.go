@@ -0,0 +1,200 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gpupartitioner_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/mig"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/resource"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/nebuly-ai/nos/pkg/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCompactionLoop__RunOnce(t *testing.T) {
+	migLabels := map[string]string{
+		v1alpha1.LabelGpuPartitioning: gpu.PartitioningKindMig.String(),
+		constant.LabelNvidiaCount:     "1",
+		constant.LabelNvidiaProduct:   string(gpu.GPUModel_A30),
+	}
+	scatteredNode := factory.BuildNode("node-1").
+		WithLabels(migLabels).
+		WithAnnotations(map[string]string{
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, "1g.6gb", resource.StatusFree): "4",
+		}).
+		Get()
+	compactNode := factory.BuildNode("node-1").
+		WithLabels(migLabels).
+		WithAnnotations(map[string]string{
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, "4g.24gb", resource.StatusFree): "1",
+		}).
+		Get()
+	notReportedNode := factory.BuildNode("node-1").
+		WithLabels(migLabels).
+		WithAnnotations(map[string]string{
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, "1g.6gb", resource.StatusFree): "4",
+			v1alpha1.AnnotationPartitioningPlan:                                               "2",
+			v1alpha1.AnnotationReportedPartitioningPlan:                                       "1",
+		}).
+		Get()
+	unschedulablePod := factory.BuildPod("ns-1", "pod-1").WithPhase(v1.PodPending).Get()
+	unschedulablePod.Status.Conditions = []v1.PodCondition{
+		{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: v1.PodReasonUnschedulable},
+	}
+
+	testCases := []struct {
+		name              string
+		node              v1.Node
+		pods              []v1.Pod
+		quietPeriod       time.Duration
+		expectedCompacted bool
+	}{
+		{
+			name:              "Pending pods could be helped by extra resources, should not compact",
+			node:              scatteredNode,
+			pods:              []v1.Pod{unschedulablePod},
+			quietPeriod:       0,
+			expectedCompacted: false,
+		},
+		{
+			name:              "Quiet period not elapsed, should not compact",
+			node:              scatteredNode,
+			quietPeriod:       time.Hour,
+			expectedCompacted: false,
+		},
+		{
+			name:              "Node has not reported last plan, should not compact",
+			node:              notReportedNode,
+			quietPeriod:       0,
+			expectedCompacted: false,
+		},
+		{
+			name:              "Node already compact, should not compact",
+			node:              compactNode,
+			quietPeriod:       0,
+			expectedCompacted: false,
+		},
+		{
+			name:              "Quiet period elapsed, should compact",
+			node:              scatteredNode,
+			quietPeriod:       0,
+			expectedCompacted: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			node := tt.node.DeepCopy()
+			objects := []client.Object{node}
+			for i := range tt.pods {
+				objects = append(objects, &tt.pods[i])
+			}
+			fakeClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+			ni := framework.NewNodeInfo()
+			ni.SetNode(node)
+			clusterState := state.NewClusterState(map[string]framework.NodeInfo{node.Name: *ni})
+
+			mockPartitioner := mocks.NewPartitioner(t)
+			if tt.expectedCompacted {
+				expectedPartitioning := state.NodePartitioning{
+					GPUs: []state.GPUPartitioning{
+						{GPUIndex: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-4g.24gb": 1}},
+					},
+				}
+				mockPartitioner.On("ApplyPartitioning", mock.Anything, mock.Anything, mock.Anything, expectedPartitioning).
+					Return(nil).
+					Once()
+			}
+
+			loop := mig.NewCompactionLoop(
+				fakeClient,
+				clusterState,
+				core.NewActuator(fakeClient, mockPartitioner),
+				time.Minute,
+				tt.quietPeriod,
+				&sync.Mutex{},
+			)
+			compacted, err := loop.RunOnce(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCompacted, compacted)
+		})
+	}
+}
+
+func TestCompactionLoop__RunOnce_WaitsForPartitioningMutex(t *testing.T) {
+	node := factory.BuildNode("node-1").
+		WithLabels(map[string]string{
+			v1alpha1.LabelGpuPartitioning: gpu.PartitioningKindMig.String(),
+			constant.LabelNvidiaCount:     "1",
+			constant.LabelNvidiaProduct:   string(gpu.GPUModel_A30),
+		}).
+		WithAnnotations(map[string]string{
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, "1g.6gb", resource.StatusFree): "4",
+		}).
+		Get()
+	fakeClient := fake.NewClientBuilder().WithObjects(&node).Build()
+	ni := framework.NewNodeInfo()
+	ni.SetNode(&node)
+	clusterState := state.NewClusterState(map[string]framework.NodeInfo{node.Name: *ni})
+	mockPartitioner := mocks.NewPartitioner(t)
+	mockPartitioner.On("ApplyPartitioning", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil).
+		Once()
+
+	// Simulate another controller applying a plan of the same partitioning kind
+	var partitioningMtx sync.Mutex
+	partitioningMtx.Lock()
+	loop := mig.NewCompactionLoop(
+		fakeClient,
+		clusterState,
+		core.NewActuator(fakeClient, mockPartitioner),
+		time.Minute,
+		0,
+		&partitioningMtx,
+	)
+	done := make(chan bool)
+	go func() {
+		compacted, err := loop.RunOnce(context.Background())
+		assert.NoError(t, err)
+		done <- compacted
+	}()
+
+	select {
+	case <-done:
+		assert.Fail(t, "compaction should wait for the partitioning mutex to be released")
+	case <-time.After(100 * time.Millisecond):
+	}
+	mockPartitioner.AssertNotCalled(t, "ApplyPartitioning", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	partitioningMtx.Unlock()
+	assert.True(t, <-done)
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package gpupartitioner
+
+import (
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"time"
+)
+
+const (
+	metricsNamespace = "nos"
+	metricsSubsystem = "gpu_partitioner"
+
+	labelKind = "kind"
+	labelNode = "node"
+)
+
+var (
+	plansComputedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "plans_computed_total",
+			Help:      "Number of partitioning plans computed by the planner.",
+		},
+		[]string{labelKind},
+	)
+	plansAppliedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "plans_applied_total",
+			Help:      "Number of partitioning plans applied to the cluster.",
+		},
+		[]string{labelKind},
+	)
+	plansFailedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "plans_failed_total",
+			Help:      "Number of partitioning plans that could not be computed or applied.",
+		},
+		[]string{labelKind},
+	)
+	planningDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "planning_duration_seconds",
+			Help:      "Time taken by the planner to compute a partitioning plan.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+		},
+		[]string{labelKind},
+	)
+	batchSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "batch_size",
+			Help:      "Number of pending pods included in each batch processed by the partitioner.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		},
+		[]string{labelKind},
+	)
+	pendingPodsCouldBeHelped = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "pending_pods_could_be_helped",
+			Help:      "Number of pending pods that could currently be scheduled by changing the partitioning of the GPUs.",
+		},
+		[]string{labelKind},
+	)
+	pendingPodsHelpedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "pending_pods_helped_total",
+			Help:      "Number of pending pods placed by the partitioning plans applied to the cluster.",
+		},
+		[]string{labelKind},
+	)
+	nodeGeometryChangesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "node_geometry_changes_total",
+			Help:      "Number of times the partitioning of the GPUs of a node has been changed.",
+		},
+		[]string{labelKind, labelNode},
+	)
+	planReportWaitSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "plan_report_wait_seconds",
+			Help:      "Time the partitioner has been waiting for the nodes to report the last partitioning plan, zero if not waiting.",
+		},
+		[]string{labelKind},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		plansComputedTotal,
+		plansAppliedTotal,
+		plansFailedTotal,
+		planningDurationSeconds,
+		batchSize,
+		pendingPodsCouldBeHelped,
+		pendingPodsHelpedTotal,
+		nodeGeometryChangesTotal,
+		planReportWaitSeconds,
+	)
+}
+
+func observeBatch(kind gpu.PartitioningKind, size int) {
+	batchSize.WithLabelValues(string(kind)).Observe(float64(size))
+}
+
+func observePendingPods(kind gpu.PartitioningKind, couldBeHelped int) {
+	pendingPodsCouldBeHelped.WithLabelValues(string(kind)).Set(float64(couldBeHelped))
+}
+
+func observePlanning(kind gpu.PartitioningKind, duration time.Duration, err error) {
+	if err != nil {
+		plansFailedTotal.WithLabelValues(string(kind)).Inc()
+		return
+	}
+	plansComputedTotal.WithLabelValues(string(kind)).Inc()
+	planningDurationSeconds.WithLabelValues(string(kind)).Observe(duration.Seconds())
+}
+
+// observeApply records the outcome of applying the plan provided as argument. The nodes whose
+// partitioning is changed by the plan are counted only if the plan was actually applied.
+func observeApply(kind gpu.PartitioningKind, current state.PartitioningState, plan core.PartitioningPlan, applied bool, err error) {
+	if err != nil {
+		plansFailedTotal.WithLabelValues(string(kind)).Inc()
+		return
+	}
+	if !applied {
+		return
+	}
+	plansAppliedTotal.WithLabelValues(string(kind)).Inc()
+	pendingPodsHelpedTotal.WithLabelValues(string(kind)).Add(float64(len(plan.Placements)))
+	for node, desired := range plan.DesiredState {
+		if !desired.Equal(current[node]) {
+			nodeGeometryChangesTotal.WithLabelValues(string(kind), node).Inc()
+		}
+	}
+}
+
+func observePlanReportWait(kind gpu.PartitioningKind, waitingSince time.Time) {
+	if waitingSince.IsZero() {
+		planReportWaitSeconds.WithLabelValues(string(kind)).Set(0)
+		return
+	}
+	planReportWaitSeconds.WithLabelValues(string(kind)).Set(time.Since(waitingSince).Seconds())
+}
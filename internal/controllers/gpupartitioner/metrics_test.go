@@ -0,0 +1,106 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package gpupartitioner
+
+import (
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+	"time"
+)
+
+func TestObservePlanning(t *testing.T) {
+	kind := gpu.PartitioningKind("test-planning")
+
+	observePlanning(kind, time.Second, nil)
+	observePlanning(kind, time.Second, nil)
+	observePlanning(kind, time.Second, fmt.Errorf("error"))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(plansComputedTotal.WithLabelValues(string(kind))))
+	assert.Equal(t, 1.0, testutil.ToFloat64(plansFailedTotal.WithLabelValues(string(kind))))
+}
+
+func TestObserveApply(t *testing.T) {
+	current := state.PartitioningState{
+		"node-1": state.NodePartitioning{
+			GPUs: []state.GPUPartitioning{{GPUIndex: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-1g.10gb": 7}}},
+		},
+		"node-2": state.NodePartitioning{
+			GPUs: []state.GPUPartitioning{{GPUIndex: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-7g.80gb": 1}}},
+		},
+	}
+	plan := core.NewPartitioningPlan(state.PartitioningState{
+		"node-1": current["node-1"],
+		"node-2": state.NodePartitioning{
+			GPUs: []state.GPUPartitioning{{GPUIndex: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-3g.40gb": 2}}},
+		},
+	})
+	plan.Placements = []core.PodPlacement{
+		{Pod: types.NamespacedName{Namespace: "ns", Name: "pod-1"}, NodeName: "node-2"},
+		{Pod: types.NamespacedName{Namespace: "ns", Name: "pod-2"}, NodeName: "node-2"},
+	}
+
+	t.Run("Plan not applied", func(t *testing.T) {
+		kind := gpu.PartitioningKind("test-apply-not-applied")
+		observeApply(kind, current, plan, false, nil)
+		assert.Equal(t, 0.0, testutil.ToFloat64(plansAppliedTotal.WithLabelValues(string(kind))))
+		assert.Equal(t, 0.0, testutil.ToFloat64(pendingPodsHelpedTotal.WithLabelValues(string(kind))))
+		assert.Equal(t, 0.0, testutil.ToFloat64(nodeGeometryChangesTotal.WithLabelValues(string(kind), "node-2")))
+	})
+
+	t.Run("Error applying plan", func(t *testing.T) {
+		kind := gpu.PartitioningKind("test-apply-error")
+		observeApply(kind, current, plan, false, fmt.Errorf("error"))
+		assert.Equal(t, 1.0, testutil.ToFloat64(plansFailedTotal.WithLabelValues(string(kind))))
+		assert.Equal(t, 0.0, testutil.ToFloat64(plansAppliedTotal.WithLabelValues(string(kind))))
+	})
+
+	t.Run("Plan applied", func(t *testing.T) {
+		kind := gpu.PartitioningKind("test-apply-applied")
+		observeApply(kind, current, plan, true, nil)
+		assert.Equal(t, 1.0, testutil.ToFloat64(plansAppliedTotal.WithLabelValues(string(kind))))
+		assert.Equal(t, 2.0, testutil.ToFloat64(pendingPodsHelpedTotal.WithLabelValues(string(kind))))
+		assert.Equal(t, 0.0, testutil.ToFloat64(nodeGeometryChangesTotal.WithLabelValues(string(kind), "node-1")))
+		assert.Equal(t, 1.0, testutil.ToFloat64(nodeGeometryChangesTotal.WithLabelValues(string(kind), "node-2")))
+	})
+}
+
+func TestObservePendingPods(t *testing.T) {
+	kind := gpu.PartitioningKind("test-pending-pods")
+
+	observePendingPods(kind, 3)
+	observePendingPods(kind, 2)
+	assert.Equal(t, 2.0, testutil.ToFloat64(pendingPodsCouldBeHelped.WithLabelValues(string(kind))))
+
+	observePendingPods(kind, 0)
+	assert.Equal(t, 0.0, testutil.ToFloat64(pendingPodsCouldBeHelped.WithLabelValues(string(kind))))
+}
+
+func TestObservePlanReportWait(t *testing.T) {
+	kind := gpu.PartitioningKind("test-report-wait")
+
+	observePlanReportWait(kind, time.Now().Add(-time.Minute))
+	assert.GreaterOrEqual(t, testutil.ToFloat64(planReportWaitSeconds.WithLabelValues(string(kind))), 60.0)
+
+	observePlanReportWait(kind, time.Time{})
+	assert.Equal(t, 0.0, testutil.ToFloat64(planReportWaitSeconds.WithLabelValues(string(kind))))
+}
@@ -93,8 +93,8 @@ func (c *NodeController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 			return ctrl.Result{}, fmt.Errorf("failed to initialize node MIG partitioning: %w", err)
 		}
 	}
-	// Handle MPS node initialization
-	if gpu.IsMpsPartitioningEnabled(instance) && !nodeInitialized {
+	// Handle MPS and hybrid node initialization: GPUs are partitioned on demand, so they don't need any initial geometry
+	if (gpu.IsMpsPartitioningEnabled(instance) || gpu.IsHybridPartitioningEnabled(instance)) && !nodeInitialized {
 		nodeInitialized = true
 	}
 
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gpupartitioner_test
+
+import (
+	"context"
+	"github.com/nebuly-ai/nos/internal/controllers/gpupartitioner"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/mig"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strings"
+	"testing"
+)
+
+func TestNodeController__Reconcile_DryRunDoesNotInitializeMigNodes(t *testing.T) {
+	node := factory.BuildNode("node-1").
+		WithLabels(map[string]string{
+			constant.LabelNvidiaProduct:   gpu.GPUModel_A100_PCIe_80GB.String(),
+			constant.LabelNvidiaCount:     "1",
+			v1alpha1.LabelGpuPartitioning: gpu.PartitioningKindMig.String(),
+		}).
+		WithAnnotations(map[string]string{"foo": "bar"}).
+		Get()
+	k8sClient := fake.NewClientBuilder().WithObjects(&node).Build()
+	recorder := record.NewFakeRecorder(10)
+	clusterState := state.NewEmptyClusterState()
+	controller := gpupartitioner.NewNodeController(
+		k8sClient,
+		scheme.Scheme,
+		mig.NewDryRunNodeInitializer(recorder),
+		clusterState,
+	)
+
+	_, err := controller.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: node.Name}})
+	assert.NoError(t, err)
+
+	// The node annotations are untouched and the node is still not initialized
+	var updated v1.Node
+	assert.NoError(t, k8sClient.Get(context.Background(), client.ObjectKey{Name: node.Name}, &updated))
+	assert.Equal(t, node.Annotations, updated.Annotations)
+	assert.False(t, core.IsNodeInitialized(updated))
+	_, ok := clusterState.GetNode(node.Name)
+	assert.False(t, ok)
+
+	// The initial geometry is recorded as an event
+	assert.Len(t, recorder.Events, 1)
+	event := <-recorder.Events
+	assert.True(t, strings.Contains(event, core.EventReasonDryRunPartitioning), event)
+}
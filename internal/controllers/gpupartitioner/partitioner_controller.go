@@ -28,9 +28,12 @@ import (
 	"github.com/nebuly-ai/nos/pkg/util/pod"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sync"
 	"time"
 )
 
@@ -44,6 +47,13 @@ type Controller struct {
 	actuator      core.Actuator
 	snapshotTaker core.SnapshotTaker
 	kind          gpu.PartitioningKind
+	recorder      record.EventRecorder
+	// partitioningMtx serializes the changes to the partitioning of the nodes with the
+	// other components applying plans of the same partitioning kind
+	partitioningMtx *sync.Mutex
+	// waitingReportSince is the time at which the controller started waiting for the
+	// nodes to report the last partitioning plan, zero if it is not waiting
+	waitingReportSince time.Time
 }
 
 func NewController(
@@ -54,22 +64,28 @@ func NewController(
 	kind gpu.PartitioningKind,
 	planner core.Planner,
 	actuator core.Actuator,
-	snapshotTaker core.SnapshotTaker) Controller {
+	snapshotTaker core.SnapshotTaker,
+	recorder record.EventRecorder,
+	partitioningMtx *sync.Mutex) Controller {
 	return Controller{
-		Scheme:        scheme,
-		Client:        client,
-		clusterState:  clusterState,
-		currentBatch:  make(map[string]v1.Pod),
-		podBatcher:    podBatcher,
-		planner:       planner,
-		actuator:      actuator,
-		snapshotTaker: snapshotTaker,
-		kind:          kind,
+		Scheme:          scheme,
+		Client:          client,
+		clusterState:    clusterState,
+		currentBatch:    make(map[string]v1.Pod),
+		podBatcher:      podBatcher,
+		planner:         planner,
+		actuator:        actuator,
+		snapshotTaker:   snapshotTaker,
+		kind:            kind,
+		recorder:        recorder,
+		partitioningMtx: partitioningMtx,
 	}
 }
 
 //+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;patch;create
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 //+kubebuilder:rbac:groups=core,resources=persistentvolumes;persistentvolumeclaims;namespaces;services;replicationcontrollers,verbs=get;list;watch
 //+kubebuilder:rbac:groups=apps,resources=statefulsets;replicasets,verbs=get;list;watch
 //+kubebuilder:rbac:groups=storage.k8s.io,resources=csinodes;storageclasses;csidrivers;csistoragecapacities,verbs=get;list;watch
@@ -115,11 +131,17 @@ func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	}
 
 	// Check if last plan has been reported
-	if waiting := c.waitingAnyNodeToReportPlan(); waiting {
+	if waiting := waitingAnyNodeToReportPlan(c.clusterState); waiting {
 		logger.V(1).Info("last partitioning plan has not been reported by all nodes yet, skipping reconcile")
+		if c.waitingReportSince.IsZero() {
+			c.waitingReportSince = time.Now()
+		}
+		observePlanReportWait(c.kind, c.waitingReportSince)
 		c.podBatcher.Reset()
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 	}
+	c.waitingReportSince = time.Time{}
+	observePlanReportWait(c.kind, c.waitingReportSince)
 
 	// Add Pod to current batch only if not already present
 	if _, ok := c.currentBatch[namespacedName]; !ok {
@@ -132,6 +154,7 @@ func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	select {
 	case <-c.podBatcher.Ready():
 		logger.V(1).Info("batch ready")
+		observeBatch(c.kind, len(c.currentBatch))
 		c.currentBatch = make(map[string]v1.Pod)
 		err := c.processPendingPods(ctx)
 		return ctrl.Result{}, err
@@ -149,19 +172,18 @@ func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 }
 
 func (c *Controller) processPendingPods(ctx context.Context) error {
+	c.partitioningMtx.Lock()
+	defer c.partitioningMtx.Unlock()
 	logger := log.FromContext(ctx)
 	logger.Info("processing pending pods")
 
 	// Fetch pending pods
-	allPendingPods, err := c.fetchPendingPods(ctx)
+	allPendingPods, err := fetchPendingPods(ctx, c.Client)
 	if err != nil {
 		logger.Error(err, "unable to fetch pending pods")
 		return err
 	}
 	logger.Info(fmt.Sprintf("found %d pending pods", len(allPendingPods)))
-	if len(allPendingPods) == 0 {
-		return nil
-	}
 
 	// Extract pods that can be helped with extra resources
 	var pods = make([]v1.Pod, 0)
@@ -171,6 +193,7 @@ func (c *Controller) processPendingPods(ctx context.Context) error {
 		}
 	}
 	logger.Info(fmt.Sprintf("%d out of %d pending pods could be helped", len(pods), len(allPendingPods)))
+	observePendingPods(c.kind, len(pods))
 	if len(allPendingPods) == 0 {
 		return nil
 	}
@@ -182,7 +205,9 @@ func (c *Controller) processPendingPods(ctx context.Context) error {
 	}
 
 	// Compute desired state
+	planningStart := time.Now()
 	plan, err := c.planner.Plan(ctx, snapshot.Clone(), pods)
+	observePlanning(c.kind, time.Since(planningStart), err)
 	if err != nil {
 		logger.Error(err, "unable to plan desired partitioning state")
 		return err
@@ -190,16 +215,61 @@ func (c *Controller) processPendingPods(ctx context.Context) error {
 	logger.Info("computed desired partitioning state", "partitioning", plan)
 
 	// Apply partitioning plan
-	_, err = c.actuator.Apply(ctx, snapshot.Clone(), plan)
+	applied, err := c.actuator.Apply(ctx, snapshot.Clone(), plan)
+	observeApply(c.kind, snapshot.GetPartitioningState(), plan, applied, err)
 	if err != nil {
 		logger.Error(err, "unable to apply desired partitioning state")
 		return err
 	}
 
+	// Pods could still be placed by plans waiting for approval or recorded in dry-run mode
+	if isPlanPending(snapshot.GetPartitioningState(), plan, applied) {
+		logger.V(1).Info("partitioning plan has not been applied, skipping unplaced pods events")
+		return nil
+	}
+	c.recordUnplacedPods(snapshot, pods, plan)
+
 	return nil
 }
 
-func (c *Controller) fetchPendingPods(ctx context.Context) ([]v1.Pod, error) {
+// isPlanPending returns true if the plan would change the partitioning of the nodes or evict any pod,
+// but it has not been applied, for instance because it is waiting for approval or because the
+// partitioner runs in dry-run mode
+func isPlanPending(currentState state.PartitioningState, plan core.PartitioningPlan, applied bool) bool {
+	if applied {
+		return false
+	}
+	if len(plan.Victims) > 0 {
+		return true
+	}
+	return !currentState.Equal(plan.DesiredState) && !plan.DesiredState.IsEmpty()
+}
+
+// recordUnplacedPods records an Event on each of the pods provided as argument that lacks GPU slices
+// in the snapshot and that the partitioning plan is not able to make schedulable
+func (c *Controller) recordUnplacedPods(snapshot core.Snapshot, pods []v1.Pod, plan core.PartitioningPlan) {
+	placed := make(map[types.NamespacedName]bool, len(plan.Placements))
+	for _, p := range plan.Placements {
+		placed[p.Pod] = true
+	}
+	for i := range pods {
+		if placed[util.GetNamespacedName(&pods[i])] {
+			continue
+		}
+		if len(snapshot.GetLackingSlices(pods[i])) == 0 {
+			continue
+		}
+		c.recorder.Eventf(
+			&pods[i],
+			v1.EventTypeWarning,
+			core.EventReasonGpuSlicesUnavailable,
+			"No node can provide the requested GPU slices %s",
+			core.FormatRequestedGpuResources(pods[i]),
+		)
+	}
+}
+
+func fetchPendingPods(ctx context.Context, c client.Client) ([]v1.Pod, error) {
 	var podList v1.PodList
 	if err := c.List(ctx, &podList, client.MatchingFields{constant.PodPhaseKey: string(v1.PodPending)}); err != nil {
 		return nil, err
@@ -209,17 +279,17 @@ func (c *Controller) fetchPendingPods(ctx context.Context) ([]v1.Pod, error) {
 	}), nil
 }
 
-func (c *Controller) waitingAnyNodeToReportPlan() bool {
-	nodes := c.clusterState.GetNodes()
+func waitingAnyNodeToReportPlan(clusterState *state.ClusterState) bool {
+	nodes := clusterState.GetNodes()
 	for _, n := range nodes {
-		if c.waitingToReportPlan(*n.Node()) {
+		if waitingToReportPlan(*n.Node()) {
 			return true
 		}
 	}
 	return false
 }
 
-func (c *Controller) waitingToReportPlan(n v1.Node) bool {
+func waitingToReportPlan(n v1.Node) bool {
 	plan, ok := n.Annotations[v1alpha1.AnnotationPartitioningPlan]
 	if !ok || plan == "" {
 		return false
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gpupartitioner
+
+import (
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/nebuly-ai/nos/pkg/test/mocks"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"testing"
+)
+
+func TestController__recordUnplacedPods(t *testing.T) {
+	newPod := func(name string, profile v1.ResourceName) v1.Pod {
+		return factory.BuildPod("ns-1", name).
+			WithContainer(
+				factory.BuildContainer("test", "test").
+					WithScalarResourceRequest(profile, 1).
+					Get(),
+			).
+			Get()
+	}
+	placedPod := newPod("pod-1", "nvidia.com/mig-1g.5gb")
+	lackingPod := newPod("pod-2", "nvidia.com/mig-7g.40gb")
+	notLackingPod := newPod("pod-3", "nvidia.com/mig-1g.5gb")
+	pods := []v1.Pod{placedPod, lackingPod, notLackingPod}
+	plan := core.NewPartitioningPlan(state.PartitioningState{})
+	plan.Placements = []core.PodPlacement{
+		{Pod: types.NamespacedName{Namespace: "ns-1", Name: "pod-1"}, NodeName: "node-1"},
+	}
+
+	mockSnapshot := mocks.NewSnapshot(t)
+	mockSnapshot.On("GetLackingSlices", lackingPod).Return(map[gpu.Slice]int{mig.Profile7g40gb: 1})
+	mockSnapshot.On("GetLackingSlices", notLackingPod).Return(map[gpu.Slice]int{})
+
+	recorder := record.NewFakeRecorder(10)
+	c := Controller{recorder: recorder}
+	c.recordUnplacedPods(mockSnapshot, pods, plan)
+
+	close(recorder.Events)
+	events := make([]string, 0)
+	for e := range recorder.Events {
+		events = append(events, e)
+	}
+	assert.Equal(
+		t,
+		[]string{"Warning GpuSlicesUnavailable No node can provide the requested GPU slices [nvidia.com/mig-7g.40gb=1]"},
+		events,
+	)
+}
+
+func TestIsPlanPending(t *testing.T) {
+	currentState := state.PartitioningState{
+		"node-1": {
+			GPUs: []state.GPUPartitioning{
+				{GPUIndex: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-7g.40gb": 1}},
+			},
+		},
+	}
+	changingPlan := core.NewPartitioningPlan(state.PartitioningState{
+		"node-1": {
+			GPUs: []state.GPUPartitioning{
+				{GPUIndex: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-1g.5gb": 7}},
+			},
+		},
+	})
+	preemptingPlan := core.NewPartitioningPlan(currentState)
+	preemptingPlan.Victims = []core.PodPlacement{
+		{Pod: types.NamespacedName{Namespace: "ns-1", Name: "pod-1"}, NodeName: "node-1"},
+	}
+
+	testCases := []struct {
+		name     string
+		plan     core.PartitioningPlan
+		applied  bool
+		expected bool
+	}{
+		{
+			name:     "Plan applied",
+			plan:     changingPlan,
+			applied:  true,
+			expected: false,
+		},
+		{
+			name:     "Plan not applied, waiting for approval or in dry-run mode",
+			plan:     changingPlan,
+			applied:  false,
+			expected: true,
+		},
+		{
+			name:     "Plan with victims not applied",
+			plan:     preemptingPlan,
+			applied:  false,
+			expected: true,
+		},
+		{
+			name:     "Plan equal to current state",
+			plan:     core.NewPartitioningPlan(currentState),
+			applied:  false,
+			expected: false,
+		},
+		{
+			name:     "Empty plan",
+			plan:     core.NewPartitioningPlan(state.PartitioningState{}),
+			applied:  false,
+			expected: false,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isPlanPending(currentState, tt.plan, tt.applied))
+		})
+	}
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gpupartitioner
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sync"
+	"time"
+)
+
+// unrecordedPlanTimeout is the time after which a plan whose status has not been recorded is considered failed
+const unrecordedPlanTimeout = time.Minute
+
+// PlanController reconciles the PartitioningPlan resources of a partitioning kind:
+// it applies the plans once they get approved, and it tracks the application of
+// the plans until all their nodes report them.
+//
+// Approved plans are applied only if they are still valid against the current state
+// of the cluster, otherwise they are marked as stale. Plans that are not approved
+// within the approval timeout are marked as stale as well. An approval timeout equal
+// to 0 means that plans can wait for approval indefinitely.
+//
+// Approved plans are applied holding the partitioning mutex shared with the other
+// controllers of the same partitioning kind.
+//
+// Plans without a phase are plans whose status could not be recorded after their creation:
+// since it is unknown whether they were applied, they are marked as failed once the
+// unrecordedPlanTimeout expires, so that they are eventually pruned from the history.
+type PlanController struct {
+	client.Client
+	clusterState    *state.ClusterState
+	kind            gpu.PartitioningKind
+	actuator        core.Actuator
+	snapshotTaker   core.SnapshotTaker
+	approvalTimeout time.Duration
+	partitioningMtx *sync.Mutex
+}
+
+func NewPlanController(
+	client client.Client,
+	clusterState *state.ClusterState,
+	kind gpu.PartitioningKind,
+	actuator core.Actuator,
+	snapshotTaker core.SnapshotTaker,
+	approvalTimeout time.Duration,
+	partitioningMtx *sync.Mutex,
+) PlanController {
+	return PlanController{
+		Client:          client,
+		clusterState:    clusterState,
+		kind:            kind,
+		actuator:        actuator,
+		snapshotTaker:   snapshotTaker,
+		approvalTimeout: approvalTimeout,
+		partitioningMtx: partitioningMtx,
+	}
+}
+
+//+kubebuilder:rbac:groups=nos.nebuly.com,resources=partitioningplans,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=nos.nebuly.com,resources=partitioningplans/status,verbs=get;update;patch
+
+func (c *PlanController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	// Fetch instance
+	var instance v1alpha1.PartitioningPlan
+	if err := c.Get(ctx, client.ObjectKey{Name: req.Name}, &instance); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	switch instance.Status.Phase {
+	case v1alpha1.PartitioningPlanPhasePendingApproval:
+		if instance.Spec.Approved == nil {
+			return c.waitApproval(ctx, instance)
+		}
+		if !*instance.Spec.Approved {
+			logger.Info("partitioning plan rejected", "plan", instance.Name)
+			instance.Status.Phase = v1alpha1.PartitioningPlanPhaseRejected
+			instance.Status.Message = "the plan has been rejected"
+			return ctrl.Result{}, c.Status().Update(ctx, &instance)
+		}
+		return c.applyPlan(ctx, instance)
+	case v1alpha1.PartitioningPlanPhaseApplied:
+		return c.trackPlan(ctx, instance)
+	case "":
+		return c.failUnrecordedPlan(ctx, instance)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// waitApproval marks the plan as stale if it has not been approved within the approval timeout,
+// otherwise it requeues the plan for when the timeout expires
+func (c *PlanController) waitApproval(ctx context.Context, instance v1alpha1.PartitioningPlan) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	if c.approvalTimeout == 0 {
+		logger.V(1).Info("partitioning plan is pending approval", "plan", instance.Name)
+		return ctrl.Result{}, nil
+	}
+	remaining := time.Until(instance.CreationTimestamp.Add(c.approvalTimeout))
+	if remaining > 0 {
+		logger.V(1).Info("partitioning plan is pending approval", "plan", instance.Name, "timeout", remaining.String())
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	logger.Info("partitioning plan has not been approved in time", "plan", instance.Name)
+	core.SetPartitioningPlanStaleStatus(&instance, fmt.Sprintf("not approved within %s", c.approvalTimeout))
+	return ctrl.Result{}, c.Status().Update(ctx, &instance)
+}
+
+// failUnrecordedPlan marks the plan as failed if its status has not been recorded within the
+// unrecordedPlanTimeout, otherwise it requeues the plan for when the timeout expires
+func (c *PlanController) failUnrecordedPlan(ctx context.Context, instance v1alpha1.PartitioningPlan) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	remaining := time.Until(instance.CreationTimestamp.Add(unrecordedPlanTimeout))
+	if remaining > 0 {
+		logger.V(1).Info("waiting for the status of the partitioning plan to be recorded", "plan", instance.Name)
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	logger.Info("the status of the partitioning plan has not been recorded, marking it as failed", "plan", instance.Name)
+	instance.Status.Phase = v1alpha1.PartitioningPlanPhaseFailed
+	instance.Status.Message = "the status of the plan was not recorded, so it is unknown whether it was applied"
+	return ctrl.Result{}, c.Status().Update(ctx, &instance)
+}
+
+// applyPlan applies an approved plan, provided that it is still valid against the current state of the cluster
+func (c *PlanController) applyPlan(ctx context.Context, instance v1alpha1.PartitioningPlan) (ctrl.Result, error) {
+	c.partitioningMtx.Lock()
+	defer c.partitioningMtx.Unlock()
+	logger := log.FromContext(ctx)
+
+	snapshot, err := c.snapshotTaker.TakeSnapshot(c.clusterState)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to take a snapshot of the cluster state: %w", err)
+	}
+	staleReason, err := core.GetPartitioningPlanStaleReason(ctx, c.Client, snapshot, instance)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to check partitioning plan: %w", err)
+	}
+	if staleReason != "" {
+		logger.Info("approved partitioning plan is stale, skipping it", "plan", instance.Name, "reason", staleReason)
+		core.SetPartitioningPlanStaleStatus(&instance, staleReason)
+		return ctrl.Result{}, c.Status().Update(ctx, &instance)
+	}
+
+	logger.Info("applying approved partitioning plan", "plan", instance.Name)
+	_, applyErr := c.actuator.Apply(ctx, snapshot, core.NewPartitioningPlanFromResource(instance))
+	if applyErr != nil {
+		logger.Error(applyErr, "unable to apply partitioning plan", "plan", instance.Name)
+	}
+	core.SetPartitioningPlanApplyStatus(&instance, applyErr)
+	if err = c.Status().Update(ctx, &instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if applyErr != nil {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+}
+
+// trackPlan updates the status of an applied plan according to the plans reported by its nodes,
+// and marks it as completed once all of them reported it
+func (c *PlanController) trackPlan(ctx context.Context, instance v1alpha1.PartitioningPlan) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var pending int
+	var updated bool
+	for i, nodeStatus := range instance.Status.Nodes {
+		if nodeStatus.Phase != v1alpha1.NodePartitioningPhaseApplied {
+			continue
+		}
+		var node v1.Node
+		err := c.Get(ctx, client.ObjectKey{Name: nodeStatus.Name}, &node)
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		phase, message := getNodePartitioningPhase(node, instance.Spec.PlanId)
+		if apierrors.IsNotFound(err) {
+			phase, message = v1alpha1.NodePartitioningPhaseFailed, "node not found"
+		}
+		if phase == v1alpha1.NodePartitioningPhaseApplied {
+			pending++
+			continue
+		}
+		instance.Status.Nodes[i].Phase = phase
+		instance.Status.Nodes[i].Message = message
+		instance.Status.Nodes[i].LastTransitionTime = metav1.Now()
+		updated = true
+	}
+
+	if pending == 0 {
+		logger.Info("all nodes reported the partitioning plan", "plan", instance.Name)
+		instance.Status.Phase = v1alpha1.PartitioningPlanPhaseCompleted
+		instance.Status.Message = "all the nodes reported either the plan or a newer one"
+		updated = true
+	}
+	if updated {
+		if err := c.Status().Update(ctx, &instance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if pending > 0 {
+		logger.V(1).Info("waiting for nodes to report the partitioning plan", "plan", instance.Name, "pending", pending)
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// getNodePartitioningPhase returns the phase of the plan on a node to which the plan was applied.
+// Nodes that do not track partitioning plans through annotations (e.g. MPS nodes) are
+// considered to have reported the plan as soon as it is applied, while nodes to which
+// a different plan was applied afterwards are considered to have superseded the plan.
+func getNodePartitioningPhase(node v1.Node, planId string) (v1alpha1.NodePartitioningPhase, string) {
+	if node.Annotations[v1alpha1.AnnotationReportedPartitioningPlan] == planId {
+		return v1alpha1.NodePartitioningPhaseReported, ""
+	}
+	currentPlanId, ok := node.Annotations[v1alpha1.AnnotationPartitioningPlan]
+	if !ok {
+		return v1alpha1.NodePartitioningPhaseReported, "the node does not track the plan"
+	}
+	if currentPlanId != planId {
+		return v1alpha1.NodePartitioningPhaseSuperseded, fmt.Sprintf("plan %s was applied to the node afterwards", currentPlanId)
+	}
+	return v1alpha1.NodePartitioningPhaseApplied, ""
+}
+
+func (c *PlanController) SetupWithManager(mgr ctrl.Manager, name string) error {
+	kindPredicate := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		plan, ok := o.(*v1alpha1.PartitioningPlan)
+		return ok && plan.Spec.PartitioningKind == c.kind.String()
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		For(&v1alpha1.PartitioningPlan{}, builder.WithPredicates(kindPredicate)).
+		Complete(c)
+}
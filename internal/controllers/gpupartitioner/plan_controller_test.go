@@ -0,0 +1,293 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gpupartitioner_test
+
+import (
+	"context"
+	"github.com/nebuly-ai/nos/internal/controllers/gpupartitioner"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/nebuly-ai/nos/pkg/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPlanController__Reconcile(t *testing.T) {
+	approved := true
+	rejected := false
+	planSpec := v1alpha1.PartitioningPlanSpec{
+		PlanId:           "1000",
+		PartitioningKind: gpu.PartitioningKindMig.String(),
+		Nodes: []v1alpha1.NodePartitioningSpec{
+			{
+				Name: "node-1",
+				GPUs: []v1alpha1.GPUPartitioningSpec{
+					{Index: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-1g.10gb": 7}},
+				},
+			},
+			{
+				Name: "node-2",
+				GPUs: []v1alpha1.GPUPartitioningSpec{
+					{Index: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-7g.80gb": 1}},
+				},
+			},
+		},
+	}
+	appliedNodes := []v1alpha1.NodePartitioningStatus{
+		{Name: "node-1", Phase: v1alpha1.NodePartitioningPhaseApplied},
+		{Name: "node-2", Phase: v1alpha1.NodePartitioningPhaseApplied},
+	}
+
+	testCases := []struct {
+		name                string
+		approved            *bool
+		baseNodes           []v1alpha1.NodePartitioningSpec
+		approvalTimeout     time.Duration
+		createdAgo          time.Duration
+		phase               v1alpha1.PartitioningPlanPhase
+		nodeStatuses        []v1alpha1.NodePartitioningStatus
+		nodes               []v1.Node
+		expectApply         bool
+		expectedPhase       v1alpha1.PartitioningPlanPhase
+		expectedNodesPhases []v1alpha1.NodePartitioningPhase
+		expectedRequeue     bool
+	}{
+		{
+			name:          "Plan pending approval, should do nothing",
+			phase:         v1alpha1.PartitioningPlanPhasePendingApproval,
+			expectedPhase: v1alpha1.PartitioningPlanPhasePendingApproval,
+		},
+		{
+			name:            "Plan pending approval within the approval timeout, should requeue it",
+			phase:           v1alpha1.PartitioningPlanPhasePendingApproval,
+			approvalTimeout: time.Hour,
+			createdAgo:      time.Minute,
+			expectedPhase:   v1alpha1.PartitioningPlanPhasePendingApproval,
+			expectedRequeue: true,
+		},
+		{
+			name:            "Plan not approved within the approval timeout, should mark it as stale",
+			phase:           v1alpha1.PartitioningPlanPhasePendingApproval,
+			approvalTimeout: time.Minute,
+			createdAgo:      time.Hour,
+			expectedPhase:   v1alpha1.PartitioningPlanPhaseStale,
+		},
+		{
+			name:            "Plan status not recorded yet, should requeue it",
+			createdAgo:      10 * time.Second,
+			expectedRequeue: true,
+		},
+		{
+			name:          "Plan status never recorded, should mark it as failed",
+			createdAgo:    time.Hour,
+			expectedPhase: v1alpha1.PartitioningPlanPhaseFailed,
+		},
+		{
+			name:          "Plan rejected",
+			approved:      &rejected,
+			phase:         v1alpha1.PartitioningPlanPhasePendingApproval,
+			expectedPhase: v1alpha1.PartitioningPlanPhaseRejected,
+		},
+		{
+			name:     "Plan approved, should apply it",
+			approved: &approved,
+			phase:    v1alpha1.PartitioningPlanPhasePendingApproval,
+			nodes: []v1.Node{
+				factory.BuildNode("node-1").Get(),
+				factory.BuildNode("node-2").Get(),
+			},
+			expectApply:   true,
+			expectedPhase: v1alpha1.PartitioningPlanPhaseApplied,
+			expectedNodesPhases: []v1alpha1.NodePartitioningPhase{
+				v1alpha1.NodePartitioningPhaseApplied,
+				v1alpha1.NodePartitioningPhaseApplied,
+			},
+			expectedRequeue: true,
+		},
+		{
+			name:     "Plan approved but the partitioning of its nodes changed, should mark it as stale",
+			approved: &approved,
+			phase:    v1alpha1.PartitioningPlanPhasePendingApproval,
+			baseNodes: []v1alpha1.NodePartitioningSpec{
+				{
+					Name: "node-1",
+					GPUs: []v1alpha1.GPUPartitioningSpec{
+						{Index: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-7g.80gb": 1}},
+					},
+				},
+			},
+			nodes: []v1.Node{
+				factory.BuildNode("node-1").Get(),
+				factory.BuildNode("node-2").Get(),
+			},
+			expectApply:   false,
+			expectedPhase: v1alpha1.PartitioningPlanPhaseStale,
+		},
+		{
+			name:         "Plan applied, only some nodes reported it",
+			phase:        v1alpha1.PartitioningPlanPhaseApplied,
+			nodeStatuses: appliedNodes,
+			nodes: []v1.Node{
+				factory.BuildNode("node-1").WithAnnotations(map[string]string{
+					v1alpha1.AnnotationPartitioningPlan:         "1000",
+					v1alpha1.AnnotationReportedPartitioningPlan: "1000",
+				}).Get(),
+				factory.BuildNode("node-2").WithAnnotations(map[string]string{
+					v1alpha1.AnnotationPartitioningPlan:         "1000",
+					v1alpha1.AnnotationReportedPartitioningPlan: "900",
+				}).Get(),
+			},
+			expectedPhase: v1alpha1.PartitioningPlanPhaseApplied,
+			expectedNodesPhases: []v1alpha1.NodePartitioningPhase{
+				v1alpha1.NodePartitioningPhaseReported,
+				v1alpha1.NodePartitioningPhaseApplied,
+			},
+			expectedRequeue: true,
+		},
+		{
+			name:         "Plan applied, all nodes reported it or do not track it",
+			phase:        v1alpha1.PartitioningPlanPhaseApplied,
+			nodeStatuses: appliedNodes,
+			nodes: []v1.Node{
+				factory.BuildNode("node-1").WithAnnotations(map[string]string{
+					v1alpha1.AnnotationPartitioningPlan:         "1000",
+					v1alpha1.AnnotationReportedPartitioningPlan: "1000",
+				}).Get(),
+				factory.BuildNode("node-2").Get(),
+			},
+			expectedPhase: v1alpha1.PartitioningPlanPhaseCompleted,
+			expectedNodesPhases: []v1alpha1.NodePartitioningPhase{
+				v1alpha1.NodePartitioningPhaseReported,
+				v1alpha1.NodePartitioningPhaseReported,
+			},
+		},
+		{
+			name:         "Plan applied, a newer plan was applied to a node before it reported the plan",
+			phase:        v1alpha1.PartitioningPlanPhaseApplied,
+			nodeStatuses: appliedNodes,
+			nodes: []v1.Node{
+				factory.BuildNode("node-1").WithAnnotations(map[string]string{
+					v1alpha1.AnnotationPartitioningPlan:         "1000",
+					v1alpha1.AnnotationReportedPartitioningPlan: "1000",
+				}).Get(),
+				factory.BuildNode("node-2").WithAnnotations(map[string]string{
+					v1alpha1.AnnotationPartitioningPlan:         "1100",
+					v1alpha1.AnnotationReportedPartitioningPlan: "900",
+				}).Get(),
+			},
+			expectedPhase: v1alpha1.PartitioningPlanPhaseCompleted,
+			expectedNodesPhases: []v1alpha1.NodePartitioningPhase{
+				v1alpha1.NodePartitioningPhaseReported,
+				v1alpha1.NodePartitioningPhaseSuperseded,
+			},
+		},
+		{
+			name:         "Plan applied, node not found",
+			phase:        v1alpha1.PartitioningPlanPhaseApplied,
+			nodeStatuses: appliedNodes,
+			nodes: []v1.Node{
+				factory.BuildNode("node-1").WithAnnotations(map[string]string{
+					v1alpha1.AnnotationPartitioningPlan:         "1000",
+					v1alpha1.AnnotationReportedPartitioningPlan: "1000",
+				}).Get(),
+			},
+			expectedPhase: v1alpha1.PartitioningPlanPhaseCompleted,
+			expectedNodesPhases: []v1alpha1.NodePartitioningPhase{
+				v1alpha1.NodePartitioningPhaseReported,
+				v1alpha1.NodePartitioningPhaseFailed,
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+			assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+			spec := *planSpec.DeepCopy()
+			spec.Approved = tt.approved
+			spec.BaseNodes = tt.baseNodes
+			plan := v1alpha1.PartitioningPlan{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "mig-1000",
+					CreationTimestamp: metav1.NewTime(time.Now().Add(-tt.createdAgo)),
+				},
+				Spec: spec,
+				Status: v1alpha1.PartitioningPlanStatus{
+					Phase: tt.phase,
+					Nodes: append([]v1alpha1.NodePartitioningStatus{}, tt.nodeStatuses...),
+				},
+			}
+			objects := []client.Object{&plan}
+			for i := range tt.nodes {
+				objects = append(objects, &tt.nodes[i])
+			}
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+			mockPartitioner := mocks.NewPartitioner(t)
+			if tt.expectApply {
+				mockPartitioner.On("ApplyPartitioning", mock.Anything, mock.Anything, "1000", mock.Anything).
+					Return(nil).
+					Times(len(spec.Nodes))
+			}
+			mockSnapshot := mocks.NewSnapshot(t)
+			mockSnapshot.On("GetPartitioningState").Return(state.PartitioningState{}).Maybe()
+			mockSnapshotTaker := mocks.NewSnapshotTaker(t)
+			mockSnapshotTaker.On("TakeSnapshot", mock.Anything).Return(mockSnapshot, nil).Maybe()
+
+			controller := gpupartitioner.NewPlanController(
+				fakeClient,
+				state.NewClusterState(map[string]framework.NodeInfo{}),
+				gpu.PartitioningKindMig,
+				core.NewActuator(fakeClient, mockPartitioner),
+				mockSnapshotTaker,
+				tt.approvalTimeout,
+				&sync.Mutex{},
+			)
+			res, err := controller.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&plan)})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRequeue, res.RequeueAfter > 0)
+
+			var updated v1alpha1.PartitioningPlan
+			assert.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(&plan), &updated))
+			assert.Equal(t, tt.expectedPhase, updated.Status.Phase)
+			nodesPhases := make([]v1alpha1.NodePartitioningPhase, 0)
+			for _, n := range updated.Status.Nodes {
+				nodesPhases = append(nodesPhases, n.Phase)
+			}
+			if tt.expectedNodesPhases == nil {
+				tt.expectedNodesPhases = []v1alpha1.NodePartitioningPhase{}
+			}
+			assert.Equal(t, tt.expectedNodesPhases, nodesPhases)
+		})
+	}
+}
@@ -18,6 +18,7 @@ package migagent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/go-logr/logr"
 	"github.com/nebuly-ai/nos/internal/controllers/migagent/plan"
@@ -33,6 +34,24 @@ import (
 	"time"
 )
 
+// createError is returned when some create operations of a MIG config plan fail
+type createError struct {
+	err error
+	// rollbackErr is the error that prevented restoring the previous MIG geometry, if any
+	rollbackErr error
+}
+
+func (e createError) Error() string {
+	if e.rollbackErr != nil {
+		return fmt.Sprintf("%s, unable to restore previous MIG geometry: %s", e.err, e.rollbackErr)
+	}
+	return fmt.Sprintf("%s, previous MIG geometry restored", e.err)
+}
+
+func (e createError) Unwrap() error {
+	return e.err
+}
+
 type MigActuator struct {
 	client.Client
 	migClient    mig.Client
@@ -46,13 +65,19 @@ type MigActuator struct {
 	lastAppliedStatus *gpu.StatusAnnotationList
 }
 
-func NewActuator(client client.Client, migClient mig.Client, sharedState *SharedState, nodeName string) MigActuator {
+func NewActuator(
+	client client.Client,
+	migClient mig.Client,
+	devicePlugin gpu.DevicePluginClient,
+	sharedState *SharedState,
+	nodeName string,
+) MigActuator {
 	return MigActuator{
 		Client:       client,
 		migClient:    migClient,
 		nodeName:     nodeName,
 		sharedState:  sharedState,
-		devicePlugin: gpu.NewDevicePluginClient(client),
+		devicePlugin: devicePlugin,
 	}
 }
 
@@ -97,7 +122,7 @@ func (a *MigActuator) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Res
 	}
 
 	// Compute MIG config plan
-	configPlan, err := a.plan(ctx, specAnnotations)
+	configPlan, currentState, err := a.plan(ctx, specAnnotations)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -116,25 +141,30 @@ func (a *MigActuator) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Res
 	}
 
 	// Apply MIG config plan
-	res, err := a.apply(ctx, configPlan)
+	res, err := a.apply(ctx, configPlan, currentState)
 	a.sharedState.OnApplyDone()
+	if reportErr := a.reportApplyOutcome(ctx, instance, specAnnotations, currentState, err); reportErr != nil {
+		logger.Error(reportErr, "unable to report outcome of MIG config plan")
+	}
 
 	return res, err
 }
 
-func (a *MigActuator) plan(ctx context.Context, specAnnotations gpu.SpecAnnotationList) (plan.MigConfigPlan, error) {
+// plan computes the MIG config plan required for applying the spec provided as argument, returning
+// it together with the current MIG state of the GPUs
+func (a *MigActuator) plan(ctx context.Context, specAnnotations gpu.SpecAnnotationList) (plan.MigConfigPlan, plan.MigState, error) {
 	logger := a.newLogger(ctx)
 
 	// Compute current state
 	migDeviceResources, err := a.migClient.GetMigDevices(ctx)
 	if gpu.IgnoreNotFound(err) != nil {
 		logger.Error(err, "unable to get MIG device resources")
-		return plan.MigConfigPlan{}, err
+		return plan.MigConfigPlan{}, nil, err
 	}
 	// If err is not found, restart the NVIDIA device plugin for updating the resources exposed to k8s
 	if gpu.IsNotFound(err) {
 		logger.Error(err, "unable to get MIG device resources")
-		return plan.MigConfigPlan{}, a.restartNvidiaDevicePlugin(ctx)
+		return plan.MigConfigPlan{}, nil, a.restartNvidiaDevicePlugin(ctx)
 	}
 
 	state := plan.NewMigState(migDeviceResources)
@@ -142,14 +172,16 @@ func (a *MigActuator) plan(ctx context.Context, specAnnotations gpu.SpecAnnotati
 	// Check if actual state already matches spec
 	if state.Matches(specAnnotations) {
 		logger.Info("actual state matches desired MIG config")
-		return plan.MigConfigPlan{}, nil
+		return plan.MigConfigPlan{}, state, nil
 	}
 
 	// Compute MIG config plan
-	return plan.NewMigConfigPlan(state, specAnnotations), nil
+	return plan.NewMigConfigPlan(state, specAnnotations), state, nil
 }
 
-func (a *MigActuator) apply(ctx context.Context, plan plan.MigConfigPlan) (ctrl.Result, error) {
+// apply applies the MIG config plan provided as argument. If any create operation fails, apply tries to
+// restore the MIG geometry described by previousState and returns a createError.
+func (a *MigActuator) apply(ctx context.Context, plan plan.MigConfigPlan, previousState plan.MigState) (ctrl.Result, error) {
 	logger := a.newLogger(ctx)
 	logger.Info(
 		"applying MIG config plan",
@@ -184,6 +216,20 @@ func (a *MigActuator) apply(ctx context.Context, plan plan.MigConfigPlan) (ctrl.
 		restartRequired = true
 	}
 
+	// If any create operation failed, restore the previous MIG geometry so that the GPUs
+	// are not left with fewer MIG devices than before
+	var createErr *createError
+	if status.Err != nil {
+		rollbackStatus := a.rollback(ctx, previousState)
+		if rollbackStatus.Err != nil {
+			logger.Error(rollbackStatus.Err, "unable to restore previous MIG geometry")
+		}
+		if rollbackStatus.PluginRestartRequired {
+			restartRequired = true
+		}
+		createErr = &createError{err: status.Err, rollbackErr: rollbackStatus.Err}
+	}
+
 	// Restart the NVIDIA device plugin if necessary
 	if restartRequired {
 		if err := a.restartNvidiaDevicePlugin(ctx); err != nil {
@@ -193,6 +239,9 @@ func (a *MigActuator) apply(ctx context.Context, plan plan.MigConfigPlan) (ctrl.
 	}
 
 	// Check if any error happened
+	if createErr != nil {
+		return ctrl.Result{}, *createErr
+	}
 	if atLeastOneErr {
 		return ctrl.Result{}, fmt.Errorf("at least one operation failed while applying desired MIG config")
 	}
@@ -200,12 +249,93 @@ func (a *MigActuator) apply(ctx context.Context, plan plan.MigConfigPlan) (ctrl.
 	return ctrl.Result{}, nil
 }
 
+// rollback tries to restore the MIG geometry described by the state provided as argument, deleting the MIG
+// devices created by a partially applied plan and re-creating the ones it deleted
+func (a *MigActuator) rollback(ctx context.Context, previousState plan.MigState) plan.OperationStatus {
+	logger := a.newLogger(ctx)
+
+	migDeviceResources, err := a.migClient.GetMigDevices(ctx)
+	if err != nil {
+		return plan.OperationStatus{Err: err}
+	}
+	rollbackPlan := plan.NewMigConfigPlan(plan.NewMigState(migDeviceResources), previousState.AsSpecAnnotations())
+	logger.Info(
+		"rolling back MIG config plan",
+		"createOperations",
+		rollbackPlan.CreateOperations,
+		"deleteOperations",
+		rollbackPlan.DeleteOperations,
+	)
+
+	var res plan.OperationStatus
+	for _, op := range rollbackPlan.DeleteOperations {
+		status := a.applyDeleteOp(ctx, op)
+		if status.Err != nil {
+			res.Err = status.Err
+		}
+		res.PluginRestartRequired = res.PluginRestartRequired || status.PluginRestartRequired
+	}
+	if len(rollbackPlan.CreateOperations) > 0 {
+		status := a.applyCreateOps(ctx, rollbackPlan.CreateOperations)
+		if status.Err != nil {
+			res.Err = status.Err
+		}
+		res.PluginRestartRequired = res.PluginRestartRequired || status.PluginRestartRequired
+	}
+
+	return res
+}
+
+// reportApplyOutcome exposes through the annotation v1alpha1.AnnotationMigPartitioningFailure the MIG
+// geometries that could not be applied to the GPUs of the node, so that the GPU partitioner does not
+// retry them. The annotation is removed as soon as a plan is applied successfully.
+func (a *MigActuator) reportApplyOutcome(ctx context.Context, node v1.Node, specAnnotations gpu.SpecAnnotationList, previousState plan.MigState, applyErr error) error {
+	_, annotated := node.Annotations[v1alpha1.AnnotationMigPartitioningFailure]
+	var createErr createError
+	isCreateErr := errors.As(applyErr, &createErr)
+	if applyErr == nil && !annotated {
+		return nil
+	}
+	if applyErr != nil && !isCreateErr {
+		return nil
+	}
+
+	updated := node.DeepCopy()
+	if applyErr == nil {
+		delete(updated.Annotations, v1alpha1.AnnotationMigPartitioningFailure)
+		return a.Patch(ctx, updated, client.MergeFrom(&node))
+	}
+
+	previousGeometries := mig.GetSpecGeometries(previousState.AsSpecAnnotations())
+	failure := mig.PartitioningFailure{
+		PlanId:     node.Annotations[v1alpha1.AnnotationPartitioningPlan],
+		Geometries: make(map[int]gpu.Geometry),
+		RolledBack: createErr.rollbackErr == nil,
+	}
+	for gpuIndex, geometry := range mig.GetSpecGeometries(specAnnotations) {
+		if geometry.Id() != previousGeometries[gpuIndex].Id() {
+			failure.Geometries[gpuIndex] = geometry
+		}
+	}
+	value, err := mig.MarshalPartitioningFailureAnnotation(failure)
+	if err != nil {
+		return err
+	}
+	if updated.Annotations == nil {
+		updated.Annotations = make(map[string]string)
+	}
+	updated.Annotations[v1alpha1.AnnotationMigPartitioningFailure] = value
+	return a.Patch(ctx, updated, client.MergeFrom(&node))
+}
+
 // restartNvidiaDevicePlugin deletes the Nvidia Device Plugin pod and blocks until it is successfully recreated by
 // its daemonset
 func (a *MigActuator) restartNvidiaDevicePlugin(ctx context.Context) error {
 	logger := log.FromContext(ctx)
 	logger.Info("restarting NVIDIA device plugin")
-	return a.devicePlugin.Restart(ctx, a.nodeName, 1*time.Minute)
+	err := a.devicePlugin.Restart(ctx, a.nodeName, 1*time.Minute)
+	observeDevicePluginRestart(a.nodeName, err)
+	return err
 }
 
 func (a *MigActuator) applyDeleteOp(ctx context.Context, op plan.DeleteOperation) plan.OperationStatus {
@@ -240,6 +370,7 @@ func (a *MigActuator) applyDeleteOp(ctx context.Context, op plan.DeleteOperation
 	if nDeleted > 0 {
 		restartRequired = true
 	}
+	observeMigDeviceOperations(a.nodeName, operationDelete, nDeleted, len(deleteErrors))
 
 	if len(deleteErrors) > 0 {
 		return plan.OperationStatus{
@@ -259,6 +390,7 @@ func (a *MigActuator) applyCreateOps(ctx context.Context, ops plan.CreateOperati
 
 	profileList := ops.Flatten()
 	created, err := a.migClient.CreateMigDevices(ctx, profileList)
+	observeMigDeviceOperations(a.nodeName, operationCreate, len(created), len(profileList)-len(created))
 	if err != nil {
 		nCreated := len(created)
 		return plan.OperationStatus{
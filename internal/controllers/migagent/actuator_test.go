@@ -18,11 +18,20 @@ package migagent
 
 import (
 	"context"
+	"fmt"
 	"github.com/nebuly-ai/nos/internal/controllers/migagent/plan"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
 	"github.com/nebuly-ai/nos/pkg/resource"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/nebuly-ai/nos/pkg/test/mocks"
 	migtest "github.com/nebuly-ai/nos/pkg/test/mocks/mig"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"testing"
 )
 
@@ -119,6 +128,220 @@ func TestMigActuator_applyDeleteOp(t *testing.T) {
 	}
 }
 
+func TestMigActuator_apply__RollbackOnCreateFailure(t *testing.T) {
+	previousDevices := gpu.DeviceList{
+		{
+			Device: resource.Device{
+				ResourceName: "nvidia.com/mig-1g.10gb",
+				DeviceId:     "uid-1",
+				Status:       resource.StatusFree,
+			},
+			GpuIndex: 0,
+		},
+		{
+			Device: resource.Device{
+				ResourceName: "nvidia.com/mig-1g.10gb",
+				DeviceId:     "uid-2",
+				Status:       resource.StatusFree,
+			},
+			GpuIndex: 0,
+		},
+	}
+	configPlan := plan.MigConfigPlan{
+		DeleteOperations: plan.DeleteOperationList{{Resources: previousDevices}},
+		CreateOperations: plan.CreateOperationList{
+			{MigProfile: mig.Profile{GpuIndex: 0, Name: "3g.40gb"}, Quantity: 1},
+		},
+	}
+
+	migClient := migtest.Client{
+		// all the devices have been deleted by the plan
+		ReturnedMigDeviceResources: gpu.DeviceList{},
+		ReturnedCreateError:        fmt.Errorf("insufficient resources"),
+	}
+	devicePlugin := mocks.NewDevicePluginClient(t)
+	devicePlugin.On("Restart", mock.Anything, "node-1", mock.Anything).Return(nil).Once()
+	actuator := MigActuator{migClient: &migClient, devicePlugin: devicePlugin, nodeName: "node-1"}
+
+	_, err := actuator.apply(context.Background(), configPlan, plan.NewMigState(previousDevices))
+
+	var createErr createError
+	assert.ErrorAs(t, err, &createErr)
+	// the rollback could not re-create the deleted devices
+	assert.Error(t, createErr.rollbackErr)
+	assert.Equal(t, uint(2), migClient.NumCallsCreateMigResources)
+	assert.Equal(t, uint(1), migClient.NumCallsGetMigDeviceResources)
+}
+
+func TestMigActuator_rollback(t *testing.T) {
+	previousState := plan.NewMigState(gpu.DeviceList{
+		{
+			Device: resource.Device{
+				ResourceName: "nvidia.com/mig-1g.10gb",
+				DeviceId:     "uid-1",
+				Status:       resource.StatusUsed,
+			},
+			GpuIndex: 0,
+		},
+		{
+			Device: resource.Device{
+				ResourceName: "nvidia.com/mig-1g.10gb",
+				DeviceId:     "uid-2",
+				Status:       resource.StatusFree,
+			},
+			GpuIndex: 0,
+		},
+	})
+
+	testCases := []struct {
+		name                string
+		currentDevices      gpu.DeviceList
+		clientReturnedError gpu.Error
+
+		expectedDeleteCalls uint
+		expectedCreateCalls uint
+		errorExpected       bool
+		restartExpected     bool
+	}{
+		{
+			name:           "Previous geometry already restored, should do nothing",
+			currentDevices: previousState.Flatten(),
+		},
+		{
+			name: "Partially applied plan, should delete created devices and re-create deleted ones",
+			currentDevices: gpu.DeviceList{
+				previousState[0][0],
+				{
+					Device: resource.Device{
+						ResourceName: "nvidia.com/mig-2g.20gb",
+						DeviceId:     "uid-3",
+						Status:       resource.StatusFree,
+					},
+					GpuIndex: 0,
+				},
+			},
+			expectedDeleteCalls: 1,
+			expectedCreateCalls: 1,
+			restartExpected:     true,
+		},
+		{
+			name:                "MIG client returns error, should return error",
+			clientReturnedError: gpu.GenericErr.Errorf("an error"),
+			errorExpected:       true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			migClient := migtest.Client{
+				ReturnedMigDeviceResources: tt.currentDevices,
+				ReturnedError:              tt.clientReturnedError,
+			}
+			actuator := MigActuator{migClient: &migClient}
+			status := actuator.rollback(context.Background(), previousState)
+			if tt.errorExpected {
+				assert.Error(t, status.Err)
+			} else {
+				assert.NoError(t, status.Err)
+			}
+			assert.Equal(t, tt.restartExpected, status.PluginRestartRequired)
+			assert.Equal(t, tt.expectedDeleteCalls, migClient.NumCallsDeleteMigResource)
+			assert.Equal(t, tt.expectedCreateCalls, migClient.NumCallsCreateMigResources)
+		})
+	}
+}
+
+func TestMigActuator_reportApplyOutcome(t *testing.T) {
+	previousState := plan.NewMigState(gpu.DeviceList{
+		{
+			Device: resource.Device{
+				ResourceName: "nvidia.com/mig-1g.10gb",
+				DeviceId:     "uid-1",
+				Status:       resource.StatusFree,
+			},
+			GpuIndex: 0,
+		},
+		{
+			Device: resource.Device{
+				ResourceName: "nvidia.com/mig-7g.80gb",
+				DeviceId:     "uid-2",
+				Status:       resource.StatusFree,
+			},
+			GpuIndex: 1,
+		},
+	})
+	specAnnotations := gpu.SpecAnnotationList{
+		{ProfileName: "1g.10gb", Index: 0, Quantity: 1},
+		{ProfileName: "3g.40gb", Index: 1, Quantity: 2},
+	}
+	failureAnnotation := `{"planId":"old-plan","geometries":{"0":{"2g.20gb":1}},"rolledBack":true}`
+
+	testCases := []struct {
+		name            string
+		annotations     map[string]string
+		applyErr        error
+		expectedFailure *mig.PartitioningFailure
+	}{
+		{
+			name:            "Plan applied, node without annotation: should do nothing",
+			annotations:     map[string]string{},
+			expectedFailure: nil,
+		},
+		{
+			name:            "Plan applied, node with annotation: should remove annotation",
+			annotations:     map[string]string{v1alpha1.AnnotationMigPartitioningFailure: failureAnnotation},
+			expectedFailure: nil,
+		},
+		{
+			name:        "Error not related to create operations: should not change annotation",
+			annotations: map[string]string{v1alpha1.AnnotationMigPartitioningFailure: failureAnnotation},
+			applyErr:    fmt.Errorf("delete failed"),
+			expectedFailure: &mig.PartitioningFailure{
+				PlanId:     "old-plan",
+				Geometries: map[int]gpu.Geometry{0: {mig.Profile2g20gb: 1}},
+				RolledBack: true,
+			},
+		},
+		{
+			name:        "Create failed and rollback failed: should report only the changed GPUs",
+			annotations: map[string]string{v1alpha1.AnnotationPartitioningPlan: "plan-1"},
+			applyErr:    createError{err: fmt.Errorf("create failed"), rollbackErr: fmt.Errorf("rollback failed")},
+			expectedFailure: &mig.PartitioningFailure{
+				PlanId:     "plan-1",
+				Geometries: map[int]gpu.Geometry{1: {mig.Profile3g40gb: 2}},
+				RolledBack: false,
+			},
+		},
+		{
+			name:        "Create failed and rollback succeeded: should report rolled back",
+			annotations: map[string]string{v1alpha1.AnnotationPartitioningPlan: "plan-1"},
+			applyErr:    createError{err: fmt.Errorf("create failed")},
+			expectedFailure: &mig.PartitioningFailure{
+				PlanId:     "plan-1",
+				Geometries: map[int]gpu.Geometry{1: {mig.Profile3g40gb: 2}},
+				RolledBack: true,
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			node := factory.BuildNode("node-1").WithAnnotations(tt.annotations).Get()
+			k8sClient := fake.NewClientBuilder().WithObjects(&node).Build()
+			actuator := MigActuator{Client: k8sClient, nodeName: "node-1"}
+
+			err := actuator.reportApplyOutcome(context.Background(), node, specAnnotations, previousState, tt.applyErr)
+			assert.NoError(t, err)
+
+			var updated v1.Node
+			assert.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(&node), &updated))
+			failure, err := mig.ParsePartitioningFailureAnnotation(updated)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedFailure, failure)
+		})
+	}
+}
+
 //func TestMigActuator_applyCreateOps(t *testing.T) {
 //	testCases := []struct {
 //		name                string
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package migagent
+
+import (
+	"github.com/nebuly-ai/nos/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	labelOperation = "operation"
+	labelResult    = "result"
+
+	operationCreate = "create"
+	operationDelete = "delete"
+
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	migDeviceOperationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "nos",
+			Subsystem: "mig_agent",
+			Name:      "mig_device_operations_total",
+			Help:      "Number of MIG devices created or deleted by the MIG agent, by result.",
+		},
+		[]string{metrics.LabelNode, labelOperation, labelResult},
+	)
+	devicePluginRestartsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "nos",
+			Subsystem: "mig_agent",
+			Name:      "device_plugin_restarts_total",
+			Help:      "Number of restarts of the NVIDIA device plugin performed by the MIG agent, by result.",
+		},
+		[]string{metrics.LabelNode, labelResult},
+	)
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(migDeviceOperationsTotal, devicePluginRestartsTotal)
+}
+
+func observeMigDeviceOperations(nodeName, operation string, succeeded, failed int) {
+	migDeviceOperationsTotal.WithLabelValues(nodeName, operation, resultSuccess).Add(float64(succeeded))
+	migDeviceOperationsTotal.WithLabelValues(nodeName, operation, resultFailure).Add(float64(failed))
+}
+
+func observeDevicePluginRestart(nodeName string, err error) {
+	if err != nil {
+		devicePluginRestartsTotal.WithLabelValues(nodeName, resultFailure).Inc()
+		return
+	}
+	devicePluginRestartsTotal.WithLabelValues(nodeName, resultSuccess).Inc()
+}
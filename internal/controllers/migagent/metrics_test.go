@@ -0,0 +1,64 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package migagent
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/controllers/migagent/plan"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/resource"
+	migtest "github.com/nebuly-ai/nos/pkg/test/mocks/mig"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMigActuator_applyDeleteOp__Metrics(t *testing.T) {
+	const nodeName = "node-delete-metrics"
+	var migClient = migtest.Client{}
+	var actuator = MigActuator{migClient: &migClient, nodeName: nodeName}
+
+	op := plan.DeleteOperation{
+		Resources: gpu.DeviceList{
+			{
+				Device:   resource.Device{ResourceName: "nvidia.com/mig-1g.10gb", DeviceId: "uid-1", Status: resource.StatusFree},
+				GpuIndex: 0,
+			},
+			{
+				Device:   resource.Device{ResourceName: "nvidia.com/mig-1g.10gb", DeviceId: "uid-2", Status: resource.StatusFree},
+				GpuIndex: 0,
+			},
+		},
+	}
+	actuator.applyDeleteOp(context.Background(), op)
+	assert.Equal(t, 2.0, testutil.ToFloat64(migDeviceOperationsTotal.WithLabelValues(nodeName, operationDelete, resultSuccess)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(migDeviceOperationsTotal.WithLabelValues(nodeName, operationDelete, resultFailure)))
+
+	migClient.ReturnedError = gpu.GenericErr.Errorf("an error")
+	actuator.applyDeleteOp(context.Background(), op)
+	assert.Equal(t, 2.0, testutil.ToFloat64(migDeviceOperationsTotal.WithLabelValues(nodeName, operationDelete, resultSuccess)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(migDeviceOperationsTotal.WithLabelValues(nodeName, operationDelete, resultFailure)))
+}
+
+func TestObserveDevicePluginRestart(t *testing.T) {
+	const nodeName = "node-restart-metrics"
+	observeDevicePluginRestart(nodeName, nil)
+	observeDevicePluginRestart(nodeName, nil)
+	observeDevicePluginRestart(nodeName, fmt.Errorf("timeout"))
+	assert.Equal(t, 2.0, testutil.ToFloat64(devicePluginRestartsTotal.WithLabelValues(nodeName, resultSuccess)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(devicePluginRestartsTotal.WithLabelValues(nodeName, resultFailure)))
+}
@@ -85,3 +85,21 @@ func (s MigState) WithoutMigProfiles(gpuIndex int, migProfiles []mig.ProfileName
 	}
 	return res
 }
+
+// AsSpecAnnotations returns the spec annotations describing the MIG geometry of each GPU of the state,
+// which can be used for computing the plan that restores the state
+func (s MigState) AsSpecAnnotations() gpu.SpecAnnotationList {
+	res := make(gpu.SpecAnnotationList, 0)
+	for gpuIndex, devices := range s {
+		for profile, profileDevices := range devices.GroupBy(func(d gpu.Device) string {
+			return mig.GetMigProfileName(d).String()
+		}) {
+			res = append(res, gpu.SpecAnnotation{
+				ProfileName: profile,
+				Index:       gpuIndex,
+				Quantity:    len(profileDevices),
+			})
+		}
+	}
+	return res
+}
@@ -95,3 +95,40 @@ func TestMigState_Matches(t *testing.T) {
 		})
 	}
 }
+
+func TestMigState_AsSpecAnnotations(t *testing.T) {
+	state := NewMigState(gpu.DeviceList{
+		{
+			Device: resource.Device{
+				ResourceName: "nvidia.com/mig-1g.10gb",
+				DeviceId:     "1",
+				Status:       resource.StatusFree,
+			},
+			GpuIndex: 0,
+		},
+		{
+			Device: resource.Device{
+				ResourceName: "nvidia.com/mig-1g.10gb",
+				DeviceId:     "2",
+				Status:       resource.StatusUsed,
+			},
+			GpuIndex: 0,
+		},
+		{
+			Device: resource.Device{
+				ResourceName: "nvidia.com/mig-3g.40gb",
+				DeviceId:     "3",
+				Status:       resource.StatusFree,
+			},
+			GpuIndex: 1,
+		},
+	})
+	expected := gpu.SpecAnnotationList{
+		{ProfileName: "1g.10gb", Index: 0, Quantity: 2},
+		{ProfileName: "3g.40gb", Index: 1, Quantity: 1},
+	}
+
+	annotations := state.AsSpecAnnotations()
+	assert.ElementsMatch(t, expected, annotations)
+	assert.True(t, state.Matches(annotations))
+}
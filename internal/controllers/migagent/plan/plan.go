@@ -76,7 +76,7 @@ func NewMigConfigPlan(state MigState, desired gpu.SpecAnnotationList) MigConfigP
 		}
 
 		// if there's any create op on the GPU, then re-create existing *free* resources so that
-		// when applying the create operations the number of possible MIG permutations to try is larger
+		// when applying the create operations their placement can be computed together with the new ones
 		resourcesToRecreate := extractResourcesToRecreate(stateResourcesByGpu[gpuIndex], plan)
 		if len(resourcesToRecreate) > 0 {
 			// delete free resources not already included in plan
@@ -18,9 +18,11 @@ package migagent
 
 import (
 	"context"
+	"github.com/nebuly-ai/nos/internal/metrics"
 	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/gpu"
 	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/gpu/slicing"
 	"github.com/nebuly-ai/nos/pkg/util/predicate"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
@@ -33,17 +35,22 @@ import (
 
 type MigReporter struct {
 	client.Client
-	migClient       mig.Client
-	refreshInterval time.Duration
-	sharedState     *SharedState
+	migClient                 mig.Client
+	refreshInterval           time.Duration
+	sharedState               *SharedState
+	discoverAllowedGeometries bool
+	// allowedGeometriesAnnotation caches the value of the allowed geometries annotation, since the
+	// geometries supported by the GPUs do not change while the agent is running
+	allowedGeometriesAnnotation *string
 }
 
-func NewReporter(client client.Client, migClient mig.Client, sharedState *SharedState, refreshInterval time.Duration) MigReporter {
+func NewReporter(client client.Client, migClient mig.Client, sharedState *SharedState, refreshInterval time.Duration, discoverAllowedGeometries bool) MigReporter {
 	reporter := MigReporter{
-		Client:          client,
-		migClient:       migClient,
-		sharedState:     sharedState,
-		refreshInterval: refreshInterval,
+		Client:                    client,
+		migClient:                 migClient,
+		sharedState:               sharedState,
+		refreshInterval:           refreshInterval,
+		discoverAllowedGeometries: discoverAllowedGeometries,
 	}
 	return reporter
 }
@@ -74,10 +81,34 @@ func (r *MigReporter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Res
 	logger.V(3).Info("loaded free MIG devices", "freeMIGs", freeMigs)
 	logger.V(3).Info("loaded used MIG devices", "usedMIGs", usedMigs)
 	newStatusAnnotations := migResources.AsStatusAnnotation(mig.ExtractProfileNameStr)
+	gpuModel, _ := gpu.GetModel(instance)
+	metrics.SetGpuSlices(instance.Name, gpuModel, newStatusAnnotations)
 
-	// Get current status annotations and compare with new ones
+	// Compute allowed geometries annotation
+	allowedGeometries, geometriesErr := r.getAllowedGeometriesAnnotation(ctx)
+	if geometriesErr != nil {
+		logger.Error(geometriesErr, "unable to discover allowed MIG geometries")
+		return ctrl.Result{}, geometriesErr
+	}
+
+	// Compute MIG-enabled GPUs annotation
+	migEnabledGpus, err := r.migClient.GetMigEnabledGPUs(ctx)
+	if err != nil {
+		logger.Error(err, "unable to get MIG-enabled GPUs")
+		return ctrl.Result{}, err
+	}
+	migEnabledGpusAnnotation := mig.MarshalMigEnabledGpusAnnotation(migEnabledGpus)
+
+	// Get current status annotations and compare with new ones. On nodes with hybrid partitioning, the
+	// status annotations of the MPS GPUs are reported by the GPU agent, so they must be ignored.
 	oldStatusAnnotations, _ := gpu.ParseNodeAnnotations(instance)
-	if newStatusAnnotations.Equal(oldStatusAnnotations) {
+	oldStatusAnnotations = oldStatusAnnotations.Filter(func(a gpu.StatusAnnotation) bool {
+		return !isSlicingStatusAnnotation(a)
+	})
+	oldAllowedGeometries := instance.Annotations[v1alpha1.AnnotationAllowedMigGeometries]
+	oldMigEnabledGpus, migEnabledGpusReported := instance.Annotations[v1alpha1.AnnotationMigEnabledGpus]
+	migEnabledGpusChanged := !migEnabledGpusReported || oldMigEnabledGpus != migEnabledGpusAnnotation
+	if newStatusAnnotations.Equal(oldStatusAnnotations) && oldAllowedGeometries == allowedGeometries && !migEnabledGpusChanged {
 		if instance.Annotations[v1alpha1.AnnotationReportedPartitioningPlan] == r.sharedState.lastParsedPlanId {
 			logger.Info("current status is equal to last reported status, nothing to do")
 			return ctrl.Result{RequeueAfter: r.refreshInterval}, nil
@@ -90,15 +121,25 @@ func (r *MigReporter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Res
 	if updated.Annotations == nil {
 		updated.Annotations = make(map[string]string)
 	}
-	for k := range updated.Annotations {
-		if strings.HasPrefix(k, v1alpha1.AnnotationGpuStatusPrefix) {
-			delete(updated.Annotations, k)
+	for k, v := range updated.Annotations {
+		if !strings.HasPrefix(k, v1alpha1.AnnotationGpuStatusPrefix) {
+			continue
+		}
+		if a, err := gpu.ParseStatusAnnotation(k, v); err == nil && isSlicingStatusAnnotation(a) {
+			continue
 		}
+		delete(updated.Annotations, k)
 	}
 	for _, a := range newStatusAnnotations {
 		updated.Annotations[a.String()] = a.GetValue()
 	}
 	updated.Annotations[v1alpha1.AnnotationReportedPartitioningPlan] = r.sharedState.lastParsedPlanId
+	updated.Annotations[v1alpha1.AnnotationMigEnabledGpus] = migEnabledGpusAnnotation
+	if allowedGeometries != "" {
+		updated.Annotations[v1alpha1.AnnotationAllowedMigGeometries] = allowedGeometries
+	} else {
+		delete(updated.Annotations, v1alpha1.AnnotationAllowedMigGeometries)
+	}
 	if err := r.Client.Patch(ctx, updated, client.MergeFrom(&instance)); err != nil {
 		logger.Error(err, "unable to update node status annotations", "annotations", updated.Annotations)
 		return ctrl.Result{}, err
@@ -108,6 +149,36 @@ func (r *MigReporter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Res
 	return ctrl.Result{RequeueAfter: r.refreshInterval}, nil
 }
 
+// getAllowedGeometriesAnnotation returns the value of the annotation exposing the MIG geometries allowed
+// by the GPUs of the node, or an empty string if the discovery of the allowed geometries is disabled
+// or no geometry was discovered.
+func (r *MigReporter) getAllowedGeometriesAnnotation(ctx context.Context) (string, error) {
+	if !r.discoverAllowedGeometries {
+		return "", nil
+	}
+	if r.allowedGeometriesAnnotation != nil {
+		return *r.allowedGeometriesAnnotation, nil
+	}
+	geometries, gpuErr := r.migClient.GetAllowedGeometries(ctx)
+	if gpuErr != nil {
+		return "", gpuErr
+	}
+	var value string
+	if len(geometries) > 0 {
+		var err error
+		if value, err = mig.MarshalAllowedGeometriesAnnotation(geometries); err != nil {
+			return "", err
+		}
+	}
+	r.allowedGeometriesAnnotation = &value
+	return value, nil
+}
+
+// isSlicingStatusAnnotation returns true if the annotation refers to a GPU slice (e.g. MPS) instead of a MIG device
+func isSlicingStatusAnnotation(a gpu.StatusAnnotation) bool {
+	return slicing.ProfileName(a.ProfileName).IsValid()
+}
+
 func (r *MigReporter) SetupWithManager(mgr ctrl.Manager, controllerName string, nodeName string) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(
@@ -87,6 +87,8 @@ var _ = Describe("MigAgent - Reporter", func() {
 				expectedAnnotationOne:                       "1",
 				expectedAnnotationTwo:                       "1",
 				v1alpha1.AnnotationReportedPartitioningPlan: "", // we're not using a real shared state in tests, so it does not get updated
+				v1alpha1.AnnotationAllowedMigGeometries:     `{"0":[{"1g.10gb":7},{"7g.80gb":1}]}`,
+				v1alpha1.AnnotationMigEnabledGpus:           "0,1",
 			}
 			Eventually(func() map[string]string {
 				var updatedNode v1.Node
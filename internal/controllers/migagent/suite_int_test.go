@@ -22,6 +22,8 @@ import (
 	"context"
 	"github.com/go-logr/logr"
 	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
 	"github.com/nebuly-ai/nos/pkg/test/factory"
 	mockedmig "github.com/nebuly-ai/nos/pkg/test/mocks/mig"
 	. "github.com/onsi/ginkgo/v2"
@@ -123,12 +125,22 @@ var _ = BeforeSuite(func() {
 	reporterSharedState = NewSharedState()
 
 	// Setup Reporter
-	reporter := NewReporter(k8sClient, reporterMigClient, reporterSharedState, 3*time.Second)
+	reporterMigClient.ReturnedAllowedGeometries = map[int][]gpu.Geometry{
+		0: {{mig.Profile1g10gb: 7}, {mig.Profile7g80gb: 1}},
+	}
+	reporterMigClient.ReturnedMigEnabledGPUs = []int{0, 1}
+	reporter := NewReporter(k8sClient, reporterMigClient, reporterSharedState, 3*time.Second, true)
 	err = reporter.SetupWithManager(k8sManager, "MIGReporter", reporterNodeName)
 	Expect(err).ToNot(HaveOccurred())
 
 	// Setup Actuator
-	actuator = NewActuator(k8sClient, actuatorMigClient, actuatorSharedState, actuatorNodeName)
+	actuator = NewActuator(
+		k8sClient,
+		actuatorMigClient,
+		gpu.NewDevicePluginClient(k8sClient),
+		actuatorSharedState,
+		actuatorNodeName,
+	)
 	err = actuator.SetupWithManager(k8sManager, "MIGActuator")
 	Expect(err).ToNot(HaveOccurred())
 
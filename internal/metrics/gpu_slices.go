@@ -0,0 +1,61 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package metrics contains the Prometheus metrics shared by the agents running on the GPU nodes.
+package metrics
+
+import (
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"strconv"
+)
+
+const (
+	LabelNode     = "node"
+	LabelGpuIndex = "gpu_index"
+	LabelModel    = "model"
+	LabelProfile  = "profile"
+	LabelStatus   = "status"
+)
+
+var gpuSlices = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "nos",
+		Name:      "gpu_slices",
+		Help:      "Number of slices (MIG devices or MPS slices) of each GPU, by profile and status.",
+	},
+	[]string{LabelNode, LabelGpuIndex, LabelModel, LabelProfile, LabelStatus},
+)
+
+func init() {
+	metrics.Registry.MustRegister(gpuSlices)
+}
+
+// SetGpuSlices updates the number of free and used slices of the GPUs of the node provided as argument
+// according to the status annotations provided as argument. The series of the slices of the node
+// that are not included in the annotations are removed.
+func SetGpuSlices(nodeName string, model gpu.Model, annotations gpu.StatusAnnotationList) {
+	gpuSlices.DeletePartialMatch(prometheus.Labels{LabelNode: nodeName})
+	for _, a := range annotations {
+		gpuSlices.With(prometheus.Labels{
+			LabelNode:     nodeName,
+			LabelGpuIndex: strconv.Itoa(a.Index),
+			LabelModel:    string(model),
+			LabelProfile:  a.ProfileName,
+			LabelStatus:   string(a.Status),
+		}).Add(float64(a.Quantity))
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package metrics
+
+import (
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/resource"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSetGpuSlices(t *testing.T) {
+	const node = "node-1"
+	var value = func(index, profile string, status resource.Status) float64 {
+		return testutil.ToFloat64(gpuSlices.With(prometheus.Labels{
+			LabelNode:     node,
+			LabelGpuIndex: index,
+			LabelModel:    string(gpu.GPUModel_A100_SXM4_40GB),
+			LabelProfile:  profile,
+			LabelStatus:   string(status),
+		}))
+	}
+
+	SetGpuSlices(node, gpu.GPUModel_A100_SXM4_40GB, gpu.StatusAnnotationList{
+		{ProfileName: "1g.5gb", Index: 0, Status: resource.StatusFree, Quantity: 3},
+		{ProfileName: "1g.5gb", Index: 0, Status: resource.StatusUsed, Quantity: 2},
+		{ProfileName: "3g.20gb", Index: 1, Status: resource.StatusUsed, Quantity: 1},
+	})
+	assert.Equal(t, 3, testutil.CollectAndCount(gpuSlices))
+	assert.Equal(t, 3.0, value("0", "1g.5gb", resource.StatusFree))
+	assert.Equal(t, 2.0, value("0", "1g.5gb", resource.StatusUsed))
+	assert.Equal(t, 1.0, value("1", "3g.20gb", resource.StatusUsed))
+
+	// Slices not included in the new annotations are removed
+	SetGpuSlices(node, gpu.GPUModel_A100_SXM4_40GB, gpu.StatusAnnotationList{
+		{ProfileName: "7g.40gb", Index: 0, Status: resource.StatusFree, Quantity: 1},
+	})
+	assert.Equal(t, 1, testutil.CollectAndCount(gpuSlices))
+	assert.Equal(t, 1.0, value("0", "7g.40gb", resource.StatusFree))
+}
@@ -24,6 +24,20 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// NodePartitioningError is the error returned when the partitioning of a node cannot be applied
+type NodePartitioningError struct {
+	NodeName string
+	Err      error
+}
+
+func (e NodePartitioningError) Error() string {
+	return fmt.Sprintf("error partitioning node %s: %s", e.NodeName, e.Err)
+}
+
+func (e NodePartitioningError) Unwrap() error {
+	return e.Err
+}
+
 type actuator struct {
 	Partitioner
 	client.Client
@@ -57,7 +71,7 @@ func (a actuator) Apply(ctx context.Context, snapshot Snapshot, plan Partitionin
 		}
 		logger.Info("partitioning node", "node", node.Name, "partitioning", partitioningState)
 		if err = a.ApplyPartitioning(ctx, node, plan.GetId(), partitioningState); err != nil {
-			return false, fmt.Errorf("error partitioning node %s: %w", nodeName, err)
+			return false, NodePartitioningError{NodeName: nodeName, Err: err}
 		}
 	}
 	logger.Info("plan applied")
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sort"
+	"strings"
+)
+
+// EventReasonDryRunPartitioning is the reason of the Events recorded on the nodes
+// by the dry-run actuator for each partitioning that it would have applied
+const EventReasonDryRunPartitioning = "DryRunPartitioning"
+
+// dryRunActuator is an Actuator that never changes the GPU partitioning of the nodes:
+// it only logs the partitioning plans and records an Event on each node
+// whose partitioning would have been changed by the plan.
+type dryRunActuator struct {
+	client.Client
+	recorder record.EventRecorder
+}
+
+func NewDryRunActuator(client client.Client, recorder record.EventRecorder) Actuator {
+	return dryRunActuator{
+		Client:   client,
+		recorder: recorder,
+	}
+}
+
+func (a dryRunActuator) Apply(ctx context.Context, snapshot Snapshot, plan PartitioningPlan) (bool, error) {
+	logger := log.FromContext(ctx).WithValues("dryRun", true)
+	logger.Info("applying desired partitioning")
+
+	currentState := snapshot.GetPartitioningState()
+	if currentState.Equal(plan.DesiredState) {
+		logger.Info("current and desired partitioning states are equal, nothing to do")
+		return false, nil
+	}
+	if plan.DesiredState.IsEmpty() {
+		logger.Info("desired partitioning state is empty, nothing to do")
+		return false, nil
+	}
+
+	for nodeName, partitioningState := range plan.DesiredState {
+		if partitioningState.Equal(currentState[nodeName]) {
+			continue
+		}
+		node := v1.Node{}
+		if err := a.Get(ctx, client.ObjectKey{Name: nodeName}, &node); err != nil {
+			return false, fmt.Errorf("failed to get node %s: %w", nodeName, err)
+		}
+		logger.Info("skipping node partitioning", "node", node.Name, "plan", plan.GetId(), "partitioning", partitioningState)
+		a.recorder.Eventf(
+			&node,
+			v1.EventTypeNormal,
+			EventReasonDryRunPartitioning,
+			"Dry run: plan %s would partition GPUs as %s",
+			plan.GetId(),
+			formatNodePartitioning(partitioningState),
+		)
+	}
+	logger.Info("plan recorded without being applied")
+
+	return false, nil
+}
+
+// dryRunPartitioner is a Partitioner that never changes the GPU partitioning of the nodes:
+// it only logs the partitioning and records an Event on the node.
+type dryRunPartitioner struct {
+	recorder record.EventRecorder
+}
+
+func NewDryRunPartitioner(recorder record.EventRecorder) Partitioner {
+	return dryRunPartitioner{recorder: recorder}
+}
+
+func (p dryRunPartitioner) ApplyPartitioning(ctx context.Context, node v1.Node, planId string, partitioning state.NodePartitioning) error {
+	logger := log.FromContext(ctx).WithValues("dryRun", true)
+	logger.Info("skipping node partitioning", "node", node.Name, "plan", planId, "partitioning", partitioning)
+	p.recorder.Eventf(
+		&node,
+		v1.EventTypeNormal,
+		EventReasonDryRunPartitioning,
+		"Dry run: plan %s would partition GPUs as %s",
+		planId,
+		formatNodePartitioning(partitioning),
+	)
+	return nil
+}
+
+// formatNodePartitioning returns a compact, deterministic representation of the
+// node partitioning, e.g. "[GPU 0: nvidia.com/mig-1g.10gb=2, nvidia.com/mig-2g.20gb=1]"
+func formatNodePartitioning(partitioning state.NodePartitioning) string {
+	gpus := make([]state.GPUPartitioning, len(partitioning.GPUs))
+	copy(gpus, partitioning.GPUs)
+	sort.Slice(gpus, func(i, j int) bool {
+		return gpus[i].GPUIndex < gpus[j].GPUIndex
+	})
+
+	gpuStrings := make([]string, 0, len(gpus))
+	for _, g := range gpus {
+		resources := make([]string, 0, len(g.Resources))
+		for r, q := range g.Resources {
+			resources = append(resources, fmt.Sprintf("%s=%d", r, q))
+		}
+		sort.Strings(resources)
+		gpuStrings = append(gpuStrings, fmt.Sprintf("GPU %d: %s", g.GPUIndex, strings.Join(resources, ", ")))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(gpuStrings, "; "))
+}
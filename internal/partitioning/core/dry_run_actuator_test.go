@@ -0,0 +1,158 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/nebuly-ai/nos/pkg/test/mocks"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestDryRunActuator__Apply(t *testing.T) {
+	currentState := state.PartitioningState{
+		"node-1": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/gpu-10gb": 1,
+					},
+				},
+			},
+		},
+		"node-2": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/gpu-10gb": 1,
+					},
+				},
+			},
+		},
+	}
+
+	node1 := factory.BuildNode("node-1").Get()
+	node2 := factory.BuildNode("node-2").Get()
+
+	testCases := []struct {
+		name           string
+		plan           core.PartitioningPlan
+		mockedNodes    []client.Object
+		expectedEvents []string
+		expectedErr    bool
+	}{
+		{
+			name:           "Empty plan, should do nothing",
+			plan:           core.NewPartitioningPlan(state.PartitioningState{}),
+			expectedEvents: []string{},
+		},
+		{
+			name:           "Snapshot equal to plan, should do nothing",
+			plan:           core.NewPartitioningPlan(currentState),
+			expectedEvents: []string{},
+		},
+		{
+			name: "Node not found, should return error",
+			plan: core.NewPartitioningPlan(state.PartitioningState{
+				"node-1": {
+					GPUs: []state.GPUPartitioning{
+						{
+							GPUIndex: 0,
+							Resources: map[v1.ResourceName]int{
+								"nvidia.com/gpu-5gb": 2,
+							},
+						},
+					},
+				},
+			}),
+			expectedEvents: []string{},
+			expectedErr:    true,
+		},
+		{
+			name: "Plan changes partitioning, should record an event only for the changed nodes",
+			plan: core.NewPartitioningPlan(state.PartitioningState{
+				"node-1": {
+					GPUs: []state.GPUPartitioning{
+						{
+							GPUIndex: 0,
+							Resources: map[v1.ResourceName]int{
+								"nvidia.com/gpu-5gb": 2,
+								"nvidia.com/gpu-2gb": 1,
+							},
+						},
+					},
+				},
+				"node-2": currentState["node-2"],
+			}),
+			mockedNodes: []client.Object{
+				&node1,
+				&node2,
+			},
+			expectedEvents: []string{
+				"Normal DryRunPartitioning Dry run: plan %s would partition GPUs as " +
+					"[GPU 0: nvidia.com/gpu-2gb=1, nvidia.com/gpu-5gb=2]",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := fake.NewClientBuilder().WithObjects(tt.mockedNodes...).Build()
+			recorder := record.NewFakeRecorder(10)
+			actuator := core.NewDryRunActuator(mockClient, recorder)
+
+			mockSnapshot := mocks.NewSnapshot(t)
+			mockSnapshot.On("GetPartitioningState").Return(currentState).Maybe()
+
+			applied, err := actuator.Apply(context.Background(), mockSnapshot, tt.plan)
+			assert.False(t, applied)
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			close(recorder.Events)
+			events := make([]string, 0)
+			for e := range recorder.Events {
+				events = append(events, e)
+			}
+			expectedEvents := make([]string, 0, len(tt.expectedEvents))
+			for _, e := range tt.expectedEvents {
+				expectedEvents = append(expectedEvents, fmt.Sprintf(e, tt.plan.GetId()))
+			}
+			assert.Equal(t, expectedEvents, events)
+
+			// Nodes must never be changed
+			for _, obj := range tt.mockedNodes {
+				var node v1.Node
+				assert.NoError(t, mockClient.Get(context.Background(), client.ObjectKeyFromObject(obj), &node))
+				assert.Empty(t, node.Annotations)
+			}
+		})
+	}
+}
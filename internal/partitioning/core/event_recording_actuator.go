@@ -0,0 +1,138 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/resource"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sort"
+	"strings"
+)
+
+const (
+	// EventReasonPartitioningChanged is the reason of the Events recorded on the nodes whose
+	// GPU partitioning is changed by a partitioning plan
+	EventReasonPartitioningChanged = "PartitioningChanged"
+	// EventReasonPartitioningFailed is the reason of the Events recorded on the nodes whose
+	// GPU partitioning could not be applied
+	EventReasonPartitioningFailed = "PartitioningFailed"
+	// EventReasonGpuSlicesPlanned is the reason of the Events recorded on the pending pods
+	// that a partitioning plan is going to make schedulable
+	EventReasonGpuSlicesPlanned = "GpuSlicesPlanned"
+	// EventReasonGpuSlicesUnavailable is the reason of the Events recorded on the pending pods
+	// that no partitioning plan could make schedulable
+	EventReasonGpuSlicesUnavailable = "GpuSlicesUnavailable"
+)
+
+// eventRecordingActuator is an Actuator that records Events about the outcome of the
+// partitioning plans applied by the wrapped actuator, both on the nodes whose partitioning
+// is changed and on the pods placed by the plans.
+type eventRecordingActuator struct {
+	client.Client
+	actuator Actuator
+	recorder record.EventRecorder
+}
+
+func NewEventRecordingActuator(client client.Client, recorder record.EventRecorder, actuator Actuator) Actuator {
+	return eventRecordingActuator{
+		Client:   client,
+		actuator: actuator,
+		recorder: recorder,
+	}
+}
+
+func (a eventRecordingActuator) Apply(ctx context.Context, snapshot Snapshot, plan PartitioningPlan) (bool, error) {
+	logger := log.FromContext(ctx)
+	currentState := snapshot.GetPartitioningState()
+
+	applied, err := a.actuator.Apply(ctx, snapshot, plan)
+	var nodeErr NodePartitioningError
+	if errors.As(err, &nodeErr) {
+		var node v1.Node
+		if getErr := a.Get(ctx, client.ObjectKey{Name: nodeErr.NodeName}, &node); getErr == nil {
+			a.recorder.Eventf(
+				&node,
+				v1.EventTypeWarning,
+				EventReasonPartitioningFailed,
+				"Unable to apply plan %s: %s",
+				plan.GetId(),
+				nodeErr.Err,
+			)
+		}
+	}
+	if err != nil || !applied {
+		return applied, err
+	}
+
+	for nodeName, partitioning := range plan.DesiredState {
+		if partitioning.Equal(currentState[nodeName]) {
+			continue
+		}
+		var node v1.Node
+		if err := a.Get(ctx, client.ObjectKey{Name: nodeName}, &node); err != nil {
+			logger.Error(err, "unable to record partitioning event", "node", nodeName)
+			continue
+		}
+		a.recorder.Eventf(
+			&node,
+			v1.EventTypeNormal,
+			EventReasonPartitioningChanged,
+			"Plan %s partitions GPUs as %s",
+			plan.GetId(),
+			formatNodePartitioning(partitioning),
+		)
+	}
+
+	for _, placement := range plan.Placements {
+		var pod v1.Pod
+		if err := a.Get(ctx, placement.Pod, &pod); err != nil {
+			logger.V(1).Info("unable to record placement event", "pod", placement.Pod, "err", err)
+			continue
+		}
+		a.recorder.Eventf(
+			&pod,
+			v1.EventTypeNormal,
+			EventReasonGpuSlicesPlanned,
+			"GPU slices %s being created on node %s by plan %s",
+			FormatRequestedGpuResources(pod),
+			placement.NodeName,
+			plan.GetId(),
+		)
+	}
+
+	return true, nil
+}
+
+// FormatRequestedGpuResources returns a compact, deterministic representation of the
+// NVIDIA resources requested by the pod, e.g. "[nvidia.com/mig-1g.10gb=2]"
+func FormatRequestedGpuResources(pod v1.Pod) string {
+	resources := make([]string, 0)
+	for r, q := range resource.ComputePodRequest(pod) {
+		if !strings.HasPrefix(r.String(), constant.NvidiaResourcePrefix) {
+			continue
+		}
+		resources = append(resources, fmt.Sprintf("%s=%s", r, q.String()))
+	}
+	sort.Strings(resources)
+	return fmt.Sprintf("[%s]", strings.Join(resources, ", "))
+}
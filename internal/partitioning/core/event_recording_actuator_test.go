@@ -0,0 +1,199 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/nebuly-ai/nos/pkg/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestEventRecordingActuator__Apply(t *testing.T) {
+	currentState := state.PartitioningState{
+		"node-1": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/mig-7g.40gb": 1,
+					},
+				},
+			},
+		},
+		"node-2": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/mig-7g.40gb": 1,
+					},
+				},
+			},
+		},
+	}
+	desiredState := state.PartitioningState{
+		"node-1": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/mig-1g.5gb":  2,
+						"nvidia.com/mig-3g.20gb": 1,
+					},
+				},
+			},
+		},
+		"node-2": currentState["node-2"],
+	}
+
+	node1 := factory.BuildNode("node-1").Get()
+	node2 := factory.BuildNode("node-2").Get()
+	pod := factory.BuildPod("ns-1", "pod-1").
+		WithContainer(
+			factory.BuildContainer("test", "test").
+				WithScalarResourceRequest("nvidia.com/mig-1g.5gb", 2).
+				WithCPUMilliRequest(100).
+				Get(),
+		).
+		Get()
+
+	testCases := []struct {
+		name           string
+		plan           core.PartitioningPlan
+		partitionerErr error
+		expectedEvents []string
+		expectedErr    bool
+	}{
+		{
+			name:           "Plan not applied, should not record any event",
+			plan:           core.NewPartitioningPlan(currentState),
+			expectedEvents: []string{},
+		},
+		{
+			name: "Plan applied, should record events on changed nodes and placed pods",
+			plan: func() core.PartitioningPlan {
+				plan := core.NewPartitioningPlan(desiredState)
+				plan.Placements = []core.PodPlacement{
+					{Pod: types.NamespacedName{Namespace: "ns-1", Name: "pod-1"}, NodeName: "node-1"},
+					{Pod: types.NamespacedName{Namespace: "ns-1", Name: "not-found"}, NodeName: "node-1"},
+				}
+				return plan
+			}(),
+			expectedEvents: []string{
+				"Normal PartitioningChanged Plan %s partitions GPUs as " +
+					"[GPU 0: nvidia.com/mig-1g.5gb=2, nvidia.com/mig-3g.20gb=1]",
+				"Normal GpuSlicesPlanned GPU slices [nvidia.com/mig-1g.5gb=2] being created on node node-1 by plan %s",
+			},
+		},
+		{
+			name:           "Partitioning fails, should record a warning on the node and return error",
+			plan:           core.NewPartitioningPlan(desiredState),
+			partitionerErr: errors.New("device plugin not ready"),
+			expectedEvents: []string{
+				"Warning PartitioningFailed Unable to apply plan %s: device plugin not ready",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := fake.NewClientBuilder().WithObjects(&node1, &node2, &pod).Build()
+			recorder := record.NewFakeRecorder(10)
+
+			mockPartitioner := mocks.NewPartitioner(t)
+			mockPartitioner.On("ApplyPartitioning", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(tt.partitionerErr).
+				Maybe()
+
+			mockSnapshot := mocks.NewSnapshot(t)
+			mockSnapshot.On("GetPartitioningState").Return(currentState).Maybe()
+
+			actuator := core.NewEventRecordingActuator(
+				mockClient,
+				recorder,
+				core.NewActuator(mockClient, mockPartitioner),
+			)
+			_, err := actuator.Apply(context.Background(), mockSnapshot, tt.plan)
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			close(recorder.Events)
+			events := make([]string, 0)
+			for e := range recorder.Events {
+				events = append(events, e)
+			}
+			expectedEvents := make([]string, 0, len(tt.expectedEvents))
+			for _, e := range tt.expectedEvents {
+				expectedEvents = append(expectedEvents, fmt.Sprintf(e, tt.plan.GetId()))
+			}
+			assert.Equal(t, expectedEvents, events)
+		})
+	}
+}
+
+func TestFormatRequestedGpuResources(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pod      v1.Pod
+		expected string
+	}{
+		{
+			name:     "Pod without containers",
+			pod:      factory.BuildPod("ns-1", "pod-1").Get(),
+			expected: "[]",
+		},
+		{
+			name: "Non-GPU resources are ignored, output is sorted",
+			pod: factory.BuildPod("ns-1", "pod-1").
+				WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest("nvidia.com/mig-2g.10gb", 1).
+						WithCPUMilliRequest(100).
+						Get(),
+				).
+				WithContainer(
+					factory.BuildContainer("c2", "test").
+						WithScalarResourceRequest("nvidia.com/mig-1g.5gb", 1).
+						WithScalarResourceRequest("nvidia.com/mig-2g.10gb", 1).
+						Get(),
+				).
+				Get(),
+			expected: "[nvidia.com/mig-1g.5gb=1, nvidia.com/mig-2g.10gb=2]",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, core.FormatRequestedGpuResources(tt.pod))
+		})
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// evictingActuator is an Actuator that evicts the victims of a plan before applying it.
+//
+// Victims are evicted through the Eviction API, so that their PodDisruptionBudgets are honored.
+// All the evictions are first submitted in dry-run mode: if any victim cannot be evicted,
+// no victim is evicted and the plan is not applied.
+type evictingActuator struct {
+	actuator   Actuator
+	kubeClient kubernetes.Interface
+}
+
+func NewEvictingActuator(kubeClient kubernetes.Interface, actuator Actuator) Actuator {
+	return evictingActuator{
+		actuator:   actuator,
+		kubeClient: kubeClient,
+	}
+}
+
+func (a evictingActuator) Apply(ctx context.Context, snapshot Snapshot, plan PartitioningPlan) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	if len(plan.Victims) > 0 {
+		for _, victim := range plan.Victims {
+			if err := a.evict(ctx, victim, true); err != nil {
+				return false, err
+			}
+		}
+		logger.V(1).Info("all the victims of the plan can be evicted", "victims", len(plan.Victims))
+	}
+	for _, victim := range plan.Victims {
+		logger.Info("evicting pod", "namespace", victim.Pod.Namespace, "pod", victim.Pod.Name, "node", victim.NodeName)
+		if err := a.evict(ctx, victim, false); err != nil {
+			return false, err
+		}
+	}
+
+	return a.actuator.Apply(ctx, snapshot, plan)
+}
+
+// evict evicts the victim through the Eviction API, without persisting the eviction if dryRun is true.
+// Victims that do not exist anymore are considered as already evicted.
+func (a evictingActuator) evict(ctx context.Context, victim PodPlacement, dryRun bool) error {
+	eviction := policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      victim.Pod.Name,
+			Namespace: victim.Pod.Namespace,
+		},
+	}
+	if dryRun {
+		eviction.DeleteOptions = &metav1.DeleteOptions{DryRun: []string{metav1.DryRunAll}}
+	}
+	err := a.kubeClient.CoreV1().Pods(victim.Pod.Namespace).EvictV1(ctx, &eviction)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if apierrors.IsTooManyRequests(err) {
+		return fmt.Errorf("cannot evict pod %s without violating its disruption budget: %w", victim.Pod, err)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to evict pod %s: %w", victim.Pod, err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/nebuly-ai/nos/pkg/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestEvictingActuator__Apply(t *testing.T) {
+	plan := core.NewPartitioningPlanWithId("1", state.PartitioningState{
+		"node-1": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/mig-1g.6gb": 4,
+					},
+				},
+			},
+		},
+	})
+	plan.Victims = []core.PodPlacement{
+		{Pod: types.NamespacedName{Namespace: "ns-1", Name: "pod-1"}, NodeName: "node-1"},
+		{Pod: types.NamespacedName{Namespace: "ns-1", Name: "pod-2"}, NodeName: "node-1"},
+	}
+
+	testCases := []struct {
+		name                    string
+		evictionErrors          map[string]error
+		expectedDryRunEvictions []string
+		expectedEvictions       []string
+		expectedApplied         bool
+		expectedErr             bool
+	}{
+		{
+			name:                    "Victims evicted, should apply plan",
+			expectedDryRunEvictions: []string{"pod-1", "pod-2"},
+			expectedEvictions:       []string{"pod-1", "pod-2"},
+			expectedApplied:         true,
+		},
+		{
+			name: "Victims already deleted, should apply plan",
+			evictionErrors: map[string]error{
+				"pod-1": apierrors.NewNotFound(v1.Resource("pods"), "pod-1"),
+			},
+			expectedDryRunEvictions: []string{"pod-1", "pod-2"},
+			expectedEvictions:       []string{"pod-1", "pod-2"},
+			expectedApplied:         true,
+		},
+		{
+			name: "Eviction would violate disruption budget, should not evict any victim nor apply plan",
+			evictionErrors: map[string]error{
+				"pod-2": apierrors.NewTooManyRequests("disruption budget", 10),
+			},
+			expectedDryRunEvictions: []string{"pod-1", "pod-2"},
+			expectedEvictions:       []string{},
+			expectedApplied:         false,
+			expectedErr:             true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			dryRunEvictions := make([]string, 0)
+			evictions := make([]string, 0)
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+				if eviction.DeleteOptions != nil && len(eviction.DeleteOptions.DryRun) > 0 {
+					dryRunEvictions = append(dryRunEvictions, eviction.Name)
+				} else {
+					evictions = append(evictions, eviction.Name)
+				}
+				return true, nil, tt.evictionErrors[eviction.Name]
+			})
+
+			node := factory.BuildNode("node-1").Get()
+			mockPartitioner := mocks.NewPartitioner(t)
+			if tt.expectedApplied {
+				mockPartitioner.On("ApplyPartitioning", mock.Anything, mock.Anything, "1", mock.Anything).Return(nil).Once()
+			}
+			mockSnapshot := mocks.NewSnapshot(t)
+			mockSnapshot.On("GetPartitioningState").Return(state.PartitioningState{}).Maybe()
+
+			actuator := core.NewEvictingActuator(
+				kubeClient,
+				core.NewActuator(fake.NewClientBuilder().WithObjects(&node).Build(), mockPartitioner),
+			)
+			applied, err := actuator.Apply(context.Background(), mockSnapshot, plan)
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedApplied, applied)
+			assert.Equal(t, tt.expectedDryRunEvictions, dryRunEvictions)
+			assert.Equal(t, tt.expectedEvictions, evictions)
+		})
+	}
+}
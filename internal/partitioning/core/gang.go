@@ -0,0 +1,159 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"strconv"
+)
+
+// planningUnit is a set of pending pods that the planner processes together. If required is greater
+// than 0, the pods belong to the same pod group, and the planner must place at least required of them
+// at once or none of them.
+type planningUnit struct {
+	pods     []v1.Pod
+	required int
+}
+
+// podGroupKey returns the key identifying the pod group the pod belongs to,
+// and false if the pod does not belong to any group
+func podGroupKey(pod v1.Pod) (string, bool) {
+	group, ok := pod.Labels[v1alpha1.LabelPodGroup]
+	if !ok || group == "" {
+		return "", false
+	}
+	return pod.Namespace + "/" + group, true
+}
+
+// getPodGroupMinMember returns the min number of members of the group of the pod,
+// or 0 if the pod does not specify any valid value
+func getPodGroupMinMember(pod v1.Pod) int {
+	minMember, err := strconv.Atoi(pod.Labels[v1alpha1.LabelPodGroupMinMember])
+	if err != nil || minMember < 0 {
+		return 0
+	}
+	return minMember
+}
+
+// getPodGroupRequirements returns, for each pod group of the provided pending pods, the number
+// of pending pods of the group that must be placed together for the group to start. The pods of the
+// group already assigned to any node of the snapshot count towards the min member of the group.
+// Groups that do not need any other pod for starting are not included in the result.
+//
+// If a group does not specify its min member, all its pending pods must be placed together.
+func getPodGroupRequirements(snapshot Snapshot, pendingPods []v1.Pod) map[string]int {
+	pending := make(map[string]int)
+	minMembers := make(map[string]int)
+	pendingNames := make(map[types.NamespacedName]struct{})
+	for _, pod := range pendingPods {
+		key, ok := podGroupKey(pod)
+		if !ok {
+			continue
+		}
+		pending[key]++
+		if minMember := getPodGroupMinMember(pod); minMember > minMembers[key] {
+			minMembers[key] = minMember
+		}
+		pendingNames[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}] = struct{}{}
+	}
+	if len(pending) == 0 {
+		return map[string]int{}
+	}
+
+	running := make(map[string]int)
+	for _, node := range snapshot.GetNodes() {
+		for _, podInfo := range node.NodeInfo().Pods {
+			pod := podInfo.Pod
+			if _, isPending := pendingNames[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}]; isPending {
+				continue
+			}
+			if key, ok := podGroupKey(*pod); ok {
+				running[key]++
+			}
+		}
+	}
+
+	res := make(map[string]int)
+	for key, nPending := range pending {
+		required := nPending
+		if minMember, ok := minMembers[key]; ok && minMember > 0 {
+			required = minMember - running[key]
+		}
+		if required > 0 {
+			res[key] = required
+		}
+	}
+	return res
+}
+
+// getPlanningUnits splits the provided sorted pods into planning units, preserving their order.
+// The pods of each group with requirements are put in a unit on their own, placed at the position
+// of the first pod of the group, while consecutive pods without requirements share the same unit.
+func getPlanningUnits(sortedPods []v1.Pod, groupRequirements map[string]int) []planningUnit {
+	groups := make(map[string][]v1.Pod)
+	for _, pod := range sortedPods {
+		if key, ok := podGroupKey(pod); ok && groupRequirements[key] > 0 {
+			groups[key] = append(groups[key], pod)
+		}
+	}
+
+	res := make([]planningUnit, 0)
+	var current []v1.Pod
+	for _, pod := range sortedPods {
+		key, ok := podGroupKey(pod)
+		if !ok || groupRequirements[key] == 0 {
+			current = append(current, pod)
+			continue
+		}
+		members, found := groups[key]
+		if !found {
+			continue
+		}
+		if len(current) > 0 {
+			res = append(res, planningUnit{pods: current})
+			current = nil
+		}
+		res = append(res, planningUnit{pods: members, required: groupRequirements[key]})
+		delete(groups, key)
+	}
+	if len(current) > 0 {
+		res = append(res, planningUnit{pods: current})
+	}
+	return res
+}
+
+// arePodGroupsSatisfied returns true if, for each group with requirements, the placements
+// either contain none of its pods or at least the required number of them
+func arePodGroupsSatisfied(groupOf map[types.NamespacedName]string, groupRequirements map[string]int, placements []PodPlacement) bool {
+	if len(groupRequirements) == 0 {
+		return true
+	}
+	placed := make(map[string]int)
+	for _, placement := range placements {
+		if key, ok := groupOf[placement.Pod]; ok {
+			placed[key]++
+		}
+	}
+	for key, count := range placed {
+		if count < groupRequirements[key] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	partitioning_mig "github.com/nebuly-ai/nos/internal/partitioning/mig"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	configv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	nosresource "github.com/nebuly-ai/nos/pkg/resource"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	scheduler_mock "github.com/nebuly-ai/nos/pkg/test/mocks/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPlanner__Plan__PodGroups(t *testing.T) {
+	a30Node := func(name string) v1.Node {
+		return factory.BuildNode(name).
+			WithAnnotations(map[string]string{
+				fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, mig.Profile1g6gb, nosresource.StatusFree): "4",
+			}).
+			WithLabels(map[string]string{
+				constant.LabelNvidiaProduct:   string(gpu.GPUModel_A30),
+				constant.LabelNvidiaCount:     "1",
+				v1alpha1.LabelGpuPartitioning: gpu.PartitioningKindMig.String(),
+			}).
+			WithAllocatableResources(v1.ResourceList{
+				mig.Profile1g6gb.AsResourceName(): *resource.NewQuantity(4, resource.DecimalSI),
+			}).
+			Get()
+	}
+	migPod := func(name string, profile mig.ProfileName) v1.Pod {
+		return factory.BuildPod("ns-1", name).WithContainer(
+			factory.BuildContainer("test", "test").
+				WithScalarResourceRequest(profile.AsResourceName(), 1).
+				Get(),
+		).Get()
+	}
+	groupPod := func(name string, minMember int) v1.Pod {
+		pod := migPod(name, mig.Profile2g12gb)
+		pod.Labels = map[string]string{v1alpha1.LabelPodGroup: "job-1"}
+		if minMember > 0 {
+			pod.Labels[v1alpha1.LabelPodGroupMinMember] = strconv.Itoa(minMember)
+		}
+		return pod
+	}
+
+	testCases := []struct {
+		name          string
+		snapshotNodes []v1.Node
+		runningPods   map[string][]v1.Pod
+		candidatePods []v1.Pod
+
+		expectedPlacedPods []string
+	}{
+		{
+			name:          "Group fits a single node",
+			snapshotNodes: []v1.Node{a30Node("node-1")},
+			candidatePods: []v1.Pod{
+				groupPod("worker-1", 0),
+				groupPod("worker-2", 0),
+			},
+			expectedPlacedPods: []string{"worker-1", "worker-2"},
+		},
+		{
+			name:          "Group does not fit, should not change any geometry",
+			snapshotNodes: []v1.Node{a30Node("node-1")},
+			candidatePods: []v1.Pod{
+				groupPod("worker-1", 0),
+				groupPod("worker-2", 0),
+				groupPod("worker-3", 0),
+			},
+			expectedPlacedPods: []string{},
+		},
+		{
+			name:          "Group spanning multiple nodes",
+			snapshotNodes: []v1.Node{a30Node("node-1"), a30Node("node-2")},
+			candidatePods: []v1.Pod{
+				groupPod("worker-1", 0),
+				groupPod("worker-2", 0),
+				groupPod("worker-3", 0),
+			},
+			expectedPlacedPods: []string{"worker-1", "worker-2", "worker-3"},
+		},
+		{
+			name:          "Min member reached, should place the pods that fit",
+			snapshotNodes: []v1.Node{a30Node("node-1")},
+			candidatePods: []v1.Pod{
+				groupPod("worker-1", 2),
+				groupPod("worker-2", 2),
+				groupPod("worker-3", 2),
+			},
+			expectedPlacedPods: []string{"worker-1", "worker-2"},
+		},
+		{
+			name:          "Not enough pending pods for reaching min member",
+			snapshotNodes: []v1.Node{a30Node("node-1"), a30Node("node-2")},
+			candidatePods: []v1.Pod{
+				groupPod("worker-1", 3),
+				groupPod("worker-2", 3),
+			},
+			expectedPlacedPods: []string{},
+		},
+		{
+			name:          "Running pods of the group count towards min member",
+			snapshotNodes: []v1.Node{a30Node("node-1"), a30Node("node-2")},
+			runningPods: map[string][]v1.Pod{
+				"node-2": {
+					func() v1.Pod {
+						pod := factory.BuildPod("ns-1", "worker-0").WithNodeName("node-2").Get()
+						pod.Labels = map[string]string{v1alpha1.LabelPodGroup: "job-1"}
+						return pod
+					}(),
+				},
+			},
+			candidatePods: []v1.Pod{
+				groupPod("worker-1", 3),
+				groupPod("worker-2", 3),
+			},
+			expectedPlacedPods: []string{"worker-1", "worker-2"},
+		},
+		{
+			name:          "Single pods are planned even if the group does not fit",
+			snapshotNodes: []v1.Node{a30Node("node-1")},
+			candidatePods: []v1.Pod{
+				migPod("pd-1", mig.Profile2g12gb),
+				groupPod("worker-1", 0),
+				groupPod("worker-2", 0),
+				groupPod("worker-3", 0),
+			},
+			expectedPlacedPods: []string{"pd-1"},
+		},
+	}
+
+	planners := map[string]func(framework.Framework) core.Planner{
+		"greedy": func(scheduler framework.Framework) core.Planner {
+			return partitioning_mig.NewPlanner(scheduler, configv1alpha1.PodSorterKindPriority, nil)
+		},
+		"optimal": func(scheduler framework.Framework) core.Planner {
+			return partitioning_mig.NewOptimalPlanner(scheduler, configv1alpha1.PodSorterKindPriority, nil, 10*time.Second)
+		},
+	}
+
+	for plannerName, newPlanner := range planners {
+		for _, tt := range testCases {
+			t.Run(plannerName+"/"+tt.name, func(t *testing.T) {
+				mockedScheduler := scheduler_mock.NewFramework(t)
+				mockedScheduler.On(
+					"RunPreFilterPlugins",
+					mock.Anything,
+					mock.Anything,
+					mock.Anything,
+				).Return(nil, framework.NewStatus(framework.Success)).Maybe()
+				mockedScheduler.On(
+					"RunFilterPlugins",
+					mock.Anything,
+					mock.Anything,
+					mock.Anything,
+					mock.Anything,
+				).Return(framework.PluginToStatus{"": framework.NewStatus(framework.Success)}).Maybe()
+
+				snapshot := newSnapshotWithPods(tt.snapshotNodes, tt.runningPods)
+				initialState := snapshot.GetPartitioningState()
+				plan, err := newPlanner(mockedScheduler).Plan(context.Background(), snapshot, tt.candidatePods)
+				assert.NoError(t, err)
+
+				placedPods := make([]string, 0)
+				for _, placement := range plan.Placements {
+					placedPods = append(placedPods, placement.Pod.Name)
+				}
+				assert.ElementsMatch(t, tt.expectedPlacedPods, placedPods)
+				if len(tt.expectedPlacedPods) == 0 {
+					assert.True(t, initialState.Equal(plan.DesiredState), "expected %v, got %v", initialState, plan.DesiredState)
+				}
+			})
+		}
+	}
+}
+
+func newSnapshotWithPods(nodes []v1.Node, pods map[string][]v1.Pod) core.Snapshot {
+	nodeInfos := make(map[string]framework.NodeInfo)
+	for _, node := range nodes {
+		n := node
+		ni := framework.NewNodeInfo()
+		ni.Requested = framework.NewResource(v1.ResourceList{})
+		ni.Allocatable = framework.NewResource(v1.ResourceList{})
+		ni.SetNode(&n)
+		for _, pod := range pods[n.Name] {
+			p := pod
+			ni.AddPod(&p)
+		}
+		nodeInfos[n.Name] = *ni
+	}
+	snapshot, err := partitioning_mig.NewSnapshotTaker().TakeSnapshot(state.NewClusterState(nodeInfos))
+	if err != nil {
+		panic(err)
+	}
+	return snapshot
+}
@@ -32,6 +32,10 @@ type Actuator interface {
 	Apply(ctx context.Context, snapshot Snapshot, plan PartitioningPlan) (bool, error)
 }
 
+type Compactor interface {
+	Compact(ctx context.Context, snapshot Snapshot) (PartitioningPlan, error)
+}
+
 type PartitionableNode interface {
 	UpdateGeometryFor(slices map[gpu.Slice]int) (bool, error)
 	GetName() string
@@ -42,6 +46,13 @@ type PartitionableNode interface {
 	HasFreeCapacity() bool
 }
 
+// PreemptibleNode is a PartitionableNode whose pods can be removed for making room
+// to higher-priority pods
+type PreemptibleNode interface {
+	PartitionableNode
+	RemovePod(pod v1.Pod) error
+}
+
 type PartitionCalculator interface {
 	GetPartitioning(node PartitionableNode) state.NodePartitioning
 }
@@ -0,0 +1,200 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sort"
+	"time"
+)
+
+// optimalPlanner is a Planner that searches the partitioning state that allows to schedule the highest
+// number of candidate pods by jointly exploring all the possible assignments of pods to nodes, rather
+// than considering one node at a time like the greedy planner does.
+//
+// The search is a depth-first branch-and-bound: the solution of the greedy planner is used as initial
+// lower bound, and a branch is pruned as soon as the number of pods it could still schedule cannot
+// improve the best solution found so far. Since the search space grows exponentially with the number
+// of pods, the search is bounded by a time budget: if the budget expires before the search completes,
+// the planner returns the best solution found so far, which is the greedy one if the search could not
+// improve it.
+type optimalPlanner struct {
+	planner
+	timeout time.Duration
+}
+
+func NewOptimalPlanner(
+	partitioner PartitionCalculator,
+	sliceCalculator gpu.SliceCalculator,
+	schedulerFramework framework.Framework,
+	sorter Sorter,
+	timeout time.Duration,
+) Planner {
+	return optimalPlanner{
+		planner: planner{
+			partitioner:        partitioner,
+			sliceCalculator:    sliceCalculator,
+			schedulerFramework: schedulerFramework,
+			sorter:             sorter,
+		},
+		timeout: timeout,
+	}
+}
+
+// searchState holds the state shared by all the branches of the search
+type searchState struct {
+	pods           []v1.Pod
+	groupOf        map[types.NamespacedName]string
+	groupRequired  map[string]int
+	nodeNames      []string
+	deadline       time.Time
+	expired        bool
+	bestPods       int
+	bestSnapshot   Snapshot
+	bestPlacements []PodPlacement
+}
+
+func (p optimalPlanner) Plan(ctx context.Context, snapshot Snapshot, candidatePods []v1.Pod) (PartitioningPlan, error) {
+	logger := log.FromContext(ctx)
+	deadline := time.Now().Add(p.timeout)
+
+	// Compute the greedy solution, used both as lower bound and as fallback
+	greedyState, greedyPlacements, err := p.planner.plan(ctx, snapshot.Clone(), candidatePods)
+	if err != nil {
+		return PartitioningPlan{}, err
+	}
+	greedyPlan := NewPartitioningPlan(greedyState)
+	greedyPlan.Placements = greedyPlacements
+	greedyPods := len(greedyPlacements)
+
+	// If no pod is lacking GPU slices, there is nothing to repartition
+	tracker := NewSliceTracker(snapshot, p.sliceCalculator, candidatePods)
+	if len(tracker.GetLackingSlices()) == 0 {
+		return greedyPlan, nil
+	}
+	// Consider all the pods requesting GPU slices, since the placements of the greedy solution
+	// include also the pods that can be scheduled without repartitioning any GPU
+	pods := make([]v1.Pod, 0, len(candidatePods))
+	for _, pod := range p.sorter.Sort(candidatePods) {
+		if len(p.sliceCalculator.GetRequestedSlices(pod)) > 0 {
+			pods = append(pods, pod)
+		}
+	}
+	// The greedy solution already schedules all the pods, it cannot be improved
+	if greedyPods >= len(pods) {
+		return greedyPlan, nil
+	}
+
+	nodeNames := make([]string, 0, len(snapshot.GetNodes()))
+	for name := range snapshot.GetNodes() {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+
+	// Pods of the same group count only if the group can start
+	groupRequired := getPodGroupRequirements(snapshot, candidatePods)
+	groupOf := make(map[types.NamespacedName]string)
+	for _, pod := range pods {
+		if key, ok := podGroupKey(pod); ok && groupRequired[key] > 0 {
+			groupOf[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}] = key
+		}
+	}
+
+	s := &searchState{
+		pods:          pods,
+		groupOf:       groupOf,
+		groupRequired: groupRequired,
+		nodeNames:     nodeNames,
+		deadline:      deadline,
+		bestPods:      greedyPods,
+	}
+	p.search(ctx, s, snapshot.Clone(), 0, make([]PodPlacement, 0, len(pods)))
+
+	if s.expired {
+		logger.Info(
+			"time budget of optimal planner expired, returning best plan found so far",
+			"timeout",
+			p.timeout,
+			"scheduledPods",
+			s.bestPods,
+			"greedyScheduledPods",
+			greedyPods,
+		)
+	}
+	// The best snapshot is set only if the search found a solution better than the greedy one
+	if s.bestSnapshot == nil {
+		if !s.expired {
+			logger.V(1).Info("greedy plan is optimal", "scheduledPods", greedyPods)
+		}
+		return greedyPlan, nil
+	}
+	logger.V(1).Info(
+		"found plan better than greedy one",
+		"scheduledPods",
+		s.bestPods,
+		"greedyScheduledPods",
+		greedyPods,
+	)
+	plan := NewPartitioningPlan(s.bestSnapshot.GetPartitioningState())
+	plan.Placements = s.bestPlacements
+	return plan, nil
+}
+
+// search explores the possible assignments of the pods starting from the one at index podIdx, given
+// the snapshot resulting from the placements of the previous pods.
+func (p optimalPlanner) search(ctx context.Context, s *searchState, snapshot Snapshot, podIdx int, placements []PodPlacement) {
+	if time.Now().After(s.deadline) {
+		s.expired = true
+		return
+	}
+	scheduledPods := len(placements)
+	if scheduledPods > s.bestPods && arePodGroupsSatisfied(s.groupOf, s.groupRequired, placements) {
+		s.bestPods = scheduledPods
+		s.bestSnapshot = snapshot
+		s.bestPlacements = make([]PodPlacement, len(placements))
+		copy(s.bestPlacements, placements)
+	}
+	// Bound: even scheduling all the remaining pods would not improve the best solution
+	if podIdx >= len(s.pods) || scheduledPods+len(s.pods)-podIdx <= s.bestPods {
+		return
+	}
+
+	// Branch: try to schedule the pod on each node
+	pod := s.pods[podIdx]
+	for _, nodeName := range s.nodeNames {
+		candidate := snapshot.Clone()
+		if !p.tryPlacePod(ctx, pod, nodeName, candidate) {
+			continue
+		}
+		placement := PodPlacement{
+			Pod:      types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name},
+			NodeName: nodeName,
+		}
+		p.search(ctx, s, candidate, podIdx+1, append(placements, placement))
+		if s.expired {
+			return
+		}
+	}
+
+	// Branch: leave the pod unscheduled
+	p.search(ctx, s, snapshot, podIdx+1, placements)
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	partitioning_mig "github.com/nebuly-ai/nos/internal/partitioning/mig"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	configv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	nosresource "github.com/nebuly-ai/nos/pkg/resource"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	scheduler_mock "github.com/nebuly-ai/nos/pkg/test/mocks/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"testing"
+	"time"
+)
+
+func TestOptimalPlanner__Plan(t *testing.T) {
+	a30Node := func(name string) v1.Node {
+		return factory.BuildNode(name).
+			WithAnnotations(map[string]string{
+				fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, mig.Profile1g6gb, nosresource.StatusFree): "4",
+			}).
+			WithLabels(map[string]string{
+				constant.LabelNvidiaProduct:   string(gpu.GPUModel_A30),
+				constant.LabelNvidiaCount:     "1",
+				v1alpha1.LabelGpuPartitioning: gpu.PartitioningKindMig.String(),
+			}).
+			WithAllocatableResources(v1.ResourceList{
+				mig.Profile1g6gb.AsResourceName(): *resource.NewQuantity(4, resource.DecimalSI),
+			}).
+			Get()
+	}
+	migPod := func(name string, profile mig.ProfileName) v1.Pod {
+		return factory.BuildPod("ns-1", name).WithContainer(
+			factory.BuildContainer("test", "test").
+				WithScalarResourceRequest(profile.AsResourceName(), 1).
+				Get(),
+		).Get()
+	}
+
+	// The large pod can be scheduled only on node-1: the greedy planner
+	// assigns the small pods to node-1, leaving the large pod pending
+	snapshotNodes := []v1.Node{a30Node("node-1"), a30Node("node-2")}
+	candidatePods := []v1.Pod{
+		migPod("pd-large", mig.Profile4g24gb),
+		migPod("pd-small-1", mig.Profile2g12gb),
+		migPod("pd-small-2", mig.Profile2g12gb),
+	}
+	filterStatus := func(_ context.Context, _ *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) framework.PluginToStatus {
+		if pod.Name == "pd-large" && nodeInfo.Node().Name != "node-1" {
+			return framework.PluginToStatus{"": framework.NewStatus(framework.Unschedulable)}
+		}
+		return framework.PluginToStatus{"": framework.NewStatus(framework.Success)}
+	}
+
+	testCases := []struct {
+		name               string
+		timeout            time.Duration
+		expected           state.PartitioningState
+		expectedPlacements []core.PodPlacement
+	}{
+		{
+			name:    "Search completes, plan schedules more pods than greedy plan",
+			timeout: 10 * time.Second,
+			expected: state.PartitioningState{
+				"node-1": state.NodePartitioning{
+					GPUs: []state.GPUPartitioning{
+						{
+							GPUIndex: 0,
+							Resources: map[v1.ResourceName]int{
+								mig.Profile4g24gb.AsResourceName(): 1,
+							},
+						},
+					},
+				},
+				"node-2": state.NodePartitioning{
+					GPUs: []state.GPUPartitioning{
+						{
+							GPUIndex: 0,
+							Resources: map[v1.ResourceName]int{
+								mig.Profile2g12gb.AsResourceName(): 2,
+							},
+						},
+					},
+				},
+			},
+			expectedPlacements: []core.PodPlacement{
+				{Pod: types.NamespacedName{Namespace: "ns-1", Name: "pd-large"}, NodeName: "node-1"},
+				{Pod: types.NamespacedName{Namespace: "ns-1", Name: "pd-small-1"}, NodeName: "node-2"},
+				{Pod: types.NamespacedName{Namespace: "ns-1", Name: "pd-small-2"}, NodeName: "node-2"},
+			},
+		},
+		{
+			name:    "Time budget expires before finding a better plan, planner returns greedy plan",
+			timeout: 0,
+			expected: state.PartitioningState{
+				"node-1": state.NodePartitioning{
+					GPUs: []state.GPUPartitioning{
+						{
+							GPUIndex: 0,
+							Resources: map[v1.ResourceName]int{
+								mig.Profile2g12gb.AsResourceName(): 2,
+							},
+						},
+					},
+				},
+				"node-2": state.NodePartitioning{
+					GPUs: []state.GPUPartitioning{
+						{
+							GPUIndex: 0,
+							Resources: map[v1.ResourceName]int{
+								mig.Profile1g6gb.AsResourceName(): 4,
+							},
+						},
+					},
+				},
+			},
+			expectedPlacements: []core.PodPlacement{
+				{Pod: types.NamespacedName{Namespace: "ns-1", Name: "pd-small-1"}, NodeName: "node-1"},
+				{Pod: types.NamespacedName{Namespace: "ns-1", Name: "pd-small-2"}, NodeName: "node-1"},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedScheduler := scheduler_mock.NewFramework(t)
+			mockedScheduler.On(
+				"RunPreFilterPlugins",
+				mock.Anything,
+				mock.Anything,
+				mock.Anything,
+			).Return(nil, framework.NewStatus(framework.Success)).Maybe()
+			mockedScheduler.On(
+				"RunFilterPlugins",
+				mock.Anything,
+				mock.Anything,
+				mock.Anything,
+				mock.Anything,
+			).Return(filterStatus).Maybe()
+
+			snapshot := newSnapshotFromNodes(snapshotNodes, partitioning_mig.NewSnapshotTaker())
+			planner := partitioning_mig.NewOptimalPlanner(mockedScheduler, configv1alpha1.PodSorterKindPriority, nil, tt.timeout)
+			plan, err := planner.Plan(context.Background(), snapshot, candidatePods)
+
+			assert.NoError(t, err)
+			assert.True(t, tt.expected.Equal(plan.DesiredState), "expected %v, got %v", tt.expected, plan.DesiredState)
+			assert.ElementsMatch(t, tt.expectedPlacements, plan.Placements)
+		})
+	}
+}
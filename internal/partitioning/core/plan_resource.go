@@ -0,0 +1,222 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/util/pod"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+)
+
+// GetPartitioningPlanResourceGenerateName returns the prefix of the name of the PartitioningPlan resource
+// storing the plan with the provided ID. The API server appends a random suffix to the prefix, since
+// plans computed within the same second (e.g. by the partitioner and by the compaction loop) have the same ID.
+func GetPartitioningPlanResourceGenerateName(kind gpu.PartitioningKind, planId string) string {
+	return fmt.Sprintf("%s-%s-", kind, planId)
+}
+
+// NewPartitioningPlanResource converts the plan into a PartitioningPlan resource. The base state is the
+// partitioning state from which the plan was computed: the resource stores the base partitioning of the
+// nodes of the plan, so that the plan can be applied only if the nodes did not change in the meantime.
+// The nodes and the pods of the resource are sorted by name, so that the same plan
+// always results in the same resource.
+func NewPartitioningPlanResource(kind gpu.PartitioningKind, plan PartitioningPlan, baseState state.PartitioningState) v1alpha1.PartitioningPlan {
+	base := make(state.PartitioningState, len(plan.DesiredState))
+	for nodeName := range plan.DesiredState {
+		if nodePartitioning, ok := baseState[nodeName]; ok {
+			base[nodeName] = nodePartitioning
+		}
+	}
+	return v1alpha1.PartitioningPlan{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: GetPartitioningPlanResourceGenerateName(kind, plan.GetId()),
+		},
+		Spec: v1alpha1.PartitioningPlanSpec{
+			PlanId:           plan.GetId(),
+			PartitioningKind: kind.String(),
+			Nodes:            toNodePartitioningSpecs(plan.DesiredState),
+			BaseNodes:        toNodePartitioningSpecs(base),
+			Pods:             toPartitioningPlanPods(plan.Placements),
+			Victims:          toPartitioningPlanPods(plan.Victims),
+		},
+	}
+}
+
+// NewPartitioningPlanFromResource converts a PartitioningPlan resource into a plan
+// that can be applied by an Actuator. The returned plan keeps the ID of the resource.
+func NewPartitioningPlanFromResource(resource v1alpha1.PartitioningPlan) PartitioningPlan {
+	plan := NewPartitioningPlanWithId(resource.Spec.PlanId, fromNodePartitioningSpecs(resource.Spec.Nodes))
+	plan.Placements = fromPartitioningPlanPods(resource.Spec.Pods)
+	plan.Victims = fromPartitioningPlanPods(resource.Spec.Victims)
+	return plan
+}
+
+// GetPartitioningPlanStaleReason checks whether the plan stored in the PartitioningPlan resource can still be
+// applied to the cluster, and returns the reason why the plan is stale, or an empty string if it is not.
+// A plan is stale if the partitioning of its nodes changed since it was computed, if any of its pods
+// is no longer pending, or if any of its victims is no longer running on its node.
+func GetPartitioningPlanStaleReason(ctx context.Context, c client.Reader, snapshot Snapshot, resource v1alpha1.PartitioningPlan) (string, error) {
+	currentState := snapshot.GetPartitioningState()
+	for nodeName, base := range fromNodePartitioningSpecs(resource.Spec.BaseNodes) {
+		current, ok := currentState[nodeName]
+		if !ok {
+			return fmt.Sprintf("node %s not found", nodeName), nil
+		}
+		if !current.Equal(base) {
+			return fmt.Sprintf("the partitioning of node %s changed since the plan was computed", nodeName), nil
+		}
+	}
+
+	for _, p := range resource.Spec.Pods {
+		var instance v1.Pod
+		err := c.Get(ctx, client.ObjectKey{Namespace: p.Namespace, Name: p.Name}, &instance)
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("pod %s/%s not found", p.Namespace, p.Name), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if pod.IsScheduled(instance) || !pod.IsPending(instance) {
+			return fmt.Sprintf("pod %s/%s is no longer pending", p.Namespace, p.Name), nil
+		}
+	}
+
+	for _, victim := range resource.Spec.Victims {
+		var instance v1.Pod
+		err := c.Get(ctx, client.ObjectKey{Namespace: victim.Namespace, Name: victim.Name}, &instance)
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("victim pod %s/%s not found", victim.Namespace, victim.Name), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if instance.Spec.NodeName != victim.NodeName || instance.DeletionTimestamp != nil {
+			return fmt.Sprintf("victim pod %s/%s is no longer running on node %s", victim.Namespace, victim.Name, victim.NodeName), nil
+		}
+	}
+
+	return "", nil
+}
+
+// SetPartitioningPlanStaleStatus marks the plan as stale for the reason provided as argument
+func SetPartitioningPlanStaleStatus(plan *v1alpha1.PartitioningPlan, reason string) {
+	plan.Status.Phase = v1alpha1.PartitioningPlanPhaseStale
+	plan.Status.Message = fmt.Sprintf("the plan is stale: %s", reason)
+}
+
+func toNodePartitioningSpecs(partitioningState state.PartitioningState) []v1alpha1.NodePartitioningSpec {
+	if len(partitioningState) == 0 {
+		return nil
+	}
+	nodes := make([]v1alpha1.NodePartitioningSpec, 0, len(partitioningState))
+	for nodeName, nodePartitioning := range partitioningState {
+		gpus := make([]v1alpha1.GPUPartitioningSpec, 0, len(nodePartitioning.GPUs))
+		for _, g := range nodePartitioning.GPUs {
+			resources := make(map[v1.ResourceName]int, len(g.Resources))
+			for r, q := range g.Resources {
+				resources[r] = q
+			}
+			gpus = append(gpus, v1alpha1.GPUPartitioningSpec{Index: g.GPUIndex, Resources: resources})
+		}
+		sort.Slice(gpus, func(i, j int) bool {
+			return gpus[i].Index < gpus[j].Index
+		})
+		nodes = append(nodes, v1alpha1.NodePartitioningSpec{Name: nodeName, GPUs: gpus})
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes
+}
+
+func fromNodePartitioningSpecs(nodes []v1alpha1.NodePartitioningSpec) state.PartitioningState {
+	res := make(state.PartitioningState, len(nodes))
+	for _, n := range nodes {
+		gpus := make([]state.GPUPartitioning, 0, len(n.GPUs))
+		for _, g := range n.GPUs {
+			resources := make(map[v1.ResourceName]int, len(g.Resources))
+			for r, q := range g.Resources {
+				resources[r] = q
+			}
+			gpus = append(gpus, state.GPUPartitioning{GPUIndex: g.Index, Resources: resources})
+		}
+		res[n.Name] = state.NodePartitioning{GPUs: gpus}
+	}
+	return res
+}
+
+func toPartitioningPlanPods(placements []PodPlacement) []v1alpha1.PartitioningPlanPod {
+	if len(placements) == 0 {
+		return nil
+	}
+	res := make([]v1alpha1.PartitioningPlanPod, 0, len(placements))
+	for _, p := range placements {
+		res = append(res, v1alpha1.PartitioningPlanPod{
+			Namespace: p.Pod.Namespace,
+			Name:      p.Pod.Name,
+			NodeName:  p.NodeName,
+		})
+	}
+	return res
+}
+
+func fromPartitioningPlanPods(pods []v1alpha1.PartitioningPlanPod) []PodPlacement {
+	if len(pods) == 0 {
+		return nil
+	}
+	res := make([]PodPlacement, 0, len(pods))
+	for _, p := range pods {
+		res = append(res, PodPlacement{
+			Pod:      types.NamespacedName{Namespace: p.Namespace, Name: p.Name},
+			NodeName: p.NodeName,
+		})
+	}
+	return res
+}
+
+// IsDisruptive returns true if applying the desired state requires to delete
+// any of the GPU resources exposed by the nodes in the current state, which
+// may affect the pods that are using them.
+func IsDisruptive(current, desired state.PartitioningState) bool {
+	for nodeName, desiredNode := range desired {
+		currentNode, ok := current[nodeName]
+		if !ok {
+			continue
+		}
+		desiredGPUs := make(map[int]map[v1.ResourceName]int, len(desiredNode.GPUs))
+		for _, g := range desiredNode.GPUs {
+			desiredGPUs[g.GPUIndex] = g.Resources
+		}
+		for _, g := range currentNode.GPUs {
+			for r, q := range g.Resources {
+				if desiredGPUs[g.GPUIndex][r] < q {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
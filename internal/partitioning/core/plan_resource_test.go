@@ -0,0 +1,290 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/nebuly-ai/nos/pkg/test/mocks"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestIsDisruptive(t *testing.T) {
+	currentState := state.PartitioningState{
+		"node-1": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/mig-1g.10gb": 2,
+						"nvidia.com/mig-2g.20gb": 1,
+					},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		desired  state.PartitioningState
+		expected bool
+	}{
+		{
+			name:     "Empty desired state",
+			desired:  state.PartitioningState{},
+			expected: false,
+		},
+		{
+			name:     "Desired state equal to current state",
+			desired:  currentState,
+			expected: false,
+		},
+		{
+			name: "Desired state only adds resources",
+			desired: state.PartitioningState{
+				"node-1": {
+					GPUs: []state.GPUPartitioning{
+						{
+							GPUIndex: 0,
+							Resources: map[v1.ResourceName]int{
+								"nvidia.com/mig-1g.10gb": 3,
+								"nvidia.com/mig-2g.20gb": 1,
+							},
+						},
+					},
+				},
+				"node-2": {
+					GPUs: []state.GPUPartitioning{
+						{
+							GPUIndex: 0,
+							Resources: map[v1.ResourceName]int{
+								"nvidia.com/mig-1g.10gb": 1,
+							},
+						},
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "Desired state decreases the quantity of a resource",
+			desired: state.PartitioningState{
+				"node-1": {
+					GPUs: []state.GPUPartitioning{
+						{
+							GPUIndex: 0,
+							Resources: map[v1.ResourceName]int{
+								"nvidia.com/mig-1g.10gb": 4,
+								"nvidia.com/mig-2g.20gb": 0,
+							},
+						},
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "Desired state removes all the resources of a GPU",
+			desired: state.PartitioningState{
+				"node-1": {
+					GPUs: []state.GPUPartitioning{},
+				},
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, core.IsDisruptive(currentState, tt.desired))
+		})
+	}
+}
+
+func TestPartitioningPlanResource(t *testing.T) {
+	desiredState := state.PartitioningState{
+		"node-2": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 1,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/gpu-10gb": 2,
+					},
+				},
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/gpu-5gb": 4,
+					},
+				},
+			},
+		},
+		"node-1": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/mig-1g.10gb": 1,
+					},
+				},
+			},
+		},
+	}
+	plan := core.NewPartitioningPlanWithId("1234", desiredState)
+	plan.Placements = []core.PodPlacement{
+		{Pod: types.NamespacedName{Namespace: "ns-1", Name: "pod-1"}, NodeName: "node-2"},
+	}
+	plan.Victims = []core.PodPlacement{
+		{Pod: types.NamespacedName{Namespace: "ns-2", Name: "pod-2"}, NodeName: "node-1"},
+	}
+
+	baseState := state.PartitioningState{
+		"node-1": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/mig-2g.20gb": 1,
+					},
+				},
+			},
+		},
+		"node-3": {
+			GPUs: []state.GPUPartitioning{},
+		},
+	}
+
+	resource := core.NewPartitioningPlanResource(gpu.PartitioningKindMps, plan, baseState)
+	assert.Empty(t, resource.Name)
+	assert.Equal(t, "mps-1234-", resource.GenerateName)
+	assert.Equal(t, "1234", resource.Spec.PlanId)
+	assert.Equal(t, "mps", resource.Spec.PartitioningKind)
+	assert.Len(t, resource.Spec.Nodes, 2)
+	assert.Equal(t, "node-1", resource.Spec.Nodes[0].Name)
+	assert.Equal(t, "node-2", resource.Spec.Nodes[1].Name)
+	assert.Equal(t, 0, resource.Spec.Nodes[1].GPUs[0].Index)
+	assert.Equal(t, 1, resource.Spec.Nodes[1].GPUs[1].Index)
+	assert.Len(t, resource.Spec.Pods, 1)
+	assert.Equal(t, "pod-1", resource.Spec.Pods[0].Name)
+	assert.Equal(t, "ns-1", resource.Spec.Pods[0].Namespace)
+	assert.Equal(t, "node-2", resource.Spec.Pods[0].NodeName)
+	assert.Len(t, resource.Spec.Victims, 1)
+	assert.Equal(t, "pod-2", resource.Spec.Victims[0].Name)
+	assert.Nil(t, resource.Spec.Approved)
+	assert.Len(t, resource.Spec.BaseNodes, 1)
+	assert.Equal(t, "node-1", resource.Spec.BaseNodes[0].Name)
+	assert.Equal(t, 1, resource.Spec.BaseNodes[0].GPUs[0].Resources["nvidia.com/mig-2g.20gb"])
+
+	converted := core.NewPartitioningPlanFromResource(resource)
+	assert.Equal(t, plan.GetId(), converted.GetId())
+	assert.True(t, desiredState.Equal(converted.DesiredState))
+	assert.Equal(t, plan.Placements, converted.Placements)
+	assert.Equal(t, plan.Victims, converted.Victims)
+}
+
+func TestGetPartitioningPlanStaleReason(t *testing.T) {
+	basePartitioning := state.NodePartitioning{
+		GPUs: []state.GPUPartitioning{
+			{
+				GPUIndex: 0,
+				Resources: map[v1.ResourceName]int{
+					"nvidia.com/mig-1g.10gb": 7,
+				},
+			},
+		},
+	}
+	resource := core.NewPartitioningPlanResource(
+		gpu.PartitioningKindMig,
+		core.PartitioningPlan{
+			DesiredState: state.PartitioningState{"node-1": {GPUs: []state.GPUPartitioning{}}},
+			Placements: []core.PodPlacement{
+				{Pod: types.NamespacedName{Namespace: "ns-1", Name: "pending"}, NodeName: "node-1"},
+			},
+			Victims: []core.PodPlacement{
+				{Pod: types.NamespacedName{Namespace: "ns-1", Name: "victim"}, NodeName: "node-1"},
+			},
+		},
+		state.PartitioningState{"node-1": basePartitioning},
+	)
+	pendingPod := factory.BuildPod("ns-1", "pending").WithPhase(v1.PodPending).Get()
+	victimPod := factory.BuildPod("ns-1", "victim").WithPhase(v1.PodRunning).WithNodeName("node-1").Get()
+
+	testCases := []struct {
+		name          string
+		currentState  state.PartitioningState
+		pods          []v1.Pod
+		expectedStale bool
+	}{
+		{
+			name:          "Plan still valid",
+			currentState:  state.PartitioningState{"node-1": basePartitioning},
+			pods:          []v1.Pod{pendingPod, victimPod},
+			expectedStale: false,
+		},
+		{
+			name:          "Node partitioning changed",
+			currentState:  state.PartitioningState{"node-1": {GPUs: []state.GPUPartitioning{}}},
+			pods:          []v1.Pod{pendingPod, victimPod},
+			expectedStale: true,
+		},
+		{
+			name:          "Node not found",
+			currentState:  state.PartitioningState{},
+			pods:          []v1.Pod{pendingPod, victimPod},
+			expectedStale: true,
+		},
+		{
+			name:         "Pod of the plan has been scheduled",
+			currentState: state.PartitioningState{"node-1": basePartitioning},
+			pods: []v1.Pod{
+				factory.BuildPod("ns-1", "pending").WithPhase(v1.PodRunning).WithNodeName("node-2").Get(),
+				victimPod,
+			},
+			expectedStale: true,
+		},
+		{
+			name:          "Victim not found",
+			currentState:  state.PartitioningState{"node-1": basePartitioning},
+			pods:          []v1.Pod{pendingPod},
+			expectedStale: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := make([]client.Object, 0, len(tt.pods))
+			for i := range tt.pods {
+				objects = append(objects, &tt.pods[i])
+			}
+			fakeClient := fake.NewClientBuilder().WithScheme(newPlanTestScheme(t)).WithObjects(objects...).Build()
+			mockSnapshot := mocks.NewSnapshot(t)
+			mockSnapshot.On("GetPartitioningState").Return(tt.currentState).Maybe()
+
+			reason, err := core.GetPartitioningPlanStaleReason(context.Background(), fakeClient, mockSnapshot, resource)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStale, reason != "", reason)
+		})
+	}
+}
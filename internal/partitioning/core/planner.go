@@ -22,6 +22,7 @@ import (
 	"github.com/nebuly-ai/nos/internal/partitioning/state"
 	"github.com/nebuly-ai/nos/pkg/gpu"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"strconv"
@@ -30,7 +31,19 @@ import (
 
 type PartitioningPlan struct {
 	DesiredState state.PartitioningState
-	id           string
+	// Placements are the candidate pods that the planner was able to schedule
+	// by simulating the application of the desired state
+	Placements []PodPlacement
+	// Victims are the running pods that must be evicted before applying the desired state,
+	// along with the node on which they are running
+	Victims []PodPlacement
+	id      string
+}
+
+// PodPlacement is a pod along with the node on which its scheduling was simulated
+type PodPlacement struct {
+	Pod      types.NamespacedName
+	NodeName string
 }
 
 func NewPartitioningPlanId() string {
@@ -44,6 +57,15 @@ func NewPartitioningPlan(s state.PartitioningState) PartitioningPlan {
 	}
 }
 
+// NewPartitioningPlanWithId returns a plan with the provided ID, which is used for
+// re-building plans previously computed by the planner
+func NewPartitioningPlanWithId(id string, s state.PartitioningState) PartitioningPlan {
+	return PartitioningPlan{
+		DesiredState: s,
+		id:           id,
+	}
+}
+
 func (p PartitioningPlan) GetId() string {
 	return p.id
 }
@@ -55,19 +77,38 @@ type planner struct {
 	sorter             Sorter
 }
 
-func NewPlanner(partitioner PartitionCalculator, sliceCalculator gpu.SliceCalculator, schedulerFramework framework.Framework) Planner {
+func NewPlanner(
+	partitioner PartitionCalculator,
+	sliceCalculator gpu.SliceCalculator,
+	schedulerFramework framework.Framework,
+	sorter Sorter,
+) Planner {
 	return planner{
 		partitioner:        partitioner,
 		sliceCalculator:    sliceCalculator,
 		schedulerFramework: schedulerFramework,
-		sorter:             NewPodSorter(sliceCalculator),
+		sorter:             sorter,
 	}
 }
 
 func (p planner) Plan(ctx context.Context, snapshot Snapshot, candidatePods []v1.Pod) (PartitioningPlan, error) {
+	partitioningState, placements, err := p.plan(ctx, snapshot, candidatePods)
+	if err != nil {
+		return PartitioningPlan{}, err
+	}
+	plan := NewPartitioningPlan(partitioningState)
+	plan.Placements = placements
+	return plan, nil
+}
+
+// plan greedily computes the desired partitioning state, and returns it together with the
+// placements of the candidate pods that the state allows to schedule. If the snapshot does not
+// lack any slice, plan returns the current partitioning state without any placement.
+func (p planner) plan(ctx context.Context, snapshot Snapshot, candidatePods []v1.Pod) (state.PartitioningState, []PodPlacement, error) {
 	logger := log.FromContext(ctx)
 	logger.V(3).Info("planning desired GPU partitioning", "candidatePods", len(candidatePods))
 	var err error
+	var placements = make([]PodPlacement, 0)
 
 	partitioningState := snapshot.GetPartitioningState()
 	tracker := NewSliceTracker(
@@ -79,11 +120,46 @@ func (p planner) Plan(ctx context.Context, snapshot Snapshot, candidatePods []v1
 	// No lacking slices, nothing to do
 	if len(tracker.GetLackingSlices()) == 0 {
 		logger.V(1).Info("no lacking profiles, nothing to do")
-		return NewPartitioningPlan(partitioningState), nil
+		return partitioningState, placements, nil
 	}
 
-	// Sort candidate pods
+	// Sort candidate pods and split them into planning units, so that
+	// the pods of each group are placed all together or not at all
 	sortedCandidatePods := p.sorter.Sort(candidatePods)
+	units := getPlanningUnits(sortedCandidatePods, getPodGroupRequirements(snapshot, candidatePods))
+
+	for _, unit := range units {
+		var unitPlacements []PodPlacement
+		if unit.required > 0 {
+			unitPlacements, err = p.planPodGroup(ctx, snapshot, unit)
+		} else {
+			unitPlacements, err = p.planPods(ctx, snapshot, unit.pods)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, placement := range unitPlacements {
+			node, _ := snapshot.GetNode(placement.NodeName)
+			partitioningState[placement.NodeName] = p.partitioner.GetPartitioning(node)
+		}
+		placements = append(placements, unitPlacements...)
+	}
+
+	return partitioningState, placements, nil
+}
+
+// planPods greedily adds the provided sorted pods to the candidate nodes of the snapshot,
+// updating the geometry of one node at a time for providing the slices lacked by the pods
+func (p planner) planPods(ctx context.Context, snapshot Snapshot, sortedPods []v1.Pod) ([]PodPlacement, error) {
+	logger := log.FromContext(ctx)
+	var placements = make([]PodPlacement, 0)
+	var placedPods = make(map[types.NamespacedName]struct{})
+
+	tracker := NewSliceTracker(
+		snapshot,
+		p.sliceCalculator,
+		sortedPods,
+	)
 
 	// Get candidate nodes
 	candidateNodes := snapshot.GetCandidateNodes()
@@ -93,18 +169,18 @@ func (p planner) Plan(ctx context.Context, snapshot Snapshot, candidatePods []v1
 		// If there are no more lacking slices we can stop
 		lackingSlices := tracker.GetLackingSlices()
 		if len(lackingSlices) == 0 {
-			return NewPartitioningPlan(partitioningState), nil
+			return placements, nil
 		}
 
 		// Fork the state
-		if err = snapshot.Fork(); err != nil {
-			return PartitioningPlan{}, fmt.Errorf("error forking snapshot, this should never happen: %v", err)
+		if err := snapshot.Fork(); err != nil {
+			return nil, fmt.Errorf("error forking snapshot, this should never happen: %v", err)
 		}
 
 		// Try to update geometry
 		nodeGeometryUpdated, err := n.UpdateGeometryFor(tracker.GetLackingSlices())
 		if err != nil {
-			return PartitioningPlan{}, err
+			return nil, err
 		}
 		if nodeGeometryUpdated {
 			logger.V(1).Info("updated node geometry", "node", n.GetName(), "geometry", n.Geometry())
@@ -112,8 +188,12 @@ func (p planner) Plan(ctx context.Context, snapshot Snapshot, candidatePods []v1
 		}
 
 		// Try to add candidate pods to the node with the updated geometry
-		var addedPods int
-		for _, pod := range sortedCandidatePods {
+		var addedPods = make([]PodPlacement, 0)
+		for _, pod := range sortedPods {
+			podName := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+			if _, placed := placedPods[podName]; placed {
+				continue
+			}
 			if added := p.tryAddPod(ctx, pod, n.GetName(), snapshot); !added {
 				logger.V(1).Info(
 					"pod does not fit node",
@@ -135,21 +215,96 @@ func (p planner) Plan(ctx context.Context, snapshot Snapshot, candidatePods []v1
 				"node",
 				n.GetName,
 			)
-			partitioningState[n.GetName()] = p.partitioner.GetPartitioning(n)
 			tracker.Remove(pod)
-			addedPods++
+			addedPods = append(addedPods, PodPlacement{Pod: podName, NodeName: n.GetName()})
 		}
 
 		// If the new geometry allowed to add any pod then commit changes, otherwise revert
-		if addedPods == 0 {
+		if len(addedPods) == 0 {
 			snapshot.Revert()
 		}
-		if addedPods > 0 {
+		if len(addedPods) > 0 {
 			snapshot.Commit()
+			for _, placement := range addedPods {
+				placedPods[placement.Pod] = struct{}{}
+			}
+			placements = append(placements, addedPods...)
 		}
 	}
 
-	return NewPartitioningPlan(partitioningState), nil
+	return placements, nil
+}
+
+// planPodGroup tries to add the pods of a group to the candidate nodes of the snapshot. The changes
+// to the snapshot are committed only if at least the number of pods required by the group
+// could be added, otherwise they are reverted and no placement is returned.
+func (p planner) planPodGroup(ctx context.Context, snapshot Snapshot, unit planningUnit) ([]PodPlacement, error) {
+	logger := log.FromContext(ctx)
+	if len(unit.pods) < unit.required {
+		logger.V(1).Info(
+			"not enough pending pods for starting pod group",
+			"pods",
+			len(unit.pods),
+			"required",
+			unit.required,
+		)
+		return nil, nil
+	}
+
+	if err := snapshot.Fork(); err != nil {
+		return nil, fmt.Errorf("error forking snapshot, this should never happen: %v", err)
+	}
+	placements := make([]PodPlacement, 0, len(unit.pods))
+	for _, pod := range unit.pods {
+		for _, n := range snapshot.GetCandidateNodes() {
+			if p.tryPlacePod(ctx, pod, n.GetName(), snapshot) {
+				placements = append(placements, PodPlacement{
+					Pod:      types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name},
+					NodeName: n.GetName(),
+				})
+				break
+			}
+		}
+	}
+
+	if len(placements) < unit.required {
+		logger.V(1).Info(
+			"pod group does not fit, reverting changes",
+			"placedPods",
+			len(placements),
+			"required",
+			unit.required,
+		)
+		snapshot.Revert()
+		return nil, nil
+	}
+	logger.V(1).Info("pod group fits", "placedPods", len(placements), "required", unit.required)
+	snapshot.Commit()
+	return placements, nil
+}
+
+// tryPlacePod tries to add the pod to the node, updating the geometry of the node for providing
+// the slices requested by the pod if its current geometry does not provide them.
+// It returns true if the pod was added, false otherwise, in which case the node is left unchanged.
+func (p planner) tryPlacePod(ctx context.Context, pod v1.Pod, nodeName string, snapshot Snapshot) bool {
+	if p.tryAddPod(ctx, pod, nodeName, snapshot) {
+		return true
+	}
+	node, ok := snapshot.GetNode(nodeName)
+	if !ok {
+		return false
+	}
+	updatedNode := node.Clone().(PartitionableNode)
+	updated, err := updatedNode.UpdateGeometryFor(p.sliceCalculator.GetRequestedSlices(pod))
+	if err != nil || !updated {
+		return false
+	}
+	snapshot.SetNode(updatedNode)
+	if p.tryAddPod(ctx, pod, nodeName, snapshot) {
+		return true
+	}
+	snapshot.SetNode(node)
+	return false
 }
 
 func (p planner) tryAddPod(ctx context.Context, pod v1.Pod, nodeName string, snapshot Snapshot) bool {
@@ -176,12 +331,18 @@ func (p planner) tryAddPod(ctx context.Context, pod v1.Pod, nodeName string, sna
 
 // canSchedulePod runs a scheduler cycle to check whether the Pod can be scheduled on the specified Node
 func (p planner) canSchedulePod(ctx context.Context, pod v1.Pod, node framework.NodeInfo) bool {
+	return CanSchedulePod(ctx, p.schedulerFramework, pod, node)
+}
+
+// CanSchedulePod simulates a scheduling cycle of the pod provided as argument by running the PreFilter and
+// Filter plugins of the scheduler framework, and returns true if the pod can be scheduled on the node.
+func CanSchedulePod(ctx context.Context, schedulerFramework framework.Framework, pod v1.Pod, node framework.NodeInfo) bool {
 	logger := log.FromContext(ctx)
 	logger.V(1).Info("simulating pod scheduling", "pod", pod.Name, "namespace", pod.Namespace)
 	cycleState := framework.NewCycleState()
 
 	// Run PreFilter plugins
-	_, preFilterStatus := p.schedulerFramework.RunPreFilterPlugins(ctx, cycleState, &pod)
+	_, preFilterStatus := schedulerFramework.RunPreFilterPlugins(ctx, cycleState, &pod)
 	logger.V(1).Info(
 		"scheduler PreFilter status",
 		"statusCode",
@@ -194,7 +355,7 @@ func (p planner) canSchedulePod(ctx context.Context, pod v1.Pod, node framework.
 	}
 
 	// Run Filter plugins
-	filterStatus := p.schedulerFramework.RunFilterPlugins(ctx, cycleState, &pod, &node).Merge()
+	filterStatus := schedulerFramework.RunFilterPlugins(ctx, cycleState, &pod, &node).Merge()
 	logger.V(1).Info(
 		"scheduler Filter status",
 		"statusCode",
@@ -23,6 +23,7 @@ import (
 	partitioning_mig "github.com/nebuly-ai/nos/internal/partitioning/mig"
 	partitioning_ts "github.com/nebuly-ai/nos/internal/partitioning/mps"
 	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	configv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/constant"
 	"github.com/nebuly-ai/nos/pkg/gpu"
@@ -480,7 +481,7 @@ func TestPlanner__Plan__MIG(t *testing.T) {
 			).Return(framework.PluginToStatus{"": tt.schedulerFilterStatus}).Maybe()
 
 			snapshot := newSnapshotFromNodes(tt.snapshotNodes, partitioning_mig.NewSnapshotTaker())
-			planner := partitioning_mig.NewPlanner(mockedScheduler)
+			planner := partitioning_mig.NewPlanner(mockedScheduler, configv1alpha1.PodSorterKindPriority, nil)
 			plan, err := planner.Plan(context.Background(), snapshot, tt.candidatePods)
 
 			// Compute overall partitioning ignoring GPU index
@@ -834,7 +835,7 @@ func TestPlanner__Plan__MPS(t *testing.T) {
 			).Return(framework.PluginToStatus{"": tt.schedulerFilterStatus}).Maybe()
 
 			snapshot := newSnapshotFromNodes(tt.snapshotNodes, partitioning_ts.NewSnapshotTaker())
-			planner := partitioning_ts.NewPlanner(mockedScheduler)
+			planner := partitioning_ts.NewPlanner(mockedScheduler, configv1alpha1.PodSorterKindPriority, nil)
 			plan, err := planner.Plan(context.Background(), snapshot, tt.candidatePods)
 
 			overallGpuPartitioning := make([]state.GPUPartitioning, 0)
@@ -0,0 +1,245 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/util/pod"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sort"
+)
+
+// preemptionPlanner is a Planner that extends the plans computed by another Planner by
+// considering the eviction of over-quota pods for making room to the candidate pods
+// that the other Planner is not able to schedule.
+//
+// A running pod is a possible victim of a candidate pod only if it is over-quota, it has lower priority
+// than the candidate pod, and it uses GPU slices. Preemption is considered only on the nodes whose
+// partitioning is not already changed by the plan computed by the other Planner.
+type preemptionPlanner struct {
+	planner
+	delegate Planner
+}
+
+func NewPreemptionPlanner(
+	delegate Planner,
+	partitioner PartitionCalculator,
+	sliceCalculator gpu.SliceCalculator,
+	schedulerFramework framework.Framework,
+	sorter Sorter,
+) Planner {
+	return preemptionPlanner{
+		planner: planner{
+			partitioner:        partitioner,
+			sliceCalculator:    sliceCalculator,
+			schedulerFramework: schedulerFramework,
+			sorter:             sorter,
+		},
+		delegate: delegate,
+	}
+}
+
+func (p preemptionPlanner) Plan(ctx context.Context, snapshot Snapshot, candidatePods []v1.Pod) (PartitioningPlan, error) {
+	logger := log.FromContext(ctx)
+
+	plan, err := p.delegate.Plan(ctx, snapshot.Clone(), candidatePods)
+	if err != nil {
+		return PartitioningPlan{}, err
+	}
+
+	// Nodes changed by the plan, or on which the plan schedules any pod, are excluded from preemption
+	currentState := snapshot.GetPartitioningState()
+	excludedNodes := make(map[string]struct{})
+	for nodeName, nodePartitioning := range plan.DesiredState {
+		if !nodePartitioning.Equal(currentState[nodeName]) {
+			excludedNodes[nodeName] = struct{}{}
+		}
+	}
+	placedPods := make(map[types.NamespacedName]struct{})
+	for _, placement := range plan.Placements {
+		placedPods[placement.Pod] = struct{}{}
+		excludedNodes[placement.NodeName] = struct{}{}
+	}
+	nodeNames := make([]string, 0)
+	for nodeName := range snapshot.GetNodes() {
+		if _, excluded := excludedNodes[nodeName]; !excluded {
+			nodeNames = append(nodeNames, nodeName)
+		}
+	}
+	sort.Strings(nodeNames)
+
+	// Pods lacking GPU slices that the plan cannot schedule. Pods of groups that need other pods
+	// for starting are excluded, since evicting pods for a part of a group would be pointless.
+	groupRequired := getPodGroupRequirements(snapshot, candidatePods)
+	unplacedPods := make([]v1.Pod, 0)
+	for _, candidate := range candidatePods {
+		if _, placed := placedPods[types.NamespacedName{Namespace: candidate.Namespace, Name: candidate.Name}]; placed {
+			continue
+		}
+		if key, ok := podGroupKey(candidate); ok && groupRequired[key] > 1 {
+			continue
+		}
+		// Pods that do not lack any slice could be scheduled without changing the partitioning
+		if len(snapshot.GetLackingSlices(candidate)) == 0 {
+			continue
+		}
+		unplacedPods = append(unplacedPods, candidate)
+	}
+	if len(unplacedPods) == 0 || len(nodeNames) == 0 {
+		return plan, nil
+	}
+	logger.V(1).Info("considering preemption for unplaced pods", "pods", len(unplacedPods), "nodes", len(nodeNames))
+
+	if plan.DesiredState == nil {
+		plan.DesiredState = make(state.PartitioningState)
+	}
+	for _, candidate := range p.sorter.Sort(unplacedPods) {
+		for _, nodeName := range nodeNames {
+			victims, ok := p.tryPreempt(ctx, snapshot, candidate, nodeName)
+			if !ok {
+				continue
+			}
+			logger.Info(
+				"pod fits node by evicting over-quota pods",
+				"namespace",
+				candidate.Namespace,
+				"pod",
+				candidate.Name,
+				"node",
+				nodeName,
+				"victims",
+				len(victims),
+			)
+			node, _ := snapshot.GetNode(nodeName)
+			plan.DesiredState[nodeName] = p.partitioner.GetPartitioning(node)
+			plan.Placements = append(plan.Placements, PodPlacement{
+				Pod:      types.NamespacedName{Namespace: candidate.Namespace, Name: candidate.Name},
+				NodeName: nodeName,
+			})
+			for _, victim := range victims {
+				plan.Victims = append(plan.Victims, PodPlacement{
+					Pod:      types.NamespacedName{Namespace: victim.Namespace, Name: victim.Name},
+					NodeName: nodeName,
+				})
+			}
+			break
+		}
+	}
+
+	return plan, nil
+}
+
+// tryPreempt tries to schedule the pod on the node by evicting its possible victims one at a time,
+// until either the pod fits the node or there are no more victims. If the pod fits, the victims are
+// added back to the node one at a time, starting from the ones with the highest priority, and the
+// victims that still fit the node together with the pod are spared. The changes are then committed
+// to the snapshot and the method returns the victims that must actually be evicted.
+func (p preemptionPlanner) tryPreempt(ctx context.Context, snapshot Snapshot, candidate v1.Pod, nodeName string) ([]v1.Pod, bool) {
+	if err := snapshot.Fork(); err != nil {
+		return nil, false
+	}
+	node, ok := snapshot.GetNode(nodeName)
+	if !ok {
+		snapshot.Revert()
+		return nil, false
+	}
+	preemptibleNode, ok := node.(PreemptibleNode)
+	if !ok {
+		snapshot.Revert()
+		return nil, false
+	}
+
+	requestedSlices := p.sliceCalculator.GetRequestedSlices(candidate)
+	victims := make([]v1.Pod, 0)
+	for _, victim := range p.getPossibleVictims(candidate, preemptibleNode.NodeInfo()) {
+		if err := preemptibleNode.RemovePod(victim); err != nil {
+			continue
+		}
+		victims = append(victims, victim)
+		if _, err := preemptibleNode.UpdateGeometryFor(requestedSlices); err != nil {
+			break
+		}
+		snapshot.SetNode(preemptibleNode)
+		if p.tryAddPod(ctx, candidate, nodeName, snapshot) {
+			victims = p.reprieveVictims(ctx, snapshot, nodeName, victims)
+			snapshot.Commit()
+			return victims, true
+		}
+	}
+
+	snapshot.Revert()
+	return nil, false
+}
+
+// reprieveVictims adds back to the node the victims that fit it without evicting any other pod,
+// considering first the victims with the highest priority, and returns the victims that must be evicted
+func (p preemptionPlanner) reprieveVictims(ctx context.Context, snapshot Snapshot, nodeName string, victims []v1.Pod) []v1.Pod {
+	evicted := make([]v1.Pod, 0, len(victims))
+	for i := len(victims) - 1; i >= 0; i-- {
+		victim := victims[i]
+		node, ok := snapshot.GetNode(nodeName)
+		if ok && p.canSchedulePod(ctx, victim, node.NodeInfo()) && snapshot.AddPod(nodeName, victim) == nil {
+			continue
+		}
+		evicted = append(evicted, victim)
+	}
+	// Keep the victims sorted by increasing priority, as they were selected
+	for i, j := 0, len(evicted)-1; i < j; i, j = i+1, j-1 {
+		evicted[i], evicted[j] = evicted[j], evicted[i]
+	}
+	return evicted
+}
+
+// getPossibleVictims returns the pods running on the node that could be evicted for making room
+// to the candidate pod, sorted by increasing priority and, for equal priority, by decreasing creation time
+func (p preemptionPlanner) getPossibleVictims(candidate v1.Pod, nodeInfo framework.NodeInfo) []v1.Pod {
+	candidatePriority := corev1.PodPriority(&candidate)
+	victims := make([]v1.Pod, 0)
+	for _, podInfo := range nodeInfo.Pods {
+		if podInfo == nil || podInfo.Pod == nil {
+			continue
+		}
+		running := *podInfo.Pod
+		if running.DeletionTimestamp != nil {
+			continue
+		}
+		if !pod.IsOverQuota(running) {
+			continue
+		}
+		if corev1.PodPriority(&running) >= candidatePriority {
+			continue
+		}
+		if len(p.sliceCalculator.GetRequestedSlices(running)) == 0 {
+			continue
+		}
+		victims = append(victims, running)
+	}
+	sort.SliceStable(victims, func(i, j int) bool {
+		iPriority, jPriority := corev1.PodPriority(&victims[i]), corev1.PodPriority(&victims[j])
+		if iPriority != jPriority {
+			return iPriority < jPriority
+		}
+		return victims[j].CreationTimestamp.Before(&victims[i].CreationTimestamp)
+	})
+	return victims
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	partitioning_mig "github.com/nebuly-ai/nos/internal/partitioning/mig"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	configv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	nosresource "github.com/nebuly-ai/nos/pkg/resource"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	scheduler_mock "github.com/nebuly-ai/nos/pkg/test/mocks/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"testing"
+)
+
+func TestPreemptionPlanner__Plan(t *testing.T) {
+	newNode := func(annotations map[string]string, allocatable v1.ResourceList) v1.Node {
+		return factory.BuildNode("node-1").
+			WithLabels(map[string]string{
+				v1alpha1.LabelGpuPartitioning: gpu.PartitioningKindMig.String(),
+				constant.LabelNvidiaProduct:   gpu.GPUModel_A30.String(),
+				constant.LabelNvidiaCount:     "1",
+			}).
+			WithAnnotations(annotations).
+			WithAllocatableResources(allocatable).
+			Get()
+	}
+	newPod := func(name string, profile mig.ProfileName, priority int32, overQuota bool) v1.Pod {
+		builder := factory.BuildPod("ns-1", name).
+			WithUID(name).
+			WithPriority(priority).
+			WithContainer(
+				factory.BuildContainer("c-1", "foo").
+					WithScalarResourceRequest(profile.AsResourceName(), 1).
+					Get(),
+			)
+		if overQuota {
+			builder = builder.WithLabel(v1alpha1.LabelCapacityInfo, string(constant.CapacityInfoOverQuota))
+		}
+		return builder.Get()
+	}
+
+	fullNode := newNode(
+		map[string]string{
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, mig.Profile4g24gb, nosresource.StatusUsed): "1",
+		},
+		v1.ResourceList{
+			mig.Profile4g24gb.AsResourceName(): *resource.NewQuantity(1, resource.DecimalSI),
+		},
+	)
+	nodeWithFreeDevices := newNode(
+		map[string]string{
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, mig.Profile2g12gb, nosresource.StatusUsed): "1",
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, mig.Profile1g6gb, nosresource.StatusFree):  "2",
+		},
+		v1.ResourceList{
+			mig.Profile2g12gb.AsResourceName(): *resource.NewQuantity(1, resource.DecimalSI),
+			mig.Profile1g6gb.AsResourceName():  *resource.NewQuantity(2, resource.DecimalSI),
+		},
+	)
+	candidate := newPod("candidate", mig.Profile1g6gb, 10, false)
+
+	testCases := []struct {
+		name               string
+		node               v1.Node
+		runningPod         v1.Pod
+		expectedPlacements []core.PodPlacement
+		expectedVictims    []core.PodPlacement
+	}{
+		{
+			name:       "Over-quota pod with lower priority, should be evicted",
+			node:       fullNode,
+			runningPod: newPod("running", mig.Profile4g24gb, 1, true),
+			expectedPlacements: []core.PodPlacement{
+				{Pod: types.NamespacedName{Namespace: "ns-1", Name: "candidate"}, NodeName: "node-1"},
+			},
+			expectedVictims: []core.PodPlacement{
+				{Pod: types.NamespacedName{Namespace: "ns-1", Name: "running"}, NodeName: "node-1"},
+			},
+		},
+		{
+			name:       "In-quota pod, should not be evicted",
+			node:       fullNode,
+			runningPod: newPod("running", mig.Profile4g24gb, 1, false),
+		},
+		{
+			name:       "Over-quota pod with same priority, should not be evicted",
+			node:       fullNode,
+			runningPod: newPod("running", mig.Profile4g24gb, 10, true),
+		},
+		{
+			name:       "Candidate does not lack any slice, should not evict any pod",
+			node:       nodeWithFreeDevices,
+			runningPod: newPod("running", mig.Profile2g12gb, 1, true),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedScheduler := scheduler_mock.NewFramework(t)
+			mockedScheduler.On("RunPreFilterPlugins", mock.Anything, mock.Anything, mock.Anything).
+				Return(nil, framework.NewStatus(framework.Success)).
+				Maybe()
+			mockedScheduler.On("RunFilterPlugins", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(framework.PluginToStatus{"": framework.NewStatus(framework.Success)}).
+				Maybe()
+
+			node := tt.node
+			runningPod := tt.runningPod
+			ni := framework.NewNodeInfo()
+			ni.SetNode(&node)
+			ni.AddPod(&runningPod)
+			snapshot, err := partitioning_mig.NewSnapshotTaker().TakeSnapshot(
+				state.NewClusterState(map[string]framework.NodeInfo{node.Name: *ni}),
+			)
+			assert.NoError(t, err)
+
+			planner := partitioning_mig.NewPreemptionPlanner(
+				partitioning_mig.NewPlanner(mockedScheduler, configv1alpha1.PodSorterKindPriority, nil),
+				mockedScheduler,
+				configv1alpha1.PodSorterKindPriority,
+				nil,
+			)
+			plan, err := planner.Plan(context.Background(), snapshot, []v1.Pod{candidate})
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expectedPlacements, plan.Placements)
+			assert.ElementsMatch(t, tt.expectedVictims, plan.Victims)
+			if len(tt.expectedVictims) > 0 {
+				resources := plan.DesiredState["node-1"].GPUs[0].Resources
+				assert.Zero(t, resources[mig.Profile4g24gb.AsResourceName()])
+				assert.Positive(t, resources[mig.Profile1g6gb.AsResourceName()])
+			}
+		})
+	}
+}
+
+func TestPreemptionPlanner__Plan_SparesUnneededVictims(t *testing.T) {
+	node := factory.BuildNode("node-1").
+		WithLabels(map[string]string{
+			v1alpha1.LabelGpuPartitioning: gpu.PartitioningKindMig.String(),
+			constant.LabelNvidiaProduct:   gpu.GPUModel_A30.String(),
+			constant.LabelNvidiaCount:     "1",
+		}).
+		WithAnnotations(map[string]string{
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, mig.Profile2g12gb, nosresource.StatusUsed): "1",
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, mig.Profile1g6gb, nosresource.StatusUsed):  "2",
+		}).
+		WithAllocatableResources(v1.ResourceList{
+			mig.Profile2g12gb.AsResourceName(): *resource.NewQuantity(1, resource.DecimalSI),
+			mig.Profile1g6gb.AsResourceName():  *resource.NewQuantity(2, resource.DecimalSI),
+		}).
+		Get()
+	newPod := func(name string, profile mig.ProfileName, priority int32, overQuota bool) v1.Pod {
+		builder := factory.BuildPod("ns-1", name).
+			WithUID(name).
+			WithPriority(priority).
+			WithNodeName("node-1").
+			WithContainer(
+				factory.BuildContainer("c-1", "foo").
+					WithScalarResourceRequest(profile.AsResourceName(), 1).
+					Get(),
+			)
+		if overQuota {
+			builder = builder.WithLabel(v1alpha1.LabelCapacityInfo, string(constant.CapacityInfoOverQuota))
+		}
+		return builder.Get()
+	}
+	// Evicting the lowest-priority pod alone does not free a 2g.12gb device, while evicting
+	// the second one alone is enough: the lowest-priority pod should be spared
+	runningPods := []v1.Pod{
+		newPod("low", mig.Profile1g6gb, 1, true),
+		newPod("high", mig.Profile2g12gb, 2, true),
+		newPod("in-quota", mig.Profile1g6gb, 1, false),
+	}
+	candidate := newPod("candidate", mig.Profile2g12gb, 10, false)
+	candidate.Spec.NodeName = ""
+
+	mockedScheduler := scheduler_mock.NewFramework(t)
+	mockedScheduler.On("RunPreFilterPlugins", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, framework.NewStatus(framework.Success)).
+		Maybe()
+	mockedScheduler.On("RunFilterPlugins", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(framework.PluginToStatus{"": framework.NewStatus(framework.Success)}).
+		Maybe()
+
+	ni := framework.NewNodeInfo()
+	ni.SetNode(&node)
+	for i := range runningPods {
+		ni.AddPod(&runningPods[i])
+	}
+	snapshot, err := partitioning_mig.NewSnapshotTaker().TakeSnapshot(
+		state.NewClusterState(map[string]framework.NodeInfo{node.Name: *ni}),
+	)
+	assert.NoError(t, err)
+
+	planner := partitioning_mig.NewPreemptionPlanner(
+		partitioning_mig.NewPlanner(mockedScheduler, configv1alpha1.PodSorterKindPriority, nil),
+		mockedScheduler,
+		configv1alpha1.PodSorterKindPriority,
+		nil,
+	)
+	plan, err := planner.Plan(context.Background(), snapshot, []v1.Pod{candidate})
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		[]core.PodPlacement{{Pod: types.NamespacedName{Namespace: "ns-1", Name: "candidate"}, NodeName: "node-1"}},
+		plan.Placements,
+	)
+	assert.Equal(
+		t,
+		[]core.PodPlacement{{Pod: types.NamespacedName{Namespace: "ns-1", Name: "high"}, NodeName: "node-1"}},
+		plan.Victims,
+	)
+}
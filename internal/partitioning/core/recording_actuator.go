@@ -0,0 +1,204 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
+	nosv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sort"
+)
+
+// recordingActuator is an Actuator that stores each partitioning plan as a PartitioningPlan
+// resource before applying it. Plans requiring approval according to the approval policy
+// are not applied: they are left pending until they get either approved or rejected, or until
+// they become stale, in which case they are superseded by the next plan.
+type recordingActuator struct {
+	client.Client
+	actuator       Actuator
+	kind           gpu.PartitioningKind
+	approvalPolicy v1alpha1.PlanApprovalPolicy
+	historyLimit   int
+}
+
+func NewRecordingActuator(
+	client client.Client,
+	kind gpu.PartitioningKind,
+	actuator Actuator,
+	approvalPolicy v1alpha1.PlanApprovalPolicy,
+	historyLimit int,
+) Actuator {
+	return recordingActuator{
+		Client:         client,
+		actuator:       actuator,
+		kind:           kind,
+		approvalPolicy: approvalPolicy,
+		historyLimit:   historyLimit,
+	}
+}
+
+func (a recordingActuator) Apply(ctx context.Context, snapshot Snapshot, plan PartitioningPlan) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	currentState := snapshot.GetPartitioningState()
+	if len(plan.Victims) == 0 && (currentState.Equal(plan.DesiredState) || plan.DesiredState.IsEmpty()) {
+		return a.actuator.Apply(ctx, snapshot, plan)
+	}
+
+	plans, err := a.listPlans(ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to list partitioning plans: %w", err)
+	}
+	for _, p := range plans {
+		if p.IsApprovalPending() {
+			// A stale plan would never be applied, so the new plan supersedes it
+			reason, err := GetPartitioningPlanStaleReason(ctx, a.Client, snapshot, p)
+			if err != nil {
+				return false, fmt.Errorf("unable to check partitioning plan %s: %w", p.Name, err)
+			}
+			if reason == "" {
+				logger.Info("a partitioning plan is pending approval, skipping new plan", "pendingPlan", p.Name)
+				return false, nil
+			}
+			logger.Info("partitioning plan pending approval is stale, superseding it", "stalePlan", p.Name, "reason", reason)
+			p := p
+			SetPartitioningPlanStaleStatus(&p, reason)
+			if err = a.Status().Update(ctx, &p); err != nil {
+				return false, fmt.Errorf("unable to update status of partitioning plan %s: %w", p.Name, err)
+			}
+			continue
+		}
+		if p.Status.Phase == nosv1alpha1.PartitioningPlanPhaseRejected {
+			if NewPartitioningPlanFromResource(p).DesiredState.Equal(plan.DesiredState) {
+				logger.Info("an identical partitioning plan has been rejected, skipping new plan", "rejectedPlan", p.Name)
+				return false, nil
+			}
+		}
+	}
+
+	resource := NewPartitioningPlanResource(a.kind, plan, currentState)
+	disruptive := IsDisruptive(currentState, plan.DesiredState) || len(plan.Victims) > 0
+	if err = a.Create(ctx, &resource); err != nil {
+		return false, fmt.Errorf("unable to create partitioning plan %s: %w", resource.Name, err)
+	}
+	resource.Status.Disruptive = disruptive
+
+	// Wait for approval
+	if a.requiresApproval(disruptive) {
+		logger.Info("partitioning plan requires approval", "plan", resource.Name, "disruptive", disruptive)
+		resource.Status.Phase = nosv1alpha1.PartitioningPlanPhasePendingApproval
+		resource.Status.Message = "waiting for the plan to be approved or rejected"
+		if err = a.Status().Update(ctx, &resource); err != nil {
+			return false, fmt.Errorf("unable to update status of partitioning plan %s: %w", resource.Name, err)
+		}
+		return false, nil
+	}
+
+	applied, applyErr := a.actuator.Apply(ctx, snapshot, plan)
+	SetPartitioningPlanApplyStatus(&resource, applyErr)
+	if err = a.Status().Update(ctx, &resource); err != nil {
+		logger.Error(err, "unable to update status of partitioning plan", "plan", resource.Name)
+	}
+	if err = a.pruneHistory(ctx); err != nil {
+		logger.Error(err, "unable to prune partitioning plans history")
+	}
+
+	return applied, applyErr
+}
+
+func (a recordingActuator) requiresApproval(disruptive bool) bool {
+	switch a.approvalPolicy {
+	case v1alpha1.PlanApprovalPolicyAll:
+		return true
+	case v1alpha1.PlanApprovalPolicyDisruptive:
+		return disruptive
+	default:
+		return false
+	}
+}
+
+// listPlans returns the plans with the partitioning kind of the actuator
+func (a recordingActuator) listPlans(ctx context.Context) ([]nosv1alpha1.PartitioningPlan, error) {
+	var planList nosv1alpha1.PartitioningPlanList
+	if err := a.List(ctx, &planList); err != nil {
+		return nil, err
+	}
+	var res = make([]nosv1alpha1.PartitioningPlan, 0, len(planList.Items))
+	for _, p := range planList.Items {
+		if p.Spec.PartitioningKind == a.kind.String() {
+			res = append(res, p)
+		}
+	}
+	return res, nil
+}
+
+// pruneHistory deletes the oldest terminated plans exceeding the history limit
+func (a recordingActuator) pruneHistory(ctx context.Context) error {
+	plans, err := a.listPlans(ctx)
+	if err != nil {
+		return err
+	}
+	var terminated = make([]nosv1alpha1.PartitioningPlan, 0, len(plans))
+	for _, p := range plans {
+		if p.IsTerminated() {
+			terminated = append(terminated, p)
+		}
+	}
+	if len(terminated) <= a.historyLimit {
+		return nil
+	}
+	sort.Slice(terminated, func(i, j int) bool {
+		if terminated[i].CreationTimestamp.Equal(&terminated[j].CreationTimestamp) {
+			return terminated[i].Spec.PlanId < terminated[j].Spec.PlanId
+		}
+		return terminated[i].CreationTimestamp.Before(&terminated[j].CreationTimestamp)
+	})
+	for _, p := range terminated[:len(terminated)-a.historyLimit] {
+		p := p
+		if err = a.Delete(ctx, &p); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetPartitioningPlanApplyStatus updates the status of the plan according to the result
+// of its application, which failed if applyErr is not nil
+func SetPartitioningPlanApplyStatus(plan *nosv1alpha1.PartitioningPlan, applyErr error) {
+	now := metav1.Now()
+	nodePhase := nosv1alpha1.NodePartitioningPhaseApplied
+	plan.Status.Phase = nosv1alpha1.PartitioningPlanPhaseApplied
+	plan.Status.Message = "waiting for the nodes to report the plan"
+	if applyErr != nil {
+		nodePhase = nosv1alpha1.NodePartitioningPhaseFailed
+		plan.Status.Phase = nosv1alpha1.PartitioningPlanPhaseFailed
+		plan.Status.Message = applyErr.Error()
+	}
+	plan.Status.Nodes = make([]nosv1alpha1.NodePartitioningStatus, 0, len(plan.Spec.Nodes))
+	for _, n := range plan.Spec.Nodes {
+		plan.Status.Nodes = append(plan.Status.Nodes, nosv1alpha1.NodePartitioningStatus{
+			Name:               n.Name,
+			Phase:              nodePhase,
+			LastTransitionTime: now,
+		})
+	}
+}
@@ -0,0 +1,275 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	configv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/nebuly-ai/nos/pkg/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+func newPlanTestScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+func TestRecordingActuator__Apply(t *testing.T) {
+	currentState := state.PartitioningState{
+		"node-1": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/gpu-10gb": 1,
+					},
+				},
+			},
+		},
+	}
+	additivePlan := core.NewPartitioningPlanWithId("2000", state.PartitioningState{
+		"node-1": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/gpu-10gb": 1,
+						"nvidia.com/gpu-5gb":  2,
+					},
+				},
+			},
+		},
+	})
+	disruptivePlan := core.NewPartitioningPlanWithId("2000", state.PartitioningState{
+		"node-1": {
+			GPUs: []state.GPUPartitioning{
+				{
+					GPUIndex: 0,
+					Resources: map[v1.ResourceName]int{
+						"nvidia.com/gpu-5gb": 4,
+					},
+				},
+			},
+		},
+	})
+	node := factory.BuildNode("node-1").Get()
+	newPlan := func(planId string, phase v1alpha1.PartitioningPlanPhase, creation time.Time) *v1alpha1.PartitioningPlan {
+		return &v1alpha1.PartitioningPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: "mps-" + planId, CreationTimestamp: metav1.NewTime(creation)},
+			Spec:       v1alpha1.PartitioningPlanSpec{PlanId: planId, PartitioningKind: gpu.PartitioningKindMps.String()},
+			Status:     v1alpha1.PartitioningPlanStatus{Phase: phase},
+		}
+	}
+
+	stalePlan := newPlan("1000", v1alpha1.PartitioningPlanPhasePendingApproval, time.Now())
+	stalePlan.Spec.BaseNodes = []v1alpha1.NodePartitioningSpec{
+		{
+			Name: "node-1",
+			GPUs: []v1alpha1.GPUPartitioningSpec{
+				{Index: 0, Resources: map[v1.ResourceName]int{"nvidia.com/gpu-20gb": 1}},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name                 string
+		plan                 core.PartitioningPlan
+		approvalPolicy       configv1alpha1.PlanApprovalPolicy
+		historyLimit         int
+		existingPlans        []client.Object
+		expectedApplied      bool
+		expectedPhase        v1alpha1.PartitioningPlanPhase
+		expectedDisruptive   bool
+		expectedPlanResource bool
+		expectedPlans        []string
+		expectedStalePlans   []string
+	}{
+		{
+			name:                 "Plan equal to current state, should not create any resource",
+			plan:                 core.NewPartitioningPlanWithId("2000", currentState),
+			approvalPolicy:       configv1alpha1.PlanApprovalPolicyAll,
+			historyLimit:         10,
+			expectedApplied:      false,
+			expectedPlanResource: false,
+			expectedPlans:        []string{},
+		},
+		{
+			name:                 "Approval not required, should apply plan and record it",
+			plan:                 disruptivePlan,
+			approvalPolicy:       configv1alpha1.PlanApprovalPolicyNone,
+			historyLimit:         10,
+			expectedApplied:      true,
+			expectedPhase:        v1alpha1.PartitioningPlanPhaseApplied,
+			expectedDisruptive:   true,
+			expectedPlanResource: true,
+			expectedPlans:        []string{"2000"},
+		},
+		{
+			name:                 "Disruptive policy, non-disruptive plan should be applied",
+			plan:                 additivePlan,
+			approvalPolicy:       configv1alpha1.PlanApprovalPolicyDisruptive,
+			historyLimit:         10,
+			expectedApplied:      true,
+			expectedPhase:        v1alpha1.PartitioningPlanPhaseApplied,
+			expectedDisruptive:   false,
+			expectedPlanResource: true,
+			expectedPlans:        []string{"2000"},
+		},
+		{
+			name:                 "Disruptive policy, disruptive plan should wait for approval",
+			plan:                 disruptivePlan,
+			approvalPolicy:       configv1alpha1.PlanApprovalPolicyDisruptive,
+			historyLimit:         10,
+			expectedApplied:      false,
+			expectedPhase:        v1alpha1.PartitioningPlanPhasePendingApproval,
+			expectedDisruptive:   true,
+			expectedPlanResource: true,
+			expectedPlans:        []string{"2000"},
+		},
+		{
+			name:           "Another plan is pending approval, should skip the plan",
+			plan:           additivePlan,
+			approvalPolicy: configv1alpha1.PlanApprovalPolicyAll,
+			historyLimit:   10,
+			existingPlans: []client.Object{
+				newPlan("1000", v1alpha1.PartitioningPlanPhasePendingApproval, time.Now()),
+			},
+			expectedApplied:      false,
+			expectedPlanResource: false,
+			expectedPlans:        []string{"1000"},
+		},
+		{
+			name:           "Another plan is pending approval but it is stale, should supersede it",
+			plan:           additivePlan,
+			approvalPolicy: configv1alpha1.PlanApprovalPolicyAll,
+			historyLimit:   10,
+			existingPlans: []client.Object{
+				stalePlan,
+			},
+			expectedApplied:      false,
+			expectedPhase:        v1alpha1.PartitioningPlanPhasePendingApproval,
+			expectedPlanResource: true,
+			expectedPlans:        []string{"1000", "2000"},
+			expectedStalePlans:   []string{"1000"},
+		},
+		{
+			name:           "History limit exceeded, should delete the oldest terminated plans",
+			plan:           additivePlan,
+			approvalPolicy: configv1alpha1.PlanApprovalPolicyNone,
+			historyLimit:   1,
+			existingPlans: []client.Object{
+				newPlan("1000", v1alpha1.PartitioningPlanPhaseCompleted, time.Now().Add(-2*time.Hour)),
+				newPlan("1001", v1alpha1.PartitioningPlanPhaseRejected, time.Now().Add(-1*time.Hour)),
+			},
+			expectedApplied:      true,
+			expectedPhase:        v1alpha1.PartitioningPlanPhaseApplied,
+			expectedPlanResource: true,
+			expectedPlans:        []string{"1001", "2000"},
+		},
+		{
+			name:           "Another plan with the same ID exists, should record the new plan as well",
+			plan:           additivePlan,
+			approvalPolicy: configv1alpha1.PlanApprovalPolicyNone,
+			historyLimit:   10,
+			existingPlans: []client.Object{
+				newPlan("2000", v1alpha1.PartitioningPlanPhaseCompleted, time.Now()),
+			},
+			expectedApplied:      true,
+			expectedPhase:        v1alpha1.PartitioningPlanPhaseApplied,
+			expectedPlanResource: true,
+			expectedPlans:        []string{"2000", "2000"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]client.Object{&node}, tt.existingPlans...)
+			mockClient := fake.NewClientBuilder().
+				WithScheme(newPlanTestScheme(t)).
+				WithObjects(objects...).
+				Build()
+			mockPartitioner := mocks.NewPartitioner(t)
+			mockPartitioner.On("ApplyPartitioning", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(nil).
+				Maybe()
+
+			mockSnapshot := mocks.NewSnapshot(t)
+			mockSnapshot.On("GetPartitioningState").Return(currentState).Maybe()
+
+			actuator := core.NewRecordingActuator(
+				mockClient,
+				gpu.PartitioningKindMps,
+				core.NewActuator(mockClient, mockPartitioner),
+				tt.approvalPolicy,
+				tt.historyLimit,
+			)
+			applied, err := actuator.Apply(context.Background(), mockSnapshot, tt.plan)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedApplied, applied)
+			if !tt.expectedApplied {
+				mockPartitioner.AssertNotCalled(t, "ApplyPartitioning", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+
+			var planList v1alpha1.PartitioningPlanList
+			assert.NoError(t, mockClient.List(context.Background(), &planList))
+			planIds := make([]string, 0)
+			stalePlanIds := make([]string, 0)
+			var resource *v1alpha1.PartitioningPlan
+			for i, p := range planList.Items {
+				planIds = append(planIds, p.Spec.PlanId)
+				if p.Status.Phase == v1alpha1.PartitioningPlanPhaseStale {
+					stalePlanIds = append(stalePlanIds, p.Spec.PlanId)
+				}
+				if p.GenerateName == core.GetPartitioningPlanResourceGenerateName(gpu.PartitioningKindMps, tt.plan.GetId()) {
+					resource = &planList.Items[i]
+				}
+			}
+			assert.ElementsMatch(t, tt.expectedPlans, planIds)
+			if tt.expectedStalePlans == nil {
+				tt.expectedStalePlans = []string{}
+			}
+			assert.ElementsMatch(t, tt.expectedStalePlans, stalePlanIds)
+
+			if !tt.expectedPlanResource {
+				assert.Nil(t, resource)
+				return
+			}
+			if !assert.NotNil(t, resource) {
+				return
+			}
+			assert.Equal(t, tt.expectedPhase, resource.Status.Phase)
+			assert.Equal(t, tt.expectedDisruptive, resource.Status.Disruptive)
+			assert.Equal(t, tt.plan.GetId(), resource.Spec.PlanId)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
+	nosv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	gpuutil "github.com/nebuly-ai/nos/pkg/gpu/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-helpers/scheduling/corev1"
+	"sort"
+)
+
+var _ Sorter = SorterAdapter(nil)
+
+type SorterAdapter func(pods []v1.Pod) []v1.Pod
+
+func (f SorterAdapter) Sort(pods []v1.Pod) []v1.Pod {
+	return f(pods)
+}
+
+// NewSorter returns the Sorter corresponding to the provided kind. If the kind is empty
+// or unknown, it returns the default priority sorter. The tenants are used only by the
+// fair-share sorter: if nil, the namespace of each pod is considered as its tenant.
+func NewSorter(kind v1alpha1.PodSorterKind, sliceCalculator gpu.SliceCalculator, tenants TenantGetter) Sorter {
+	switch kind {
+	case v1alpha1.PodSorterKindFifo:
+		return NewFifoSorter()
+	case v1alpha1.PodSorterKindLargestFirst:
+		return NewLargestFirstSorter(sliceCalculator)
+	case v1alpha1.PodSorterKindFairShare:
+		return NewFairShareSorter(tenants)
+	case v1alpha1.PodSorterKindGang:
+		return NewGangSorter(sliceCalculator)
+	default:
+		return NewPodSorter(sliceCalculator)
+	}
+}
+
+// NewPodSorter returns a Sorter that sorts pods by priority and, among pods with the same priority,
+// places first the pods requesting smaller GPU slices in order to maximize the number of pods
+// that can be scheduled
+func NewPodSorter(sliceCalculator gpu.SliceCalculator) SorterAdapter {
+	return newPrioritySorter(func(first, second v1.Pod) bool {
+		return compareRequestedSlices(sliceCalculator, first, second) < 0
+	})
+}
+
+// NewLargestFirstSorter returns a Sorter that sorts pods by priority and, among pods with the same
+// priority, places first the pods requesting larger GPU slices, so that large slices are
+// created while the GPUs still have enough free capacity
+func NewLargestFirstSorter(sliceCalculator gpu.SliceCalculator) SorterAdapter {
+	return newPrioritySorter(func(first, second v1.Pod) bool {
+		return compareRequestedSlices(sliceCalculator, first, second) > 0
+	})
+}
+
+// NewFifoSorter returns a Sorter that sorts pods by priority and, among pods with the same
+// priority, by creation time
+func NewFifoSorter() SorterAdapter {
+	return newPrioritySorter(createdBefore)
+}
+
+// NewFairShareSorter returns a Sorter that sorts pods by priority and, among pods with the same
+// priority, interleaves the pods of different tenants: each pod is taken from the tenant that
+// requested the least GPU memory so far, so that a tenant with many pending pods cannot
+// starve the others. Pods of the same tenant are sorted by creation time.
+// If tenants is nil, the namespace of each pod is considered as its tenant.
+func NewFairShareSorter(tenants TenantGetter) SorterAdapter {
+	if tenants == nil {
+		tenants = namespaceTenantGetter{}
+	}
+	return func(pods []v1.Pod) []v1.Pod {
+		sorted := NewFifoSorter().Sort(pods)
+		res := make([]v1.Pod, 0, len(sorted))
+		for start := 0; start < len(sorted); {
+			end := start + 1
+			for end < len(sorted) && corev1.PodPriority(&sorted[end]) == corev1.PodPriority(&sorted[start]) {
+				end++
+			}
+			res = append(res, interleaveTenants(tenants, sorted[start:end])...)
+			start = end
+		}
+		return res
+	}
+}
+
+// NewGangSorter returns a Sorter that sorts pods as the Sorter returned by NewPodSorter, but that
+// keeps together the pods belonging to the same pod group (see v1alpha1.LabelPodGroup).
+// Each group is placed at the position of its first pod.
+func NewGangSorter(sliceCalculator gpu.SliceCalculator) SorterAdapter {
+	return func(pods []v1.Pod) []v1.Pod {
+		sorted := NewPodSorter(sliceCalculator).Sort(pods)
+		groups := make(map[string][]v1.Pod)
+		for _, pod := range sorted {
+			if key, ok := podGroupKey(pod); ok {
+				groups[key] = append(groups[key], pod)
+			}
+		}
+		res := make([]v1.Pod, 0, len(sorted))
+		for _, pod := range sorted {
+			key, ok := podGroupKey(pod)
+			if !ok {
+				res = append(res, pod)
+				continue
+			}
+			if members, found := groups[key]; found {
+				res = append(res, members...)
+				delete(groups, key)
+			}
+		}
+		return res
+	}
+}
+
+// newPrioritySorter returns a Sorter that sorts pods by priority, and that uses the provided
+// function for sorting pods with the same priority
+func newPrioritySorter(less func(first, second v1.Pod) bool) SorterAdapter {
+	return func(pods []v1.Pod) []v1.Pod {
+		sorted := make([]v1.Pod, len(pods))
+		copy(sorted, pods)
+
+		sort.SliceStable(sorted, func(i, j int) bool {
+			firstPodPriority := corev1.PodPriority(&sorted[i])
+			secondPodPriority := corev1.PodPriority(&sorted[j])
+			if firstPodPriority != secondPodPriority {
+				return firstPodPriority > secondPodPriority
+			}
+			return less(sorted[i], sorted[j])
+		})
+
+		return sorted
+	}
+}
+
+// compareRequestedSlices compares the GPU slices requested by the two pods, returning
+// a negative number if the first pod requests less than the second one, a positive number if
+// it requests more and 0 otherwise. Pods are compared by the largest slice they request and,
+// if equal, by the total number of slices. Pods not requesting any slice are considered equal
+// to any other pod.
+func compareRequestedSlices(sliceCalculator gpu.SliceCalculator, first, second v1.Pod) int {
+	firstPodSlices := sliceCalculator.GetRequestedSlices(first)
+	secondPodSlices := sliceCalculator.GetRequestedSlices(second)
+	if len(firstPodSlices) == 0 || len(secondPodSlices) == 0 {
+		return 0
+	}
+	firstLargest := getLargestSlice(firstPodSlices)
+	secondLargest := getLargestSlice(secondPodSlices)
+	if firstLargest.SmallerThan(secondLargest) {
+		return -1
+	}
+	if secondLargest.SmallerThan(firstLargest) {
+		return 1
+	}
+	return countSlices(firstPodSlices) - countSlices(secondPodSlices)
+}
+
+// getLargestSlice returns the largest of the provided slices. Slices that are not comparable
+// are ordered by name, so that the result does not depend on the map iteration order.
+func getLargestSlice(slices map[gpu.Slice]int) gpu.Slice {
+	var largest gpu.Slice
+	for s := range slices {
+		if largest == nil || largest.SmallerThan(s) {
+			largest = s
+			continue
+		}
+		if !s.SmallerThan(largest) && s.String() > largest.String() {
+			largest = s
+		}
+	}
+	return largest
+}
+
+func countSlices(slices map[gpu.Slice]int) int {
+	var res int
+	for _, quantity := range slices {
+		res += quantity
+	}
+	return res
+}
+
+func createdBefore(first, second v1.Pod) bool {
+	if !first.CreationTimestamp.Equal(&second.CreationTimestamp) {
+		return first.CreationTimestamp.Before(&second.CreationTimestamp)
+	}
+	if first.Namespace != second.Namespace {
+		return first.Namespace < second.Namespace
+	}
+	return first.Name < second.Name
+}
+
+// interleaveTenants returns the provided pods, which must be sorted by creation time,
+// repeatedly taking the oldest pod of the tenant with the least GPU memory taken so far
+func interleaveTenants(tenantGetter TenantGetter, pods []v1.Pod) []v1.Pod {
+	queues := make(map[string][]v1.Pod)
+	tenants := make([]string, 0)
+	for _, pod := range pods {
+		tenant := tenantGetter.GetTenant(pod)
+		if _, ok := queues[tenant]; !ok {
+			tenants = append(tenants, tenant)
+		}
+		queues[tenant] = append(queues[tenant], pod)
+	}
+	sort.Strings(tenants)
+
+	calculator := gpuutil.ResourceCalculator{NvidiaGPUDeviceMemoryGB: constant.DefaultNvidiaGPUResourceMemory}
+	taken := make(map[string]int64)
+	res := make([]v1.Pod, 0, len(pods))
+	for len(res) < len(pods) {
+		next := -1
+		for i, tenant := range tenants {
+			if len(queues[tenant]) == 0 {
+				continue
+			}
+			if next < 0 || taken[tenant] < taken[tenants[next]] {
+				next = i
+			}
+		}
+		tenant := tenants[next]
+		pod := queues[tenant][0]
+		queues[tenant] = queues[tenant][1:]
+		gpuMemory := calculator.ComputePodRequest(pod)[nosv1alpha1.ResourceGPUMemory]
+		taken[tenant] += gpuMemory.Value()
+		res = append(res, pod)
+	}
+	return res
+}
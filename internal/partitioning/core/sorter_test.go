@@ -0,0 +1,333 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	mig_partitioner "github.com/nebuly-ai/nos/internal/partitioning/mig"
+	configv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+func TestPodSorter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pods     []v1.Pod
+		expected []v1.Pod
+	}{
+		{
+			name:     "Empty list",
+			pods:     make([]v1.Pod, 0),
+			expected: make([]v1.Pod, 0),
+		},
+		{
+			name: "Single pod",
+			pods: []v1.Pod{
+				factory.BuildPod("ns-1", "pd-1").Get(),
+			},
+			expected: []v1.Pod{
+				factory.BuildPod("ns-1", "pd-1").Get(),
+			},
+		},
+		{
+			name: "Pod with same priority not requesting MIG resources, order should not change",
+			pods: []v1.Pod{
+				factory.BuildPod("ns-1", "pd-1").Get(),
+				factory.BuildPod("ns-1", "pd-2").Get(),
+				factory.BuildPod("ns-1", "pd-3").Get(),
+			},
+			expected: []v1.Pod{
+				factory.BuildPod("ns-1", "pd-1").Get(),
+				factory.BuildPod("ns-1", "pd-2").Get(),
+				factory.BuildPod("ns-1", "pd-3").Get(),
+			},
+		},
+		{
+			name: "Pod with different priorities: Pod with higher priority should be first",
+			pods: []v1.Pod{
+				factory.BuildPod("ns-1", "pd-1").WithPriority(1).WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile1g6gb.AsResourceName(), 1).
+						Get(),
+				).Get(),
+				factory.BuildPod("ns-1", "pd-2").WithPriority(2).WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile7g79gb.AsResourceName(), 1).
+						Get(),
+				).Get(),
+			},
+			expected: []v1.Pod{
+				factory.BuildPod("ns-1", "pd-2").WithPriority(2).WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile7g79gb.AsResourceName(), 1).
+						Get(),
+				).Get(),
+				factory.BuildPod("ns-1", "pd-1").WithPriority(1).WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile1g6gb.AsResourceName(), 1).
+						Get(),
+				).Get(),
+			},
+		},
+		{
+			name: "Pod with MIG Resources: Pod requesting smaller MIG profiles should be first",
+			pods: []v1.Pod{
+				factory.BuildPod("ns-1", "pd-1").WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile7g40gb.AsResourceName(), 1).
+						Get(),
+				).Get(),
+				factory.BuildPod("ns-1", "pd-2").WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile1g10gb.AsResourceName(), 1).
+						Get(),
+				).Get(),
+				factory.BuildPod("ns-1", "pd-3").WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile4g20gb.AsResourceName(), 1).
+						Get(),
+				).Get(),
+			},
+			expected: []v1.Pod{
+				factory.BuildPod("ns-1", "pd-2").WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile1g10gb.AsResourceName(), 1).
+						Get(),
+				).Get(),
+				factory.BuildPod("ns-1", "pd-3").WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile4g20gb.AsResourceName(), 1).
+						Get(),
+				).Get(),
+				factory.BuildPod("ns-1", "pd-1").WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile7g40gb.AsResourceName(), 1).
+						Get(),
+				).Get(),
+			},
+		},
+		{
+			name: "Pods requesting multiple MIG profiles: Pod whose largest profile is smaller should be first",
+			pods: []v1.Pod{
+				factory.BuildPod("ns-1", "pd-1").WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile1g10gb.AsResourceName(), 1).
+						WithScalarResourceRequest(mig.Profile7g79gb.AsResourceName(), 1).
+						Get(),
+				).Get(),
+				factory.BuildPod("ns-1", "pd-2").WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile3g40gb.AsResourceName(), 2).
+						Get(),
+				).Get(),
+			},
+			expected: []v1.Pod{
+				factory.BuildPod("ns-1", "pd-2").WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile3g40gb.AsResourceName(), 2).
+						Get(),
+				).Get(),
+				factory.BuildPod("ns-1", "pd-1").WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile1g10gb.AsResourceName(), 1).
+						WithScalarResourceRequest(mig.Profile7g79gb.AsResourceName(), 1).
+						Get(),
+				).Get(),
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			sliceCalculator := mig_partitioner.NewSliceCalculator()
+			res := core.NewPodSorter(sliceCalculator).Sort(tt.pods)
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+}
+
+func buildMigPod(namespace, name string, priority int32, created int, profile mig.ProfileName, quantity int) v1.Pod {
+	return factory.BuildPod(namespace, name).
+		WithPriority(priority).
+		WithCreationTimestamp(metav1.NewTime(time.Unix(int64(created), 0))).
+		WithContainer(
+			factory.BuildContainer("c1", "test").
+				WithScalarResourceRequest(profile.AsResourceName(), quantity).
+				Get(),
+		).Get()
+}
+
+func podNames(pods []v1.Pod) []string {
+	res := make([]string, len(pods))
+	for i, pod := range pods {
+		res[i] = pod.Namespace + "/" + pod.Name
+	}
+	return res
+}
+
+func TestFifoSorter(t *testing.T) {
+	pods := []v1.Pod{
+		buildMigPod("ns-1", "pd-1", 0, 3, mig.Profile1g10gb, 1),
+		buildMigPod("ns-1", "pd-2", 0, 1, mig.Profile7g79gb, 1),
+		buildMigPod("ns-1", "pd-3", 1, 2, mig.Profile3g40gb, 1),
+		buildMigPod("ns-2", "pd-4", 0, 1, mig.Profile1g10gb, 1),
+	}
+	res := core.NewFifoSorter().Sort(pods)
+	assert.Equal(t, []string{"ns-1/pd-3", "ns-1/pd-2", "ns-2/pd-4", "ns-1/pd-1"}, podNames(res))
+}
+
+func TestLargestFirstSorter(t *testing.T) {
+	pods := []v1.Pod{
+		buildMigPod("ns-1", "pd-1", 0, 0, mig.Profile1g10gb, 1),
+		buildMigPod("ns-1", "pd-2", 0, 0, mig.Profile3g40gb, 1),
+		buildMigPod("ns-1", "pd-3", 0, 0, mig.Profile3g40gb, 2),
+		buildMigPod("ns-1", "pd-4", 1, 0, mig.Profile1g10gb, 1),
+		buildMigPod("ns-1", "pd-5", 0, 0, mig.Profile7g79gb, 1),
+	}
+	res := core.NewLargestFirstSorter(mig_partitioner.NewSliceCalculator()).Sort(pods)
+	assert.Equal(t, []string{"ns-1/pd-4", "ns-1/pd-5", "ns-1/pd-3", "ns-1/pd-2", "ns-1/pd-1"}, podNames(res))
+}
+
+func TestFairShareSorter(t *testing.T) {
+	compositeEq := v1alpha1.BuildCompositeEq("ns-1", "ceq-1").WithNamespaces("ns-1", "ns-2").Get()
+	quotas := fake.NewClientBuilder().WithScheme(newPlanTestScheme(t)).WithObjects(&compositeEq).Build()
+
+	testCases := []struct {
+		name     string
+		tenants  core.TenantGetter
+		pods     []v1.Pod
+		expected []string
+	}{
+		{
+			name:     "Empty list",
+			pods:     []v1.Pod{},
+			expected: []string{},
+		},
+		{
+			name: "Namespaces requesting the same slices should alternate",
+			pods: []v1.Pod{
+				buildMigPod("ns-1", "pd-1", 0, 1, mig.Profile1g10gb, 1),
+				buildMigPod("ns-1", "pd-2", 0, 2, mig.Profile1g10gb, 1),
+				buildMigPod("ns-1", "pd-3", 0, 3, mig.Profile1g10gb, 1),
+				buildMigPod("ns-2", "pd-4", 0, 4, mig.Profile1g10gb, 1),
+				buildMigPod("ns-2", "pd-5", 0, 5, mig.Profile1g10gb, 1),
+			},
+			expected: []string{"ns-1/pd-1", "ns-2/pd-4", "ns-1/pd-2", "ns-2/pd-5", "ns-1/pd-3"},
+		},
+		{
+			name: "Namespace requesting many slices should not starve the others",
+			pods: []v1.Pod{
+				buildMigPod("ns-1", "pd-1", 0, 1, mig.Profile1g10gb, 3),
+				buildMigPod("ns-1", "pd-2", 0, 2, mig.Profile1g10gb, 1),
+				buildMigPod("ns-2", "pd-3", 0, 3, mig.Profile1g10gb, 1),
+				buildMigPod("ns-2", "pd-4", 0, 4, mig.Profile1g10gb, 1),
+				buildMigPod("ns-2", "pd-5", 0, 5, mig.Profile1g10gb, 1),
+			},
+			expected: []string{"ns-1/pd-1", "ns-2/pd-3", "ns-2/pd-4", "ns-2/pd-5", "ns-1/pd-2"},
+		},
+		{
+			name: "Namespace with least GPU memory taken so far should go next, regardless of the number of slices",
+			pods: []v1.Pod{
+				buildMigPod("ns-1", "pd-1", 0, 1, mig.Profile3g40gb, 1),
+				buildMigPod("ns-1", "pd-2", 0, 2, mig.Profile1g10gb, 1),
+				buildMigPod("ns-2", "pd-3", 0, 3, mig.Profile1g10gb, 1),
+				buildMigPod("ns-2", "pd-4", 0, 4, mig.Profile1g10gb, 1),
+				buildMigPod("ns-2", "pd-5", 0, 5, mig.Profile1g10gb, 1),
+			},
+			expected: []string{"ns-1/pd-1", "ns-2/pd-3", "ns-2/pd-4", "ns-2/pd-5", "ns-1/pd-2"},
+		},
+		{
+			name:    "Namespaces of the same CompositeElasticQuota should be a single tenant",
+			tenants: core.NewElasticQuotaTenantGetter(quotas),
+			pods: []v1.Pod{
+				buildMigPod("ns-1", "pd-1", 0, 1, mig.Profile1g10gb, 1),
+				buildMigPod("ns-2", "pd-2", 0, 2, mig.Profile1g10gb, 1),
+				buildMigPod("ns-3", "pd-3", 0, 3, mig.Profile1g10gb, 1),
+				buildMigPod("ns-3", "pd-4", 0, 4, mig.Profile1g10gb, 1),
+			},
+			expected: []string{"ns-1/pd-1", "ns-3/pd-3", "ns-2/pd-2", "ns-3/pd-4"},
+		},
+		{
+			name: "Pods with higher priority should be first regardless of their namespace",
+			pods: []v1.Pod{
+				buildMigPod("ns-1", "pd-1", 0, 1, mig.Profile1g10gb, 1),
+				buildMigPod("ns-1", "pd-2", 1, 2, mig.Profile1g10gb, 1),
+				buildMigPod("ns-1", "pd-3", 1, 3, mig.Profile1g10gb, 1),
+				buildMigPod("ns-2", "pd-4", 0, 4, mig.Profile1g10gb, 1),
+			},
+			expected: []string{"ns-1/pd-2", "ns-1/pd-3", "ns-1/pd-1", "ns-2/pd-4"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			res := core.NewFairShareSorter(tt.tenants).Sort(tt.pods)
+			assert.Equal(t, tt.expected, podNames(res))
+		})
+	}
+}
+
+func TestGangSorter(t *testing.T) {
+	withGroup := func(pod v1.Pod, group string) v1.Pod {
+		pod.Labels = map[string]string{v1alpha1.LabelPodGroup: group}
+		return pod
+	}
+	pods := []v1.Pod{
+		withGroup(buildMigPod("ns-1", "worker-1", 0, 0, mig.Profile7g79gb, 1), "job-1"),
+		buildMigPod("ns-1", "pd-1", 0, 0, mig.Profile3g40gb, 1),
+		withGroup(buildMigPod("ns-1", "worker-2", 0, 0, mig.Profile1g10gb, 1), "job-1"),
+		withGroup(buildMigPod("ns-2", "worker-1", 0, 0, mig.Profile7g79gb, 1), "job-1"),
+		buildMigPod("ns-1", "pd-2", 0, 0, mig.Profile1g10gb, 2),
+	}
+	res := core.NewGangSorter(mig_partitioner.NewSliceCalculator()).Sort(pods)
+	assert.Equal(
+		t,
+		[]string{"ns-1/worker-2", "ns-1/worker-1", "ns-1/pd-2", "ns-1/pd-1", "ns-2/worker-1"},
+		podNames(res),
+	)
+}
+
+func TestNewSorter(t *testing.T) {
+	pods := []v1.Pod{
+		buildMigPod("ns-1", "pd-1", 0, 2, mig.Profile1g10gb, 1),
+		buildMigPod("ns-1", "pd-2", 0, 1, mig.Profile7g79gb, 1),
+	}
+	testCases := []struct {
+		kind     configv1alpha1.PodSorterKind
+		expected []string
+	}{
+		{kind: "", expected: []string{"ns-1/pd-1", "ns-1/pd-2"}},
+		{kind: configv1alpha1.PodSorterKindPriority, expected: []string{"ns-1/pd-1", "ns-1/pd-2"}},
+		{kind: configv1alpha1.PodSorterKindFifo, expected: []string{"ns-1/pd-2", "ns-1/pd-1"}},
+		{kind: configv1alpha1.PodSorterKindLargestFirst, expected: []string{"ns-1/pd-2", "ns-1/pd-1"}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			res := core.NewSorter(tt.kind, mig_partitioner.NewSliceCalculator(), nil).Sort(pods)
+			assert.Equal(t, tt.expected, podNames(res))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// TenantGetter returns the tenant a pod belongs to, namely the entity among which
+// the fair-share sorter shares the GPU slices
+type TenantGetter interface {
+	GetTenant(pod v1.Pod) string
+}
+
+// namespaceTenantGetter considers the namespace of each pod as its tenant
+type namespaceTenantGetter struct{}
+
+func (namespaceTenantGetter) GetTenant(pod v1.Pod) string {
+	return pod.Namespace
+}
+
+// elasticQuotaTenantGetter considers the quota of the namespace of each pod as its tenant
+type elasticQuotaTenantGetter struct {
+	client.Reader
+}
+
+// NewElasticQuotaTenantGetter returns a TenantGetter that considers as tenant of a pod the quota
+// the pod is subject to: either the CompositeElasticQuota including the namespace of the pod, so that
+// all its namespaces form a single tenant, or the ElasticQuota of the namespace of the pod.
+// Pods whose namespace is not subject to any quota are considered as belonging to their namespace.
+func NewElasticQuotaTenantGetter(c client.Reader) TenantGetter {
+	return elasticQuotaTenantGetter{Reader: c}
+}
+
+func (g elasticQuotaTenantGetter) GetTenant(pod v1.Pod) string {
+	ctx := context.Background()
+	logger := log.FromContext(ctx)
+
+	var compositeEqList v1alpha1.CompositeElasticQuotaList
+	if err := g.List(ctx, &compositeEqList); err != nil {
+		logger.Error(err, "unable to list CompositeElasticQuotas, considering the namespace as tenant", "pod", pod.Name)
+		return pod.Namespace
+	}
+	for _, compositeEq := range compositeEqList.Items {
+		if util.InSlice(pod.Namespace, compositeEq.Spec.Namespaces) {
+			return fmt.Sprintf("CompositeElasticQuota/%s/%s", compositeEq.Namespace, compositeEq.Name)
+		}
+	}
+
+	var eqList v1alpha1.ElasticQuotaList
+	if err := g.List(ctx, &eqList, client.InNamespace(pod.Namespace)); err != nil {
+		logger.Error(err, "unable to list ElasticQuotas, considering the namespace as tenant", "pod", pod.Name)
+		return pod.Namespace
+	}
+	if len(eqList.Items) > 0 {
+		return fmt.Sprintf("ElasticQuota/%s/%s", eqList.Items[0].Namespace, eqList.Items[0].Name)
+	}
+	return pod.Namespace
+}
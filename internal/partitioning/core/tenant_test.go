@@ -0,0 +1,62 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestElasticQuotaTenantGetter__GetTenant(t *testing.T) {
+	compositeEq := v1alpha1.BuildCompositeEq("ns-1", "ceq-1").WithNamespaces("ns-1", "ns-2").Get()
+	eq := v1alpha1.BuildEq("ns-3", "eq-3").Get()
+	c := fake.NewClientBuilder().WithScheme(newPlanTestScheme(t)).WithObjects(&compositeEq, &eq).Build()
+	tenants := core.NewElasticQuotaTenantGetter(c)
+
+	testCases := []struct {
+		name      string
+		namespace string
+		expected  string
+	}{
+		{
+			name:      "Namespace of a CompositeElasticQuota",
+			namespace: "ns-2",
+			expected:  "CompositeElasticQuota/ns-1/ceq-1",
+		},
+		{
+			name:      "Namespace with an ElasticQuota",
+			namespace: "ns-3",
+			expected:  "ElasticQuota/ns-3/eq-3",
+		},
+		{
+			name:      "Namespace without any quota, should return the namespace",
+			namespace: "ns-4",
+			expected:  "ns-4",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := factory.BuildPod(tt.namespace, "pd-1").Get()
+			assert.Equal(t, tt.expected, tenants.GetTenant(pod))
+		})
+	}
+}
@@ -19,57 +19,8 @@ package core
 import (
 	"github.com/nebuly-ai/nos/pkg/gpu"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/component-helpers/scheduling/corev1"
-	"sort"
 )
 
-var _ Sorter = SorterAdapter(nil)
-
-type SorterAdapter func(pods []v1.Pod) []v1.Pod
-
-func (f SorterAdapter) Sort(pods []v1.Pod) []v1.Pod {
-	return f(pods)
-}
-
-func NewPodSorter(sliceCalculator gpu.SliceCalculator) SorterAdapter {
-	var sorter = func(pods []v1.Pod) []v1.Pod {
-		sorted := make([]v1.Pod, len(pods))
-		copy(sorted, pods)
-
-		sort.SliceStable(sorted, func(i, j int) bool {
-			// check priority first
-			firstPodPriority := corev1.PodPriority(&sorted[i])
-			secondPodPriority := corev1.PodPriority(&sorted[j])
-			if firstPodPriority != secondPodPriority {
-				return firstPodPriority > secondPodPriority
-			}
-
-			// if priority is equal, sort by requested MIG resources, placing first
-			// the pods that require smaller MIG profiles in order to
-			// maximize the number of pods that can be scheduled
-			firstPodMigResources := sliceCalculator.GetRequestedSlices(sorted[i])
-			if len(firstPodMigResources) == 0 {
-				return false
-			}
-			secondPodMigResources := sliceCalculator.GetRequestedSlices(sorted[j])
-			if len(secondPodMigResources) == 0 {
-				return false
-			}
-			for firstPodProfile := range firstPodMigResources {
-				for secondPodProfile := range secondPodMigResources {
-					// we assume that a Pod requests at most one MIG profile
-					return firstPodProfile.SmallerThan(secondPodProfile)
-				}
-			}
-
-			return false
-		})
-
-		return sorted
-	}
-	return sorter
-}
-
 // IsNodeInitialized checks if the GPU Partitioning on the provided node has already been initialized is initialized.
 // A node is initialized if it has GPU Spec partitioning annotations, and according to these annotations all
 // the GPUs of the node have at least one GPU partition.
@@ -19,7 +19,6 @@ package core_test
 import (
 	"fmt"
 	"github.com/nebuly-ai/nos/internal/partitioning/core"
-	mig_partitioner "github.com/nebuly-ai/nos/internal/partitioning/mig"
 	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/constant"
 	"github.com/nebuly-ai/nos/pkg/gpu/mig"
@@ -30,114 +29,6 @@ import (
 	"testing"
 )
 
-func TestPodSorter(t *testing.T) {
-	testCases := []struct {
-		name     string
-		pods     []v1.Pod
-		expected []v1.Pod
-	}{
-		{
-			name:     "Empty list",
-			pods:     make([]v1.Pod, 0),
-			expected: make([]v1.Pod, 0),
-		},
-		{
-			name: "Single pod",
-			pods: []v1.Pod{
-				factory.BuildPod("ns-1", "pd-1").Get(),
-			},
-			expected: []v1.Pod{
-				factory.BuildPod("ns-1", "pd-1").Get(),
-			},
-		},
-		{
-			name: "Pod with same priority not requesting MIG resources, order should not change",
-			pods: []v1.Pod{
-				factory.BuildPod("ns-1", "pd-1").Get(),
-				factory.BuildPod("ns-1", "pd-2").Get(),
-				factory.BuildPod("ns-1", "pd-3").Get(),
-			},
-			expected: []v1.Pod{
-				factory.BuildPod("ns-1", "pd-1").Get(),
-				factory.BuildPod("ns-1", "pd-2").Get(),
-				factory.BuildPod("ns-1", "pd-3").Get(),
-			},
-		},
-		{
-			name: "Pod with different priorities: Pod with higher priority should be first",
-			pods: []v1.Pod{
-				factory.BuildPod("ns-1", "pd-1").WithPriority(1).WithContainer(
-					factory.BuildContainer("c1", "test").
-						WithScalarResourceRequest(mig.Profile1g6gb.AsResourceName(), 1).
-						Get(),
-				).Get(),
-				factory.BuildPod("ns-1", "pd-2").WithPriority(2).WithContainer(
-					factory.BuildContainer("c1", "test").
-						WithScalarResourceRequest(mig.Profile7g79gb.AsResourceName(), 1).
-						Get(),
-				).Get(),
-			},
-			expected: []v1.Pod{
-				factory.BuildPod("ns-1", "pd-2").WithPriority(2).WithContainer(
-					factory.BuildContainer("c1", "test").
-						WithScalarResourceRequest(mig.Profile7g79gb.AsResourceName(), 1).
-						Get(),
-				).Get(),
-				factory.BuildPod("ns-1", "pd-1").WithPriority(1).WithContainer(
-					factory.BuildContainer("c1", "test").
-						WithScalarResourceRequest(mig.Profile1g6gb.AsResourceName(), 1).
-						Get(),
-				).Get(),
-			},
-		},
-		{
-			name: "Pod with MIG Resources: Pod requesting smaller MIG profiles should be first",
-			pods: []v1.Pod{
-				factory.BuildPod("ns-1", "pd-1").WithContainer(
-					factory.BuildContainer("c1", "test").
-						WithScalarResourceRequest(mig.Profile7g40gb.AsResourceName(), 1).
-						Get(),
-				).Get(),
-				factory.BuildPod("ns-1", "pd-2").WithContainer(
-					factory.BuildContainer("c1", "test").
-						WithScalarResourceRequest(mig.Profile1g10gb.AsResourceName(), 1).
-						Get(),
-				).Get(),
-				factory.BuildPod("ns-1", "pd-3").WithContainer(
-					factory.BuildContainer("c1", "test").
-						WithScalarResourceRequest(mig.Profile4g20gb.AsResourceName(), 1).
-						Get(),
-				).Get(),
-			},
-			expected: []v1.Pod{
-				factory.BuildPod("ns-1", "pd-2").WithContainer(
-					factory.BuildContainer("c1", "test").
-						WithScalarResourceRequest(mig.Profile1g10gb.AsResourceName(), 1).
-						Get(),
-				).Get(),
-				factory.BuildPod("ns-1", "pd-3").WithContainer(
-					factory.BuildContainer("c1", "test").
-						WithScalarResourceRequest(mig.Profile4g20gb.AsResourceName(), 1).
-						Get(),
-				).Get(),
-				factory.BuildPod("ns-1", "pd-1").WithContainer(
-					factory.BuildContainer("c1", "test").
-						WithScalarResourceRequest(mig.Profile7g40gb.AsResourceName(), 1).
-						Get(),
-				).Get(),
-			},
-		},
-	}
-
-	for _, tt := range testCases {
-		t.Run(tt.name, func(t *testing.T) {
-			sliceCalculator := mig_partitioner.NewSliceCalculator()
-			res := core.NewPodSorter(sliceCalculator).Sort(tt.pods)
-			assert.Equal(t, tt.expected, res)
-		})
-	}
-}
-
 func TestIsNodeInitialized(t *testing.T) {
 	testCases := []struct {
 		name     string
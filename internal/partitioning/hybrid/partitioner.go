@@ -0,0 +1,107 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hybrid
+
+import (
+	"context"
+	"fmt"
+	nvidiav1 "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/mig"
+	"github.com/nebuly-ai/nos/internal/partitioning/mps"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	migutil "github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/gpu/slicing"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+var _ core.Partitioner = partitioner{}
+
+// partitioner applies a hybrid node partitioning by delegating the MIG GPUs to the MIG partitioner,
+// which sets the node spec annotations, and the MPS GPUs to the MPS partitioner, which
+// updates the device plugin config.
+type partitioner struct {
+	migPartitioner core.Partitioner
+	mpsPartitioner core.Partitioner
+}
+
+func NewPartitioner(
+	client client.Client,
+	devicePluginCM types.NamespacedName,
+	devicePluginDelay time.Duration,
+) core.Partitioner {
+
+	return partitioner{
+		migPartitioner: mig.NewPartitioner(client),
+		mpsPartitioner: mps.NewPartitionerWithMigStrategy(
+			client,
+			devicePluginCM,
+			devicePluginDelay,
+			nvidiav1.MigStrategyMixed,
+		),
+	}
+}
+
+func (p partitioner) ApplyPartitioning(ctx context.Context, node v1.Node, planId string, partitioning state.NodePartitioning) error {
+	migPartitioning, mpsPartitioning, err := SplitPartitioning(partitioning)
+	if err != nil {
+		return err
+	}
+	if err = p.migPartitioner.ApplyPartitioning(ctx, node, planId, migPartitioning); err != nil {
+		return fmt.Errorf("error applying MIG partitioning: %v", err)
+	}
+	if err = p.mpsPartitioner.ApplyPartitioning(ctx, node, planId, mpsPartitioning); err != nil {
+		return fmt.Errorf("error applying MPS partitioning: %v", err)
+	}
+	return nil
+}
+
+// SplitPartitioning splits the node partitioning provided as argument into the partitioning of the GPUs
+// partitioned with MIG and the partitioning of the GPUs partitioned with MPS.
+//
+// GPUs without any resource are not included in any of the two partitionings. SplitPartitioning returns
+// an error if a GPU includes both MIG and MPS resources, or resources that are neither of the two.
+func SplitPartitioning(partitioning state.NodePartitioning) (state.NodePartitioning, state.NodePartitioning, error) {
+	migPartitioning := state.NodePartitioning{GPUs: make([]state.GPUPartitioning, 0)}
+	mpsPartitioning := state.NodePartitioning{GPUs: make([]state.GPUPartitioning, 0)}
+	for _, g := range partitioning.GPUs {
+		var nMig, nMps int
+		for r := range g.Resources {
+			switch {
+			case migutil.IsNvidiaMigDevice(r):
+				nMig++
+			case slicing.IsGpuSlice(r):
+				nMps++
+			default:
+				return migPartitioning, mpsPartitioning, fmt.Errorf("GPU %d: invalid resource %s", g.GPUIndex, r)
+			}
+		}
+		if nMig > 0 && nMps > 0 {
+			return migPartitioning, mpsPartitioning, fmt.Errorf("GPU %d has both MIG and MPS resources", g.GPUIndex)
+		}
+		if nMig > 0 {
+			migPartitioning.GPUs = append(migPartitioning.GPUs, g)
+		}
+		if nMps > 0 {
+			mpsPartitioning.GPUs = append(mpsPartitioning.GPUs, g)
+		}
+	}
+	return migPartitioning, mpsPartitioning, nil
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hybrid_test
+
+import (
+	"context"
+	"fmt"
+	nvidiav1 "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/nebuly-ai/nos/internal/partitioning/hybrid"
+	"github.com/nebuly-ai/nos/internal/partitioning/mps"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/nebuly-ai/nos/pkg/util"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+	"testing"
+	"time"
+)
+
+func TestSplitPartitioning(t *testing.T) {
+	testCases := []struct {
+		name         string
+		partitioning state.NodePartitioning
+		expectedMig  state.NodePartitioning
+		expectedMps  state.NodePartitioning
+		errExpected  bool
+	}{
+		{
+			name:         "Empty partitioning",
+			partitioning: state.NodePartitioning{},
+			expectedMig:  state.NodePartitioning{GPUs: []state.GPUPartitioning{}},
+			expectedMps:  state.NodePartitioning{GPUs: []state.GPUPartitioning{}},
+		},
+		{
+			name: "MIG, MPS and empty GPUs",
+			partitioning: state.NodePartitioning{GPUs: []state.GPUPartitioning{
+				{GPUIndex: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-1g.10gb": 2}},
+				{GPUIndex: 1, Resources: map[v1.ResourceName]int{"nvidia.com/gpu-10gb": 2}},
+				{GPUIndex: 2, Resources: map[v1.ResourceName]int{}},
+			}},
+			expectedMig: state.NodePartitioning{GPUs: []state.GPUPartitioning{
+				{GPUIndex: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-1g.10gb": 2}},
+			}},
+			expectedMps: state.NodePartitioning{GPUs: []state.GPUPartitioning{
+				{GPUIndex: 1, Resources: map[v1.ResourceName]int{"nvidia.com/gpu-10gb": 2}},
+			}},
+		},
+		{
+			name: "GPU with both MIG and MPS resources, should return error",
+			partitioning: state.NodePartitioning{GPUs: []state.GPUPartitioning{
+				{GPUIndex: 0, Resources: map[v1.ResourceName]int{
+					"nvidia.com/mig-1g.10gb": 2,
+					"nvidia.com/gpu-10gb":    2,
+				}},
+			}},
+			errExpected: true,
+		},
+		{
+			name: "GPU with invalid resources, should return error",
+			partitioning: state.NodePartitioning{GPUs: []state.GPUPartitioning{
+				{GPUIndex: 0, Resources: map[v1.ResourceName]int{v1.ResourceCPU: 2}},
+			}},
+			errExpected: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			migPartitioning, mpsPartitioning, err := hybrid.SplitPartitioning(tt.partitioning)
+			if tt.errExpected {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedMig, migPartitioning)
+			assert.Equal(t, tt.expectedMps, mpsPartitioning)
+		})
+	}
+}
+
+func TestPartitioner__ApplyPartitioning(t *testing.T) {
+	node := factory.BuildNode("node-1").WithAnnotations(map[string]string{
+		fmt.Sprintf(v1alpha1.AnnotationGpuSpecFormat, 1, "1g.10gb"): "1",
+	}).Get()
+	devicePluginCM := v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "test-namespace",
+			Name:      "test-name",
+		},
+	}
+	cmNamespacedName := types.NamespacedName{
+		Namespace: devicePluginCM.Namespace,
+		Name:      devicePluginCM.Name,
+	}
+	k8sClient := fake.NewClientBuilder().
+		WithObjects(&node).
+		WithObjects(&devicePluginCM).
+		Build()
+	partitioner := hybrid.NewPartitioner(
+		k8sClient,
+		cmNamespacedName,
+		1*time.Millisecond,
+	)
+	ctx := context.Background()
+	planId := "plan-id"
+
+	nodePartitioning := state.NodePartitioning{
+		GPUs: []state.GPUPartitioning{
+			{
+				GPUIndex:  0,
+				Resources: map[v1.ResourceName]int{"nvidia.com/mig-3g.40gb": 2},
+			},
+			{
+				GPUIndex:  1,
+				Resources: map[v1.ResourceName]int{"nvidia.com/gpu-10gb": 2},
+			},
+		},
+	}
+	err := partitioner.ApplyPartitioning(ctx, node, planId, nodePartitioning)
+	assert.NoError(t, err)
+
+	// MIG GPUs must be included in node spec annotations
+	var updatedNode v1.Node
+	assert.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Name: node.Name}, &updatedNode))
+	assert.Equal(t, planId, updatedNode.Annotations[v1alpha1.AnnotationPartitioningPlan])
+	assert.Equal(t, "2", updatedNode.Annotations[fmt.Sprintf(v1alpha1.AnnotationGpuSpecFormat, 0, "3g.40gb")])
+	assert.NotContains(t, updatedNode.Annotations, fmt.Sprintf(v1alpha1.AnnotationGpuSpecFormat, 1, "1g.10gb"))
+
+	// MPS GPUs must be included in device plugin config
+	configKey := fmt.Sprintf(mps.DevicePluginConfigKeyFormat, node.Name, planId)
+	assert.Equal(t, configKey, updatedNode.Labels[constant.LabelNvidiaDevicePluginConfig])
+	var cm v1.ConfigMap
+	assert.NoError(t, k8sClient.Get(ctx, cmNamespacedName, &cm))
+	expectedConfig, err := mps.ToPluginConfig(state.NodePartitioning{GPUs: nodePartitioning.GPUs[1:]})
+	assert.NoError(t, err)
+	expectedConfig.Flags.MigStrategy = util.StringAddr(nvidiav1.MigStrategyMixed)
+	expectedConfigYaml, err := yaml.Marshal(expectedConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expectedConfigYaml), cm.Data[configKey])
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mig
+
+import (
+	"context"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var _ core.Compactor = compactor{}
+
+// compactor computes partitioning plans that merge the free MIG devices of each GPU
+// into the fewest possible devices, without deleting any used device
+type compactor struct {
+	partitionCalculator core.PartitionCalculator
+}
+
+func NewCompactor() core.Compactor {
+	return compactor{
+		partitionCalculator: NewPartitionCalculator(),
+	}
+}
+
+func (c compactor) Compact(ctx context.Context, snapshot core.Snapshot) (core.PartitioningPlan, error) {
+	logger := log.FromContext(ctx)
+	partitioningState := snapshot.GetPartitioningState()
+	for _, n := range snapshot.GetCandidateNodes() {
+		migNode, ok := n.(*mig.Node)
+		if !ok {
+			continue
+		}
+		if !migNode.Compact() {
+			continue
+		}
+		logger.V(1).Info("compacted node geometry", "node", migNode.GetName(), "geometry", migNode.Geometry())
+		partitioningState[migNode.GetName()] = c.partitionCalculator.GetPartitioning(migNode)
+	}
+	return core.NewPartitioningPlan(partitioningState), nil
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mig_test
+
+import (
+	"context"
+	"fmt"
+	mig_partitioner "github.com/nebuly-ai/nos/internal/partitioning/mig"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/resource"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"testing"
+)
+
+func TestCompactor__Compact(t *testing.T) {
+	migLabels := map[string]string{
+		v1alpha1.LabelGpuPartitioning: gpu.PartitioningKindMig.String(),
+		constant.LabelNvidiaCount:     "1",
+		constant.LabelNvidiaProduct:   string(gpu.GPUModel_A30),
+	}
+
+	testCases := []struct {
+		name          string
+		nodes         []v1.Node
+		expectedState state.PartitioningState
+	}{
+		{
+			name:          "Empty snapshot",
+			nodes:         []v1.Node{},
+			expectedState: state.PartitioningState{},
+		},
+		{
+			name: "Should compact only nodes with scattered free MIG devices",
+			nodes: []v1.Node{
+				factory.BuildNode("node-1").
+					WithLabels(migLabels).
+					WithAnnotations(map[string]string{
+						fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, "1g.6gb", resource.StatusFree): "4",
+					}).
+					Get(),
+				factory.BuildNode("node-2").
+					WithLabels(migLabels).
+					WithAnnotations(map[string]string{
+						fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, "2g.12gb", resource.StatusUsed): "1",
+						fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, "2g.12gb", resource.StatusFree): "1",
+					}).
+					Get(),
+			},
+			expectedState: state.PartitioningState{
+				"node-1": {
+					GPUs: []state.GPUPartitioning{
+						{
+							GPUIndex: 0,
+							Resources: map[v1.ResourceName]int{
+								"nvidia.com/mig-4g.24gb": 1,
+							},
+						},
+					},
+				},
+				"node-2": {
+					GPUs: []state.GPUPartitioning{
+						{
+							GPUIndex: 0,
+							Resources: map[v1.ResourceName]int{
+								"nvidia.com/mig-2g.12gb": 2,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeInfos := make(map[string]framework.NodeInfo)
+			for _, n := range tt.nodes {
+				n := n
+				ni := framework.NewNodeInfo()
+				ni.SetNode(&n)
+				nodeInfos[n.Name] = *ni
+			}
+			snapshot, err := mig_partitioner.NewSnapshotTaker().TakeSnapshot(state.NewClusterState(nodeInfos))
+			assert.NoError(t, err)
+
+			plan, err := mig_partitioner.NewCompactor().Compact(context.Background(), snapshot)
+			assert.NoError(t, err)
+			assert.True(t, tt.expectedState.Equal(plan.DesiredState), "expected %v, got %v", tt.expectedState, plan.DesiredState)
+		})
+	}
+}
@@ -20,19 +20,39 @@ import (
 	"github.com/nebuly-ai/nos/internal/controllers/gpupartitioner"
 	"github.com/nebuly-ai/nos/internal/partitioning/core"
 	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/gpu"
 	"github.com/nebuly-ai/nos/pkg/util"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sync"
+	"time"
 )
 
-func NewPlanner(scheduler framework.Framework) core.Planner {
+func NewPlanner(scheduler framework.Framework, sorterKind v1alpha1.PodSorterKind, tenants core.TenantGetter) core.Planner {
 	return core.NewPlanner(
 		NewPartitionCalculator(),
 		NewSliceCalculator(),
 		scheduler,
+		core.NewSorter(sorterKind, NewSliceCalculator(), tenants),
+	)
+}
+
+func NewOptimalPlanner(
+	scheduler framework.Framework,
+	sorterKind v1alpha1.PodSorterKind,
+	tenants core.TenantGetter,
+	timeout time.Duration,
+) core.Planner {
+	return core.NewOptimalPlanner(
+		NewPartitionCalculator(),
+		NewSliceCalculator(),
+		scheduler,
+		core.NewSorter(sorterKind, NewSliceCalculator(), tenants),
+		timeout,
 	)
 }
 
@@ -48,7 +68,10 @@ func NewController(
 	client client.Client,
 	podBatcher util.Batcher[v1.Pod],
 	clusterState *state.ClusterState,
-	scheduler framework.Framework,
+	planner core.Planner,
+	actuator core.Actuator,
+	recorder record.EventRecorder,
+	partitioningMtx *sync.Mutex,
 ) gpupartitioner.Controller {
 
 	return gpupartitioner.NewController(
@@ -57,8 +80,66 @@ func NewController(
 		podBatcher,
 		clusterState,
 		gpu.PartitioningKindMig,
-		NewPlanner(scheduler),
-		NewActuator(client),
+		planner,
+		actuator,
+		NewSnapshotTaker(),
+		recorder,
+		partitioningMtx,
+	)
+}
+
+func NewPlanController(
+	client client.Client,
+	clusterState *state.ClusterState,
+	actuator core.Actuator,
+	approvalTimeout time.Duration,
+	partitioningMtx *sync.Mutex,
+) gpupartitioner.PlanController {
+
+	return gpupartitioner.NewPlanController(
+		client,
+		clusterState,
+		gpu.PartitioningKindMig,
+		actuator,
 		NewSnapshotTaker(),
+		approvalTimeout,
+		partitioningMtx,
+	)
+}
+
+func NewCompactionLoop(
+	client client.Client,
+	clusterState *state.ClusterState,
+	actuator core.Actuator,
+	interval time.Duration,
+	quietPeriod time.Duration,
+	partitioningMtx *sync.Mutex,
+) *gpupartitioner.CompactionLoop {
+
+	return gpupartitioner.NewCompactionLoop(
+		client,
+		clusterState,
+		gpu.PartitioningKindMig,
+		NewCompactor(),
+		actuator,
+		NewSnapshotTaker(),
+		interval,
+		quietPeriod,
+		partitioningMtx,
+	)
+}
+
+func NewPreemptionPlanner(
+	delegate core.Planner,
+	scheduler framework.Framework,
+	sorterKind v1alpha1.PodSorterKind,
+	tenants core.TenantGetter,
+) core.Planner {
+	return core.NewPreemptionPlanner(
+		delegate,
+		NewPartitionCalculator(),
+		NewSliceCalculator(),
+		scheduler,
+		core.NewSorter(sorterKind, NewSliceCalculator(), tenants),
 	)
 }
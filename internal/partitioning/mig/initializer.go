@@ -23,6 +23,7 @@ import (
 	"github.com/nebuly-ai/nos/pkg/gpu"
 	"github.com/nebuly-ai/nos/pkg/gpu/mig"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -41,6 +42,16 @@ func NewNodeInitializer(client client.Client) core.NodeInitializer {
 	}
 }
 
+// NewDryRunNodeInitializer returns a NodeInitializer that computes the initial MIG geometry of the nodes
+// without applying it: the geometry is only logged and recorded as an Event on the node, so that
+// the node annotations are never changed.
+func NewDryRunNodeInitializer(recorder record.EventRecorder) core.NodeInitializer {
+	return nodeInitializer{
+		partitioner:         core.NewDryRunPartitioner(recorder),
+		partitionCalculator: NewPartitionCalculator(),
+	}
+}
+
 func (n nodeInitializer) InitNodePartitioning(ctx context.Context, node v1.Node) error {
 	logger := log.FromContext(ctx)
 
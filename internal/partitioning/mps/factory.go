@@ -20,13 +20,16 @@ import (
 	"github.com/nebuly-ai/nos/internal/controllers/gpupartitioner"
 	"github.com/nebuly-ai/nos/internal/partitioning/core"
 	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/gpu"
 	"github.com/nebuly-ai/nos/pkg/util"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sync"
 	"time"
 )
 
@@ -41,11 +44,27 @@ func NewActuator(client client.Client, devicePluginCM types.NamespacedName, devi
 	)
 }
 
-func NewPlanner(scheduler framework.Framework) core.Planner {
+func NewPlanner(scheduler framework.Framework, sorterKind v1alpha1.PodSorterKind, tenants core.TenantGetter) core.Planner {
 	return core.NewPlanner(
 		NewPartitionCalculator(),
 		NewSliceCalculator(),
 		scheduler,
+		core.NewSorter(sorterKind, NewSliceCalculator(), tenants),
+	)
+}
+
+func NewOptimalPlanner(
+	scheduler framework.Framework,
+	sorterKind v1alpha1.PodSorterKind,
+	tenants core.TenantGetter,
+	timeout time.Duration,
+) core.Planner {
+	return core.NewOptimalPlanner(
+		NewPartitionCalculator(),
+		NewSliceCalculator(),
+		scheduler,
+		core.NewSorter(sorterKind, NewSliceCalculator(), tenants),
+		timeout,
 	)
 }
 
@@ -54,9 +73,10 @@ func NewController(
 	client client.Client,
 	podBatcher util.Batcher[v1.Pod],
 	clusterState *state.ClusterState,
-	scheduler framework.Framework,
-	devicePluginCM types.NamespacedName,
-	devicePluginDelay time.Duration,
+	planner core.Planner,
+	actuator core.Actuator,
+	recorder record.EventRecorder,
+	partitioningMtx *sync.Mutex,
 ) gpupartitioner.Controller {
 
 	return gpupartitioner.NewController(
@@ -65,8 +85,29 @@ func NewController(
 		podBatcher,
 		clusterState,
 		gpu.PartitioningKindMps,
-		NewPlanner(scheduler),
-		NewActuator(client, devicePluginCM, devicePluginDelay),
+		planner,
+		actuator,
+		NewSnapshotTaker(),
+		recorder,
+		partitioningMtx,
+	)
+}
+
+func NewPlanController(
+	client client.Client,
+	clusterState *state.ClusterState,
+	actuator core.Actuator,
+	approvalTimeout time.Duration,
+	partitioningMtx *sync.Mutex,
+) gpupartitioner.PlanController {
+
+	return gpupartitioner.NewPlanController(
+		client,
+		clusterState,
+		gpu.PartitioningKindMps,
+		actuator,
 		NewSnapshotTaker(),
+		approvalTimeout,
+		partitioningMtx,
 	)
 }
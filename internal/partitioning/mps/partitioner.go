@@ -43,6 +43,7 @@ type partitioner struct {
 	client.Client
 	devicePluginCM    types.NamespacedName
 	devicePluginDelay time.Duration
+	migStrategy       string
 }
 
 func NewPartitioner(
@@ -51,10 +52,28 @@ func NewPartitioner(
 	devicePluginDelay time.Duration,
 ) core.Partitioner {
 
+	return NewPartitionerWithMigStrategy(
+		client,
+		devicePluginCM,
+		devicePluginDelay,
+		nvidiav1.MigStrategyNone,
+	)
+}
+
+// NewPartitionerWithMigStrategy returns a Partitioner that writes device plugin configs
+// with the MIG strategy provided as argument.
+func NewPartitionerWithMigStrategy(
+	client client.Client,
+	devicePluginCM types.NamespacedName,
+	devicePluginDelay time.Duration,
+	migStrategy string,
+) core.Partitioner {
+
 	return partitioner{
 		Client:            client,
 		devicePluginCM:    devicePluginCM,
 		devicePluginDelay: devicePluginDelay,
+		migStrategy:       migStrategy,
 	}
 }
 
@@ -82,7 +101,7 @@ func (p partitioner) ApplyPartitioning(ctx context.Context, node v1.Node, planId
 
 	// Update ConfigMap with new node config
 	key := fmt.Sprintf(DevicePluginConfigKeyFormat, node.Name, planId)
-	pluginConfig, err := ToPluginConfig(partitioning)
+	pluginConfig, err := toPluginConfig(partitioning, p.migStrategy)
 	if err != nil {
 		return fmt.Errorf("unable to convert node partitioning state to device plugin config: %v", err)
 	}
@@ -121,6 +140,10 @@ func (p partitioner) getDevicePluginCM(ctx context.Context) (v1.ConfigMap, error
 }
 
 func ToPluginConfig(partitioning state.NodePartitioning) (nvidiav1.Config, error) {
+	return toPluginConfig(partitioning, nvidiav1.MigStrategyNone)
+}
+
+func toPluginConfig(partitioning state.NodePartitioning, migStrategy string) (nvidiav1.Config, error) {
 	replicatedResources := make([]nvidiav1.MPSResource, 0)
 	for _, g := range partitioning.GPUs {
 		for r, q := range g.Resources {
@@ -144,7 +167,7 @@ func ToPluginConfig(partitioning state.NodePartitioning) (nvidiav1.Config, error
 		Version: nvidiav1.Version,
 		Flags: nvidiav1.Flags{
 			CommandLineFlags: nvidiav1.CommandLineFlags{
-				MigStrategy: util.StringAddr("none"),
+				MigStrategy: util.StringAddr(migStrategy),
 			},
 		},
 		Sharing: nvidiav1.Sharing{
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package whatif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"os"
+)
+
+// ClusterDump contains the nodes and the pods of a cluster, both the ones
+// already running on the nodes and the pending ones.
+type ClusterDump struct {
+	Nodes []v1.Node
+	Pods  []v1.Pod
+}
+
+// LoadClusterDump reads the cluster dump from the file provided as argument.
+// See DecodeClusterDump for the supported formats.
+func LoadClusterDump(file string) (ClusterDump, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ClusterDump{}, err
+	}
+	return DecodeClusterDump(data)
+}
+
+// DecodeClusterDump decodes a cluster dump from the data provided as argument.
+// The data can contain one or more YAML documents, or a JSON object, each of them
+// being either a Node, a Pod, or a List of Nodes and Pods, such as the output of
+// "kubectl get nodes,pods -A -o yaml". Objects of any other kind are ignored.
+func DecodeClusterDump(data []byte) (ClusterDump, error) {
+	var dump = ClusterDump{
+		Nodes: make([]v1.Node, 0),
+		Pods:  make([]v1.Pod, 0),
+	}
+	decoder := serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer()
+	reader := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var raw runtime.RawExtension
+		if err := reader.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return ClusterDump{}, fmt.Errorf("error reading cluster dump: %v", err)
+		}
+		if len(bytes.TrimSpace(raw.Raw)) == 0 {
+			continue
+		}
+		obj, _, err := decoder.Decode(raw.Raw, nil, nil)
+		if err != nil {
+			return ClusterDump{}, fmt.Errorf("error decoding cluster dump object: %v", err)
+		}
+		if err = dump.add(decoder, obj); err != nil {
+			return ClusterDump{}, err
+		}
+	}
+	return dump, nil
+}
+
+func (d *ClusterDump) add(decoder runtime.Decoder, obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *v1.Node:
+		d.Nodes = append(d.Nodes, *o)
+	case *v1.Pod:
+		d.addPod(*o)
+	case *v1.NodeList:
+		d.Nodes = append(d.Nodes, o.Items...)
+	case *v1.PodList:
+		for _, p := range o.Items {
+			d.addPod(p)
+		}
+	case *v1.List:
+		for _, item := range o.Items {
+			itemObj, _, err := decoder.Decode(item.Raw, nil, nil)
+			if err != nil {
+				return fmt.Errorf("error decoding cluster dump list item: %v", err)
+			}
+			if err = d.add(decoder, itemObj); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addPod adds the pod to the dump, defaulting the container requests to the limits like
+// the API server does, since pods written by hand often specify only the limits
+func (d *ClusterDump) addPod(pod v1.Pod) {
+	for _, containers := range [][]v1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			resources := &containers[i].Resources
+			for r, q := range resources.Limits {
+				if resources.Requests == nil {
+					resources.Requests = make(v1.ResourceList)
+				}
+				if _, ok := resources.Requests[r]; !ok {
+					resources.Requests[r] = q.DeepCopy()
+				}
+			}
+		}
+	}
+	d.Pods = append(d.Pods, pod)
+}
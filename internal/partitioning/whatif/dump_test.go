@@ -0,0 +1,149 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package whatif
+
+import (
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"testing"
+)
+
+func TestDecodeClusterDump(t *testing.T) {
+	testCases := []struct {
+		name          string
+		data          string
+		expectedNodes []string
+		expectedPods  []string
+		expectedErr   bool
+	}{
+		{
+			name:          "Empty data",
+			data:          "",
+			expectedNodes: []string{},
+			expectedPods:  []string{},
+		},
+		{
+			name: "Multiple YAML documents, objects of unknown kinds are ignored",
+			data: `
+apiVersion: v1
+kind: Node
+metadata:
+  name: node-1
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pod-1
+  namespace: ns-1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm-1
+  namespace: ns-1
+`,
+			expectedNodes: []string{"node-1"},
+			expectedPods:  []string{"pod-1"},
+		},
+		{
+			name: "List of nodes and pods",
+			data: `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Node
+  metadata:
+    name: node-1
+- apiVersion: v1
+  kind: Node
+  metadata:
+    name: node-2
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: pod-1
+    namespace: ns-1
+`,
+			expectedNodes: []string{"node-1", "node-2"},
+			expectedPods:  []string{"pod-1"},
+		},
+		{
+			name:          "JSON list of pods",
+			data:          `{"apiVersion": "v1", "kind": "PodList", "items": [{"metadata": {"name": "pod-1"}}, {"metadata": {"name": "pod-2"}}]}`,
+			expectedNodes: []string{},
+			expectedPods:  []string{"pod-1", "pod-2"},
+		},
+		{
+			name:        "Object without kind, should return error",
+			data:        "metadata:\n  name: node-1\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			dump, err := DecodeClusterDump([]byte(tt.data))
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			nodes := make([]string, 0)
+			for _, n := range dump.Nodes {
+				nodes = append(nodes, n.Name)
+			}
+			pods := make([]string, 0)
+			for _, p := range dump.Pods {
+				pods = append(pods, p.Name)
+			}
+			assert.Equal(t, tt.expectedNodes, nodes)
+			assert.Equal(t, tt.expectedPods, pods)
+		})
+	}
+}
+
+func TestDecodeClusterDump__PodRequestsDefaultToLimits(t *testing.T) {
+	data := `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pod-1
+  namespace: ns-1
+spec:
+  containers:
+  - name: test
+    resources:
+      requests:
+        cpu: 100m
+        nvidia.com/mig-1g.10gb: 2
+      limits:
+        cpu: 200m
+        nvidia.com/mig-1g.10gb: 2
+        nvidia.com/mig-2g.20gb: 1
+`
+	dump, err := DecodeClusterDump([]byte(data))
+	assert.NoError(t, err)
+	assert.Len(t, dump.Pods, 1)
+	expected := v1.ResourceList{
+		v1.ResourceCPU:           resource.MustParse("100m"),
+		"nvidia.com/mig-1g.10gb": resource.MustParse("2"),
+		"nvidia.com/mig-2g.20gb": resource.MustParse("1"),
+	}
+	assert.Equal(t, expected, dump.Pods[0].Spec.Containers[0].Resources.Requests)
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package whatif
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	v1 "k8s.io/api/core/v1"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Report describes the outcome of a what-if simulation
+type Report struct {
+	// Nodes contains the current and planned GPU partitioning of each node
+	Nodes []NodeReport `json:"nodes"`
+	// SchedulablePods are the pending pods that would become schedulable by applying the plan
+	SchedulablePods []PodReport `json:"schedulablePods"`
+	// NotSchedulablePods are the pending pods for which the plan does not create the required resources
+	NotSchedulablePods []PodReport `json:"notSchedulablePods"`
+}
+
+type NodeReport struct {
+	Name    string      `json:"name"`
+	Changed bool        `json:"changed"`
+	Current []GPUReport `json:"current"`
+	Planned []GPUReport `json:"planned"`
+}
+
+type GPUReport struct {
+	Index     int                     `json:"index"`
+	Resources map[v1.ResourceName]int `json:"resources"`
+}
+
+type PodReport struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	NodeName  string `json:"nodeName,omitempty"`
+}
+
+// WriteJSON writes the report to w in JSON format
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteText writes the report to w in a human-readable, tabular format
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "NODE\tGPU\tCURRENT\tPLANNED\tCHANGED")
+	for _, n := range r.Nodes {
+		current := indexGPUReports(n.Current)
+		planned := indexGPUReports(n.Planned)
+		for _, idx := range gpuIndexes(current, planned) {
+			fmt.Fprintf(
+				tw,
+				"%s\t%d\t%s\t%s\t%t\n",
+				n.Name,
+				idx,
+				formatResources(current[idx]),
+				formatResources(planned[idx]),
+				n.Changed,
+			)
+		}
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintf(tw, "Pods that would become schedulable: %d\n", len(r.SchedulablePods))
+	if len(r.SchedulablePods) > 0 {
+		fmt.Fprintln(tw, "NAMESPACE\tPOD\tNODE")
+		for _, p := range r.SchedulablePods {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Namespace, p.Name, p.NodeName)
+		}
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintf(tw, "Pods that would remain pending: %d\n", len(r.NotSchedulablePods))
+	if len(r.NotSchedulablePods) > 0 {
+		fmt.Fprintln(tw, "NAMESPACE\tPOD")
+		for _, p := range r.NotSchedulablePods {
+			fmt.Fprintf(tw, "%s\t%s\n", p.Namespace, p.Name)
+		}
+	}
+
+	return tw.Flush()
+}
+
+func indexGPUReports(gpus []GPUReport) map[int]map[v1.ResourceName]int {
+	res := make(map[int]map[v1.ResourceName]int, len(gpus))
+	for _, g := range gpus {
+		res[g.Index] = g.Resources
+	}
+	return res
+}
+
+func gpuIndexes(reports ...map[int]map[v1.ResourceName]int) []int {
+	seen := make(map[int]struct{})
+	res := make([]int, 0)
+	for _, r := range reports {
+		for idx := range r {
+			if _, ok := seen[idx]; !ok {
+				seen[idx] = struct{}{}
+				res = append(res, idx)
+			}
+		}
+	}
+	sort.Ints(res)
+	return res
+}
+
+func formatResources(resources map[v1.ResourceName]int) string {
+	if len(resources) == 0 {
+		return "-"
+	}
+	res := make([]string, 0, len(resources))
+	for r, q := range resources {
+		res = append(res, fmt.Sprintf("%s=%d", r, q))
+	}
+	sort.Strings(res)
+	return strings.Join(res, ",")
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package whatif
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func newTestReport() Report {
+	return Report{
+		Nodes: []NodeReport{
+			{
+				Name:    "node-1",
+				Changed: true,
+				Current: []GPUReport{
+					{Index: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-1g.6gb": 4}},
+				},
+				Planned: []GPUReport{
+					{Index: 0, Resources: map[v1.ResourceName]int{"nvidia.com/mig-2g.12gb": 2}},
+					{Index: 1, Resources: map[v1.ResourceName]int{"nvidia.com/mig-1g.6gb": 2, "nvidia.com/mig-2g.12gb": 1}},
+				},
+			},
+		},
+		SchedulablePods: []PodReport{
+			{Namespace: "ns-1", Name: "pod-1", NodeName: "node-1"},
+		},
+		NotSchedulablePods: []PodReport{
+			{Namespace: "ns-2", Name: "pod-2"},
+		},
+	}
+}
+
+func TestReport__WriteText(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, newTestReport().WriteText(&buf))
+	expected := `NODE    GPU  CURRENT                  PLANNED                                           CHANGED
+node-1  0    nvidia.com/mig-1g.6gb=4  nvidia.com/mig-2g.12gb=2                          true
+node-1  1    -                        nvidia.com/mig-1g.6gb=2,nvidia.com/mig-2g.12gb=1  true
+
+Pods that would become schedulable: 1
+NAMESPACE  POD    NODE
+ns-1       pod-1  node-1
+
+Pods that would remain pending: 1
+NAMESPACE  POD
+ns-2       pod-2
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestReport__WriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	report := newTestReport()
+	assert.NoError(t, report.WriteJSON(&buf))
+
+	var decoded Report
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, report, decoded)
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package whatif
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/internal/partitioning/core"
+	"github.com/nebuly-ai/nos/internal/partitioning/state"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/gpu/slicing"
+	"github.com/nebuly-ai/nos/pkg/util/pod"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"sort"
+)
+
+// Simulate computes the partitioning plan that the planner would produce for the pending pods of the
+// cluster dump, without interacting with any cluster, and returns a report describing it.
+//
+// The pods reported as schedulable are the ones that the scheduler framework can schedule on the nodes
+// partitioned according to the plan, including the pods that fit slices already available on the nodes.
+func Simulate(
+	ctx context.Context,
+	dump ClusterDump,
+	snapshotTaker core.SnapshotTaker,
+	planner core.Planner,
+	schedulerFramework framework.Framework,
+) (Report, error) {
+	// Build cluster state
+	clusterState := state.NewEmptyClusterState()
+	nodePods := make(map[string][]v1.Pod)
+	candidatePods := make([]v1.Pod, 0)
+	for _, p := range dump.Pods {
+		if pod.IsScheduled(p) {
+			// Consider only the running pods, consistently with the cluster state of the GPU partitioner
+			if p.Status.Phase == v1.PodRunning {
+				nodePods[p.Spec.NodeName] = append(nodePods[p.Spec.NodeName], p)
+			}
+			continue
+		}
+		// Consider only the pods considered by the GPU partitioner
+		if pod.ExtraResourcesCouldHelpScheduling(p) {
+			candidatePods = append(candidatePods, p)
+		}
+	}
+	for _, n := range dump.Nodes {
+		clusterState.UpdateNode(n, nodePods[n.Name])
+	}
+
+	// Compute plan
+	snapshot, err := snapshotTaker.TakeSnapshot(clusterState)
+	if err != nil {
+		return Report{}, fmt.Errorf("unable to take a snapshot of the cluster state: %v", err)
+	}
+	currentState := snapshot.GetPartitioningState()
+	plan, err := planner.Plan(ctx, snapshot.Clone(), candidatePods)
+	if err != nil {
+		return Report{}, fmt.Errorf("unable to plan desired partitioning state: %v", err)
+	}
+
+	// Simulate the scheduling of the candidate pods on the nodes partitioned according to the plan
+	nodeInfos := newPlannedNodeInfos(dump.Nodes, nodePods, currentState, plan.DesiredState)
+	placements := simulateScheduling(ctx, schedulerFramework, nodeInfos, plan.Placements, candidatePods)
+
+	return newReport(currentState, plan, placements, candidatePods), nil
+}
+
+// newPlannedNodeInfos returns the nodes provided as argument, grouped by name, with the allocatable
+// GPU slices updated according to the desired partitioning state
+func newPlannedNodeInfos(
+	nodes []v1.Node,
+	nodePods map[string][]v1.Pod,
+	currentState state.PartitioningState,
+	desiredState state.PartitioningState,
+) map[string]*framework.NodeInfo {
+	res := make(map[string]*framework.NodeInfo, len(nodes))
+	for _, n := range nodes {
+		node := n.DeepCopy()
+		if desired, ok := desiredState[n.Name]; ok && !desired.Equal(currentState[n.Name]) {
+			allocatable := make(v1.ResourceList, len(node.Status.Allocatable))
+			for r, q := range node.Status.Allocatable {
+				if !mig.IsNvidiaMigDevice(r) && !slicing.IsGpuSlice(r) {
+					allocatable[r] = q
+				}
+			}
+			for _, g := range desired.GPUs {
+				for r, q := range g.Resources {
+					quantity := allocatable[r]
+					quantity.Add(*resource.NewQuantity(int64(q), resource.DecimalSI))
+					allocatable[r] = quantity
+				}
+			}
+			node.Status.Allocatable = allocatable
+		}
+		podPtrs := make([]*v1.Pod, 0, len(nodePods[n.Name]))
+		for i := range nodePods[n.Name] {
+			podPtrs = append(podPtrs, &nodePods[n.Name][i])
+		}
+		nodeInfo := framework.NewNodeInfo(podPtrs...)
+		nodeInfo.SetNode(node)
+		res[n.Name] = nodeInfo
+	}
+	return res
+}
+
+// simulateScheduling simulates the scheduling of the candidate pods on the nodes provided as argument, and
+// returns the placements of the pods that can be scheduled. The pods placed by the plan are scheduled first,
+// trying the node planned for them before the other ones.
+func simulateScheduling(
+	ctx context.Context,
+	schedulerFramework framework.Framework,
+	nodeInfos map[string]*framework.NodeInfo,
+	plannedPlacements []core.PodPlacement,
+	candidatePods []v1.Pod,
+) []core.PodPlacement {
+	nodeNames := make([]string, 0, len(nodeInfos))
+	for name := range nodeInfos {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+
+	plannedNodes := make(map[types.NamespacedName]string, len(plannedPlacements))
+	for _, placement := range plannedPlacements {
+		plannedNodes[placement.Pod] = placement.NodeName
+	}
+	candidatesByName := make(map[types.NamespacedName]v1.Pod, len(candidatePods))
+	for _, p := range candidatePods {
+		candidatesByName[types.NamespacedName{Namespace: p.Namespace, Name: p.Name}] = p
+	}
+	sortedPods := make([]v1.Pod, 0, len(candidatePods))
+	for _, placement := range plannedPlacements {
+		if p, ok := candidatesByName[placement.Pod]; ok {
+			sortedPods = append(sortedPods, p)
+		}
+	}
+	for _, p := range candidatePods {
+		if _, ok := plannedNodes[types.NamespacedName{Namespace: p.Namespace, Name: p.Name}]; !ok {
+			sortedPods = append(sortedPods, p)
+		}
+	}
+
+	placements := make([]core.PodPlacement, 0, len(sortedPods))
+	for i := range sortedPods {
+		p := &sortedPods[i]
+		podName := types.NamespacedName{Namespace: p.Namespace, Name: p.Name}
+		candidateNodes := nodeNames
+		if plannedNode, ok := plannedNodes[podName]; ok {
+			candidateNodes = append([]string{plannedNode}, nodeNames...)
+		}
+		for _, nodeName := range candidateNodes {
+			nodeInfo, ok := nodeInfos[nodeName]
+			if !ok || !core.CanSchedulePod(ctx, schedulerFramework, *p, *nodeInfo) {
+				continue
+			}
+			nodeInfo.AddPod(p)
+			placements = append(placements, core.PodPlacement{Pod: podName, NodeName: nodeName})
+			break
+		}
+	}
+	return placements
+}
+
+func newReport(
+	currentState state.PartitioningState,
+	plan core.PartitioningPlan,
+	placements []core.PodPlacement,
+	candidatePods []v1.Pod,
+) Report {
+	report := Report{
+		Nodes:              make([]NodeReport, 0, len(currentState)),
+		SchedulablePods:    make([]PodReport, 0, len(placements)),
+		NotSchedulablePods: make([]PodReport, 0),
+	}
+
+	// Nodes
+	nodeNames := make([]string, 0, len(currentState))
+	for name := range currentState {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+	for _, name := range nodeNames {
+		current := currentState[name]
+		planned, ok := plan.DesiredState[name]
+		if !ok {
+			planned = current
+		}
+		report.Nodes = append(report.Nodes, NodeReport{
+			Name:    name,
+			Changed: !current.Equal(planned),
+			Current: newGPUReports(current),
+			Planned: newGPUReports(planned),
+		})
+	}
+
+	// Pods
+	placed := make(map[types.NamespacedName]struct{}, len(placements))
+	for _, placement := range placements {
+		placed[placement.Pod] = struct{}{}
+		report.SchedulablePods = append(report.SchedulablePods, PodReport{
+			Namespace: placement.Pod.Namespace,
+			Name:      placement.Pod.Name,
+			NodeName:  placement.NodeName,
+		})
+	}
+	for _, p := range candidatePods {
+		if _, ok := placed[types.NamespacedName{Namespace: p.Namespace, Name: p.Name}]; ok {
+			continue
+		}
+		report.NotSchedulablePods = append(report.NotSchedulablePods, PodReport{
+			Namespace: p.Namespace,
+			Name:      p.Name,
+		})
+	}
+	sortPodReports(report.SchedulablePods)
+	sortPodReports(report.NotSchedulablePods)
+
+	return report
+}
+
+func newGPUReports(partitioning state.NodePartitioning) []GPUReport {
+	res := make([]GPUReport, 0, len(partitioning.GPUs))
+	for _, g := range partitioning.GPUs {
+		res = append(res, GPUReport{Index: g.GPUIndex, Resources: g.Resources})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Index < res[j].Index
+	})
+	return res
+}
+
+func sortPodReports(pods []PodReport) {
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+}
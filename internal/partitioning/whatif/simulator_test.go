@@ -0,0 +1,246 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package whatif_test
+
+import (
+	"context"
+	"fmt"
+	partitioning_mig "github.com/nebuly-ai/nos/internal/partitioning/mig"
+	"github.com/nebuly-ai/nos/internal/partitioning/whatif"
+	configv1alpha1 "github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/config/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	nosresource "github.com/nebuly-ai/nos/pkg/resource"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	scheduler_mock "github.com/nebuly-ai/nos/pkg/test/mocks/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	a30Node := func(name string, profile mig.ProfileName, status nosresource.Status, quantity int) v1.Node {
+		return factory.BuildNode(name).
+			WithAnnotations(map[string]string{
+				fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, profile, status): fmt.Sprintf("%d", quantity),
+			}).
+			WithLabels(map[string]string{
+				constant.LabelNvidiaProduct:   string(gpu.GPUModel_A30),
+				constant.LabelNvidiaCount:     "1",
+				v1alpha1.LabelGpuPartitioning: gpu.PartitioningKindMig.String(),
+			}).
+			WithAllocatableResources(v1.ResourceList{
+				profile.AsResourceName(): *resource.NewQuantity(int64(quantity), resource.DecimalSI),
+			}).
+			Get()
+	}
+
+	dump := whatif.ClusterDump{
+		Nodes: []v1.Node{
+			a30Node("node-1", mig.Profile1g6gb, nosresource.StatusFree, 4),
+			a30Node("node-2", mig.Profile4g24gb, nosresource.StatusUsed, 1),
+		},
+		Pods: []v1.Pod{
+			// Running on node-2
+			factory.BuildPod("ns-1", "running").
+				WithNodeName("node-2").
+				WithPhase(v1.PodRunning).
+				WithContainer(
+					factory.BuildContainer("test", "test").
+						WithScalarResourceRequest(mig.Profile4g24gb.AsResourceName(), 1).
+						Get(),
+				).
+				Get(),
+			// Completed, must be ignored
+			factory.BuildPod("ns-1", "completed").
+				WithPhase(v1.PodSucceeded).
+				WithContainer(
+					factory.BuildContainer("test", "test").
+						WithScalarResourceRequest(mig.Profile4g24gb.AsResourceName(), 1).
+						Get(),
+				).
+				Get(),
+			// Pending, can be scheduled on node-1 by changing its geometry
+			unschedulable(factory.BuildPod("ns-1", "pending-a30").
+				WithPhase(v1.PodPending).
+				WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(mig.Profile2g12gb.AsResourceName(), 1).
+						Get(),
+				).
+				WithContainer(
+					factory.BuildContainer("c2", "test").
+						WithScalarResourceRequest(mig.Profile2g12gb.AsResourceName(), 1).
+						Get(),
+				).
+				Get()),
+			// Pending, requests a profile that no GPU of the cluster supports
+			unschedulable(factory.BuildPod("ns-2", "pending-a100").
+				WithPhase(v1.PodPending).
+				WithContainer(
+					factory.BuildContainer("test", "test").
+						WithScalarResourceRequest(mig.Profile1g10gb.AsResourceName(), 1).
+						Get(),
+				).
+				Get()),
+			// Pending, but not marked as unschedulable by the scheduler: must be ignored
+			factory.BuildPod("ns-2", "pending-not-processed").
+				WithPhase(v1.PodPending).
+				WithContainer(
+					factory.BuildContainer("test", "test").
+						WithScalarResourceRequest(mig.Profile1g10gb.AsResourceName(), 1).
+						Get(),
+				).
+				Get(),
+		},
+	}
+
+	mockedScheduler := newMockedScheduler(t)
+	report, err := whatif.Simulate(
+		context.Background(),
+		dump,
+		partitioning_mig.NewSnapshotTaker(),
+		partitioning_mig.NewPlanner(mockedScheduler, configv1alpha1.PodSorterKindPriority, nil),
+		mockedScheduler,
+	)
+	assert.NoError(t, err)
+
+	expectedNodes := []whatif.NodeReport{
+		{
+			Name:    "node-1",
+			Changed: true,
+			Current: []whatif.GPUReport{
+				{Index: 0, Resources: map[v1.ResourceName]int{mig.Profile1g6gb.AsResourceName(): 4}},
+			},
+			Planned: []whatif.GPUReport{
+				{Index: 0, Resources: map[v1.ResourceName]int{mig.Profile2g12gb.AsResourceName(): 2}},
+			},
+		},
+		{
+			Name:    "node-2",
+			Changed: false,
+			Current: []whatif.GPUReport{
+				{Index: 0, Resources: map[v1.ResourceName]int{mig.Profile4g24gb.AsResourceName(): 1}},
+			},
+			Planned: []whatif.GPUReport{
+				{Index: 0, Resources: map[v1.ResourceName]int{mig.Profile4g24gb.AsResourceName(): 1}},
+			},
+		},
+	}
+	assert.Equal(t, expectedNodes, report.Nodes)
+	assert.Equal(t, []whatif.PodReport{{Namespace: "ns-1", Name: "pending-a30", NodeName: "node-1"}}, report.SchedulablePods)
+	assert.Equal(t, []whatif.PodReport{{Namespace: "ns-2", Name: "pending-a100"}}, report.NotSchedulablePods)
+}
+
+func TestSimulate__PodsFittingFreeSlices(t *testing.T) {
+	// The node already provides the slices requested by the pending pod, so the plan does not change
+	// anything, but the pod must be reported as schedulable
+	dump := whatif.ClusterDump{
+		Nodes: []v1.Node{
+			factory.BuildNode("node-1").
+				WithAnnotations(map[string]string{
+					fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, mig.Profile2g12gb, nosresource.StatusFree): "2",
+				}).
+				WithLabels(map[string]string{
+					constant.LabelNvidiaProduct:   string(gpu.GPUModel_A30),
+					constant.LabelNvidiaCount:     "1",
+					v1alpha1.LabelGpuPartitioning: gpu.PartitioningKindMig.String(),
+				}).
+				WithAllocatableResources(v1.ResourceList{
+					mig.Profile2g12gb.AsResourceName(): *resource.NewQuantity(2, resource.DecimalSI),
+				}).
+				Get(),
+		},
+		Pods: []v1.Pod{
+			// Completed on node-1, its slices must not be considered as used
+			factory.BuildPod("ns-1", "completed").
+				WithNodeName("node-1").
+				WithPhase(v1.PodSucceeded).
+				WithContainer(
+					factory.BuildContainer("test", "test").
+						WithScalarResourceRequest(mig.Profile2g12gb.AsResourceName(), 2).
+						Get(),
+				).
+				Get(),
+			unschedulable(factory.BuildPod("ns-1", "pending").
+				WithPhase(v1.PodPending).
+				WithContainer(
+					factory.BuildContainer("test", "test").
+						WithScalarResourceRequest(mig.Profile2g12gb.AsResourceName(), 1).
+						Get(),
+				).
+				Get()),
+		},
+	}
+
+	mockedScheduler := newMockedScheduler(t)
+	report, err := whatif.Simulate(
+		context.Background(),
+		dump,
+		partitioning_mig.NewSnapshotTaker(),
+		partitioning_mig.NewPlanner(mockedScheduler, configv1alpha1.PodSorterKindPriority, nil),
+		mockedScheduler,
+	)
+	assert.NoError(t, err)
+	assert.False(t, report.Nodes[0].Changed)
+	assert.Equal(t, []whatif.PodReport{{Namespace: "ns-1", Name: "pending", NodeName: "node-1"}}, report.SchedulablePods)
+	assert.Empty(t, report.NotSchedulablePods)
+}
+
+// newMockedScheduler returns a scheduler framework that schedules a pod on a node only if
+// the node has enough allocatable scalar resources for it
+func newMockedScheduler(t *testing.T) *scheduler_mock.Framework {
+	filterStatus := func(_ context.Context, _ *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) framework.PluginToStatus {
+		requested := framework.NewResource(nil)
+		for _, c := range pod.Spec.Containers {
+			requested.Add(c.Resources.Requests)
+		}
+		for r, q := range requested.ScalarResources {
+			if q > nodeInfo.Allocatable.ScalarResources[r]-nodeInfo.Requested.ScalarResources[r] {
+				return framework.PluginToStatus{"": framework.NewStatus(framework.Unschedulable)}
+			}
+		}
+		return framework.PluginToStatus{"": framework.NewStatus(framework.Success)}
+	}
+	mockedScheduler := scheduler_mock.NewFramework(t)
+	mockedScheduler.On(
+		"RunPreFilterPlugins",
+		mock.Anything,
+		mock.Anything,
+		mock.Anything,
+	).Return(nil, framework.NewStatus(framework.Success)).Maybe()
+	mockedScheduler.On(
+		"RunFilterPlugins",
+		mock.Anything,
+		mock.Anything,
+		mock.Anything,
+		mock.Anything,
+	).Return(filterStatus).Maybe()
+	return mockedScheduler
+}
+
+func unschedulable(pod v1.Pod) v1.Pod {
+	pod.Status.Conditions = []v1.PodCondition{
+		{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: v1.PodReasonUnschedulable},
+	}
+	return pod
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/scheduler/plugins/capacityscheduling"
+	v1 "k8s.io/api/core/v1"
+	schedulerconfig "k8s.io/kubernetes/pkg/scheduler/apis/config"
+	latestschedulerconfig "k8s.io/kubernetes/pkg/scheduler/apis/config/latest"
+	schedulerscheme "k8s.io/kubernetes/pkg/scheduler/apis/config/scheme"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	schedulerplugins "k8s.io/kubernetes/pkg/scheduler/framework/plugins"
+	schedulerruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	"os"
+	// Ensure scheduler package is initialized.
+	_ "github.com/nebuly-ai/nos/pkg/api/scheduler"
+)
+
+// NewFramework returns a scheduler framework configured with the profile provided as argument,
+// in which the nos Capacity Scheduling plugin is registered along with the in-tree plugins.
+func NewFramework(ctx context.Context, profile schedulerconfig.KubeSchedulerProfile, opts ...schedulerruntime.Option) (framework.Framework, error) {
+	var registry = schedulerplugins.NewInTreeRegistry()
+	if err := registry.Register(capacityscheduling.Name, capacityscheduling.New); err != nil {
+		return nil, fmt.Errorf("couldn't register Capacity Scheduling plugin: %v", err)
+	}
+	return schedulerruntime.NewFramework(registry, &profile, ctx.Done(), opts...)
+}
+
+// GetProfile returns the profile defined in the scheduler config file provided as argument.
+// If the file is empty, it returns the profile of the default scheduler.
+func GetProfile(schedulerConfigFile string) (schedulerconfig.KubeSchedulerProfile, error) {
+	// If scheduler config is not provided, use default scheduler config
+	if schedulerConfigFile == "" {
+		defaultSchedulerConfig, err := latestschedulerconfig.Default()
+		if err != nil {
+			return schedulerconfig.KubeSchedulerProfile{}, fmt.Errorf("couldn't create scheduler config: %v", err)
+		}
+		if len(defaultSchedulerConfig.Profiles) != 1 || defaultSchedulerConfig.Profiles[0].SchedulerName != v1.DefaultSchedulerName {
+			return schedulerconfig.KubeSchedulerProfile{}, fmt.Errorf(
+				"unexpected scheduler config: expected default scheduler profile only (found %d profiles)",
+				len(defaultSchedulerConfig.Profiles),
+			)
+		}
+		return defaultSchedulerConfig.Profiles[0], nil
+	}
+
+	// Otherwise, use the provided scheduler config
+	schedulerConfig, err := loadConfigFromFile(schedulerConfigFile)
+	if err != nil {
+		return schedulerconfig.KubeSchedulerProfile{}, fmt.Errorf(
+			"couldn't load scheduler config: %v",
+			err,
+		)
+	}
+	return schedulerConfig.Profiles[0], nil
+}
+
+// DisablePlugin removes the plugin from all the extension points of the profile,
+// and returns true if the plugin was enabled in any of them.
+func DisablePlugin(profile *schedulerconfig.KubeSchedulerProfile, pluginName string) bool {
+	if profile.Plugins == nil {
+		return false
+	}
+	var found bool
+	plugins := profile.Plugins
+	for _, pluginSet := range []*schedulerconfig.PluginSet{
+		&plugins.QueueSort,
+		&plugins.PreFilter,
+		&plugins.Filter,
+		&plugins.PostFilter,
+		&plugins.PreScore,
+		&plugins.Score,
+		&plugins.Reserve,
+		&plugins.Permit,
+		&plugins.PreBind,
+		&plugins.Bind,
+		&plugins.PostBind,
+		&plugins.MultiPoint,
+	} {
+		enabled := make([]schedulerconfig.Plugin, 0, len(pluginSet.Enabled))
+		for _, p := range pluginSet.Enabled {
+			if p.Name == pluginName {
+				found = true
+				continue
+			}
+			enabled = append(enabled, p)
+		}
+		pluginSet.Enabled = enabled
+	}
+
+	pluginConfig := make([]schedulerconfig.PluginConfig, 0, len(profile.PluginConfig))
+	for _, c := range profile.PluginConfig {
+		if c.Name != pluginName {
+			pluginConfig = append(pluginConfig, c)
+		}
+	}
+	profile.PluginConfig = pluginConfig
+
+	return found
+}
+
+func loadConfigFromFile(file string) (*schedulerconfig.KubeSchedulerConfiguration, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConfig(data)
+}
+
+func decodeConfig(data []byte) (*schedulerconfig.KubeSchedulerConfiguration, error) {
+	// The UniversalDecoder runs defaulting and returns the internal type by default.
+	obj, gvk, err := schedulerscheme.Codecs.UniversalDecoder().Decode(data, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cfgObj, ok := obj.(*schedulerconfig.KubeSchedulerConfiguration); ok {
+		return cfgObj, nil
+	}
+	return nil, fmt.Errorf("couldn't decode as KubeSchedulerConfiguration, got %s: ", gvk)
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"github.com/nebuly-ai/nos/pkg/scheduler/plugins/capacityscheduling"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	schedulerconfig "k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSchedulerConfig = `
+apiVersion: kubescheduler.config.k8s.io/v1beta3
+kind: KubeSchedulerConfiguration
+profiles:
+- schedulerName: nos-scheduler
+  plugins:
+    preFilter:
+      enabled:
+        - name: CapacityScheduling
+    postFilter:
+      enabled:
+        - name: CapacityScheduling
+      disabled:
+        - name: "*"
+    reserve:
+      enabled:
+        - name: CapacityScheduling
+  pluginConfig:
+    - name: CapacityScheduling
+      args:
+        nvidiaGpuResourceMemoryGB: 32
+`
+
+func TestGetProfile(t *testing.T) {
+	t.Run("Empty config file, should return default profile", func(t *testing.T) {
+		profile, err := GetProfile("")
+		assert.NoError(t, err)
+		assert.Equal(t, v1.DefaultSchedulerName, profile.SchedulerName)
+	})
+
+	t.Run("Config file does not exist, should return error", func(t *testing.T) {
+		_, err := GetProfile(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.Error(t, err)
+	})
+
+	t.Run("Config file exists, should return its first profile", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "scheduler_config.yaml")
+		assert.NoError(t, os.WriteFile(file, []byte(testSchedulerConfig), 0600))
+		profile, err := GetProfile(file)
+		assert.NoError(t, err)
+		assert.Equal(t, "nos-scheduler", profile.SchedulerName)
+		assert.Contains(t, profile.Plugins.PreFilter.Enabled, schedulerconfig.Plugin{Name: capacityscheduling.Name})
+	})
+}
+
+func TestDisablePlugin(t *testing.T) {
+	t.Run("Plugin not enabled", func(t *testing.T) {
+		profile, err := GetProfile("")
+		assert.NoError(t, err)
+		assert.False(t, DisablePlugin(&profile, capacityscheduling.Name))
+	})
+
+	t.Run("Plugin enabled, should be removed from all extension points and plugin configs", func(t *testing.T) {
+		config, err := decodeConfig([]byte(testSchedulerConfig))
+		assert.NoError(t, err)
+		profile := config.Profiles[0]
+
+		assert.True(t, DisablePlugin(&profile, capacityscheduling.Name))
+		for _, pluginSet := range []schedulerconfig.PluginSet{
+			profile.Plugins.PreFilter,
+			profile.Plugins.PostFilter,
+			profile.Plugins.Reserve,
+			profile.Plugins.MultiPoint,
+		} {
+			assert.NotContains(t, pluginSet.Enabled, schedulerconfig.Plugin{Name: capacityscheduling.Name})
+		}
+		for _, c := range profile.PluginConfig {
+			assert.NotEqual(t, capacityscheduling.Name, c.Name)
+		}
+	})
+}
@@ -18,22 +18,84 @@ package v1alpha1
 
 import (
 	"errors"
+	"fmt"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
 	"time"
 )
 
+// PlannerKind is the kind of algorithm used by the GPU partitioner for computing partitioning plans
+type PlannerKind string
+
+const (
+	// PlannerKindGreedy computes plans by considering one node at a time
+	PlannerKindGreedy PlannerKind = "greedy"
+	// PlannerKindOptimal computes plans by jointly searching across all nodes and GPUs,
+	// falling back to the greedy plan if the search does not complete within the configured time budget
+	PlannerKindOptimal PlannerKind = "optimal"
+)
+
+// PodSorterKind is the strategy used by the GPU partitioner for deciding which pending pods
+// get GPU slices first. Regardless of the strategy, pods with higher priority always come first:
+// the strategy only defines the order of the pods with the same priority.
+type PodSorterKind string
+
+const (
+	// PodSorterKindPriority places first the pods requesting the smallest GPU slices,
+	// in order to maximize the number of pods that can be scheduled
+	PodSorterKindPriority PodSorterKind = "priority"
+	// PodSorterKindFifo places first the pods created first
+	PodSorterKindFifo PodSorterKind = "fifo"
+	// PodSorterKindLargestFirst places first the pods requesting the largest GPU slices,
+	// which reduces the fragmentation of the GPUs
+	PodSorterKindLargestFirst PodSorterKind = "largest-first"
+	// PodSorterKindFairShare interleaves the pods of different ElasticQuotas, giving precedence
+	// to the quotas that requested the least GPU memory so far
+	PodSorterKindFairShare PodSorterKind = "fair-share"
+	// PodSorterKindGang sorts pods as PodSorterKindPriority, but it keeps together
+	// the pods belonging to the same pod group
+	PodSorterKindGang PodSorterKind = "gang"
+)
+
+// PlanApprovalPolicy defines which partitioning plans require a manual approval before being applied
+type PlanApprovalPolicy string
+
+const (
+	// PlanApprovalPolicyNone applies every plan right away, without requiring any approval
+	PlanApprovalPolicyNone PlanApprovalPolicy = "none"
+	// PlanApprovalPolicyDisruptive requires approval only for the plans that delete
+	// any GPU resource currently exposed by the nodes
+	PlanApprovalPolicyDisruptive PlanApprovalPolicy = "disruptive"
+	// PlanApprovalPolicyAll requires approval for every plan
+	PlanApprovalPolicyAll PlanApprovalPolicy = "all"
+)
+
+// DefaultPlanHistoryLimit is the number of terminated partitioning plans kept for each
+// partitioning kind when the history limit is not specified
+const DefaultPlanHistoryLimit = 10
+
 // +kubebuilder:object:root=true
 
 type GpuPartitionerConfig struct {
 	metav1.TypeMeta                        `json:",inline"`
 	cfg.ControllerManagerConfigurationSpec `json:",inline"`
-	SchedulerConfigFile                    string           `json:"schedulerConfigFile,omitempty"`
-	KnownMigGeometriesFile                 string           `json:"knownMigGeometriesFile,omitempty"`
-	BatchWindowTimeoutSeconds              time.Duration    `json:"batchWindowTimeoutSeconds"`
-	BatchWindowIdleSeconds                 time.Duration    `json:"batchWindowIdleSeconds"`
-	DevicePluginConfigMap                  NamespacedObject `json:"devicePluginConfigMap,omitempty"`
-	DevicePluginDelaySeconds               time.Duration    `json:"devicePluginDelaySeconds"`
+	SchedulerConfigFile                    string             `json:"schedulerConfigFile,omitempty"`
+	KnownMigGeometriesFile                 string             `json:"knownMigGeometriesFile,omitempty"`
+	BatchWindowTimeoutSeconds              time.Duration      `json:"batchWindowTimeoutSeconds"`
+	BatchWindowIdleSeconds                 time.Duration      `json:"batchWindowIdleSeconds"`
+	DevicePluginConfigMap                  NamespacedObject   `json:"devicePluginConfigMap,omitempty"`
+	DevicePluginDelaySeconds               time.Duration      `json:"devicePluginDelaySeconds"`
+	Planner                                PlannerKind        `json:"planner,omitempty"`
+	OptimalPlannerTimeoutSeconds           time.Duration      `json:"optimalPlannerTimeoutSeconds,omitempty"`
+	DryRun                                 bool               `json:"dryRun,omitempty"`
+	PlanApprovalPolicy                     PlanApprovalPolicy `json:"planApprovalPolicy,omitempty"`
+	PlanHistoryLimit                       int                `json:"planHistoryLimit,omitempty"`
+	PlanApprovalTimeoutSeconds             time.Duration      `json:"planApprovalTimeoutSeconds,omitempty"`
+	MigCompactionEnabled                   bool               `json:"migCompactionEnabled,omitempty"`
+	MigCompactionIntervalSeconds           time.Duration      `json:"migCompactionIntervalSeconds,omitempty"`
+	MigCompactionQuietPeriodSeconds        time.Duration      `json:"migCompactionQuietPeriodSeconds,omitempty"`
+	PreemptionEnabled                      bool               `json:"preemptionEnabled,omitempty"`
+	PodSorter                              PodSorterKind      `json:"podSorter,omitempty"`
 }
 
 func (c *GpuPartitionerConfig) Validate() error {
@@ -46,9 +108,65 @@ func (c *GpuPartitionerConfig) Validate() error {
 	if c.DevicePluginDelaySeconds.Seconds() <= 0 {
 		return errors.New("devicePluginDelaySeconds must be greater than 0")
 	}
+	switch c.Planner {
+	case "", PlannerKindGreedy:
+	case PlannerKindOptimal:
+		if c.OptimalPlannerTimeoutSeconds.Seconds() <= 0 {
+			return errors.New("optimalPlannerTimeoutSeconds must be greater than 0 when using the optimal planner")
+		}
+	default:
+		return fmt.Errorf("invalid planner %q, allowed values are %q and %q", c.Planner, PlannerKindGreedy, PlannerKindOptimal)
+	}
+	switch c.PodSorter {
+	case "", PodSorterKindPriority, PodSorterKindFifo, PodSorterKindLargestFirst, PodSorterKindFairShare, PodSorterKindGang:
+	default:
+		return fmt.Errorf(
+			"invalid podSorter %q, allowed values are %q, %q, %q, %q and %q",
+			c.PodSorter,
+			PodSorterKindPriority,
+			PodSorterKindFifo,
+			PodSorterKindLargestFirst,
+			PodSorterKindFairShare,
+			PodSorterKindGang,
+		)
+	}
+	switch c.PlanApprovalPolicy {
+	case "", PlanApprovalPolicyNone, PlanApprovalPolicyDisruptive, PlanApprovalPolicyAll:
+	default:
+		return fmt.Errorf(
+			"invalid planApprovalPolicy %q, allowed values are %q, %q and %q",
+			c.PlanApprovalPolicy,
+			PlanApprovalPolicyNone,
+			PlanApprovalPolicyDisruptive,
+			PlanApprovalPolicyAll,
+		)
+	}
+	if c.PlanHistoryLimit < 0 {
+		return errors.New("planHistoryLimit must be greater than or equal to 0")
+	}
+	if c.PlanApprovalTimeoutSeconds.Seconds() < 0 {
+		return errors.New("planApprovalTimeoutSeconds must be greater than or equal to 0")
+	}
+	if c.MigCompactionEnabled {
+		if c.MigCompactionIntervalSeconds.Seconds() <= 0 {
+			return errors.New("migCompactionIntervalSeconds must be greater than 0 when MIG compaction is enabled")
+		}
+		if c.MigCompactionQuietPeriodSeconds.Seconds() < 0 {
+			return errors.New("migCompactionQuietPeriodSeconds must be greater than or equal to 0")
+		}
+	}
 	return nil
 }
 
+// GetPlanHistoryLimit returns the number of terminated partitioning plans to keep
+// for each partitioning kind
+func (c *GpuPartitionerConfig) GetPlanHistoryLimit() int {
+	if c.PlanHistoryLimit == 0 {
+		return DefaultPlanHistoryLimit
+	}
+	return c.PlanHistoryLimit
+}
+
 type NamespacedObject struct {
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
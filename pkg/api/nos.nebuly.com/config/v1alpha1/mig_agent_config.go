@@ -28,4 +28,7 @@ type MigAgentConfig struct {
 	metav1.TypeMeta                        `json:",inline"`
 	cfg.ControllerManagerConfigurationSpec `json:",inline"`
 	ReportConfigIntervalSeconds            time.Duration `json:"reportConfigIntervalSeconds"`
+	// DiscoverAllowedMigGeometries enables the discovery of the MIG geometries allowed by the GPUs of the node,
+	// which are exposed to the GPU Partitioner through node annotations
+	DiscoverAllowedMigGeometries bool `json:"discoverAllowedMigGeometries,omitempty"`
 }
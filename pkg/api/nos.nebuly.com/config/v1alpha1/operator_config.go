@@ -26,5 +26,8 @@ import (
 type OperatorConfig struct {
 	metav1.TypeMeta                        `json:",inline"`
 	cfg.ControllerManagerConfigurationSpec `json:",inline"`
-	NvidiaGpuResourceMemoryGB              int64 `json:"NvidiaGpuResourceMemoryGB"`
+	NvidiaGpuResourceMemoryGB              int64            `json:"NvidiaGpuResourceMemoryGB"`
+	NvidiaGpuModelsMemoryGB                map[string]int64 `json:"nvidiaGpuModelsMemoryGB,omitempty"`
+	UsageReportBindAddress                 string           `json:"usageReportBindAddress,omitempty"`
+	RejectOvercommittingQuotas             bool             `json:"rejectOvercommittingQuotas,omitempty"`
 }
@@ -121,6 +121,13 @@ func (in *OperatorConfig) DeepCopyInto(out *OperatorConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ControllerManagerConfigurationSpec.DeepCopyInto(&out.ControllerManagerConfigurationSpec)
+	if in.NvidiaGpuModelsMemoryGB != nil {
+		in, out := &in.NvidiaGpuModelsMemoryGB, &out.NvidiaGpuModelsMemoryGB
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfig.
@@ -26,6 +26,16 @@ const (
 	AnnotationPartitioningPlan = "nos.nebuly.com/spec-partitioning-plan"
 	// AnnotationReportedPartitioningPlan indicates the last partitioning plan reported by the node.
 	AnnotationReportedPartitioningPlan = "nos.nebuly.com/status-partitioning-plan"
+	// AnnotationAllowedMigGeometries contains the MIG geometries allowed by each GPU of the node, as discovered
+	// by the MIG agent. The value is a JSON object mapping each GPU index to its list of allowed geometries.
+	AnnotationAllowedMigGeometries = "nos.nebuly.com/status-allowed-mig-geometries"
+	// AnnotationMigEnabledGpus contains the comma-separated indexes of the GPUs of the node that have MIG mode enabled,
+	// as reported by the MIG agent.
+	AnnotationMigEnabledGpus = "nos.nebuly.com/status-mig-enabled-gpus"
+	// AnnotationMigPartitioningFailure describes the last MIG partitioning that the MIG agent could not apply
+	// to the node. The value is a JSON object containing the ID of the plan, the geometry that could not be
+	// applied to each GPU and whether the previous geometry of the GPUs was restored.
+	AnnotationMigPartitioningFailure = "nos.nebuly.com/status-mig-partitioning-failure"
 )
 
 // AnnotationGpuStatusFormat is the format of the annotation used to expose the profiles the GPUs of a node
@@ -34,6 +44,9 @@ const (
 //
 //	"nos.nebuly.com/status-gpu-<gpu-index>-<profile>"
 //
+// Since annotation keys cannot contain the character "+", the attributes of the profile are
+// separated with "_" (e.g. 1g.10gb+me becomes 1g.10gb_me).
+//
 // Example:
 //
 //	"nos.nebuly.com/status-gpu-0-1g.10gb-free"
@@ -49,6 +62,8 @@ var AnnotationGpuStatusFormat = fmt.Sprintf(
 //
 //	"nos.nebuly.com/spec-gpu-<gpu-index>-<profile>"
 //
+// Profile attributes are separated with "_", as in AnnotationGpuStatusFormat.
+//
 // Example:
 //
 //	"nos.nebuly.com/spec-gpu-0-1g.10gb"
@@ -73,6 +73,25 @@ func (e *compositeEqBuilder) WithMaxCPUMilli(cpuMilli int64) *compositeEqBuilder
 	return e
 }
 
+func (e *compositeEqBuilder) WithParent(kind, namespace, name string) *compositeEqBuilder {
+	e.CompositeElasticQuota.Spec.Parent = &ElasticQuotaReference{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+	}
+	return e
+}
+
+func (e *compositeEqBuilder) WithWeight(weight int32) *compositeEqBuilder {
+	e.CompositeElasticQuota.Spec.Weight = &weight
+	return e
+}
+
+func (e *compositeEqBuilder) WithTimeWindows(timeWindows ...ElasticQuotaTimeWindow) *compositeEqBuilder {
+	e.CompositeElasticQuota.Spec.TimeWindows = timeWindows
+	return e
+}
+
 func (e *compositeEqBuilder) Get() CompositeElasticQuota {
 	return e.CompositeElasticQuota
 }
@@ -80,7 +99,7 @@ func (e *compositeEqBuilder) Get() CompositeElasticQuota {
 func BuildCompositeEq(namespace, name string) *compositeEqBuilder {
 	eq := CompositeElasticQuota{
 		TypeMeta: metav1.TypeMeta{
-			Kind:       "CompositeElasticQuota",
+			Kind:       KindCompositeElasticQuota,
 			APIVersion: GroupVersion.String(),
 		},
 		ObjectMeta: metav1.ObjectMeta{
@@ -48,11 +48,38 @@ type CompositeElasticQuotaSpec struct {
 	// Max is the set of desired max limits for each named resource. The usage of max is based on the resource configurations of
 	// successfully scheduled pods.
 	Max v1.ResourceList `json:"max,omitempty" protobuf:"bytes,2,rep,name=max, casttype=ResourceList,castkey=ResourceName"`
+
+	// Parent is the optional quota from which this quota borrows resources before borrowing them from the
+	// rest of the cluster. The Min and Max of the parent are enforced on the total usage of its children.
+	Parent *ElasticQuotaReference `json:"parent,omitempty" protobuf:"bytes,3,opt,name=parent"`
+
+	// Weight is the optional weight used for sharing the unused Min of the other quotas.
+	// If none of the quotas sharing the same parent specifies a weight, the unused Min is shared in
+	// proportion to their Min, otherwise it is shared in proportion to their weights, considering
+	// a weight of 1 for the quotas that do not specify it.
+	//+kubebuilder:validation:Minimum=1
+	Weight *int32 `json:"weight,omitempty" protobuf:"varint,4,opt,name=weight"`
+
+	// TimeWindows is the optional list of recurring time windows overriding Min and Max.
+	// If multiple time windows are active at the same time, the first one of the list takes precedence.
+	TimeWindows []ElasticQuotaTimeWindow `json:"timeWindows,omitempty" protobuf:"bytes,5,rep,name=timeWindows"`
 }
 
 type CompositeElasticQuotaStatus struct {
 	// Used is the current observed total usage of the resource in the namespace.
 	Used v1.ResourceList `json:"used,omitempty" protobuf:"bytes,1,rep,name=used,casttype=ResourceList,castkey=ResourceName"`
+
+	// Usage is the cumulative consumption of GPU resources of the pods subject to the quota,
+	// recorded per namespace and per day.
+	Usage []ElasticQuotaUsageRecord `json:"usage,omitempty" protobuf:"bytes,2,rep,name=usage"`
+
+	// LastAccountingTime is the last time the consumption of the running pods was added to Usage.
+	LastAccountingTime *metav1.Time `json:"lastAccountingTime,omitempty" protobuf:"bytes,3,opt,name=lastAccountingTime"`
+
+	// Conditions are the latest observations of the state of the quota.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" protobuf:"bytes,4,rep,name=conditions"`
 }
 
 //+kubebuilder:object:root=true
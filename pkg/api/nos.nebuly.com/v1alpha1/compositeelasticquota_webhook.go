@@ -31,29 +31,51 @@ import (
 // log is for logging in this package.
 var ceqLog = logf.Log.WithName("ceq-resource")
 
-func (r *CompositeElasticQuota) SetupWebhookWithManager(mgr ctrl.Manager) error {
+func (r *CompositeElasticQuota) SetupWebhookWithManager(
+	mgr ctrl.Manager,
+	nvidiaGpuResourceMemoryGB int64,
+	checker OvercommitChecker,
+) error {
 	if client == nil {
 		client = mgr.GetClient()
 	}
+	nvidiaGPUResourceMemoryGB = nvidiaGpuResourceMemoryGB
+	overcommitChecker = checker
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
 }
 
-//+kubebuilder:webhook:path=/validate-nos-nebuly-ai-v1alpha1-compositeelasticquota,mutating=false,failurePolicy=fail,sideEffects=None,groups=nos.nebuly.com,resources=compositeelasticquotas,verbs=create;update,versions=v1alpha1,name=vcompositeelasticquota.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/mutate-nos-nebuly-com-v1alpha1-compositeelasticquota,mutating=true,failurePolicy=fail,sideEffects=None,groups=nos.nebuly.com,resources=compositeelasticquotas,verbs=create;update,versions=v1alpha1,name=mcompositeelasticquota.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &CompositeElasticQuota{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *CompositeElasticQuota) Default() {
+	ceqLog.V(1).Info("default", "name", r.Name)
+	defaultQuotaLimits(&r.Spec.Min, &r.Spec.Max, r.Spec.TimeWindows)
+}
+
+//+kubebuilder:webhook:path=/validate-nos-nebuly-com-v1alpha1-compositeelasticquota,mutating=false,failurePolicy=fail,sideEffects=None,groups=nos.nebuly.com,resources=compositeelasticquotas,verbs=create;update,versions=v1alpha1,name=vcompositeelasticquota.kb.io,admissionReviewVersions=v1
 
 var _ webhook.Validator = &CompositeElasticQuota{}
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (r *CompositeElasticQuota) ValidateCreate() error {
 	ceqLog.V(1).Info("validate create", "name", r.Name)
-	return validateCompositeElasticQuotaNamespaces(r)
+	if err := r.validate(); err != nil {
+		return err
+	}
+	return validateOvercommit(r)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *CompositeElasticQuota) ValidateUpdate(old runtime.Object) error {
 	ceqLog.V(1).Info("validate update", "name", r.Name)
-	return validateCompositeElasticQuotaNamespaces(r)
+	if err := r.validate(); err != nil {
+		return err
+	}
+	return validateOvercommit(r)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -61,12 +83,31 @@ func (r *CompositeElasticQuota) ValidateDelete() error {
 	return nil
 }
 
+func (r *CompositeElasticQuota) validate() error {
+	if client == nil {
+		err := fmt.Errorf(constant.InternalErrorMsg)
+		ceqLog.Error(err, "client was not initialized correctly")
+		return err
+	}
+	if err := validateCompositeElasticQuotaNamespaces(r); err != nil {
+		return err
+	}
+	return validateQuotaSpec(
+		ElasticQuotaReference{Kind: KindCompositeElasticQuota, Namespace: r.Namespace, Name: r.Name},
+		r.Spec.Min,
+		r.Spec.Max,
+		r.Spec.TimeWindows,
+		r.Spec.Weight,
+		r.Spec.Parent,
+	)
+}
+
 // validateCompositeElasticQuotaNamespaces checks if the specified namespaces are subject to
-// any other CompositeElasticQuota: if so it returns an error
+// any other CompositeElasticQuota or to an ElasticQuota: if so it returns an error
 func validateCompositeElasticQuotaNamespaces(instance *CompositeElasticQuota) error {
 	var ceqList CompositeElasticQuotaList
 	if err := client.List(context.Background(), &ceqList); err != nil {
-		eqlog.Error(err, "unable to list composite elastic quotas")
+		ceqLog.Error(err, "unable to list composite elastic quotas")
 		return fmt.Errorf(constant.InternalErrorMsg)
 	}
 	for _, ceq := range ceqList.Items {
@@ -85,5 +126,22 @@ func validateCompositeElasticQuotaNamespaces(instance *CompositeElasticQuota) er
 			}
 		}
 	}
+
+	for _, ns := range instance.Spec.Namespaces {
+		var eqList ElasticQuotaList
+		if err := client.List(context.Background(), &eqList, InNamespace(ns)); err != nil {
+			ceqLog.Error(err, "unable to list elastic quotas")
+			return fmt.Errorf(constant.InternalErrorMsg)
+		}
+		if len(eqList.Items) > 0 {
+			return fmt.Errorf(
+				"namespace %q is already subject to ElasticQuota \"%s/%s\"",
+				ns,
+				eqList.Items[0].Namespace,
+				eqList.Items[0].Name,
+			)
+		}
+	}
+
 	return nil
 }
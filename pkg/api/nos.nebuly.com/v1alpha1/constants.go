@@ -25,3 +25,21 @@ const (
 	// ResourceGPUMemory is the name of the custom resource used by nos for specifying GPU memory GigaBytes
 	ResourceGPUMemory v1.ResourceName = "nos.nebuly.com/gpu-memory"
 )
+
+// Kinds
+const (
+	KindElasticQuota          = "ElasticQuota"
+	KindCompositeElasticQuota = "CompositeElasticQuota"
+)
+
+// Conditions
+const (
+	// ConditionTypeOvercommitted is the type of the condition of the quotas reporting whether the total Min
+	// of the quotas exceeds the capacity of the cluster for any of the resources of the Min of the quota
+	ConditionTypeOvercommitted = "Overcommitted"
+
+	// ReasonMinExceedsCapacity is the reason of the Overcommitted condition when the total Min exceeds the capacity
+	ReasonMinExceedsCapacity = "MinExceedsCapacity"
+	// ReasonMinWithinCapacity is the reason of the Overcommitted condition when the total Min fits the capacity
+	ReasonMinWithinCapacity = "MinWithinCapacity"
+)
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import (
+	"github.com/nebuly-ai/nos/pkg/constant"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// defaultQuotaLimits normalizes the resources of the limits provided as argument and of the limits of
+// the time windows, and defaults their max
+func defaultQuotaLimits(min, max *v1.ResourceList, timeWindows []ElasticQuotaTimeWindow) {
+	*min = normalizeGPUResources(*min)
+	*max = defaultMax(*min, normalizeGPUResources(*max))
+	for i := range timeWindows {
+		w := &timeWindows[i]
+		w.Min = normalizeGPUResources(w.Min)
+		w.Max = normalizeGPUResources(w.Max)
+		if w.Min != nil {
+			w.Max = defaultMax(w.Min, w.Max)
+		}
+	}
+}
+
+// normalizeGPUResources returns the resource list provided as argument with the nvidia.com/gpu resource
+// converted to the equivalent amount of nos.nebuly.com/gpu-memory, which is added to the GPU memory
+// already specified by the list
+func normalizeGPUResources(resources v1.ResourceList) v1.ResourceList {
+	gpu, ok := resources[constant.ResourceNvidiaGPU]
+	if !ok || nvidiaGPUResourceMemoryGB <= 0 {
+		return resources
+	}
+	res := resources.DeepCopy()
+	delete(res, constant.ResourceNvidiaGPU)
+	gpuMemory := res[ResourceGPUMemory]
+	gpuMemory.Add(*resource.NewQuantity(gpu.Value()*nvidiaGPUResourceMemoryGB, resource.DecimalSI))
+	res[ResourceGPUMemory] = gpuMemory
+	return res
+}
+
+// defaultMax returns the max provided as argument with the resources specified by min but not by max
+// set to the respective value of min. If max is nil, the quota has no max and nil is returned.
+//
+// Without defaulting, the resources not specified by the max would be either unlimited or, for CPU and
+// memory, limited to zero, so a quota might not be able to use even its min.
+func defaultMax(min, max v1.ResourceList) v1.ResourceList {
+	if max == nil {
+		return nil
+	}
+	res := max.DeepCopy()
+	for r, quantity := range min {
+		if _, ok := res[r]; !ok {
+			res[r] = quantity.DeepCopy()
+		}
+	}
+	return res
+}
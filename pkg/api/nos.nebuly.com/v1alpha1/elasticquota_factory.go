@@ -68,6 +68,25 @@ func (e *eqBuilder) WithMaxCPUMilli(cpuMilli int64) *eqBuilder {
 	return e
 }
 
+func (e *eqBuilder) WithParent(kind, namespace, name string) *eqBuilder {
+	e.ElasticQuota.Spec.Parent = &ElasticQuotaReference{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+	}
+	return e
+}
+
+func (e *eqBuilder) WithWeight(weight int32) *eqBuilder {
+	e.ElasticQuota.Spec.Weight = &weight
+	return e
+}
+
+func (e *eqBuilder) WithTimeWindows(timeWindows ...ElasticQuotaTimeWindow) *eqBuilder {
+	e.ElasticQuota.Spec.TimeWindows = timeWindows
+	return e
+}
+
 func (e *eqBuilder) Get() ElasticQuota {
 	return e.ElasticQuota
 }
@@ -75,7 +94,7 @@ func (e *eqBuilder) Get() ElasticQuota {
 func BuildEq(namespace, name string) *eqBuilder {
 	eq := ElasticQuota{
 		TypeMeta: metav1.TypeMeta{
-			Kind:       "ElasticQuota",
+			Kind:       KindElasticQuota,
 			APIVersion: GroupVersion.String(),
 		},
 		ObjectMeta: metav1.ObjectMeta{
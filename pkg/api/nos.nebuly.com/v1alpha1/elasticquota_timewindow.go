@@ -0,0 +1,174 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import (
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/util/cron"
+	v1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"time"
+)
+
+// ElasticQuotaLimits are the Min and Max of a quota in effect at a given time
+// +kubebuilder:object:generate=false
+type ElasticQuotaLimits struct {
+	Min v1.ResourceList
+	Max v1.ResourceList
+	// ActiveTimeWindow is the name of the time window defining the limits, or empty if
+	// the limits are the ones defined by the quota spec
+	ActiveTimeWindow string
+	// NextTransition is the time at which the limits should be computed again since a time window
+	// may start or end, or the zero time if the quota has no time windows
+	NextTransition time.Time
+}
+
+// Validate returns an error if the schedule, the time zone or the duration of the time window are not valid
+func (w ElasticQuotaTimeWindow) Validate() error {
+	if _, _, err := w.parse(); err != nil {
+		return err
+	}
+	if w.Duration.Duration <= 0 {
+		return fmt.Errorf("time window %q: duration must be greater than zero", w.Name)
+	}
+	return nil
+}
+
+// IsActive returns true if the time window is active at the time provided as argument
+func (w ElasticQuotaTimeWindow) IsActive(t time.Time) (bool, error) {
+	start, err := w.activeStart(t)
+	if err != nil {
+		return false, err
+	}
+	return !start.IsZero(), nil
+}
+
+// NextTransition returns the first time after t at which the time window may start or end.
+// If the time window never starts, it returns the zero time.
+func (w ElasticQuotaTimeWindow) NextTransition(t time.Time) (time.Time, error) {
+	start, err := w.activeStart(t)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !start.IsZero() {
+		return start.Add(w.Duration.Duration), nil
+	}
+	schedule, loc, err := w.parse()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return schedule.Next(t.In(loc)), nil
+}
+
+// activeStart returns the start of the earliest occurrence of the time window that is active
+// at time t, or the zero time if the time window is not active at time t
+func (w ElasticQuotaTimeWindow) activeStart(t time.Time) (time.Time, error) {
+	schedule, loc, err := w.parse()
+	if err != nil {
+		return time.Time{}, err
+	}
+	start := schedule.Next(t.In(loc).Add(-w.Duration.Duration))
+	if start.IsZero() || start.After(t) {
+		return time.Time{}, nil
+	}
+	return start, nil
+}
+
+func (w ElasticQuotaTimeWindow) parse() (cron.Schedule, *time.Location, error) {
+	schedule, err := cron.Parse(w.Schedule)
+	if err != nil {
+		return cron.Schedule{}, nil, fmt.Errorf("time window %q: %w", w.Name, err)
+	}
+	loc := time.UTC
+	if w.TimeZone != "" {
+		if loc, err = time.LoadLocation(w.TimeZone); err != nil {
+			return cron.Schedule{}, nil, fmt.Errorf("time window %q: invalid time zone: %w", w.Name, err)
+		}
+	}
+	return schedule, loc, nil
+}
+
+// GetLimits returns the Min and Max of the ElasticQuota in effect at the time provided as argument
+func (s ElasticQuotaSpec) GetLimits(t time.Time) (ElasticQuotaLimits, error) {
+	return getLimits(s.Min, s.Max, s.TimeWindows, t)
+}
+
+// GetLimits returns the Min and Max of the CompositeElasticQuota in effect at the time provided as argument
+func (s CompositeElasticQuotaSpec) GetLimits(t time.Time) (ElasticQuotaLimits, error) {
+	return getLimits(s.Min, s.Max, s.TimeWindows, t)
+}
+
+// GetLargestMin returns, for each resource, the largest Min that the ElasticQuota can have in effect
+// considering its time windows
+func (s ElasticQuotaSpec) GetLargestMin() v1.ResourceList {
+	return getLargestMin(s.Min, s.TimeWindows)
+}
+
+// GetLargestMin returns, for each resource, the largest Min that the CompositeElasticQuota can have in effect
+// considering its time windows
+func (s CompositeElasticQuotaSpec) GetLargestMin() v1.ResourceList {
+	return getLargestMin(s.Min, s.TimeWindows)
+}
+
+func getLargestMin(min v1.ResourceList, timeWindows []ElasticQuotaTimeWindow) v1.ResourceList {
+	var res = make(v1.ResourceList, len(min))
+	for r, quantity := range min {
+		res[r] = quantity.DeepCopy()
+	}
+	for _, w := range timeWindows {
+		for r, quantity := range w.Min {
+			if current, ok := res[r]; !ok || quantity.Cmp(current) > 0 {
+				res[r] = quantity.DeepCopy()
+			}
+		}
+	}
+	return res
+}
+
+// getLimits returns the limits in effect at time t, where the Min and Max of the first active time window
+// override the ones provided as argument.
+//
+// Time windows that are not valid are ignored: the returned error reports them, but the
+// returned limits are computed from the valid ones anyway.
+func getLimits(min, max v1.ResourceList, timeWindows []ElasticQuotaTimeWindow, t time.Time) (ElasticQuotaLimits, error) {
+	var res = ElasticQuotaLimits{Min: min, Max: max}
+	var errs []error
+	for _, w := range timeWindows {
+		next, err := w.NextTransition(t)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !next.IsZero() && (res.NextTransition.IsZero() || next.Before(res.NextTransition)) {
+			res.NextTransition = next
+		}
+		if res.ActiveTimeWindow != "" {
+			continue
+		}
+		if active, _ := w.IsActive(t); !active {
+			continue
+		}
+		res.ActiveTimeWindow = w.Name
+		if w.Min != nil {
+			res.Min = w.Min
+		}
+		if w.Max != nil {
+			res.Max = w.Max
+		}
+	}
+	return res, utilerrors.NewAggregate(errs)
+}
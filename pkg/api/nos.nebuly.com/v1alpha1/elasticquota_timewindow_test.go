@@ -0,0 +1,230 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1_test
+
+import (
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestElasticQuotaTimeWindow_IsActive(t *testing.T) {
+	businessHours := v1alpha1.ElasticQuotaTimeWindow{
+		Name:     "business-hours",
+		Schedule: "0 8 * * mon-fri",
+		Duration: metav1.Duration{Duration: 10 * time.Hour},
+	}
+	testCases := []struct {
+		name     string
+		window   v1alpha1.ElasticQuotaTimeWindow
+		t        time.Time
+		expected bool
+	}{
+		{
+			name:     "Before start",
+			window:   businessHours,
+			t:        time.Date(2023, 1, 2, 7, 59, 0, 0, time.UTC), // Monday
+			expected: false,
+		},
+		{
+			name:     "At start",
+			window:   businessHours,
+			t:        time.Date(2023, 1, 2, 8, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "Before end",
+			window:   businessHours,
+			t:        time.Date(2023, 1, 2, 17, 59, 59, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "At end",
+			window:   businessHours,
+			t:        time.Date(2023, 1, 2, 18, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+		{
+			name:     "Weekend",
+			window:   businessHours,
+			t:        time.Date(2023, 1, 7, 10, 0, 0, 0, time.UTC), // Saturday
+			expected: false,
+		},
+		{
+			name: "Window spanning midnight",
+			window: v1alpha1.ElasticQuotaTimeWindow{
+				Name:     "night",
+				Schedule: "0 22 * * *",
+				Duration: metav1.Duration{Duration: 8 * time.Hour},
+			},
+			t:        time.Date(2023, 1, 3, 5, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name: "Time zone",
+			window: v1alpha1.ElasticQuotaTimeWindow{
+				Name:     "business-hours",
+				Schedule: "0 8 * * *",
+				Duration: metav1.Duration{Duration: time.Hour},
+				TimeZone: "Asia/Tokyo",
+			},
+			t:        time.Date(2023, 1, 2, 23, 30, 0, 0, time.UTC), // 08:30 in Tokyo
+			expected: true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.window.TimeZone != "" {
+				if _, err := time.LoadLocation(tt.window.TimeZone); err != nil {
+					t.Skipf("time zone database not available: %v", err)
+				}
+			}
+			active, err := tt.window.IsActive(tt.t)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, active)
+		})
+	}
+}
+
+func TestElasticQuotaTimeWindow_Validate(t *testing.T) {
+	valid := v1alpha1.ElasticQuotaTimeWindow{
+		Name:     "w",
+		Schedule: "0 8 * * *",
+		Duration: metav1.Duration{Duration: time.Hour},
+	}
+	assert.NoError(t, valid.Validate())
+
+	invalidSchedule := valid
+	invalidSchedule.Schedule = "0 25 * * *"
+	assert.Error(t, invalidSchedule.Validate())
+
+	invalidTimeZone := valid
+	invalidTimeZone.TimeZone = "Not/A_Zone"
+	assert.Error(t, invalidTimeZone.Validate())
+
+	invalidDuration := valid
+	invalidDuration.Duration = metav1.Duration{}
+	assert.Error(t, invalidDuration.Validate())
+}
+
+func TestElasticQuotaSpec_GetLimits(t *testing.T) {
+	specMin := v1.ResourceList{v1alpha1.ResourceGPUMemory: resource.MustParse("10")}
+	specMax := v1.ResourceList{v1alpha1.ResourceGPUMemory: resource.MustParse("20")}
+	dayMin := v1.ResourceList{v1alpha1.ResourceGPUMemory: resource.MustParse("5")}
+	nightMax := v1.ResourceList{v1alpha1.ResourceGPUMemory: resource.MustParse("40")}
+	spec := v1alpha1.BuildEq("ns-1", "eq").
+		WithMin(specMin).
+		WithMax(specMax).
+		WithTimeWindows(
+			v1alpha1.ElasticQuotaTimeWindow{
+				Name:     "day",
+				Schedule: "0 8 * * *",
+				Duration: metav1.Duration{Duration: 10 * time.Hour},
+				Min:      dayMin,
+			},
+			v1alpha1.ElasticQuotaTimeWindow{
+				Name:     "night",
+				Schedule: "0 20 * * *",
+				Duration: metav1.Duration{Duration: 12 * time.Hour},
+				Max:      nightMax,
+			},
+			v1alpha1.ElasticQuotaTimeWindow{
+				Name:     "invalid",
+				Schedule: "not a schedule",
+				Duration: metav1.Duration{Duration: time.Hour},
+			},
+		).
+		Get().
+		Spec
+
+	// Day window: overrides only min
+	limits, err := spec.GetLimits(time.Date(2023, 1, 2, 9, 0, 0, 0, time.UTC))
+	assert.Error(t, err) // invalid window is reported, but ignored
+	assert.Equal(t, "day", limits.ActiveTimeWindow)
+	assert.Equal(t, dayMin, limits.Min)
+	assert.Equal(t, specMax, limits.Max)
+	assert.Equal(t, time.Date(2023, 1, 2, 18, 0, 0, 0, time.UTC), limits.NextTransition)
+
+	// Between the two windows: limits of the spec
+	limits, _ = spec.GetLimits(time.Date(2023, 1, 2, 19, 0, 0, 0, time.UTC))
+	assert.Equal(t, "", limits.ActiveTimeWindow)
+	assert.Equal(t, specMin, limits.Min)
+	assert.Equal(t, specMax, limits.Max)
+	assert.Equal(t, time.Date(2023, 1, 2, 20, 0, 0, 0, time.UTC), limits.NextTransition)
+
+	// Night window
+	limits, _ = spec.GetLimits(time.Date(2023, 1, 3, 2, 0, 0, 0, time.UTC))
+	assert.Equal(t, "night", limits.ActiveTimeWindow)
+	assert.Equal(t, specMin, limits.Min)
+	assert.Equal(t, nightMax, limits.Max)
+	assert.Equal(t, time.Date(2023, 1, 3, 8, 0, 0, 0, time.UTC), limits.NextTransition)
+
+	// Overlapping windows: the first one takes precedence
+	limits, _ = spec.GetLimits(time.Date(2023, 1, 3, 8, 0, 0, 0, time.UTC))
+	assert.Equal(t, "day", limits.ActiveTimeWindow)
+
+	// No time windows
+	limits, err = v1alpha1.ElasticQuotaSpec{Min: specMin}.GetLimits(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, specMin, limits.Min)
+	assert.True(t, limits.NextTransition.IsZero())
+}
+
+func TestElasticQuotaSpec_GetLargestMin(t *testing.T) {
+	spec := v1alpha1.BuildEq("ns-1", "eq").
+		WithMin(v1.ResourceList{
+			v1alpha1.ResourceGPUMemory: resource.MustParse("10"),
+			v1.ResourceCPU:             resource.MustParse("4"),
+		}).
+		WithTimeWindows(
+			v1alpha1.ElasticQuotaTimeWindow{
+				Name:     "day",
+				Schedule: "0 8 * * *",
+				Duration: metav1.Duration{Duration: 10 * time.Hour},
+				Min: v1.ResourceList{
+					v1alpha1.ResourceGPUMemory: resource.MustParse("30"),
+				},
+			},
+			v1alpha1.ElasticQuotaTimeWindow{
+				Name:     "night",
+				Schedule: "0 20 * * *",
+				Duration: metav1.Duration{Duration: 12 * time.Hour},
+				Min: v1.ResourceList{
+					v1alpha1.ResourceGPUMemory: resource.MustParse("20"),
+					v1.ResourceMemory:          resource.MustParse("1Gi"),
+				},
+			},
+		).
+		Get().
+		Spec
+
+	largestMin := spec.GetLargestMin()
+	assert.Len(t, largestMin, 3)
+	assert.True(t, resource.MustParse("30").Equal(largestMin[v1alpha1.ResourceGPUMemory]))
+	assert.True(t, resource.MustParse("4").Equal(largestMin[v1.ResourceCPU]))
+	assert.True(t, resource.MustParse("1Gi").Equal(largestMin[v1.ResourceMemory]))
+
+	// The Min of the spec is not modified
+	assert.True(t, resource.MustParse("10").Equal(spec.Min[v1alpha1.ResourceGPUMemory]))
+
+	// No time windows
+	assert.Empty(t, v1alpha1.ElasticQuotaSpec{}.GetLargestMin())
+}
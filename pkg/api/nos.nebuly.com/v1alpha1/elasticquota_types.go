@@ -48,12 +48,102 @@ type ElasticQuotaSpec struct {
 	// Max is the set of desired max limits for each named resource. The usage of max is based on the resource configurations of
 	// successfully scheduled pods.
 	Max v1.ResourceList `json:"max,omitempty" protobuf:"bytes,2,rep,name=max, casttype=ResourceList,castkey=ResourceName"`
+
+	// Parent is the optional quota from which this quota borrows resources before borrowing them from the
+	// rest of the cluster. The Min and Max of the parent are enforced on the total usage of its children.
+	Parent *ElasticQuotaReference `json:"parent,omitempty" protobuf:"bytes,3,opt,name=parent"`
+
+	// Weight is the optional weight used for sharing the unused Min of the other quotas.
+	// If none of the quotas sharing the same parent specifies a weight, the unused Min is shared in
+	// proportion to their Min, otherwise it is shared in proportion to their weights, considering
+	// a weight of 1 for the quotas that do not specify it.
+	//+kubebuilder:validation:Minimum=1
+	Weight *int32 `json:"weight,omitempty" protobuf:"varint,4,opt,name=weight"`
+
+	// TimeWindows is the optional list of recurring time windows overriding Min and Max.
+	// If multiple time windows are active at the same time, the first one of the list takes precedence.
+	TimeWindows []ElasticQuotaTimeWindow `json:"timeWindows,omitempty" protobuf:"bytes,5,rep,name=timeWindows"`
+}
+
+// ElasticQuotaReference identifies an ElasticQuota or a CompositeElasticQuota.
+type ElasticQuotaReference struct {
+	// Kind is the kind of the referenced quota.
+	//+kubebuilder:validation:Enum=ElasticQuota;CompositeElasticQuota
+	Kind string `json:"kind" protobuf:"bytes,1,opt,name=kind"`
+
+	// Namespace is the namespace of the referenced quota.
+	Namespace string `json:"namespace" protobuf:"bytes,2,opt,name=namespace"`
+
+	// Name is the name of the referenced quota.
+	Name string `json:"name" protobuf:"bytes,3,opt,name=name"`
+}
+
+// ElasticQuotaTimeWindow is a recurring time window during which the Min and Max of a quota
+// are overridden with different values.
+type ElasticQuotaTimeWindow struct {
+	// Name is the name of the time window.
+	Name string `json:"name" protobuf:"bytes,1,opt,name=name"`
+
+	// Schedule is the cron expression defining when the time window starts, in the standard
+	// format "minute hour day-of-month month day-of-week" (e.g. "0 8 * * mon-fri").
+	Schedule string `json:"schedule" protobuf:"bytes,2,opt,name=schedule"`
+
+	// Duration is how long the time window lasts every time it starts (e.g. "10h").
+	Duration metav1.Duration `json:"duration" protobuf:"bytes,3,opt,name=duration"`
+
+	// TimeZone is the name of the IANA time zone in which Schedule is evaluated (e.g. "Europe/Rome").
+	// If not specified, Schedule is evaluated in UTC.
+	TimeZone string `json:"timeZone,omitempty" protobuf:"bytes,4,opt,name=timeZone"`
+
+	// Min overrides the Min of the quota while the time window is active.
+	// If not specified, the Min of the quota is not overridden.
+	Min v1.ResourceList `json:"min,omitempty" protobuf:"bytes,5,rep,name=min,casttype=ResourceList,castkey=ResourceName"`
+
+	// Max overrides the Max of the quota while the time window is active.
+	// If not specified, the Max of the quota is not overridden.
+	Max v1.ResourceList `json:"max,omitempty" protobuf:"bytes,6,rep,name=max,casttype=ResourceList,castkey=ResourceName"`
 }
 
 // ElasticQuotaStatus defines the observed use.
 type ElasticQuotaStatus struct {
 	// Used is the current observed total usage of the resource in the namespace.
 	Used v1.ResourceList `json:"used,omitempty" protobuf:"bytes,1,rep,name=used,casttype=ResourceList,castkey=ResourceName"`
+
+	// Usage is the cumulative consumption of GPU resources of the pods subject to the quota,
+	// recorded per namespace and per day.
+	Usage []ElasticQuotaUsageRecord `json:"usage,omitempty" protobuf:"bytes,2,rep,name=usage"`
+
+	// LastAccountingTime is the last time the consumption of the running pods was added to Usage.
+	LastAccountingTime *metav1.Time `json:"lastAccountingTime,omitempty" protobuf:"bytes,3,opt,name=lastAccountingTime"`
+
+	// Conditions are the latest observations of the state of the quota.
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" protobuf:"bytes,4,rep,name=conditions"`
+}
+
+// ElasticQuotaUsageRecord is the consumption of GPU resources of the pods of a namespace during a day.
+type ElasticQuotaUsageRecord struct {
+	// Namespace is the namespace of the pods.
+	Namespace string `json:"namespace" protobuf:"bytes,1,opt,name=namespace"`
+
+	// Date is the day of the record in UTC, in the format YYYY-MM-DD.
+	Date string `json:"date" protobuf:"bytes,2,opt,name=date"`
+
+	// InQuota is the consumption of the pods while they were in-quota.
+	InQuota GPUUsage `json:"inQuota,omitempty" protobuf:"bytes,3,opt,name=inQuota"`
+
+	// OverQuota is the consumption of the pods while they were over-quota.
+	OverQuota GPUUsage `json:"overQuota,omitempty" protobuf:"bytes,4,opt,name=overQuota"`
+}
+
+// GPUUsage is an amount of GPU resources consumed over time.
+type GPUUsage struct {
+	// GPUMemoryGBSeconds is the GPU memory consumed, in GB multiplied by seconds.
+	GPUMemoryGBSeconds int64 `json:"gpuMemoryGBSeconds,omitempty" protobuf:"varint,1,opt,name=gpuMemoryGBSeconds"`
+
+	// MigSliceSeconds is the number of MIG slices consumed multiplied by seconds.
+	MigSliceSeconds int64 `json:"migSliceSeconds,omitempty" protobuf:"varint,2,opt,name=migSliceSeconds"`
 }
 
 // +kubebuilder:object:root=true
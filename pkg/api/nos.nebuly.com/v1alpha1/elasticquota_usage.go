@@ -0,0 +1,45 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import "time"
+
+// UsageRecordDateFormat is the layout of the Date of the ElasticQuotaUsageRecord
+const UsageRecordDateFormat = "2006-01-02"
+
+// Add returns the sum of the GPUUsage and the one provided as argument
+func (u GPUUsage) Add(other GPUUsage) GPUUsage {
+	return GPUUsage{
+		GPUMemoryGBSeconds: u.GPUMemoryGBSeconds + other.GPUMemoryGBSeconds,
+		MigSliceSeconds:    u.MigSliceSeconds + other.MigSliceSeconds,
+	}
+}
+
+// GPUMemoryGBHours returns the GPU memory consumed, in GB multiplied by hours
+func (u GPUUsage) GPUMemoryGBHours() float64 {
+	return float64(u.GPUMemoryGBSeconds) / time.Hour.Seconds()
+}
+
+// MigSliceHours returns the number of MIG slices consumed multiplied by hours
+func (u GPUUsage) MigSliceHours() float64 {
+	return float64(u.MigSliceSeconds) / time.Hour.Seconds()
+}
+
+// GetDate returns the day of the record, or an error if its Date is not valid
+func (r ElasticQuotaUsageRecord) GetDate() (time.Time, error) {
+	return time.Parse(UsageRecordDateFormat, r.Date)
+}
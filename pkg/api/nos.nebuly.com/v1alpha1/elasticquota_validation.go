@@ -0,0 +1,224 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	nvidiaMigResourceRegexp = regexp.MustCompile(`^` + constant.RegexNvidiaMigResource + `$`)
+	nvidiaMpsResourceRegexp = regexp.MustCompile(`^` + constant.RegexNvidiaMpsResource + `$`)
+	knownResourceNames      = sets.NewString(
+		string(v1.ResourceCPU),
+		string(v1.ResourceMemory),
+		string(v1.ResourceEphemeralStorage),
+		string(v1.ResourcePods),
+		string(constant.ResourceNvidiaGPU),
+		string(ResourceGPUMemory),
+	)
+	// reservedResourcePrefixes are the prefixes of the resource names whose resources are all known
+	reservedResourcePrefixes = []string{
+		constant.NvidiaResourcePrefix,
+		"nos.nebuly.com/",
+		"kubernetes.io/",
+	}
+)
+
+// validateQuotaSpec returns an error if the limits, the time windows, the weight or the parent
+// of the quota identified by the reference provided as argument are not valid
+func validateQuotaSpec(
+	quota ElasticQuotaReference,
+	min, max v1.ResourceList,
+	timeWindows []ElasticQuotaTimeWindow,
+	weight *int32,
+	parent *ElasticQuotaReference,
+) error {
+	var errs []error
+	errs = append(errs, validateLimits("spec", min, max)...)
+	errs = append(errs, validateTimeWindows(min, max, timeWindows)...)
+	if weight != nil && *weight < 1 {
+		errs = append(errs, fmt.Errorf("spec.weight: must be greater than or equal to 1, got %d", *weight))
+	}
+	if parent != nil {
+		if err := validateParent(quota, *parent); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// validateLimits returns the errors of the min and max provided as argument: each quantity must be
+// non-negative, each resource must be known and each min must not be greater than the respective max
+func validateLimits(path string, min, max v1.ResourceList) []error {
+	var errs []error
+	errs = append(errs, validateResourceList(path+".min", min)...)
+	errs = append(errs, validateResourceList(path+".max", max)...)
+	for _, r := range sortedResourceNames(min) {
+		minQuantity := min[r]
+		maxQuantity, ok := max[r]
+		if ok && minQuantity.Cmp(maxQuantity) > 0 {
+			errs = append(errs, fmt.Errorf(
+				"%s: min of resource %q (%s) must be less than or equal to its max (%s)",
+				path,
+				r,
+				minQuantity.String(),
+				maxQuantity.String(),
+			))
+		}
+	}
+	return errs
+}
+
+func validateResourceList(path string, resources v1.ResourceList) []error {
+	var errs []error
+	for _, r := range sortedResourceNames(resources) {
+		if err := validateResourceName(r); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+		if quantity := resources[r]; quantity.Sign() < 0 {
+			errs = append(errs, fmt.Errorf("%s: quantity of resource %q must be non-negative, got %s", path, r, quantity.String()))
+		}
+	}
+	return errs
+}
+
+// validateResourceName returns an error if the resource name provided as argument is neither a known
+// resource nor a valid extended resource
+func validateResourceName(name v1.ResourceName) error {
+	if knownResourceNames.Has(string(name)) {
+		return nil
+	}
+	if nvidiaMigResourceRegexp.MatchString(string(name)) || nvidiaMpsResourceRegexp.MatchString(string(name)) {
+		return nil
+	}
+	for _, prefix := range reservedResourcePrefixes {
+		if strings.HasPrefix(string(name), prefix) {
+			return fmt.Errorf("unknown resource %q", name)
+		}
+	}
+	if !strings.Contains(string(name), "/") {
+		return fmt.Errorf("unknown resource %q", name)
+	}
+	if msgs := validation.IsQualifiedName(string(name)); len(msgs) > 0 {
+		return fmt.Errorf("invalid resource name %q: %s", name, strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+// validateTimeWindows returns the errors of the time windows provided as argument, checking
+// the limits in effect while each time window is active
+func validateTimeWindows(min, max v1.ResourceList, timeWindows []ElasticQuotaTimeWindow) []error {
+	var errs []error
+	names := sets.NewString()
+	for i, w := range timeWindows {
+		path := fmt.Sprintf("spec.timeWindows[%d]", i)
+		if names.Has(w.Name) {
+			errs = append(errs, fmt.Errorf("%s: duplicated time window name %q", path, w.Name))
+		}
+		names.Insert(w.Name)
+		if err := w.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+		windowMin, windowMax := min, max
+		if w.Min != nil {
+			windowMin = w.Min
+		}
+		if w.Max != nil {
+			windowMax = w.Max
+		}
+		errs = append(errs, validateLimits(path, windowMin, windowMax)...)
+	}
+	return errs
+}
+
+// validateParent returns an error if the quota references itself as parent, or if following
+// the parents of the referenced quota leads back to the quota itself.
+// Parents that do not exist are allowed, since they are ignored until they are created.
+func validateParent(quota ElasticQuotaReference, parent ElasticQuotaReference) error {
+	if parent == quota {
+		return fmt.Errorf("spec.parent: a quota cannot be the parent of itself")
+	}
+	visited := map[ElasticQuotaReference]bool{quota: true}
+	for ancestor := &parent; ancestor != nil; {
+		if visited[*ancestor] {
+			return fmt.Errorf(
+				"spec.parent: %s \"%s/%s\" is a descendant of the quota, parents cannot form a cycle",
+				parent.Kind,
+				parent.Namespace,
+				parent.Name,
+			)
+		}
+		visited[*ancestor] = true
+		next, err := getQuotaParent(*ancestor)
+		if err != nil {
+			return err
+		}
+		ancestor = next
+	}
+	return nil
+}
+
+// getQuotaParent returns the parent of the quota identified by the reference provided as argument,
+// or nil if either the quota does not exist or it does not have any parent
+func getQuotaParent(ref ElasticQuotaReference) (*ElasticQuotaReference, error) {
+	key := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+	var err error
+	var parent *ElasticQuotaReference
+	switch ref.Kind {
+	case KindElasticQuota:
+		var eq ElasticQuota
+		err = client.Get(context.Background(), key, &eq)
+		parent = eq.Spec.Parent
+	case KindCompositeElasticQuota:
+		var ceq CompositeElasticQuota
+		err = client.Get(context.Background(), key, &ceq)
+		parent = ceq.Spec.Parent
+	default:
+		return nil, fmt.Errorf("spec.parent: unknown kind %q", ref.Kind)
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		eqlog.Error(err, "unable to get quota", "kind", ref.Kind, "namespace", ref.Namespace, "name", ref.Name)
+		return nil, fmt.Errorf(constant.InternalErrorMsg)
+	}
+	return parent, nil
+}
+
+func sortedResourceNames(resources v1.ResourceList) []v1.ResourceName {
+	res := make([]v1.ResourceName, 0, len(resources))
+	for r := range resources {
+		res = append(res, r)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i] < res[j]
+	})
+	return res
+}
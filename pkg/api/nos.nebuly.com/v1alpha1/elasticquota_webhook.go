@@ -31,16 +31,32 @@ import (
 // log is for logging in this package.
 var eqlog = logf.Log.WithName("eq-resource")
 
-func (r *ElasticQuota) SetupWebhookWithManager(mgr ctrl.Manager) error {
+func (r *ElasticQuota) SetupWebhookWithManager(
+	mgr ctrl.Manager,
+	nvidiaGpuResourceMemoryGB int64,
+	checker OvercommitChecker,
+) error {
 	if client == nil {
 		client = mgr.GetClient()
 	}
+	nvidiaGPUResourceMemoryGB = nvidiaGpuResourceMemoryGB
+	overcommitChecker = checker
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
 }
 
-//+kubebuilder:webhook:path=/validate-nos-nebuly-ai-v1alpha1-elasticquota,mutating=false,failurePolicy=fail,sideEffects=None,groups=nos.nebuly.com,resources=elasticquotas,verbs=create,versions=v1alpha1,name=velasticquota.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/mutate-nos-nebuly-com-v1alpha1-elasticquota,mutating=true,failurePolicy=fail,sideEffects=None,groups=nos.nebuly.com,resources=elasticquotas,verbs=create;update,versions=v1alpha1,name=melasticquota.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &ElasticQuota{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *ElasticQuota) Default() {
+	eqlog.V(1).Info("default", "name", r.Name)
+	defaultQuotaLimits(&r.Spec.Min, &r.Spec.Max, r.Spec.TimeWindows)
+}
+
+//+kubebuilder:webhook:path=/validate-nos-nebuly-com-v1alpha1-elasticquota,mutating=false,failurePolicy=fail,sideEffects=None,groups=nos.nebuly.com,resources=elasticquotas,verbs=create;update,versions=v1alpha1,name=velasticquota.kb.io,admissionReviewVersions=v1
 
 var _ webhook.Validator = &ElasticQuota{}
 
@@ -83,15 +99,38 @@ func (r *ElasticQuota) ValidateCreate() error {
 		}
 	}
 
-	return nil
+	if err := r.validateSpec(); err != nil {
+		return err
+	}
+	return validateOvercommit(r)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *ElasticQuota) ValidateUpdate(old runtime.Object) error {
-	return nil
+	eqlog.V(1).Info("validate update", "name", r.Name)
+	if client == nil {
+		err := fmt.Errorf(constant.InternalErrorMsg)
+		eqlog.Error(err, "client was not initialized correctly")
+		return err
+	}
+	if err := r.validateSpec(); err != nil {
+		return err
+	}
+	return validateOvercommit(r)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
 func (r *ElasticQuota) ValidateDelete() error {
 	return nil
 }
+
+func (r *ElasticQuota) validateSpec() error {
+	return validateQuotaSpec(
+		ElasticQuotaReference{Kind: KindElasticQuota, Namespace: r.Namespace, Name: r.Name},
+		r.Spec.Min,
+		r.Spec.Max,
+		r.Spec.TimeWindows,
+		r.Spec.Weight,
+		r.Spec.Parent,
+	)
+}
@@ -0,0 +1,395 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+// rejectingOvercommitChecker is an OvercommitChecker that rejects all the quotas
+type rejectingOvercommitChecker struct{}
+
+func (rejectingOvercommitChecker) CheckOvercommit(_ context.Context, _ crclient.Object) error {
+	return fmt.Errorf("overcommit")
+}
+
+func newFakeClient(t *testing.T, objs ...runtime.Object) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, AddToScheme(scheme))
+	client = fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+}
+
+func TestElasticQuota_ValidateCreate(t *testing.T) {
+	validWindow := ElasticQuotaTimeWindow{
+		Name:     "night",
+		Schedule: "0 20 * * *",
+		Duration: metav1.Duration{Duration: 12 * time.Hour},
+		Max: v1.ResourceList{
+			ResourceGPUMemory: *resource.NewQuantity(40, resource.DecimalSI),
+		},
+	}
+	eqNs1 := BuildEq("ns-1", "eq-1").Get()
+	ceqNs2 := BuildCompositeEq("ns-2", "ceq-1").WithNamespaces("ns-2", "ns-3").Get()
+	ceqWithParent := BuildCompositeEq("ns-4", "ceq-2").
+		WithNamespaces("ns-4").
+		WithParent(KindElasticQuota, "ns-5", "eq-5").
+		Get()
+
+	testCases := []struct {
+		name        string
+		existing    []runtime.Object
+		eq          ElasticQuota
+		expectedErr bool
+	}{
+		{
+			name: "Valid ElasticQuota",
+			eq: BuildEq("ns-5", "eq-5").
+				WithMinGPUMemory(10).
+				WithMaxGPUMemory(20).
+				WithWeight(2).
+				WithTimeWindows(validWindow).
+				WithParent(KindCompositeElasticQuota, "ns-10", "not-existing").
+				Get(),
+			expectedErr: false,
+		},
+		{
+			name:        "Another ElasticQuota already exists in the namespace",
+			existing:    []runtime.Object{&eqNs1},
+			eq:          BuildEq("ns-1", "eq-2").Get(),
+			expectedErr: true,
+		},
+		{
+			name:        "A CompositeElasticQuota already includes the namespace",
+			existing:    []runtime.Object{&ceqNs2},
+			eq:          BuildEq("ns-3", "eq-3").Get(),
+			expectedErr: true,
+		},
+		{
+			name:        "Min greater than Max",
+			eq:          BuildEq("ns-5", "eq-5").WithMinGPUMemory(20).WithMaxGPUMemory(10).Get(),
+			expectedErr: true,
+		},
+		{
+			name:        "Negative quantity",
+			eq:          BuildEq("ns-5", "eq-5").WithMinCPUMilli(-1).Get(),
+			expectedErr: true,
+		},
+		{
+			name: "Unknown resource name",
+			eq: BuildEq("ns-5", "eq-5").WithMin(v1.ResourceList{
+				v1.ResourceName("gpu"): *resource.NewQuantity(1, resource.DecimalSI),
+			}).Get(),
+			expectedErr: true,
+		},
+		{
+			name: "Unknown NVIDIA resource name",
+			eq: BuildEq("ns-5", "eq-5").WithMin(v1.ResourceList{
+				v1.ResourceName("nvidia.com/gpus"): *resource.NewQuantity(1, resource.DecimalSI),
+			}).Get(),
+			expectedErr: true,
+		},
+		{
+			name: "MIG, MPS, pods and extended resources are allowed",
+			eq: BuildEq("ns-5", "eq-5").WithMin(v1.ResourceList{
+				v1.ResourceName("nvidia.com/mig-1g.10gb"):    *resource.NewQuantity(1, resource.DecimalSI),
+				v1.ResourceName("nvidia.com/mig-1g.10gb.me"): *resource.NewQuantity(1, resource.DecimalSI),
+				v1.ResourceName("nvidia.com/gpu-10gb"):       *resource.NewQuantity(1, resource.DecimalSI),
+				v1.ResourcePods:                              *resource.NewQuantity(1, resource.DecimalSI),
+				v1.ResourceName("example.com/foo"):           *resource.NewQuantity(1, resource.DecimalSI),
+			}).Get(),
+			expectedErr: false,
+		},
+		{
+			name: "MIG profile name with attributes is not a valid resource name",
+			eq: BuildEq("ns-5", "eq-5").WithMin(v1.ResourceList{
+				v1.ResourceName("nvidia.com/mig-1g.10gb+me"): *resource.NewQuantity(1, resource.DecimalSI),
+			}).Get(),
+			expectedErr: true,
+		},
+		{
+			name:        "Weight lower than 1",
+			eq:          BuildEq("ns-5", "eq-5").WithWeight(0).Get(),
+			expectedErr: true,
+		},
+		{
+			name: "Time window with invalid schedule",
+			eq: BuildEq("ns-5", "eq-5").WithTimeWindows(ElasticQuotaTimeWindow{
+				Name:     "invalid",
+				Schedule: "not a schedule",
+				Duration: metav1.Duration{Duration: time.Hour},
+			}).Get(),
+			expectedErr: true,
+		},
+		{
+			name:        "Time windows with duplicated names",
+			eq:          BuildEq("ns-5", "eq-5").WithTimeWindows(validWindow, validWindow).Get(),
+			expectedErr: true,
+		},
+		{
+			name: "Time window Min greater than quota Max",
+			eq: BuildEq("ns-5", "eq-5").
+				WithMaxGPUMemory(10).
+				WithTimeWindows(ElasticQuotaTimeWindow{
+					Name:     "business-hours",
+					Schedule: "0 8 * * mon-fri",
+					Duration: metav1.Duration{Duration: 10 * time.Hour},
+					Min: v1.ResourceList{
+						ResourceGPUMemory: *resource.NewQuantity(20, resource.DecimalSI),
+					},
+				}).
+				Get(),
+			expectedErr: true,
+		},
+		{
+			name:        "Quota is the parent of itself",
+			eq:          BuildEq("ns-5", "eq-5").WithParent(KindElasticQuota, "ns-5", "eq-5").Get(),
+			expectedErr: true,
+		},
+		{
+			name:        "Parents form a cycle",
+			existing:    []runtime.Object{&ceqWithParent},
+			eq:          BuildEq("ns-5", "eq-5").WithParent(KindCompositeElasticQuota, "ns-4", "ceq-2").Get(),
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			newFakeClient(t, tt.existing...)
+			err := tt.eq.ValidateCreate()
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestElasticQuota_ValidateUpdate(t *testing.T) {
+	existing := BuildEq("ns-1", "eq-1").WithMinGPUMemory(10).Get()
+	newFakeClient(t, &existing)
+
+	updated := BuildEq("ns-1", "eq-1").WithMinGPUMemory(20).WithMaxGPUMemory(30).Get()
+	assert.NoError(t, updated.ValidateUpdate(&existing))
+
+	updated = BuildEq("ns-1", "eq-1").WithMinGPUMemory(20).WithMaxGPUMemory(10).Get()
+	assert.Error(t, updated.ValidateUpdate(&existing))
+
+	overcommitChecker = rejectingOvercommitChecker{}
+	defer func() { overcommitChecker = nil }()
+	updated = BuildEq("ns-1", "eq-1").WithMinGPUMemory(20).WithMaxGPUMemory(30).Get()
+	assert.Error(t, updated.ValidateUpdate(&existing))
+}
+
+func TestCompositeElasticQuota_ValidateCreate(t *testing.T) {
+	eqNs1 := BuildEq("ns-1", "eq-1").Get()
+	ceqNs2 := BuildCompositeEq("ns-2", "ceq-1").WithNamespaces("ns-2").Get()
+
+	testCases := []struct {
+		name        string
+		existing    []runtime.Object
+		ceq         CompositeElasticQuota
+		expectedErr bool
+	}{
+		{
+			name:     "Valid CompositeElasticQuota",
+			existing: []runtime.Object{&eqNs1, &ceqNs2},
+			ceq: BuildCompositeEq("ns-3", "ceq-2").
+				WithNamespaces("ns-3", "ns-4").
+				WithMinGPUMemory(10).
+				WithMaxGPUMemory(20).
+				Get(),
+			expectedErr: false,
+		},
+		{
+			name:        "Namespace already subject to an ElasticQuota",
+			existing:    []runtime.Object{&eqNs1},
+			ceq:         BuildCompositeEq("ns-3", "ceq-2").WithNamespaces("ns-3", "ns-1").Get(),
+			expectedErr: true,
+		},
+		{
+			name:        "Namespace already belonging to another CompositeElasticQuota",
+			existing:    []runtime.Object{&ceqNs2},
+			ceq:         BuildCompositeEq("ns-3", "ceq-2").WithNamespaces("ns-2").Get(),
+			expectedErr: true,
+		},
+		{
+			name: "Min greater than Max",
+			ceq: BuildCompositeEq("ns-3", "ceq-2").
+				WithNamespaces("ns-3").
+				WithMinCPUMilli(2000).
+				WithMaxCPUMilli(1000).
+				Get(),
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			newFakeClient(t, tt.existing...)
+			err := tt.ceq.ValidateCreate()
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCompositeElasticQuota_ValidateUpdate(t *testing.T) {
+	existing := BuildCompositeEq("ns-1", "ceq-1").WithNamespaces("ns-1").Get()
+	newFakeClient(t, &existing)
+
+	updated := BuildCompositeEq("ns-1", "ceq-1").WithNamespaces("ns-1", "ns-2").Get()
+	assert.NoError(t, updated.ValidateUpdate(&existing))
+
+	updated = BuildCompositeEq("ns-1", "ceq-1").WithNamespaces("ns-1").WithWeight(0).Get()
+	assert.Error(t, updated.ValidateUpdate(&existing))
+
+	overcommitChecker = rejectingOvercommitChecker{}
+	defer func() { overcommitChecker = nil }()
+	updated = BuildCompositeEq("ns-1", "ceq-1").WithNamespaces("ns-1", "ns-2").Get()
+	assert.Error(t, updated.ValidateUpdate(&existing))
+}
+
+func TestElasticQuota_Default(t *testing.T) {
+	nvidiaGPUResourceMemoryGB = 16
+	defer func() { nvidiaGPUResourceMemoryGB = 0 }()
+
+	testCases := []struct {
+		name     string
+		eq       ElasticQuota
+		expected ElasticQuotaSpec
+	}{
+		{
+			name:     "Empty spec",
+			eq:       BuildEq("ns-1", "eq-1").Get(),
+			expected: ElasticQuotaSpec{},
+		},
+		{
+			name: "Max is not specified, should be kept nil",
+			eq:   BuildEq("ns-1", "eq-1").WithMinCPUMilli(1000).Get(),
+			expected: ElasticQuotaSpec{
+				Min: v1.ResourceList{
+					v1.ResourceCPU: *resource.NewMilliQuantity(1000, resource.DecimalSI),
+				},
+			},
+		},
+		{
+			name: "Resources of Min not specified by Max are set to the value of Min",
+			eq:   BuildEq("ns-1", "eq-1").WithMinCPUMilli(1000).WithMinGPUMemory(10).WithMaxGPUMemory(20).Get(),
+			expected: ElasticQuotaSpec{
+				Min: v1.ResourceList{
+					v1.ResourceCPU:    *resource.NewMilliQuantity(1000, resource.DecimalSI),
+					ResourceGPUMemory: *resource.NewQuantity(10, resource.DecimalSI),
+				},
+				Max: v1.ResourceList{
+					v1.ResourceCPU:    *resource.NewMilliQuantity(1000, resource.DecimalSI),
+					ResourceGPUMemory: *resource.NewQuantity(20, resource.DecimalSI),
+				},
+			},
+		},
+		{
+			name: "NVIDIA GPUs are converted to GPU memory",
+			eq: BuildEq("ns-1", "eq-1").
+				WithMin(v1.ResourceList{
+					constant.ResourceNvidiaGPU: *resource.NewQuantity(2, resource.DecimalSI),
+					ResourceGPUMemory:          *resource.NewQuantity(10, resource.DecimalSI),
+				}).
+				WithMax(v1.ResourceList{
+					constant.ResourceNvidiaGPU: *resource.NewQuantity(4, resource.DecimalSI),
+				}).
+				Get(),
+			expected: ElasticQuotaSpec{
+				Min: v1.ResourceList{
+					ResourceGPUMemory: *resource.NewQuantity(42, resource.DecimalSI),
+				},
+				Max: v1.ResourceList{
+					ResourceGPUMemory: *resource.NewQuantity(64, resource.DecimalSI),
+				},
+			},
+		},
+		{
+			name: "Time windows are defaulted",
+			eq: BuildEq("ns-1", "eq-1").
+				WithTimeWindows(ElasticQuotaTimeWindow{
+					Name: "tw",
+					Min: v1.ResourceList{
+						constant.ResourceNvidiaGPU: *resource.NewQuantity(1, resource.DecimalSI),
+					},
+					Max: v1.ResourceList{
+						v1.ResourceCPU: *resource.NewMilliQuantity(1000, resource.DecimalSI),
+					},
+				}).
+				Get(),
+			expected: ElasticQuotaSpec{
+				TimeWindows: []ElasticQuotaTimeWindow{
+					{
+						Name: "tw",
+						Min: v1.ResourceList{
+							ResourceGPUMemory: *resource.NewQuantity(16, resource.DecimalSI),
+						},
+						Max: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(1000, resource.DecimalSI),
+							ResourceGPUMemory: *resource.NewQuantity(16, resource.DecimalSI),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.eq.Default()
+			assert.Equal(t, len(tt.expected.Min), len(tt.eq.Spec.Min))
+			for r, q := range tt.expected.Min {
+				assert.True(t, q.Equal(tt.eq.Spec.Min[r]), "min %s", r)
+			}
+			assert.Equal(t, tt.expected.Max == nil, tt.eq.Spec.Max == nil)
+			assert.Equal(t, len(tt.expected.Max), len(tt.eq.Spec.Max))
+			for r, q := range tt.expected.Max {
+				assert.True(t, q.Equal(tt.eq.Spec.Max[r]), "max %s", r)
+			}
+			assert.Equal(t, len(tt.expected.TimeWindows), len(tt.eq.Spec.TimeWindows))
+			for i, w := range tt.expected.TimeWindows {
+				actual := tt.eq.Spec.TimeWindows[i]
+				assert.Equal(t, len(w.Min), len(actual.Min))
+				for r, q := range w.Min {
+					assert.True(t, q.Equal(actual.Min[r]), "time window min %s", r)
+				}
+				assert.Equal(t, len(w.Max), len(actual.Max))
+				for r, q := range w.Max {
+					assert.True(t, q.Equal(actual.Max[r]), "time window max %s", r)
+				}
+			}
+		})
+	}
+}
@@ -21,4 +21,10 @@ const (
 	LabelCapacityInfo = "nos.nebuly.com/capacity"
 	// LabelGpuPartitioning specifies the PartitioningKind that should be performed on the GPUs of a node
 	LabelGpuPartitioning = "nos.nebuly.com/gpu-partitioning"
+	// LabelPodGroup specifies the name of the group of Pods a Pod belongs to. Pods of the same
+	// group (e.g. the workers of a distributed training job) must be in the same namespace.
+	LabelPodGroup = "nos.nebuly.com/pod-group"
+	// LabelPodGroupMinMember specifies the minimum number of Pods of the group that must be
+	// running at the same time for the group to make any progress
+	LabelPodGroupMinMember = "nos.nebuly.com/pod-group-min-member"
 )
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PartitioningPlanPhase is the phase of a PartitioningPlan
+// +kubebuilder:validation:Enum=PendingApproval;Rejected;Applied;Completed;Failed;Stale
+type PartitioningPlanPhase string
+
+const (
+	// PartitioningPlanPhasePendingApproval means the plan requires approval before being applied
+	PartitioningPlanPhasePendingApproval PartitioningPlanPhase = "PendingApproval"
+	// PartitioningPlanPhaseRejected means the plan was not approved, so it will never be applied
+	PartitioningPlanPhaseRejected PartitioningPlanPhase = "Rejected"
+	// PartitioningPlanPhaseApplied means the plan was applied to all its nodes,
+	// but not all of them have reported it yet
+	PartitioningPlanPhaseApplied PartitioningPlanPhase = "Applied"
+	// PartitioningPlanPhaseCompleted means all the nodes of the plan reported it
+	PartitioningPlanPhaseCompleted PartitioningPlanPhase = "Completed"
+	// PartitioningPlanPhaseFailed means the plan could not be applied
+	PartitioningPlanPhaseFailed PartitioningPlanPhase = "Failed"
+	// PartitioningPlanPhaseStale means the plan will never be applied since it is outdated: either the cluster
+	// changed since the plan was computed, or the plan was not approved within the approval timeout
+	PartitioningPlanPhaseStale PartitioningPlanPhase = "Stale"
+)
+
+// NodePartitioningPhase is the phase of a PartitioningPlan on a single node
+// +kubebuilder:validation:Enum=Pending;Applied;Reported;Superseded;Failed
+type NodePartitioningPhase string
+
+const (
+	// NodePartitioningPhasePending means the plan has not been applied to the node yet
+	NodePartitioningPhasePending NodePartitioningPhase = "Pending"
+	// NodePartitioningPhaseApplied means the plan was applied to the node, but the node has not reported it yet
+	NodePartitioningPhaseApplied NodePartitioningPhase = "Applied"
+	// NodePartitioningPhaseReported means the node reported the plan
+	NodePartitioningPhaseReported NodePartitioningPhase = "Reported"
+	// NodePartitioningPhaseSuperseded means a newer plan was applied to the node before the node reported the plan
+	NodePartitioningPhaseSuperseded NodePartitioningPhase = "Superseded"
+	// NodePartitioningPhaseFailed means the plan could not be applied to the node
+	NodePartitioningPhaseFailed NodePartitioningPhase = "Failed"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName={pp,pps}
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Kind",type=string,JSONPath=`.spec.partitioningKind`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Disruptive",type=boolean,JSONPath=`.status.disruptive`
+// +kubebuilder:printcolumn:name="Approved",type=boolean,JSONPath=`.spec.approved`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PartitioningPlan is a GPU partitioning plan computed by the GPU partitioner
+type PartitioningPlan struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Standard object's metadata.
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// PartitioningPlanSpec defines the desired partitioning of the plan.
+	Spec PartitioningPlanSpec `json:"spec,omitempty"`
+
+	// PartitioningPlanStatus defines the observed state of the plan.
+	Status PartitioningPlanStatus `json:"status,omitempty"`
+}
+
+// PartitioningPlanSpec defines the desired partitioning of the plan.
+type PartitioningPlanSpec struct {
+	// PlanId is the ID of the plan, which is used by the nodes for reporting it.
+	PlanId string `json:"planId"`
+
+	// PartitioningKind is the kind of GPU partitioning of the nodes of the plan.
+	PartitioningKind string `json:"partitioningKind"`
+
+	// Nodes is the desired GPU partitioning of each node of the plan.
+	// +optional
+	Nodes []NodePartitioningSpec `json:"nodes,omitempty"`
+
+	// BaseNodes is the GPU partitioning of the nodes of the plan at the time the plan was computed.
+	// The plan is applied only if its nodes still have this partitioning.
+	// +optional
+	BaseNodes []NodePartitioningSpec `json:"baseNodes,omitempty"`
+
+	// Pods are the pending pods for which the plan was computed, along with the node
+	// on which their scheduling was simulated.
+	// +optional
+	Pods []PartitioningPlanPod `json:"pods,omitempty"`
+
+	// Victims are the running pods that are evicted for making room to the pods of the plan
+	// before applying it, along with the node on which they are running.
+	// +optional
+	Victims []PartitioningPlanPod `json:"victims,omitempty"`
+
+	// Approved is the approval of the plan. It is taken into account only if the plan
+	// requires approval: in this case, the plan is applied only once Approved is set to true,
+	// whereas setting it to false rejects the plan.
+	// +optional
+	Approved *bool `json:"approved,omitempty"`
+}
+
+// NodePartitioningSpec defines the desired GPU partitioning of a node.
+type NodePartitioningSpec struct {
+	// Name is the name of the node.
+	Name string `json:"name"`
+
+	// GPUs is the desired partitioning of each GPU of the node.
+	// +optional
+	GPUs []GPUPartitioningSpec `json:"gpus,omitempty"`
+}
+
+// GPUPartitioningSpec defines the desired partitioning of a GPU.
+type GPUPartitioningSpec struct {
+	// Index is the index of the GPU within the node.
+	Index int `json:"index"`
+
+	// Resources is the quantity of each resource that the GPU should expose.
+	// +optional
+	Resources map[v1.ResourceName]int `json:"resources,omitempty"`
+}
+
+// PartitioningPlanPod is a pod for which a PartitioningPlan was computed.
+type PartitioningPlanPod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// NodeName is the name of the node on which the scheduling of the pod was simulated,
+	// or on which the pod is running if it is a victim of the plan.
+	NodeName string `json:"nodeName"`
+}
+
+// PartitioningPlanStatus defines the observed state of the plan.
+type PartitioningPlanStatus struct {
+	// Phase is the current phase of the plan.
+	// +optional
+	Phase PartitioningPlanPhase `json:"phase,omitempty"`
+
+	// Disruptive is true if the plan deletes any GPU resource currently exposed by the nodes,
+	// or if it evicts any pod.
+	// +optional
+	Disruptive bool `json:"disruptive,omitempty"`
+
+	// Message is a human-readable message describing the current phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Nodes is the status of the plan on each of its nodes.
+	// +optional
+	Nodes []NodePartitioningStatus `json:"nodes,omitempty"`
+}
+
+// NodePartitioningStatus is the status of a PartitioningPlan on a node.
+type NodePartitioningStatus struct {
+	// Name is the name of the node.
+	Name string `json:"name"`
+
+	// Phase is the phase of the plan on the node.
+	Phase NodePartitioningPhase `json:"phase"`
+
+	// Message is a human-readable message describing the phase, e.g. the reason of the failure.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionTime is the last time the phase changed.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// IsApprovalPending returns true if the plan is waiting to be either approved or rejected.
+func (p *PartitioningPlan) IsApprovalPending() bool {
+	return p.Status.Phase == PartitioningPlanPhasePendingApproval && p.Spec.Approved == nil
+}
+
+// IsTerminated returns true if the plan reached a phase that will never change.
+func (p *PartitioningPlan) IsTerminated() bool {
+	switch p.Status.Phase {
+	case PartitioningPlanPhaseCompleted, PartitioningPlanPhaseFailed, PartitioningPlanPhaseRejected, PartitioningPlanPhaseStale:
+		return true
+	}
+	return false
+}
+
+// +kubebuilder:object:root=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PartitioningPlanList is a list of PartitioningPlan items.
+type PartitioningPlanList struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Standard list metadata.
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	// Items is a list of PartitioningPlan objects.
+	Items []PartitioningPlan `json:"items"`
+}
@@ -17,7 +17,34 @@
 package v1alpha1
 
 import (
+	"context"
 	. "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var client Client
+
+// nvidiaGPUResourceMemoryGB is the GPU memory of each nvidia.com/gpu resource, used by the defaulting
+// webhooks for converting the nvidia.com/gpu resources of the quotas to GPU memory.
+// If zero, the nvidia.com/gpu resources are not converted.
+var nvidiaGPUResourceMemoryGB int64
+
+// OvercommitChecker checks whether a quota would make the aggregated Min of the quotas
+// exceed the capacity of the cluster
+type OvercommitChecker interface {
+	// CheckOvercommit returns an error if the quota provided as argument would make the aggregated Min
+	// of the quotas exceed the capacity of the cluster
+	CheckOvercommit(ctx context.Context, quota Object) error
+}
+
+// overcommitChecker is used by the validating webhooks for rejecting the new or updated quotas that would
+// overcommit the cluster. If nil, quotas are not checked.
+var overcommitChecker OvercommitChecker
+
+// validateOvercommit returns an error if the quota provided as argument would overcommit the cluster.
+// If the overcommit checker is not configured, it always returns nil.
+func validateOvercommit(quota Object) error {
+	if overcommitChecker == nil {
+		return nil
+	}
+	return overcommitChecker.CheckOvercommit(context.Background(), quota)
+}
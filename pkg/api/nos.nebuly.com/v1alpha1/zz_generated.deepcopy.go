@@ -23,6 +23,7 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -107,6 +108,23 @@ func (in *CompositeElasticQuotaSpec) DeepCopyInto(out *CompositeElasticQuotaSpec
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.Parent != nil {
+		in, out := &in.Parent, &out.Parent
+		*out = new(ElasticQuotaReference)
+		**out = **in
+	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TimeWindows != nil {
+		in, out := &in.TimeWindows, &out.TimeWindows
+		*out = make([]ElasticQuotaTimeWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CompositeElasticQuotaSpec.
@@ -129,6 +147,22 @@ func (in *CompositeElasticQuotaStatus) DeepCopyInto(out *CompositeElasticQuotaSt
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.Usage != nil {
+		in, out := &in.Usage, &out.Usage
+		*out = make([]ElasticQuotaUsageRecord, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastAccountingTime != nil {
+		in, out := &in.LastAccountingTime, &out.LastAccountingTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CompositeElasticQuotaStatus.
@@ -200,6 +234,21 @@ func (in *ElasticQuotaList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElasticQuotaReference) DeepCopyInto(out *ElasticQuotaReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticQuotaReference.
+func (in *ElasticQuotaReference) DeepCopy() *ElasticQuotaReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ElasticQuotaReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ElasticQuotaSpec) DeepCopyInto(out *ElasticQuotaSpec) {
 	*out = *in
@@ -217,6 +266,23 @@ func (in *ElasticQuotaSpec) DeepCopyInto(out *ElasticQuotaSpec) {
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.Parent != nil {
+		in, out := &in.Parent, &out.Parent
+		*out = new(ElasticQuotaReference)
+		**out = **in
+	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TimeWindows != nil {
+		in, out := &in.TimeWindows, &out.TimeWindows
+		*out = make([]ElasticQuotaTimeWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticQuotaSpec.
@@ -239,6 +305,22 @@ func (in *ElasticQuotaStatus) DeepCopyInto(out *ElasticQuotaStatus) {
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.Usage != nil {
+		in, out := &in.Usage, &out.Usage
+		*out = make([]ElasticQuotaUsageRecord, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastAccountingTime != nil {
+		in, out := &in.LastAccountingTime, &out.LastAccountingTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticQuotaStatus.
@@ -250,3 +332,265 @@ func (in *ElasticQuotaStatus) DeepCopy() *ElasticQuotaStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElasticQuotaTimeWindow) DeepCopyInto(out *ElasticQuotaTimeWindow) {
+	*out = *in
+	out.Duration = in.Duration
+	if in.Min != nil {
+		in, out := &in.Min, &out.Min
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticQuotaTimeWindow.
+func (in *ElasticQuotaTimeWindow) DeepCopy() *ElasticQuotaTimeWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(ElasticQuotaTimeWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElasticQuotaUsageRecord) DeepCopyInto(out *ElasticQuotaUsageRecord) {
+	*out = *in
+	out.InQuota = in.InQuota
+	out.OverQuota = in.OverQuota
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticQuotaUsageRecord.
+func (in *ElasticQuotaUsageRecord) DeepCopy() *ElasticQuotaUsageRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(ElasticQuotaUsageRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GPUPartitioningSpec) DeepCopyInto(out *GPUPartitioningSpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(map[v1.ResourceName]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GPUPartitioningSpec.
+func (in *GPUPartitioningSpec) DeepCopy() *GPUPartitioningSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GPUPartitioningSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GPUUsage) DeepCopyInto(out *GPUUsage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GPUUsage.
+func (in *GPUUsage) DeepCopy() *GPUUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(GPUUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePartitioningSpec) DeepCopyInto(out *NodePartitioningSpec) {
+	*out = *in
+	if in.GPUs != nil {
+		in, out := &in.GPUs, &out.GPUs
+		*out = make([]GPUPartitioningSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePartitioningSpec.
+func (in *NodePartitioningSpec) DeepCopy() *NodePartitioningSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePartitioningSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePartitioningStatus) DeepCopyInto(out *NodePartitioningStatus) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePartitioningStatus.
+func (in *NodePartitioningStatus) DeepCopy() *NodePartitioningStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePartitioningStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartitioningPlan) DeepCopyInto(out *PartitioningPlan) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartitioningPlan.
+func (in *PartitioningPlan) DeepCopy() *PartitioningPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(PartitioningPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PartitioningPlan) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartitioningPlanList) DeepCopyInto(out *PartitioningPlanList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PartitioningPlan, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartitioningPlanList.
+func (in *PartitioningPlanList) DeepCopy() *PartitioningPlanList {
+	if in == nil {
+		return nil
+	}
+	out := new(PartitioningPlanList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PartitioningPlanList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartitioningPlanPod) DeepCopyInto(out *PartitioningPlanPod) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartitioningPlanPod.
+func (in *PartitioningPlanPod) DeepCopy() *PartitioningPlanPod {
+	if in == nil {
+		return nil
+	}
+	out := new(PartitioningPlanPod)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartitioningPlanSpec) DeepCopyInto(out *PartitioningPlanSpec) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodePartitioningSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BaseNodes != nil {
+		in, out := &in.BaseNodes, &out.BaseNodes
+		*out = make([]NodePartitioningSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]PartitioningPlanPod, len(*in))
+		copy(*out, *in)
+	}
+	if in.Victims != nil {
+		in, out := &in.Victims, &out.Victims
+		*out = make([]PartitioningPlanPod, len(*in))
+		copy(*out, *in)
+	}
+	if in.Approved != nil {
+		in, out := &in.Approved, &out.Approved
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartitioningPlanSpec.
+func (in *PartitioningPlanSpec) DeepCopy() *PartitioningPlanSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PartitioningPlanSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartitioningPlanStatus) DeepCopyInto(out *PartitioningPlanStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodePartitioningStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartitioningPlanStatus.
+func (in *PartitioningPlanStatus) DeepCopy() *PartitioningPlanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PartitioningPlanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
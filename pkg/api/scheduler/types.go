@@ -24,4 +24,5 @@ type CapacitySchedulingArgs struct {
 	metav1.TypeMeta
 
 	NvidiaGpuResourceMemoryGB int64
+	NvidiaGpuModelsMemoryGB   map[string]int64
 }
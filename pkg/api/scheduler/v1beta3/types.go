@@ -27,4 +27,8 @@ type CapacitySchedulingArgs struct {
 	metav1.TypeMeta `json:",inline"`
 
 	NvidiaGpuResourceMemoryGB *int64 `json:"nvidiaGpuResourceMemoryGB,omitempty"`
+	// NvidiaGpuModelsMemoryGB is the memory of each nvidia.com/gpu resource for each GPU model,
+	// where the keys are the values of the label "nvidia.com/gpu.product". It takes precedence
+	// over NvidiaGpuResourceMemoryGB for the pods requesting GPUs of the models it contains.
+	NvidiaGpuModelsMemoryGB map[string]int64 `json:"nvidiaGpuModelsMemoryGB,omitempty"`
 }
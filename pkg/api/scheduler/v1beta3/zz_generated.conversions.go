@@ -26,6 +26,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	conversion "k8s.io/apimachinery/pkg/conversion"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	unsafe "unsafe"
 )
 
 func init() {
@@ -52,6 +53,7 @@ func autoConvert_v1beta3_CapacitySchedulingArgs_To_scheduler_CapacitySchedulingA
 	if err := v1.Convert_Pointer_int64_To_int64(&in.NvidiaGpuResourceMemoryGB, &out.NvidiaGpuResourceMemoryGB, s); err != nil {
 		return err
 	}
+	out.NvidiaGpuModelsMemoryGB = *(*map[string]int64)(unsafe.Pointer(&in.NvidiaGpuModelsMemoryGB))
 	return nil
 }
 
@@ -64,6 +66,7 @@ func autoConvert_scheduler_CapacitySchedulingArgs_To_v1beta3_CapacitySchedulingA
 	if err := v1.Convert_int64_To_Pointer_int64(&in.NvidiaGpuResourceMemoryGB, &out.NvidiaGpuResourceMemoryGB, s); err != nil {
 		return err
 	}
+	out.NvidiaGpuModelsMemoryGB = *(*map[string]int64)(unsafe.Pointer(&in.NvidiaGpuModelsMemoryGB))
 	return nil
 }
 
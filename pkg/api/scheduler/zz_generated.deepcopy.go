@@ -29,6 +29,13 @@ import (
 func (in *CapacitySchedulingArgs) DeepCopyInto(out *CapacitySchedulingArgs) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
+	if in.NvidiaGpuModelsMemoryGB != nil {
+		in, out := &in.NvidiaGpuModelsMemoryGB, &out.NvidiaGpuModelsMemoryGB
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacitySchedulingArgs.
@@ -36,6 +36,15 @@ const (
 	ClusterStatePodControllerName       = "clusterstate-pod-controller"
 	MigPartitionerControllerName        = "mig-partitioner-controller"
 	MpsPartitionerControllerName        = "mps-partitioner-controller"
+	HybridPartitionerControllerName     = "hybrid-partitioner-controller"
+	MigPlanControllerName               = "mig-plan-controller"
+	MpsPlanControllerName               = "mps-plan-controller"
+	HybridPlanControllerName            = "hybrid-plan-controller"
+)
+
+// Event recorder names
+const (
+	GpuPartitionerEventRecorderName = "nos-gpu-partitioner"
 )
 
 // Error messages
@@ -46,10 +55,16 @@ const (
 
 // Common RegEx
 const (
-	// RegexNvidiaMigResource is a regex matching the name of the MIG devices exposed by the NVIDIA device plugin
-	RegexNvidiaMigResource     = `nvidia\.com\/mig-\d+g\.\d+gb`
-	RegexNvidiaMigProfile      = `\d+g\.\d+gb`
+	// RegexNvidiaMigResource is a regex matching the name of the MIG devices exposed by the NVIDIA device plugin,
+	// which appends the attributes of the profiles to the resource name with "." (e.g. nvidia.com/mig-1g.10gb.me)
+	RegexNvidiaMigResource = `nvidia\.com\/mig-\d+g\.\d+gb(\.[a-z]+)*`
+	// RegexNvidiaMigProfile is a regex matching the name of the MIG profiles, including the optional
+	// attributes appended to the name (e.g. "+me" for profiles with media extensions)
+	RegexNvidiaMigProfile      = `\d+g\.\d+gb(\+[a-z]+)*`
 	RegexNvidiaMigFormatMemory = `\d+gb`
+	// RegexNvidiaMpsResource is a regex matching the name of the GPU slices exposed by the NVIDIA device plugin
+	// when GPUs are shared with MPS (e.g. nvidia.com/gpu-10gb)
+	RegexNvidiaMpsResource = `nvidia\.com\/gpu-\d+gb`
 )
 
 // Prefixes
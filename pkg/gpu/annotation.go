@@ -26,6 +26,17 @@ import (
 	"strings"
 )
 
+// profileToAnnotationKey encodes the profile name so that it can be included in annotation keys,
+// which cannot contain the "+" used by the names of the profiles with attributes (e.g. 1g.10gb+me)
+func profileToAnnotationKey(profileName string) string {
+	return strings.ReplaceAll(profileName, "+", "_")
+}
+
+// profileFromAnnotationKey decodes a profile name encoded with profileToAnnotationKey
+func profileFromAnnotationKey(encoded string) string {
+	return strings.ReplaceAll(encoded, "_", "+")
+}
+
 func ParseSpecAnnotation(key, value string) (SpecAnnotation, error) {
 	if !strings.HasPrefix(key, v1alpha1.AnnotationGpuSpecPrefix) {
 		err := fmt.Errorf(
@@ -48,7 +59,7 @@ func ParseSpecAnnotation(key, value string) (SpecAnnotation, error) {
 		return SpecAnnotation{}, fmt.Errorf("invalid GPU index: %s", err)
 	}
 	return SpecAnnotation{
-		ProfileName: parts[len(parts)-1],
+		ProfileName: profileFromAnnotationKey(parts[len(parts)-1]),
 		Quantity:    quantity,
 		Index:       index,
 	}, nil
@@ -78,7 +89,7 @@ func ParseStatusAnnotation(key, value string) (StatusAnnotation, error) {
 
 	return StatusAnnotation{
 		Index:       index,
-		ProfileName: parts[3],
+		ProfileName: profileFromAnnotationKey(parts[3]),
 		Status:      status,
 		Quantity:    quantity,
 	}, nil
@@ -118,7 +129,7 @@ func (a StatusAnnotation) IsFree() bool {
 }
 
 func (a StatusAnnotation) String() string {
-	return fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, a.Index, a.ProfileName, a.Status)
+	return fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, a.Index, profileToAnnotationKey(a.ProfileName), a.Status)
 }
 
 func (a StatusAnnotation) GetValue() string {
@@ -146,7 +157,7 @@ type SpecAnnotation struct {
 }
 
 func (a SpecAnnotation) String() string {
-	return fmt.Sprintf(v1alpha1.AnnotationGpuSpecFormat, a.Index, a.ProfileName)
+	return fmt.Sprintf(v1alpha1.AnnotationGpuSpecFormat, a.Index, profileToAnnotationKey(a.ProfileName))
 }
 
 func (a SpecAnnotation) GetValue() string {
@@ -375,6 +375,18 @@ func TestParseStatusAnnotation(t *testing.T) {
 			},
 			expectedErr: false,
 		},
+		{
+			name:  "Valid annotation, profile with attributes",
+			key:   "nos.nebuly.com/status-gpu-0-1g.10gb_me-free",
+			value: "2",
+			expected: gpu.StatusAnnotation{
+				ProfileName: "1g.10gb+me",
+				Status:      resource.StatusFree,
+				Index:       0,
+				Quantity:    2,
+			},
+			expectedErr: false,
+		},
 	}
 
 	for _, tt := range testCases {
@@ -435,6 +447,17 @@ func TestParseSpecAnnotation(t *testing.T) {
 			},
 			expectedErr: false,
 		},
+		{
+			name:  "Valid annotation, profile with attributes",
+			key:   "nos.nebuly.com/spec-gpu-1-1g.10gb_me",
+			value: "1",
+			expected: gpu.SpecAnnotation{
+				ProfileName: "1g.10gb+me",
+				Index:       1,
+				Quantity:    1,
+			},
+			expectedErr: false,
+		},
 	}
 
 	for _, tt := range testCases {
@@ -447,3 +470,17 @@ func TestParseSpecAnnotation(t *testing.T) {
 		})
 	}
 }
+
+func TestAnnotation_String__ProfileWithAttributes(t *testing.T) {
+	spec := gpu.SpecAnnotation{ProfileName: "1g.10gb+me", Index: 0, Quantity: 1}
+	assert.Equal(t, "nos.nebuly.com/spec-gpu-0-1g.10gb_me", spec.String())
+	parsedSpec, err := gpu.ParseSpecAnnotation(spec.String(), spec.GetValue())
+	assert.NoError(t, err)
+	assert.Equal(t, spec, parsedSpec)
+
+	status := gpu.StatusAnnotation{ProfileName: "1g.10gb+me", Index: 1, Status: resource.StatusUsed, Quantity: 1}
+	assert.Equal(t, "nos.nebuly.com/status-gpu-1-1g.10gb_me-used", status.String())
+	parsedStatus, err := gpu.ParseStatusAnnotation(status.String(), status.GetValue())
+	assert.NoError(t, err)
+	assert.Equal(t, status, parsedStatus)
+}
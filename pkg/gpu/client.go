@@ -44,6 +44,19 @@ func NewDevicePluginClient(k8sClient client.Client) DevicePluginClient {
 	return devicePluginClient{Client: k8sClient}
 }
 
+// NewNoopDevicePluginClient returns a DevicePluginClient that never restarts the NVIDIA device plugin.
+// It is intended for nodes without the device plugin, such as nodes with simulated GPUs.
+func NewNoopDevicePluginClient() DevicePluginClient {
+	return noopDevicePluginClient{}
+}
+
+type noopDevicePluginClient struct{}
+
+func (noopDevicePluginClient) Restart(ctx context.Context, nodeName string, _ time.Duration) error {
+	log.FromContext(ctx).V(1).Info("skipping restart of NVIDIA device plugin", "node", nodeName)
+	return nil
+}
+
 type devicePluginClient struct {
 	client.Client
 }
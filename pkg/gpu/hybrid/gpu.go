@@ -0,0 +1,306 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hybrid
+
+import (
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/gpu/slicing"
+	v1 "k8s.io/api/core/v1"
+)
+
+// GPU is a GPU of a node with hybrid partitioning, which can be partitioned either with
+// MIG or with MPS. The partitioning kind of the GPU is decided by the slices it provides:
+// a GPU without any slice is unassigned.
+//
+// The partitioning kind a GPU can take is constrained by its MIG mode: GPUs with MIG mode enabled
+// can only be partitioned with MIG, while the other ones can only be partitioned with MPS.
+type GPU struct {
+	index    int
+	model    gpu.Model
+	memoryGB int
+	kind     gpu.PartitioningKind
+	mig      mig.GPU
+	mps      slicing.GPU
+	// allowedKind is the partitioning kind allowed by the MIG mode of the GPU,
+	// or an empty kind if the MIG mode of the GPU is unknown
+	allowedKind gpu.PartitioningKind
+	// allowedMigGeometries are the MIG geometries discovered on the node for the GPU, if any
+	allowedMigGeometries []gpu.Geometry
+}
+
+// NewUnassignedGPU returns a GPU without any slice, which can be partitioned only with the kind provided
+// as argument, namely the one allowed by the MIG mode of the GPU. If the kind is empty, the MIG mode of
+// the GPU is unknown and the GPU cannot be partitioned.
+func NewUnassignedGPU(model gpu.Model, index int, memoryGB int, allowedKind gpu.PartitioningKind) GPU {
+	return GPU{
+		index:       index,
+		model:       model,
+		memoryGB:    memoryGB,
+		allowedKind: allowedKind,
+	}
+}
+
+// NewMigGPU returns a GPU partitioned with MIG. If allowedMigGeometries is empty, the GPU allows
+// the MIG geometries known for its model.
+func NewMigGPU(model gpu.Model, index int, memoryGB int, allowedMigGeometries []gpu.Geometry, usedMigDevices, freeMigDevices map[mig.ProfileName]int) (GPU, error) {
+	migGpu, err := newMigGPU(model, index, allowedMigGeometries, usedMigDevices, freeMigDevices)
+	if err != nil {
+		return GPU{}, err
+	}
+	return GPU{
+		index:                index,
+		model:                model,
+		memoryGB:             memoryGB,
+		kind:                 gpu.PartitioningKindMig,
+		mig:                  migGpu,
+		allowedKind:          gpu.PartitioningKindMig,
+		allowedMigGeometries: allowedMigGeometries,
+	}, nil
+}
+
+// NewMpsGPU returns a GPU partitioned with MPS.
+func NewMpsGPU(model gpu.Model, index int, memoryGB int, usedProfiles, freeProfiles map[slicing.ProfileName]int) (GPU, error) {
+	mpsGpu, err := slicing.NewGPU(model, index, memoryGB, usedProfiles, freeProfiles)
+	if err != nil {
+		return GPU{}, err
+	}
+	return GPU{
+		index:       index,
+		model:       model,
+		memoryGB:    memoryGB,
+		kind:        gpu.PartitioningKindMps,
+		mps:         mpsGpu,
+		allowedKind: gpu.PartitioningKindMps,
+	}, nil
+}
+
+func (g *GPU) GetIndex() int {
+	return g.index
+}
+
+// GetKind returns the partitioning kind of the GPU, or an empty kind if the GPU is unassigned.
+func (g *GPU) GetKind() gpu.PartitioningKind {
+	return g.kind
+}
+
+// SupportsMig returns true if the GPU can be partitioned with MIG, namely if it has MIG mode enabled
+// and either MIG geometries have been discovered for it or its model has known MIG geometries.
+func (g *GPU) SupportsMig() bool {
+	if g.allowedKind != gpu.PartitioningKindMig {
+		return false
+	}
+	if len(g.allowedMigGeometries) > 0 {
+		return true
+	}
+	_, ok := mig.GetAllowedGeometries(g.model)
+	return ok
+}
+
+func (g *GPU) GetGeometry() gpu.Geometry {
+	switch g.kind {
+	case gpu.PartitioningKindMig:
+		return g.mig.GetGeometry()
+	case gpu.PartitioningKindMps:
+		return g.mps.GetGeometry()
+	default:
+		return gpu.Geometry{}
+	}
+}
+
+// GetFreeSlices returns the slices of the GPU that are not used by any pod.
+func (g *GPU) GetFreeSlices() map[gpu.Slice]int {
+	res := make(map[gpu.Slice]int)
+	switch g.kind {
+	case gpu.PartitioningKindMig:
+		for p, q := range g.mig.GetFreeMigDevices() {
+			if q > 0 {
+				res[p] = q
+			}
+		}
+	case gpu.PartitioningKindMps:
+		for p, q := range g.mps.FreeProfiles {
+			if q > 0 {
+				res[p] = q
+			}
+		}
+	}
+	return res
+}
+
+// AsResources returns the geometry of the GPU expressed as resources of the respective partitioning kind.
+func (g *GPU) AsResources() map[v1.ResourceName]int {
+	switch g.kind {
+	case gpu.PartitioningKindMig:
+		return mig.AsResources(g.mig.GetGeometry())
+	case gpu.PartitioningKindMps:
+		return slicing.AsResources(g.mps.GetGeometry())
+	default:
+		return make(map[v1.ResourceName]int)
+	}
+}
+
+// HasFreeCapacity returns true if the GPU is unassigned and can be partitioned, or if it has free slices or
+// enough spare capacity for creating new ones. Unassigned GPUs whose MIG mode is unknown cannot be
+// partitioned, so they never have free capacity.
+func (g *GPU) HasFreeCapacity() bool {
+	switch g.kind {
+	case gpu.PartitioningKindMig:
+		return g.mig.HasFreeMigDevices() || !g.mig.AllowsGeometry(g.mig.GetGeometry())
+	case gpu.PartitioningKindMps:
+		return g.mps.HasFreeCapacity()
+	default:
+		return g.allowedKind != ""
+	}
+}
+
+// AddPod adds a Pod to the GPU by updating its free and used slices according to the ones requested by the Pod.
+//
+// AddPod returns an error if the Pod requests slices of a partitioning kind different from the one of the GPU,
+// or if the GPU does not have enough free slices for the Pod.
+func (g *GPU) AddPod(pod v1.Pod) error {
+	requestsMig := len(mig.GetRequestedProfiles(pod)) > 0
+	requestsMps := len(slicing.GetRequestedProfiles(pod)) > 0
+	if requestsMig && requestsMps {
+		return fmt.Errorf("pod requests both MIG and MPS slices")
+	}
+	if requestsMig {
+		if g.kind != gpu.PartitioningKindMig {
+			return fmt.Errorf("pod requests MIG slices, but GPU %d is not partitioned with MIG", g.index)
+		}
+		return g.mig.AddPod(pod)
+	}
+	if requestsMps {
+		if g.kind != gpu.PartitioningKindMps {
+			return fmt.Errorf("pod requests MPS slices, but GPU %d is not partitioned with MPS", g.index)
+		}
+		return g.mps.AddPod(pod)
+	}
+	return nil
+}
+
+// UpdateGeometryFor tries to update the geometry of the GPU in order to create the highest possible number of
+// required slices provided as argument, without deleting any of the used slices.
+//
+// The GPU can only provide slices of the partitioning kind allowed by its MIG mode: MIG slices if MIG mode
+// is enabled, MPS slices otherwise. If the GPU currently provides slices of the other kind (e.g. because its
+// MIG mode has been changed) and none of them is in use, it is switched to the allowed kind.
+// GPUs whose MIG mode is unknown keep their current kind, while unassigned ones are not partitioned.
+//
+// The method returns true if the GPU geometry gets updated, false otherwise.
+func (g *GPU) UpdateGeometryFor(slices map[gpu.Slice]int) bool {
+	migSlices, mpsSlices := splitSlices(slices)
+
+	allowedKind := g.allowedKind
+	if allowedKind == "" {
+		allowedKind = g.kind
+	}
+	switch allowedKind {
+	case gpu.PartitioningKindMig:
+		if len(migSlices) == 0 {
+			return false
+		}
+		if g.kind == gpu.PartitioningKindMig {
+			return g.mig.UpdateGeometryFor(migSlices)
+		}
+		// The partitioning kind can be changed only if no slice is in use
+		if g.hasUsedSlices() || !g.SupportsMig() {
+			return false
+		}
+		candidate, err := newMigGPU(g.model, g.index, g.allowedMigGeometries, make(map[mig.ProfileName]int), make(map[mig.ProfileName]int))
+		if err == nil && candidate.UpdateGeometryFor(migSlices) {
+			g.kind = gpu.PartitioningKindMig
+			g.mig = candidate
+			g.mps = slicing.GPU{}
+			return true
+		}
+	case gpu.PartitioningKindMps:
+		if len(mpsSlices) == 0 {
+			return false
+		}
+		if g.kind == gpu.PartitioningKindMps {
+			return g.mps.UpdateGeometryFor(mpsSlices)
+		}
+		// The partitioning kind can be changed only if no slice is in use
+		if g.hasUsedSlices() {
+			return false
+		}
+		candidate := slicing.NewFullGPU(g.model, g.index, g.memoryGB)
+		if candidate.UpdateGeometryFor(mpsSlices) {
+			g.kind = gpu.PartitioningKindMps
+			g.mps = candidate
+			g.mig = mig.GPU{}
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *GPU) hasUsedSlices() bool {
+	switch g.kind {
+	case gpu.PartitioningKindMig:
+		return len(g.mig.GetUsedMigDevices()) > 0
+	case gpu.PartitioningKindMps:
+		return len(g.mps.UsedProfiles) > 0
+	default:
+		return false
+	}
+}
+
+func (g *GPU) Clone() GPU {
+	cloned := GPU{
+		index:                g.index,
+		model:                g.model,
+		memoryGB:             g.memoryGB,
+		kind:                 g.kind,
+		allowedKind:          g.allowedKind,
+		allowedMigGeometries: g.allowedMigGeometries,
+	}
+	switch g.kind {
+	case gpu.PartitioningKindMig:
+		cloned.mig = g.mig.Clone()
+	case gpu.PartitioningKindMps:
+		cloned.mps = g.mps.Clone()
+	}
+	return cloned
+}
+
+// newMigGPU returns a mig.GPU allowing the MIG geometries provided as argument or,
+// if none is provided, the ones known for its model
+func newMigGPU(model gpu.Model, index int, allowedMigGeometries []gpu.Geometry, used, free map[mig.ProfileName]int) (mig.GPU, error) {
+	if len(allowedMigGeometries) > 0 {
+		return mig.NewGPUWithAllowedGeometries(model, index, allowedMigGeometries, used, free)
+	}
+	return mig.NewGPU(model, index, used, free)
+}
+
+// splitSlices splits the slices provided as argument into MIG slices and MPS slices
+func splitSlices(slices map[gpu.Slice]int) (map[gpu.Slice]int, map[gpu.Slice]int) {
+	migSlices := make(map[gpu.Slice]int)
+	mpsSlices := make(map[gpu.Slice]int)
+	for s, q := range slices {
+		switch s.(type) {
+		case mig.ProfileName:
+			migSlices[s] = q
+		case slicing.ProfileName:
+			mpsSlices[s] = q
+		}
+	}
+	return migSlices, mpsSlices
+}
@@ -0,0 +1,266 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hybrid_test
+
+import (
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/hybrid"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/gpu/slicing"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func TestGPU__UpdateGeometryFor(t *testing.T) {
+	testCases := []struct {
+		name            string
+		gpu             hybrid.GPU
+		requiredSlices  map[gpu.Slice]int
+		expectedUpdated bool
+		expectedKind    gpu.PartitioningKind
+		expectedSlices  map[gpu.Slice]int
+	}{
+		{
+			name:            "Unassigned GPU, no required slices",
+			gpu:             hybrid.NewUnassignedGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, gpu.PartitioningKindMig),
+			requiredSlices:  map[gpu.Slice]int{},
+			expectedUpdated: false,
+			expectedKind:    "",
+			expectedSlices:  map[gpu.Slice]int{},
+		},
+		{
+			name: "Unassigned GPU with MIG mode enabled, required MIG slices: should become a MIG GPU",
+			gpu:  hybrid.NewUnassignedGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, gpu.PartitioningKindMig),
+			requiredSlices: map[gpu.Slice]int{
+				mig.Profile1g10gb: 7,
+			},
+			expectedUpdated: true,
+			expectedKind:    gpu.PartitioningKindMig,
+			expectedSlices: map[gpu.Slice]int{
+				mig.Profile1g10gb: 7,
+			},
+		},
+		{
+			name: "Unassigned GPU with MIG mode enabled, required only MPS slices: should not change",
+			gpu:  hybrid.NewUnassignedGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, gpu.PartitioningKindMig),
+			requiredSlices: map[gpu.Slice]int{
+				slicing.ProfileName("20gb"): 2,
+			},
+			expectedUpdated: false,
+			expectedKind:    "",
+			expectedSlices:  map[gpu.Slice]int{},
+		},
+		{
+			name: "Unassigned GPU with MIG mode disabled, required MIG and MPS slices: should become a MPS GPU",
+			gpu:  hybrid.NewUnassignedGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, gpu.PartitioningKindMps),
+			requiredSlices: map[gpu.Slice]int{
+				mig.Profile1g10gb:           7,
+				slicing.ProfileName("20gb"): 2,
+			},
+			expectedUpdated: true,
+			expectedKind:    gpu.PartitioningKindMps,
+			expectedSlices: map[gpu.Slice]int{
+				slicing.ProfileName("20gb"): 2,
+			},
+		},
+		{
+			name: "Unassigned GPU with MIG mode enabled, model not supporting MIG: should not change",
+			gpu:  hybrid.NewUnassignedGPU("foo", 0, 80, gpu.PartitioningKindMig),
+			requiredSlices: map[gpu.Slice]int{
+				mig.Profile1g10gb:           7,
+				slicing.ProfileName("20gb"): 2,
+			},
+			expectedUpdated: false,
+			expectedKind:    "",
+			expectedSlices:  map[gpu.Slice]int{},
+		},
+		{
+			name: "Unassigned GPU with unknown MIG mode: should not change",
+			gpu:  hybrid.NewUnassignedGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, ""),
+			requiredSlices: map[gpu.Slice]int{
+				mig.Profile1g10gb:           7,
+				slicing.ProfileName("20gb"): 2,
+			},
+			expectedUpdated: false,
+			expectedKind:    "",
+			expectedSlices:  map[gpu.Slice]int{},
+		},
+		{
+			name: "MPS GPU with used slices, required MIG slices: should not change",
+			gpu: newMpsGpuOrPanic(
+				map[slicing.ProfileName]int{"10gb": 1},
+				map[slicing.ProfileName]int{"10gb": 1},
+			),
+			requiredSlices: map[gpu.Slice]int{
+				mig.Profile1g10gb: 1,
+			},
+			expectedUpdated: false,
+			expectedKind:    gpu.PartitioningKindMps,
+			expectedSlices: map[gpu.Slice]int{
+				slicing.ProfileName("10gb"): 1,
+			},
+		},
+		{
+			name: "MPS GPU without used slices, required MIG slices: should not change since MIG mode is disabled",
+			gpu: newMpsGpuOrPanic(
+				map[slicing.ProfileName]int{},
+				map[slicing.ProfileName]int{"10gb": 1},
+			),
+			requiredSlices: map[gpu.Slice]int{
+				mig.Profile7g79gb: 1,
+			},
+			expectedUpdated: false,
+			expectedKind:    gpu.PartitioningKindMps,
+			expectedSlices: map[gpu.Slice]int{
+				slicing.ProfileName("10gb"): 1,
+			},
+		},
+		{
+			name: "MIG GPU without used slices, required both MIG and MPS slices: should remain a MIG GPU",
+			gpu: newMigGpuOrPanic(
+				map[mig.ProfileName]int{},
+				map[mig.ProfileName]int{mig.Profile7g79gb: 1},
+			),
+			requiredSlices: map[gpu.Slice]int{
+				mig.Profile7g79gb:           1,
+				slicing.ProfileName("10gb"): 1,
+			},
+			expectedUpdated: false,
+			expectedKind:    gpu.PartitioningKindMig,
+			expectedSlices: map[gpu.Slice]int{
+				mig.Profile7g79gb: 1,
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := tt.gpu.UpdateGeometryFor(tt.requiredSlices)
+			assert.Equal(t, tt.expectedUpdated, updated)
+			assert.Equal(t, tt.expectedKind, tt.gpu.GetKind())
+			assert.Equal(t, tt.expectedSlices, tt.gpu.GetFreeSlices())
+		})
+	}
+}
+
+func TestGPU__SupportsMig(t *testing.T) {
+	t.Run("MIG mode disabled", func(t *testing.T) {
+		g := hybrid.NewUnassignedGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, gpu.PartitioningKindMps)
+		assert.False(t, g.SupportsMig())
+	})
+
+	t.Run("MIG mode enabled, known model", func(t *testing.T) {
+		g := hybrid.NewUnassignedGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, gpu.PartitioningKindMig)
+		assert.True(t, g.SupportsMig())
+	})
+
+	t.Run("MIG mode enabled, unknown model with discovered geometries", func(t *testing.T) {
+		g, err := hybrid.NewMigGPU(
+			"foo",
+			0,
+			40,
+			[]gpu.Geometry{{mig.Profile1g5gb: 7}},
+			map[mig.ProfileName]int{},
+			map[mig.ProfileName]int{mig.Profile1g5gb: 7},
+		)
+		assert.NoError(t, err)
+		assert.True(t, g.SupportsMig())
+	})
+}
+
+func TestGPU__HasFreeCapacity(t *testing.T) {
+	t.Run("Unassigned GPU, MIG mode unknown", func(t *testing.T) {
+		g := hybrid.NewUnassignedGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, "")
+		assert.False(t, g.HasFreeCapacity())
+	})
+
+	t.Run("Unassigned GPU, MIG mode known", func(t *testing.T) {
+		g := hybrid.NewUnassignedGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, gpu.PartitioningKindMps)
+		assert.True(t, g.HasFreeCapacity())
+		g = hybrid.NewUnassignedGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, gpu.PartitioningKindMig)
+		assert.True(t, g.HasFreeCapacity())
+	})
+
+	t.Run("MPS GPU with free slices", func(t *testing.T) {
+		g, err := hybrid.NewMpsGPU(
+			gpu.GPUModel_A100_PCIe_80GB,
+			0,
+			80,
+			map[slicing.ProfileName]int{},
+			map[slicing.ProfileName]int{slicing.ProfileName("10gb"): 1},
+		)
+		assert.NoError(t, err)
+		assert.True(t, g.HasFreeCapacity())
+	})
+}
+
+func TestGPU__AddPod(t *testing.T) {
+	migPod := factory.BuildPod("ns-1", "pd-1").WithContainer(
+		factory.BuildContainer("c-1", "im-1").
+			WithScalarResourceRequest(mig.Profile1g10gb.AsResourceName(), 1).
+			Get(),
+	).Get()
+	mpsPod := factory.BuildPod("ns-1", "pd-2").WithContainer(
+		factory.BuildContainer("c-1", "im-1").
+			WithScalarResourceRequest(slicing.ProfileName("10gb").AsResourceName(), 1).
+			Get(),
+	).Get()
+	cpuPod := factory.BuildPod("ns-1", "pd-3").WithContainer(
+		factory.BuildContainer("c-1", "im-1").
+			WithScalarResourceRequest(v1.ResourceCPU, 1).
+			WithScalarResourceRequest(constant.ResourceNvidiaGPU, 1).
+			Get(),
+	).Get()
+
+	t.Run("Pod without slices can always be added", func(t *testing.T) {
+		g := hybrid.NewUnassignedGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, gpu.PartitioningKindMig)
+		assert.NoError(t, g.AddPod(cpuPod))
+	})
+
+	t.Run("MIG pod cannot be added to MPS GPU", func(t *testing.T) {
+		g := newMpsGpuOrPanic(map[slicing.ProfileName]int{}, map[slicing.ProfileName]int{"10gb": 1})
+		assert.Error(t, g.AddPod(migPod))
+		assert.NoError(t, g.AddPod(mpsPod))
+		assert.Empty(t, g.GetFreeSlices())
+	})
+
+	t.Run("MPS pod cannot be added to MIG GPU", func(t *testing.T) {
+		g := newMigGpuOrPanic(map[mig.ProfileName]int{}, map[mig.ProfileName]int{mig.Profile1g10gb: 1})
+		assert.Error(t, g.AddPod(mpsPod))
+		assert.NoError(t, g.AddPod(migPod))
+		assert.Empty(t, g.GetFreeSlices())
+	})
+}
+
+func newMigGpuOrPanic(used, free map[mig.ProfileName]int) hybrid.GPU {
+	g, err := hybrid.NewMigGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, nil, used, free)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+func newMpsGpuOrPanic(used, free map[slicing.ProfileName]int) hybrid.GPU {
+	g, err := hybrid.NewMpsGPU(gpu.GPUModel_A100_PCIe_80GB, 0, 80, used, free)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
@@ -0,0 +1,294 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hybrid
+
+import (
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/gpu/slicing"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type Node struct {
+	Name     string
+	GPUs     []GPU
+	nodeInfo framework.NodeInfo
+}
+
+// NewNode creates a new hybrid Node starting from the node provided as argument.
+//
+// The partitioning kind of each GPU is inferred from the nos.nebuly.com status annotations of the node:
+// GPUs exposing MIG devices are MIG GPUs, GPUs exposing MPS slices are MPS GPUs, while
+// GPUs without any slice are unassigned.
+//
+// The partitioning kind each GPU can take is inferred from its MIG mode, reported by the MIG agent through
+// the annotation v1alpha1.AnnotationMigEnabledGpus, while the MIG geometries allowed by each GPU are the ones
+// exposed through the annotation v1alpha1.AnnotationAllowedMigGeometries, if any.
+func NewNode(n framework.NodeInfo) (Node, error) {
+	if n.Node() == nil {
+		return Node{}, fmt.Errorf("node is nil")
+	}
+	node := *n.Node()
+	gpus, err := extractGPUs(node)
+	if err != nil {
+		return Node{}, err
+	}
+	return Node{
+		Name:     node.Name,
+		GPUs:     gpus,
+		nodeInfo: n,
+	}, nil
+}
+
+func extractGPUs(n v1.Node) ([]GPU, error) {
+	// Extract common GPU info from node labels
+	gpuModel, err := gpu.GetModel(n)
+	if err != nil {
+		return nil, err
+	}
+	gpuCount, err := gpu.GetCount(n)
+	if err != nil {
+		return nil, err
+	}
+	gpuMemoryGB, err := gpu.GetMemoryGB(n)
+	if err != nil {
+		return nil, err
+	}
+
+	statusAnnotations, _ := gpu.ParseNodeAnnotations(n)
+	annotationsByIndex := statusAnnotations.GroupByGpuIndex()
+	for gpuIndex := range annotationsByIndex {
+		if gpuIndex >= gpuCount {
+			gpuCount = gpuIndex + 1
+		}
+	}
+
+	migEnabledGpus, migModeKnown, err := mig.ParseMigEnabledGpusAnnotation(n)
+	if err != nil {
+		return nil, err
+	}
+	// Discovered allowed geometries: if the annotation is malformed, fall back to the known ones
+	discoveredGeometries, err := mig.ParseAllowedGeometriesAnnotation(n)
+	if err != nil {
+		discoveredGeometries = map[int][]gpu.Geometry{}
+	}
+
+	result := make([]GPU, 0, gpuCount)
+	for gpuIndex := 0; gpuIndex < gpuCount; gpuIndex++ {
+		var allowedKind gpu.PartitioningKind
+		if migModeKnown && migEnabledGpus[gpuIndex] {
+			allowedKind = gpu.PartitioningKindMig
+		}
+		if migModeKnown && !migEnabledGpus[gpuIndex] {
+			allowedKind = gpu.PartitioningKindMps
+		}
+		g, err := newGPUFromAnnotations(
+			gpuModel,
+			gpuIndex,
+			gpuMemoryGB,
+			annotationsByIndex[gpuIndex],
+			allowedKind,
+			discoveredGeometries[gpuIndex],
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, g)
+	}
+
+	return result, nil
+}
+
+// newGPUFromAnnotations creates a GPU from its status annotations. If allowedKind is not empty, it overrides
+// the partitioning kind allowed by the GPU, which otherwise is the one of its slices.
+func newGPUFromAnnotations(
+	model gpu.Model,
+	index int,
+	memoryGB int,
+	annotations gpu.StatusAnnotationList,
+	allowedKind gpu.PartitioningKind,
+	allowedMigGeometries []gpu.Geometry,
+) (GPU, error) {
+	usedMigDevices := make(map[mig.ProfileName]int)
+	freeMigDevices := make(map[mig.ProfileName]int)
+	usedMpsProfiles := make(map[slicing.ProfileName]int)
+	freeMpsProfiles := make(map[slicing.ProfileName]int)
+	for _, a := range annotations {
+		if migProfile := mig.ProfileName(a.ProfileName); migProfile.IsValid() {
+			if a.IsUsed() {
+				usedMigDevices[migProfile] = a.Quantity
+			}
+			if a.IsFree() {
+				freeMigDevices[migProfile] = a.Quantity
+			}
+			continue
+		}
+		if mpsProfile := slicing.ProfileName(a.ProfileName); mpsProfile.IsValid() {
+			if a.IsUsed() {
+				usedMpsProfiles[mpsProfile] = a.Quantity
+			}
+			if a.IsFree() {
+				freeMpsProfiles[mpsProfile] = a.Quantity
+			}
+		}
+	}
+
+	// While switching partitioning kind a GPU could temporarily expose both MIG and MPS slices:
+	// the kind with used slices takes precedence, since it is the one that cannot be changed.
+	hasMigDevices := len(usedMigDevices)+len(freeMigDevices) > 0
+	hasMpsProfiles := len(usedMpsProfiles)+len(freeMpsProfiles) > 0
+	var g GPU
+	var err error
+	switch {
+	case hasMigDevices && len(usedMpsProfiles) == 0:
+		g, err = NewMigGPU(model, index, memoryGB, allowedMigGeometries, usedMigDevices, freeMigDevices)
+	case hasMpsProfiles:
+		g, err = NewMpsGPU(model, index, memoryGB, usedMpsProfiles, freeMpsProfiles)
+	default:
+		g = NewUnassignedGPU(model, index, memoryGB, allowedKind)
+	}
+	if err != nil {
+		return GPU{}, err
+	}
+	if allowedKind != "" {
+		g.allowedKind = allowedKind
+	}
+	g.allowedMigGeometries = allowedMigGeometries
+	return g, nil
+}
+
+func (n *Node) Clone() interface{} {
+	gpus := make([]GPU, len(n.GPUs))
+	for i, g := range n.GPUs {
+		gpus[i] = g.Clone()
+	}
+	clonedNodeInfo := n.nodeInfo.Clone()
+	return &Node{
+		Name:     n.Name,
+		GPUs:     gpus,
+		nodeInfo: *clonedNodeInfo,
+	}
+}
+
+// UpdateGeometryFor tries to update the geometry of each single GPU of the node in order to create the
+// MIG and MPS slices provided as argument, creating on each GPU the slices of the partitioning kind
+// allowed by its MIG mode.
+//
+// The method returns true if it updates the geometry of any GPU, false otherwise.
+func (n *Node) UpdateGeometryFor(slices map[gpu.Slice]int) (bool, error) {
+	// If there are no GPUs, then there's nothing to do
+	if len(n.GPUs) == 0 {
+		return false, nil
+	}
+	if len(slices) == 0 {
+		return false, nil
+	}
+
+	// Copy slices
+	var requiredSlices = make(map[gpu.Slice]int, len(slices))
+	for k, v := range slices {
+		requiredSlices[k] = v
+	}
+
+	var anyGpuUpdated bool
+	for i := range n.GPUs {
+		g := &n.GPUs[i]
+		updated := g.UpdateGeometryFor(requiredSlices)
+		anyGpuUpdated = anyGpuUpdated || updated
+		for slice, quantity := range g.GetFreeSlices() {
+			if _, ok := requiredSlices[slice]; !ok {
+				continue
+			}
+			requiredSlices[slice] -= quantity
+			if requiredSlices[slice] <= 0 {
+				delete(requiredSlices, slice)
+			}
+		}
+	}
+
+	// Update node info
+	scalarResources := n.computeScalarResources()
+	n.nodeInfo.Allocatable.ScalarResources = scalarResources
+
+	return anyGpuUpdated, nil
+}
+
+func (n *Node) computeScalarResources() map[v1.ResourceName]int64 {
+	res := make(map[v1.ResourceName]int64)
+
+	// Set all non-MIG and non-MPS scalar resources
+	for r, v := range n.nodeInfo.Allocatable.ScalarResources {
+		if !mig.IsNvidiaMigDevice(r) && !slicing.IsGpuSlice(r) {
+			res[r] = v
+		}
+	}
+	// Set MIG and MPS scalar resources
+	for _, g := range n.GPUs {
+		for r, v := range g.AsResources() {
+			res[r] += int64(v)
+		}
+	}
+
+	return res
+}
+
+func (n *Node) GetName() string {
+	return n.Name
+}
+
+// Geometry returns the overall geometry of the node, which corresponds to the sum of the geometries of all
+// the GPUs present in the Node, regardless of their partitioning kind.
+func (n *Node) Geometry() map[gpu.Slice]int {
+	res := make(map[gpu.Slice]int)
+	for _, g := range n.GPUs {
+		for p, q := range g.GetGeometry() {
+			res[p] += q
+		}
+	}
+	return res
+}
+
+func (n *Node) NodeInfo() framework.NodeInfo {
+	return n.nodeInfo
+}
+
+// AddPod adds a Pod to the node by updating the free and used slices of the Node GPUs according to the
+// slices requested by the Pod.
+//
+// AddPod returns an error if the node does not have any GPU providing enough free slices for the Pod.
+func (n *Node) AddPod(pod v1.Pod) error {
+	for i := range n.GPUs {
+		if err := n.GPUs[i].AddPod(pod); err == nil {
+			nodeInfo := n.NodeInfo()
+			nodeInfo.AddPod(&pod)
+			return nil
+		}
+	}
+	return fmt.Errorf("not enough free GPU slices")
+}
+
+// HasFreeCapacity returns true if any of the GPUs of the node has enough free capacity for hosting more pods.
+func (n *Node) HasFreeCapacity() bool {
+	for _, g := range n.GPUs {
+		if g.HasFreeCapacity() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hybrid_test
+
+import (
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/hybrid"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/gpu/slicing"
+	"github.com/nebuly-ai/nos/pkg/resource"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"testing"
+)
+
+func newHybridNode(t *testing.T, annotations map[string]string) hybrid.Node {
+	n := factory.BuildNode("node-1").WithLabels(map[string]string{
+		constant.LabelNvidiaProduct: string(gpu.GPUModel_A100_PCIe_80GB),
+		constant.LabelNvidiaCount:   "3",
+		constant.LabelNvidiaMemory:  "80000",
+	}).WithAnnotations(annotations).Get()
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(&n)
+	node, err := hybrid.NewNode(*nodeInfo)
+	assert.NoError(t, err)
+	return node
+}
+
+func TestNewNode(t *testing.T) {
+	t.Run("Node without GPU memory label", func(t *testing.T) {
+		n := factory.BuildNode("node-1").WithLabels(map[string]string{
+			constant.LabelNvidiaProduct: string(gpu.GPUModel_A100_PCIe_80GB),
+			constant.LabelNvidiaCount:   "1",
+		}).Get()
+		nodeInfo := framework.NewNodeInfo()
+		nodeInfo.SetNode(&n)
+		_, err := hybrid.NewNode(*nodeInfo)
+		assert.Error(t, err)
+	})
+
+	t.Run("GPU kind is inferred from status annotations", func(t *testing.T) {
+		node := newHybridNode(t, map[string]string{
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, mig.Profile1g10gb, resource.StatusFree): "2",
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 1, "20gb", resource.StatusUsed):            "1",
+		})
+		assert.Len(t, node.GPUs, 3)
+		assert.Equal(t, gpu.PartitioningKindMig, node.GPUs[0].GetKind())
+		assert.Equal(t, gpu.PartitioningKindMps, node.GPUs[1].GetKind())
+		assert.Equal(t, gpu.PartitioningKind(""), node.GPUs[2].GetKind())
+		assert.Equal(
+			t,
+			map[gpu.Slice]int{
+				mig.Profile1g10gb:           2,
+				slicing.ProfileName("20gb"): 1,
+			},
+			node.Geometry(),
+		)
+	})
+
+	t.Run("GPU exposing both MIG and MPS slices, used MPS slices take precedence", func(t *testing.T) {
+		node := newHybridNode(t, map[string]string{
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, mig.Profile1g10gb, resource.StatusFree): "2",
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, "20gb", resource.StatusUsed):            "1",
+		})
+		assert.Equal(t, gpu.PartitioningKindMps, node.GPUs[0].GetKind())
+	})
+}
+
+func TestNode__UpdateGeometryFor(t *testing.T) {
+	t.Run("Required MIG and MPS slices are spread across the GPUs", func(t *testing.T) {
+		node := newHybridNode(t, map[string]string{
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, "20gb", resource.StatusUsed): "1",
+			v1alpha1.AnnotationMigEnabledGpus:                                               "1",
+		})
+		updated, err := node.UpdateGeometryFor(map[gpu.Slice]int{
+			mig.Profile7g79gb:           1,
+			slicing.ProfileName("10gb"): 2,
+		})
+		assert.NoError(t, err)
+		assert.True(t, updated)
+
+		// GPU 0 has used MPS slices, so it must remain an MPS GPU
+		assert.Equal(t, gpu.PartitioningKindMps, node.GPUs[0].GetKind())
+		assert.Equal(t, 2, node.GPUs[0].GetGeometry()[slicing.ProfileName("10gb")])
+		// The MIG slice is created on the unassigned GPU with MIG mode enabled
+		assert.Equal(t, gpu.PartitioningKindMig, node.GPUs[1].GetKind())
+		assert.Equal(t, gpu.PartitioningKind(""), node.GPUs[2].GetKind())
+
+		// Node info must be updated with both MIG and MPS resources
+		scalarResources := node.NodeInfo().Allocatable.ScalarResources
+		assert.Equal(t, int64(1), scalarResources[mig.Profile7g79gb.AsResourceName()])
+		assert.Equal(t, int64(2), scalarResources[slicing.ProfileName("10gb").AsResourceName()])
+		assert.Equal(t, int64(1), scalarResources[slicing.ProfileName("20gb").AsResourceName()])
+	})
+
+	t.Run("GPUs are partitioned only with the kind allowed by their MIG mode", func(t *testing.T) {
+		node := newHybridNode(t, map[string]string{
+			fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, "20gb", resource.StatusFree): "1",
+			v1alpha1.AnnotationMigEnabledGpus:                                               "0",
+		})
+		updated, err := node.UpdateGeometryFor(map[gpu.Slice]int{
+			mig.Profile7g79gb: 2,
+		})
+		assert.NoError(t, err)
+		assert.True(t, updated)
+
+		// GPU 0 has MIG mode enabled and no used slices, so it is switched to MIG
+		assert.Equal(t, gpu.PartitioningKindMig, node.GPUs[0].GetKind())
+		assert.Equal(t, 1, node.GPUs[0].GetGeometry()[mig.Profile7g79gb])
+		// The other GPUs have MIG mode disabled, so they cannot provide MIG slices
+		assert.Equal(t, gpu.PartitioningKind(""), node.GPUs[1].GetKind())
+		assert.Equal(t, gpu.PartitioningKind(""), node.GPUs[2].GetKind())
+	})
+
+	t.Run("Unassigned GPUs are not partitioned if their MIG mode is unknown", func(t *testing.T) {
+		node := newHybridNode(t, map[string]string{})
+		updated, err := node.UpdateGeometryFor(map[gpu.Slice]int{
+			mig.Profile7g79gb:           1,
+			slicing.ProfileName("10gb"): 1,
+		})
+		assert.NoError(t, err)
+		assert.False(t, updated)
+	})
+
+	t.Run("No required slices", func(t *testing.T) {
+		node := newHybridNode(t, map[string]string{})
+		updated, err := node.UpdateGeometryFor(map[gpu.Slice]int{})
+		assert.NoError(t, err)
+		assert.False(t, updated)
+	})
+}
+
+func TestNode__AddPod(t *testing.T) {
+	node := newHybridNode(t, map[string]string{
+		fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, "20gb", resource.StatusFree):            "1",
+		fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 1, mig.Profile1g10gb, resource.StatusFree): "1",
+	})
+	migPod := factory.BuildPod("ns-1", "pd-1").WithContainer(
+		factory.BuildContainer("c-1", "im-1").
+			WithScalarResourceRequest(mig.Profile1g10gb.AsResourceName(), 1).
+			Get(),
+	).Get()
+	mpsPod := factory.BuildPod("ns-1", "pd-2").WithContainer(
+		factory.BuildContainer("c-1", "im-1").
+			WithScalarResourceRequest(slicing.ProfileName("20gb").AsResourceName(), 1).
+			Get(),
+	).Get()
+
+	assert.NoError(t, node.AddPod(migPod))
+	assert.NoError(t, node.AddPod(mpsPod))
+	assert.Error(t, node.AddPod(mpsPod))
+	assert.Empty(t, node.GPUs[0].GetFreeSlices())
+	assert.Empty(t, node.GPUs[1].GetFreeSlices())
+}
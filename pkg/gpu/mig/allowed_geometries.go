@@ -20,6 +20,8 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/nebuly-ai/nos/pkg/gpu"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"os"
 )
 
 type AllowedMigGeometries struct {
@@ -80,3 +82,17 @@ func (a AllowedMigGeometriesList) GroupByModel() map[gpu.Model][]gpu.Geometry {
 	}
 	return res
 }
+
+// LoadAllowedMigGeometriesFromFile reads the list of allowed MIG geometries
+// from the YAML or JSON file provided as argument
+func LoadAllowedMigGeometriesFromFile(file string) (AllowedMigGeometriesList, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var allowedGeometries = make(AllowedMigGeometriesList, 0)
+	if err = yaml.Unmarshal(data, &allowedGeometries); err != nil {
+		return nil, err
+	}
+	return allowedGeometries, nil
+}
@@ -48,3 +48,19 @@ func GroupSpecAnnotationsByMigProfile(annotations gpu.SpecAnnotationList) map[Pr
 	}
 	return result
 }
+
+// GetSpecGeometries returns the MIG geometry specified for each GPU by the spec annotations
+// provided as argument, grouped by GPU index
+func GetSpecGeometries(annotations gpu.SpecAnnotationList) map[int]gpu.Geometry {
+	result := make(map[int]gpu.Geometry)
+	for gpuIndex, gpuAnnotations := range annotations.GroupByGpuIndex() {
+		geometry := make(gpu.Geometry)
+		for _, a := range gpuAnnotations {
+			if a.Quantity > 0 {
+				geometry[ProfileName(a.ProfileName)] += a.Quantity
+			}
+		}
+		result[gpuIndex] = geometry
+	}
+	return result
+}
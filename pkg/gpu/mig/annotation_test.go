@@ -0,0 +1,38 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mig_test
+
+import (
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetSpecGeometries(t *testing.T) {
+	annotations := gpu.SpecAnnotationList{
+		{Index: 0, ProfileName: "1g.5gb", Quantity: 2},
+		{Index: 0, ProfileName: "3g.20gb", Quantity: 1},
+		{Index: 0, ProfileName: "2g.10gb", Quantity: 0},
+		{Index: 1, ProfileName: "7g.40gb", Quantity: 1},
+	}
+	expected := map[int]gpu.Geometry{
+		0: {mig.Profile1g5gb: 2, mig.Profile3g20gb: 1},
+		1: {mig.Profile7g40gb: 1},
+	}
+	assert.Equal(t, expected, mig.GetSpecGeometries(annotations))
+}
@@ -32,6 +32,8 @@ type Client interface {
 	CreateMigDevices(ctx context.Context, profileList ProfileList) (ProfileList, error)
 	DeleteMigDevice(ctx context.Context, device gpu.Device) gpu.Error
 	DeleteAllExcept(ctx context.Context, resources gpu.DeviceList) error
+	GetAllowedGeometries(ctx context.Context) (map[int][]gpu.Geometry, gpu.Error)
+	GetMigEnabledGPUs(ctx context.Context) ([]int, gpu.Error)
 }
 
 type clientImpl struct {
@@ -138,6 +140,36 @@ func (c clientImpl) DeleteAllExcept(_ context.Context, resourcesToKeep gpu.Devic
 	return c.nvmlClient.DeleteAllMigDevicesExcept(idsToKeep)
 }
 
+// GetAllowedGeometries returns the MIG geometries allowed by each MIG-enabled GPU, grouped by GPU index.
+// The geometries are computed from the GPU instance profiles and placements that NVML reports as
+// supported by each GPU.
+func (c clientImpl) GetAllowedGeometries(ctx context.Context) (map[int][]gpu.Geometry, gpu.Error) {
+	logger := klog.FromContext(ctx)
+
+	gpuIndexes, err := c.nvmlClient.GetMigEnabledGPUs()
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int][]gpu.Geometry, len(gpuIndexes))
+	for _, gpuIndex := range gpuIndexes {
+		profiles, err := c.nvmlClient.GetGpuInstanceProfiles(gpuIndex)
+		if err != nil {
+			return nil, err
+		}
+		geometries := ComputeAllowedGeometries(profiles)
+		logger.V(1).Info("discovered allowed MIG geometries", "GPUIndex", gpuIndex, "geometries", geometries)
+		if len(geometries) > 0 {
+			res[gpuIndex] = geometries
+		}
+	}
+	return res, nil
+}
+
+// GetMigEnabledGPUs returns the indexes of the GPUs that have MIG mode enabled.
+func (c clientImpl) GetMigEnabledGPUs(_ context.Context) ([]int, gpu.Error) {
+	return c.nvmlClient.GetMigEnabledGPUs()
+}
+
 func (c clientImpl) extractMigDevices(ctx context.Context, devices []resource.Device) ([]gpu.Device, gpu.Error) {
 	logger := klog.FromContext(ctx)
 
@@ -21,12 +21,17 @@ import (
 	"fmt"
 	"github.com/nebuly-ai/nos/pkg/gpu"
 	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/gpu/nvml"
 	"github.com/nebuly-ai/nos/pkg/resource"
+	"github.com/nebuly-ai/nos/pkg/test/kubelet"
 	mockednvml "github.com/nebuly-ai/nos/pkg/test/mocks/nvml"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"k8s.io/apimachinery/pkg/types"
 	pdrv1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+	"k8s.io/kubernetes/pkg/kubelet/apis/podresources"
 	"testing"
+	"time"
 )
 
 type MockedPodResourcesListerClient struct {
@@ -375,3 +380,130 @@ func TestClient_GetAllocatableMigDevices(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_GetAllowedGeometries(t *testing.T) {
+	a30Profiles := []nvml.GpuInstanceProfile{
+		{Name: "2g.12gb", MaxInstances: 2, Placements: []nvml.GpuInstancePlacement{{Start: 0, Size: 2}, {Start: 2, Size: 2}}},
+		{Name: "4g.24gb", MaxInstances: 1, Placements: []nvml.GpuInstancePlacement{{Start: 0, Size: 4}}},
+	}
+
+	testCases := []struct {
+		name               string
+		migEnabledGpus     []int
+		migEnabledGpusErr  gpu.Error
+		profiles           map[int][]nvml.GpuInstanceProfile
+		profilesErr        gpu.Error
+		expectedGeometries map[int][]gpu.Geometry
+		expectedError      bool
+	}{
+		{
+			name:              "Error fetching MIG-enabled GPUs",
+			migEnabledGpusErr: gpu.GenericErr.Errorf("error"),
+			expectedError:     true,
+		},
+		{
+			name:           "Error fetching GPU instance profiles",
+			migEnabledGpus: []int{0},
+			profiles:       map[int][]nvml.GpuInstanceProfile{0: nil},
+			profilesErr:    gpu.GenericErr.Errorf("error"),
+			expectedError:  true,
+		},
+		{
+			name:           "GPUs without any supported profile are not included",
+			migEnabledGpus: []int{0, 1},
+			profiles: map[int][]nvml.GpuInstanceProfile{
+				0: a30Profiles,
+				1: {},
+			},
+			expectedGeometries: map[int][]gpu.Geometry{
+				0: {
+					{mig.Profile2g12gb: 2},
+					{mig.Profile4g24gb: 1},
+				},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			nvmlClient := mockednvml.Client{}
+			nvmlClient.On("GetMigEnabledGPUs").Return(tt.migEnabledGpus, tt.migEnabledGpusErr)
+			for gpuIndex, profiles := range tt.profiles {
+				nvmlClient.On("GetGpuInstanceProfiles", gpuIndex).Return(profiles, tt.profilesErr)
+			}
+			client := mig.NewClient(resource.NewClient(MockedPodResourcesListerClient{}), &nvmlClient)
+
+			geometries, err := client.GetAllowedGeometries(context.TODO())
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, geometries, len(tt.expectedGeometries))
+			for gpuIndex, expected := range tt.expectedGeometries {
+				assert.ElementsMatch(t, expected, geometries[gpuIndex])
+			}
+		})
+	}
+}
+
+func TestClient__GetMigDevices__KubeletPodResources(t *testing.T) {
+	nvmlClient, err := nvml.NewFakeClient(gpu.GPUModel_A100_PCIe_40GB, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, nvmlClient.CreateMigDevices([]string{"3g.20gb", "1g.5gb"}, 0))
+	assert.NoError(t, nvmlClient.CreateMigDevices([]string{"7g.40gb"}, 1))
+
+	server := kubelet.NewPodResourcesServer(t.TempDir())
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	allocatable := make([]resource.Device, 0)
+	for _, d := range nvmlClient.GetMigDevices() {
+		allocatable = append(allocatable, resource.Device{
+			ResourceName: mig.ProfileName(d.ProfileName).AsResourceName(),
+			DeviceId:     d.Id,
+		})
+	}
+	// device still advertised by the device plugin, but already deleted
+	allocatable = append(allocatable, resource.Device{ResourceName: "nvidia.com/mig-1g.5gb", DeviceId: "MIG-deleted"})
+	server.SetAllocatableDevices(allocatable...)
+	server.SetUsedDevices(types.NamespacedName{Namespace: "ns-1", Name: "pod-1"}, "c1", allocatable[0])
+
+	lister, conn, err := podresources.GetV1Client(server.Endpoint(), time.Second, 1024*1024)
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := mig.NewClient(resource.NewClient(lister), nvmlClient)
+
+	devices, err := client.GetMigDevices(context.Background())
+	assert.NoError(t, err)
+	assert.ElementsMatch(
+		t,
+		gpu.DeviceList{
+			{
+				Device: resource.Device{
+					ResourceName: allocatable[0].ResourceName,
+					DeviceId:     allocatable[0].DeviceId,
+					Status:       resource.StatusUsed,
+				},
+				GpuIndex: 0,
+			},
+			{
+				Device: resource.Device{
+					ResourceName: allocatable[1].ResourceName,
+					DeviceId:     allocatable[1].DeviceId,
+					Status:       resource.StatusFree,
+				},
+				GpuIndex: 0,
+			},
+			{
+				Device: resource.Device{
+					ResourceName: allocatable[2].ResourceName,
+					DeviceId:     allocatable[2].DeviceId,
+					Status:       resource.StatusFree,
+				},
+				GpuIndex: 1,
+			},
+		},
+		devices,
+	)
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package mig
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/nvml"
+	v1 "k8s.io/api/core/v1"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type placedProfile struct {
+	name         ProfileName
+	start        int
+	size         int
+	maxInstances int
+}
+
+// ComputeAllowedGeometries returns the MIG geometries that can be created on a GPU supporting the
+// GPU instance profiles provided as argument.
+//
+// A geometry is allowed if its profiles can be placed on the GPU without overlapping, and it is
+// returned only if it is maximal, namely if no other profile could be placed on the positions of
+// the GPU that it leaves free. Profiles with an invalid name are ignored.
+func ComputeAllowedGeometries(profiles []nvml.GpuInstanceProfile) []gpu.Geometry {
+	candidates := make([]placedProfile, 0)
+	var nPositions int
+	for _, p := range profiles {
+		name := ProfileName(p.Name)
+		if !name.IsValid() || p.MaxInstances < 1 {
+			continue
+		}
+		for _, placement := range p.Placements {
+			if placement.Size < 1 || placement.Start < 0 {
+				continue
+			}
+			candidates = append(candidates, placedProfile{
+				name:         name,
+				start:        placement.Start,
+				size:         placement.Size,
+				maxInstances: p.MaxInstances,
+			})
+			if placement.Start+placement.Size > nPositions {
+				nPositions = placement.Start + placement.Size
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].start != candidates[j].start {
+			return candidates[i].start < candidates[j].start
+		}
+		if candidates[i].size != candidates[j].size {
+			return candidates[i].size > candidates[j].size
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	var res = make([]gpu.Geometry, 0)
+	var seen = make(map[string]bool)
+	var occupied = make([]bool, nPositions)
+	var placed = make(map[ProfileName]int)
+
+	var fits = func(c placedProfile) bool {
+		if placed[c.name] >= c.maxInstances {
+			return false
+		}
+		for i := c.start; i < c.start+c.size; i++ {
+			if occupied[i] {
+				return false
+			}
+		}
+		return true
+	}
+	var setOccupied = func(c placedProfile, value bool) {
+		for i := c.start; i < c.start+c.size; i++ {
+			occupied[i] = value
+		}
+	}
+
+	var visit func(pos int)
+	visit = func(pos int) {
+		for pos < nPositions && occupied[pos] {
+			pos++
+		}
+		if pos == nPositions {
+			if len(placed) == 0 {
+				return
+			}
+			for _, c := range candidates {
+				if fits(c) {
+					return
+				}
+			}
+			geometry := make(gpu.Geometry, len(placed))
+			for p, q := range placed {
+				geometry[p] = q
+			}
+			if !seen[geometry.Id()] {
+				seen[geometry.Id()] = true
+				res = append(res, geometry)
+			}
+			return
+		}
+		for _, c := range candidates {
+			if c.start != pos || !fits(c) {
+				continue
+			}
+			setOccupied(c, true)
+			placed[c.name]++
+			visit(pos + c.size)
+			placed[c.name]--
+			if placed[c.name] == 0 {
+				delete(placed, c.name)
+			}
+			setOccupied(c, false)
+		}
+		// Leave the current position free
+		visit(pos + 1)
+	}
+	visit(0)
+
+	return res
+}
+
+// MarshalAllowedGeometriesAnnotation returns the value of the annotation used for exposing the MIG geometries
+// allowed by each GPU of a node, grouped by GPU index.
+func MarshalAllowedGeometriesAnnotation(geometries map[int][]gpu.Geometry) (string, error) {
+	b, err := json.Marshal(geometries)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ParseAllowedGeometriesAnnotation returns the MIG geometries allowed by each GPU of the node provided
+// as argument, grouped by GPU index, according to the annotation v1alpha1.AnnotationAllowedMigGeometries.
+//
+// The function returns an empty map if the node does not have the annotation.
+func ParseAllowedGeometriesAnnotation(node v1.Node) (map[int][]gpu.Geometry, error) {
+	var res = make(map[int][]gpu.Geometry)
+	value, ok := node.Annotations[v1alpha1.AnnotationAllowedMigGeometries]
+	if !ok {
+		return res, nil
+	}
+	var parsed = make(map[int][]map[ProfileName]int)
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid annotation %s: %v", v1alpha1.AnnotationAllowedMigGeometries, err)
+	}
+	for gpuIndex, migGeometries := range parsed {
+		for _, g := range migGeometries {
+			for p, q := range g {
+				if !p.IsValid() {
+					return nil, fmt.Errorf("invalid annotation %s: invalid profile %s", v1alpha1.AnnotationAllowedMigGeometries, p)
+				}
+				if q < 1 {
+					return nil, fmt.Errorf("invalid annotation %s: invalid quantity %d for profile %s", v1alpha1.AnnotationAllowedMigGeometries, q, p)
+				}
+			}
+		}
+		res[gpuIndex] = migGeometriesToGpuGeometries(migGeometries)
+	}
+	return res, nil
+}
+
+// MarshalMigEnabledGpusAnnotation returns the value of the annotation used for exposing the indexes
+// of the GPUs of a node that have MIG mode enabled.
+func MarshalMigEnabledGpusAnnotation(gpuIndexes []int) string {
+	sorted := make([]int, len(gpuIndexes))
+	copy(sorted, gpuIndexes)
+	sort.Ints(sorted)
+	values := make([]string, len(sorted))
+	for i, gpuIndex := range sorted {
+		values[i] = strconv.Itoa(gpuIndex)
+	}
+	return strings.Join(values, ",")
+}
+
+// ParseMigEnabledGpusAnnotation returns the indexes of the GPUs of the node provided as argument that have
+// MIG mode enabled, according to the annotation v1alpha1.AnnotationMigEnabledGpus.
+//
+// The returned bool is false if the node does not have the annotation, namely if the MIG mode of its GPUs
+// is unknown.
+func ParseMigEnabledGpusAnnotation(node v1.Node) (map[int]bool, bool, error) {
+	var res = make(map[int]bool)
+	value, ok := node.Annotations[v1alpha1.AnnotationMigEnabledGpus]
+	if !ok {
+		return res, false, nil
+	}
+	if value == "" {
+		return res, true, nil
+	}
+	for _, v := range strings.Split(value, ",") {
+		gpuIndex, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || gpuIndex < 0 {
+			return nil, false, fmt.Errorf("invalid annotation %s: invalid GPU index %q", v1alpha1.AnnotationMigEnabledGpus, v)
+		}
+		res[gpuIndex] = true
+	}
+	return res, true, nil
+}
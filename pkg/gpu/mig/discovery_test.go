@@ -0,0 +1,255 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package mig_test
+
+import (
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/gpu/nvml"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func newGpuInstanceProfile(name string, maxInstances int, size int, starts ...int) nvml.GpuInstanceProfile {
+	placements := make([]nvml.GpuInstancePlacement, 0, len(starts))
+	for _, start := range starts {
+		placements = append(placements, nvml.GpuInstancePlacement{Start: start, Size: size})
+	}
+	return nvml.GpuInstanceProfile{
+		Name:         name,
+		MaxInstances: maxInstances,
+		Placements:   placements,
+	}
+}
+
+func TestComputeAllowedGeometries(t *testing.T) {
+	testCases := []struct {
+		name     string
+		profiles []nvml.GpuInstanceProfile
+		expected []gpu.Geometry
+	}{
+		{
+			name:     "No profiles",
+			profiles: []nvml.GpuInstanceProfile{},
+			expected: []gpu.Geometry{},
+		},
+		{
+			name: "Profiles with invalid names are ignored",
+			profiles: []nvml.GpuInstanceProfile{
+				newGpuInstanceProfile("foo", 4, 1, 0, 1, 2, 3),
+				newGpuInstanceProfile("4g.24gb", 1, 4, 0),
+			},
+			expected: []gpu.Geometry{
+				{mig.Profile4g24gb: 1},
+			},
+		},
+		{
+			name: "A30 profiles",
+			profiles: []nvml.GpuInstanceProfile{
+				newGpuInstanceProfile("1g.6gb", 4, 1, 0, 1, 2, 3),
+				newGpuInstanceProfile("1g.6gb+me", 1, 1, 0, 1, 2, 3),
+				newGpuInstanceProfile("2g.12gb", 2, 2, 0, 2),
+				newGpuInstanceProfile("4g.24gb", 1, 4, 0),
+			},
+			expected: []gpu.Geometry{
+				{mig.Profile4g24gb: 1},
+				{mig.Profile2g12gb: 2},
+				{mig.Profile2g12gb: 1, mig.Profile1g6gb: 2},
+				{mig.Profile2g12gb: 1, mig.Profile1g6gb: 1, mig.ProfileName("1g.6gb+me"): 1},
+				{mig.Profile1g6gb: 4},
+				{mig.Profile1g6gb: 3, mig.ProfileName("1g.6gb+me"): 1},
+			},
+		},
+		{
+			name: "A100 profiles without media extensions",
+			profiles: []nvml.GpuInstanceProfile{
+				newGpuInstanceProfile("1g.5gb", 7, 1, 0, 1, 2, 3, 4, 5, 6),
+				newGpuInstanceProfile("2g.10gb", 3, 2, 0, 2, 4),
+				newGpuInstanceProfile("3g.20gb", 2, 4, 0, 4),
+				newGpuInstanceProfile("4g.20gb", 1, 4, 0),
+				newGpuInstanceProfile("7g.40gb", 1, 8, 0),
+			},
+			expected: []gpu.Geometry{
+				{mig.Profile7g40gb: 1},
+				{mig.Profile4g20gb: 1, mig.Profile2g10gb: 1, mig.Profile1g5gb: 1},
+				{mig.Profile4g20gb: 1, mig.Profile1g5gb: 3},
+				{mig.Profile4g20gb: 1, mig.Profile3g20gb: 1},
+				{mig.Profile3g20gb: 2},
+				{mig.Profile3g20gb: 1, mig.Profile2g10gb: 1, mig.Profile1g5gb: 1},
+				{mig.Profile3g20gb: 1, mig.Profile1g5gb: 4},
+				{mig.Profile3g20gb: 1, mig.Profile1g5gb: 3},
+				{mig.Profile3g20gb: 1, mig.Profile2g10gb: 2},
+				{mig.Profile3g20gb: 1, mig.Profile2g10gb: 1, mig.Profile1g5gb: 2},
+				{mig.Profile2g10gb: 3, mig.Profile1g5gb: 1},
+				{mig.Profile2g10gb: 2, mig.Profile1g5gb: 3},
+				{mig.Profile2g10gb: 1, mig.Profile1g5gb: 5},
+				{mig.Profile1g5gb: 7},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			res := mig.ComputeAllowedGeometries(tt.profiles)
+			assert.ElementsMatch(t, tt.expected, res)
+		})
+	}
+}
+
+func TestParseAllowedGeometriesAnnotation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    map[int][]gpu.Geometry
+		errExpected bool
+	}{
+		{
+			name:        "Node without annotation",
+			annotations: map[string]string{},
+			expected:    map[int][]gpu.Geometry{},
+		},
+		{
+			name: "Invalid JSON",
+			annotations: map[string]string{
+				v1alpha1.AnnotationAllowedMigGeometries: "foo",
+			},
+			errExpected: true,
+		},
+		{
+			name: "Invalid profile",
+			annotations: map[string]string{
+				v1alpha1.AnnotationAllowedMigGeometries: `{"0":[{"foo":1}]}`,
+			},
+			errExpected: true,
+		},
+		{
+			name: "Invalid quantity",
+			annotations: map[string]string{
+				v1alpha1.AnnotationAllowedMigGeometries: `{"0":[{"1g.5gb":0}]}`,
+			},
+			errExpected: true,
+		},
+		{
+			name: "Valid annotation",
+			annotations: map[string]string{
+				v1alpha1.AnnotationAllowedMigGeometries: `{"0":[{"1g.5gb":7},{"7g.40gb":1}],"1":[{"1g.5gb+me":1,"1g.5gb":6}]}`,
+			},
+			expected: map[int][]gpu.Geometry{
+				0: {
+					{mig.Profile1g5gb: 7},
+					{mig.Profile7g40gb: 1},
+				},
+				1: {
+					{mig.Profile1g5gb: 6, mig.Profile1g5gbMe: 1},
+				},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			node := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Annotations: tt.annotations}}
+			res, err := mig.ParseAllowedGeometriesAnnotation(node)
+			if tt.errExpected {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+}
+
+func TestMarshalAllowedGeometriesAnnotation(t *testing.T) {
+	geometries := map[int][]gpu.Geometry{
+		0: {
+			{mig.Profile1g5gb: 7},
+			{mig.Profile3g20gb: 1, mig.Profile2g10gb: 2},
+		},
+		1: {
+			{mig.Profile7g40gb: 1},
+		},
+	}
+	value, err := mig.MarshalAllowedGeometriesAnnotation(geometries)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"0":[{"1g.5gb":7},{"2g.10gb":2,"3g.20gb":1}],"1":[{"7g.40gb":1}]}`, value)
+
+	node := v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node",
+			Annotations: map[string]string{v1alpha1.AnnotationAllowedMigGeometries: value},
+		},
+	}
+	parsed, err := mig.ParseAllowedGeometriesAnnotation(node)
+	assert.NoError(t, err)
+	assert.Equal(t, geometries, parsed)
+}
+
+func TestParseMigEnabledGpusAnnotation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		annotations   map[string]string
+		expected      map[int]bool
+		expectedFound bool
+		errExpected   bool
+	}{
+		{
+			name:          "Node without annotation",
+			annotations:   map[string]string{},
+			expected:      map[int]bool{},
+			expectedFound: false,
+		},
+		{
+			name:          "Empty annotation: no GPU has MIG mode enabled",
+			annotations:   map[string]string{v1alpha1.AnnotationMigEnabledGpus: ""},
+			expected:      map[int]bool{},
+			expectedFound: true,
+		},
+		{
+			name:        "Invalid GPU index",
+			annotations: map[string]string{v1alpha1.AnnotationMigEnabledGpus: "0,foo"},
+			errExpected: true,
+		},
+		{
+			name:          "Valid annotation",
+			annotations:   map[string]string{v1alpha1.AnnotationMigEnabledGpus: "0,2"},
+			expected:      map[int]bool{0: true, 2: true},
+			expectedFound: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			node := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Annotations: tt.annotations}}
+			res, found, err := mig.ParseMigEnabledGpusAnnotation(node)
+			if tt.errExpected {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedFound, found)
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+}
+
+func TestMarshalMigEnabledGpusAnnotation(t *testing.T) {
+	assert.Equal(t, "", mig.MarshalMigEnabledGpusAnnotation([]int{}))
+	assert.Equal(t, "0,1,3", mig.MarshalMigEnabledGpusAnnotation([]int{3, 0, 1}))
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mig
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	v1 "k8s.io/api/core/v1"
+)
+
+// PartitioningFailure describes a MIG partitioning plan that the MIG agent was not able to apply to a node
+type PartitioningFailure struct {
+	// PlanId is the ID of the partitioning plan that could not be applied
+	PlanId string `json:"planId"`
+	// Geometries contains the geometry that could not be applied to each GPU, grouped by GPU index
+	Geometries map[int]gpu.Geometry `json:"geometries"`
+	// RolledBack is true if the GPUs have been restored to the geometry they had before applying the plan
+	RolledBack bool `json:"rolledBack"`
+}
+
+// MarshalPartitioningFailureAnnotation returns the value of the annotation used for exposing the
+// MIG partitioning failure provided as argument
+func MarshalPartitioningFailureAnnotation(failure PartitioningFailure) (string, error) {
+	b, err := json.Marshal(failure)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ParsePartitioningFailureAnnotation returns the MIG partitioning failure reported on the node provided
+// as argument through the annotation v1alpha1.AnnotationMigPartitioningFailure.
+//
+// The function returns nil if the node does not have the annotation.
+func ParsePartitioningFailureAnnotation(node v1.Node) (*PartitioningFailure, error) {
+	value, ok := node.Annotations[v1alpha1.AnnotationMigPartitioningFailure]
+	if !ok {
+		return nil, nil
+	}
+	var parsed struct {
+		PlanId     string                      `json:"planId"`
+		Geometries map[int]map[ProfileName]int `json:"geometries"`
+		RolledBack bool                        `json:"rolledBack"`
+	}
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid annotation %s: %v", v1alpha1.AnnotationMigPartitioningFailure, err)
+	}
+	res := PartitioningFailure{
+		PlanId:     parsed.PlanId,
+		Geometries: make(map[int]gpu.Geometry, len(parsed.Geometries)),
+		RolledBack: parsed.RolledBack,
+	}
+	for gpuIndex, g := range parsed.Geometries {
+		for p := range g {
+			if !p.IsValid() {
+				return nil, fmt.Errorf("invalid annotation %s: invalid profile %s", v1alpha1.AnnotationMigPartitioningFailure, p)
+			}
+		}
+		res.Geometries[gpuIndex] = migGeometriesToGpuGeometries([]map[ProfileName]int{g})[0]
+	}
+	return &res, nil
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mig_test
+
+import (
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestParsePartitioningFailureAnnotation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    *mig.PartitioningFailure
+		errExpected bool
+	}{
+		{
+			name:        "Node without annotation",
+			annotations: map[string]string{},
+			expected:    nil,
+		},
+		{
+			name: "Invalid JSON",
+			annotations: map[string]string{
+				v1alpha1.AnnotationMigPartitioningFailure: "foo",
+			},
+			errExpected: true,
+		},
+		{
+			name: "Invalid profile",
+			annotations: map[string]string{
+				v1alpha1.AnnotationMigPartitioningFailure: `{"planId":"1","geometries":{"0":{"foo":1}}}`,
+			},
+			errExpected: true,
+		},
+		{
+			name: "Valid annotation",
+			annotations: map[string]string{
+				v1alpha1.AnnotationMigPartitioningFailure: `{"planId":"1","geometries":{"0":{"1g.5gb":7},"1":{"1g.5gb+me":1,"1g.5gb":6}},"rolledBack":true}`,
+			},
+			expected: &mig.PartitioningFailure{
+				PlanId: "1",
+				Geometries: map[int]gpu.Geometry{
+					0: {mig.Profile1g5gb: 7},
+					1: {mig.Profile1g5gbMe: 1, mig.Profile1g5gb: 6},
+				},
+				RolledBack: true,
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			node := v1.Node{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			res, err := mig.ParsePartitioningFailureAnnotation(node)
+			if tt.errExpected {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+}
+
+func TestMarshalPartitioningFailureAnnotation(t *testing.T) {
+	failure := mig.PartitioningFailure{
+		PlanId: "plan-1",
+		Geometries: map[int]gpu.Geometry{
+			0: {mig.Profile1g5gb: 7},
+			1: {mig.Profile3g20gb: 2},
+		},
+		RolledBack: false,
+	}
+	value, err := mig.MarshalPartitioningFailureAnnotation(failure)
+	assert.NoError(t, err)
+
+	node := v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{v1alpha1.AnnotationMigPartitioningFailure: value},
+		},
+	}
+	parsed, err := mig.ParsePartitioningFailureAnnotation(node)
+	assert.NoError(t, err)
+	assert.Equal(t, &failure, parsed)
+}
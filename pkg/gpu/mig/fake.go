@@ -0,0 +1,52 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mig
+
+import (
+	"context"
+	"github.com/nebuly-ai/nos/pkg/gpu/nvml"
+	"github.com/nebuly-ai/nos/pkg/resource"
+)
+
+// fakeResourceClient is a resource.Client that exposes as allocatable the MIG devices
+// of the GPUs simulated by a nvml.FakeClient.
+type fakeResourceClient struct {
+	nvmlClient *nvml.FakeClient
+}
+
+// NewFakeResourceClient returns a resource.Client that reports as allocatable the MIG devices
+// existing on the GPUs simulated by the nvml.FakeClient provided as argument. Since no container can
+// use simulated devices, the client never reports any used device.
+func NewFakeResourceClient(nvmlClient *nvml.FakeClient) resource.Client {
+	return fakeResourceClient{nvmlClient: nvmlClient}
+}
+
+func (c fakeResourceClient) GetAllocatableDevices(_ context.Context) ([]resource.Device, error) {
+	devices := make([]resource.Device, 0)
+	for _, d := range c.nvmlClient.GetMigDevices() {
+		devices = append(devices, resource.Device{
+			ResourceName: ProfileName(d.ProfileName).AsResourceName(),
+			DeviceId:     d.Id,
+			Status:       resource.StatusUnknown,
+		})
+	}
+	return devices, nil
+}
+
+func (c fakeResourceClient) GetUsedDevices(_ context.Context) ([]resource.Device, error) {
+	return make([]resource.Device, 0), nil
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mig_test
+
+import (
+	"context"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/gpu/nvml"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFakeResourceClient(t *testing.T) {
+	nvmlClient, err := nvml.NewFakeClient(gpu.GPUModel_A30, 2)
+	assert.NoError(t, err)
+	resourceClient := mig.NewFakeResourceClient(nvmlClient)
+	migClient := mig.NewClient(resourceClient, nvmlClient)
+	ctx := context.Background()
+
+	// No MIG devices
+	devices, err := migClient.GetMigDevices(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, devices)
+
+	// Created MIG devices are reported as free
+	assert.NoError(t, nvmlClient.CreateMigDevices([]string{"1g.6gb", "2g.12gb"}, 0))
+	assert.NoError(t, nvmlClient.CreateMigDevices([]string{"4g.24gb"}, 1))
+	devices, err = migClient.GetMigDevices(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, devices, 3)
+	assert.Len(t, devices.GetFree(), 3)
+	byGpu := devices.GroupByGpuIndex()
+	assert.Len(t, byGpu[0], 2)
+	assert.Len(t, byGpu[1], 1)
+
+	// Deleted MIG devices are not reported anymore
+	assert.NoError(t, nvmlClient.DeleteAllMigDevicesExcept([]string{}))
+	devices, err = migClient.GetMigDevices(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, devices)
+}
@@ -54,6 +54,22 @@ func NewGPU(model gpu.Model, index int, usedMigDevices, freeMigDevices map[Profi
 	}, nil
 }
 
+// NewGPUWithAllowedGeometries creates a GPU that allows the MIG geometries provided as argument
+// instead of the ones known for its model. It is used for GPUs whose allowed geometries have been
+// discovered directly on the node.
+func NewGPUWithAllowedGeometries(model gpu.Model, index int, allowedGeometries []gpu.Geometry, usedMigDevices, freeMigDevices map[ProfileName]int) (GPU, error) {
+	if len(allowedGeometries) == 0 {
+		return GPU{}, fmt.Errorf("GPU %d does not allow any MIG geometry", index)
+	}
+	return GPU{
+		index:                index,
+		model:                model,
+		allowedMigGeometries: allowedGeometries,
+		usedMigDevices:       usedMigDevices,
+		freeMigDevices:       freeMigDevices,
+	}, nil
+}
+
 func (g *GPU) Clone() GPU {
 	cloned := GPU{
 		index:                g.index,
@@ -211,6 +227,59 @@ func (g *GPU) UpdateGeometryFor(requiredProfiles map[gpu.Slice]int) bool {
 	return true
 }
 
+// Compact merges the free capacity of the GPU into the fewest possible MIG devices, so that
+// larger MIG profiles become available again.
+//
+// If the used devices allow it, the GPU is reverted to the geometry with the fewest slices among the ones
+// allowed by its model. Otherwise, the method applies the allowed geometry with the lowest number
+// of MIG devices that does not delete any used device.
+//
+// The method returns true if the GPU geometry gets updated, false otherwise.
+func (g *GPU) Compact() bool {
+	// If all the devices are being used there's nothing to compact
+	currentGeometry := g.GetGeometry()
+	if !g.HasFreeMigDevices() && g.AllowsGeometry(currentGeometry) {
+		return false
+	}
+
+	var target gpu.Geometry
+	fewestSlicesGeometry := gpu.GetFewestSlicesGeometry(g.GetAllowedGeometries())
+	if canApply, _ := g.CanApplyGeometry(fewestSlicesGeometry); canApply {
+		target = fewestSlicesGeometry
+	}
+	if target == nil {
+		var minDevices int
+		for _, candidate := range g.GetAllowedGeometries() {
+			if canApply, _ := g.CanApplyGeometry(candidate); !canApply {
+				continue
+			}
+			if nDevices := countDevices(candidate); target == nil || nDevices < minDevices {
+				target = candidate
+				minDevices = nDevices
+			}
+		}
+	}
+
+	// No geometry can be applied, or the current one is already the most compact
+	if target == nil || cmp.Equal(target, currentGeometry) {
+		return false
+	}
+	if g.AllowsGeometry(currentGeometry) && countDevices(target) >= countDevices(currentGeometry) {
+		return false
+	}
+
+	_ = g.ApplyGeometry(target)
+	return true
+}
+
+func countDevices(geometry gpu.Geometry) int {
+	var res int
+	for _, quantity := range geometry {
+		res += quantity
+	}
+	return res
+}
+
 // AllowsGeometry returns true if the geometry provided as argument is allowed by the GPU model
 func (g *GPU) AllowsGeometry(geometry gpu.Geometry) bool {
 	for _, allowedGeometry := range g.GetAllowedGeometries() {
@@ -221,7 +290,8 @@ func (g *GPU) AllowsGeometry(geometry gpu.Geometry) bool {
 	return false
 }
 
-// GetAllowedGeometries returns the MIG geometries allowed by the GPU model
+// GetAllowedGeometries returns the MIG geometries allowed by the GPU, which are either the ones
+// discovered on its node or, if not available, the ones known for its model
 func (g *GPU) GetAllowedGeometries() []gpu.Geometry {
 	return g.allowedMigGeometries
 }
@@ -246,6 +316,32 @@ func (g *GPU) AddPod(pod v1.Pod) error {
 	return nil
 }
 
+// RemovePod removes a Pod from the GPU by releasing the used MIG devices requested by the Pod, which
+// become free MIG devices.
+//
+// RemovePod returns an error if the GPU does not have enough used MIG devices for the Pod.
+func (g *GPU) RemovePod(pod v1.Pod) error {
+	requestedProfiles := GetRequestedProfiles(pod)
+	for r, q := range requestedProfiles {
+		if g.usedMigDevices[r] < q {
+			return fmt.Errorf(
+				"not enough used MIG devices (pod requests %d %s, but GPU only uses %d)",
+				q,
+				r,
+				g.usedMigDevices[r],
+			)
+		}
+	}
+	for r, q := range requestedProfiles {
+		g.usedMigDevices[r] -= q
+		if g.usedMigDevices[r] == 0 {
+			delete(g.usedMigDevices, r)
+		}
+		g.freeMigDevices[r] += q
+	}
+	return nil
+}
+
 func (g *GPU) HasFreeMigDevices() bool {
 	return len(g.GetFreeMigDevices()) > 0
 }
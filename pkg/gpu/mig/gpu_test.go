@@ -412,6 +412,27 @@ func TestGPU__UpdateGeometryFor(t *testing.T) {
 			},
 			expectedUpdated: true,
 		},
+		{
+			name: "H100, profile with media extensions should be created",
+			gpu: mig.NewGpuOrPanic(
+				gpu.GPUModel_H100_SXM5_80GB,
+				0,
+				map[mig.ProfileName]int{
+					mig.Profile1g10gb: 2,
+				},
+				map[mig.ProfileName]int{
+					mig.Profile1g10gb: 5,
+				},
+			),
+			profiles: map[gpu.Slice]int{
+				mig.Profile1g10gbMe: 1,
+			},
+			expectedGeometry: map[gpu.Slice]int{
+				mig.Profile1g10gbMe: 1,
+				mig.Profile1g10gb:   6,
+			},
+			expectedUpdated: true,
+		},
 	}
 
 	for _, tt := range testCases {
@@ -514,3 +535,101 @@ func TestGPU__ApplyInitialGeometry(t *testing.T) {
 		})
 	}
 }
+
+func TestGPU__Compact(t *testing.T) {
+	testCases := []struct {
+		name             string
+		gpu              mig.GPU
+		expectedUpdated  bool
+		expectedGeometry gpu.Geometry
+	}{
+		{
+			name: "GPU without free devices, should not change geometry",
+			gpu: mig.NewGpuOrPanic(
+				gpu.GPUModel_A30,
+				0,
+				map[mig.ProfileName]int{
+					mig.Profile2g12gb: 2,
+				},
+				map[mig.ProfileName]int{},
+			),
+			expectedUpdated: false,
+			expectedGeometry: gpu.Geometry{
+				mig.Profile2g12gb: 2,
+			},
+		},
+		{
+			name: "GPU already has the geometry with fewest slices, should not change geometry",
+			gpu: mig.NewGpuOrPanic(
+				gpu.GPUModel_A30,
+				0,
+				map[mig.ProfileName]int{},
+				map[mig.ProfileName]int{
+					mig.Profile4g24gb: 1,
+				},
+			),
+			expectedUpdated: false,
+			expectedGeometry: gpu.Geometry{
+				mig.Profile4g24gb: 1,
+			},
+		},
+		{
+			name: "GPU has only free devices, should apply geometry with fewest slices",
+			gpu: mig.NewGpuOrPanic(
+				gpu.GPUModel_A30,
+				0,
+				map[mig.ProfileName]int{},
+				map[mig.ProfileName]int{
+					mig.Profile1g6gb: 4,
+				},
+			),
+			expectedUpdated: true,
+			expectedGeometry: gpu.Geometry{
+				mig.Profile4g24gb: 1,
+			},
+		},
+		{
+			name: "GPU has used devices, should apply geometry with fewest devices that keeps used devices",
+			gpu: mig.NewGpuOrPanic(
+				gpu.GPUModel_A30,
+				0,
+				map[mig.ProfileName]int{
+					mig.Profile1g6gb: 1,
+				},
+				map[mig.ProfileName]int{
+					mig.Profile1g6gb: 3,
+				},
+			),
+			expectedUpdated: true,
+			expectedGeometry: gpu.Geometry{
+				mig.Profile2g12gb: 1,
+				mig.Profile1g6gb:  2,
+			},
+		},
+		{
+			name: "GPU has used devices, free devices should be merged into larger ones",
+			gpu: mig.NewGpuOrPanic(
+				gpu.GPUModel_A30,
+				0,
+				map[mig.ProfileName]int{
+					mig.Profile2g12gb: 1,
+				},
+				map[mig.ProfileName]int{
+					mig.Profile1g6gb: 2,
+				},
+			),
+			expectedUpdated: true,
+			expectedGeometry: gpu.Geometry{
+				mig.Profile2g12gb: 2,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			updated := tc.gpu.Compact()
+			assert.Equal(t, tc.expectedUpdated, updated)
+			assert.Equal(t, tc.expectedGeometry, tc.gpu.GetGeometry())
+		})
+	}
+}
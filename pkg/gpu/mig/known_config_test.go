@@ -94,3 +94,36 @@ func TestValidateConfigs(t *testing.T) {
 		}
 	}
 }
+
+func TestGetAllowedGeometries__ModernModels(t *testing.T) {
+	testCases := []struct {
+		model           gpu.Model
+		fullProfile     mig.ProfileName
+		mediaExtProfile mig.ProfileName
+	}{
+		{model: gpu.GPUModel_A100_PCIe_40GB, fullProfile: mig.Profile7g40gb, mediaExtProfile: mig.Profile1g5gbMe},
+		{model: gpu.GPUModel_A100_SXM4_80GB, fullProfile: mig.Profile7g80gb, mediaExtProfile: mig.Profile1g10gbMe},
+		{model: gpu.GPUModel_H100_SXM5_80GB, fullProfile: mig.Profile7g80gb, mediaExtProfile: mig.Profile1g10gbMe},
+		{model: gpu.GPUModel_H100_PCIe, fullProfile: mig.Profile7g80gb, mediaExtProfile: mig.Profile1g10gbMe},
+		{model: gpu.GPUModel_H100_NVL, fullProfile: mig.Profile7g94gb, mediaExtProfile: mig.Profile1g12gbMe},
+		{model: gpu.GPUModel_H200, fullProfile: mig.Profile7g141gb, mediaExtProfile: mig.Profile1g18gbMe},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.model.String(), func(t *testing.T) {
+			geometries, ok := mig.GetAllowedGeometries(tt.model)
+			assert.True(t, ok)
+			assert.Contains(t, geometries, gpu.Geometry{tt.fullProfile: 1})
+
+			// Only one device with media extensions can be created on each GPU
+			var foundMediaExt bool
+			for _, geometry := range geometries {
+				if quantity, ok := geometry[tt.mediaExtProfile]; ok {
+					foundMediaExt = true
+					assert.Equal(t, 1, quantity)
+				}
+			}
+			assert.True(t, foundMediaExt)
+		})
+	}
+}
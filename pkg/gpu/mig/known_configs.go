@@ -138,9 +138,87 @@ var (
 				Profile1g10gb: 7,
 			},
 		},
+		gpu.GPUModel_A100_PCIe_40GB: a100Profiles40gb.geometries(),
+		gpu.GPUModel_A100_SXM4_80GB: a100Profiles80gb.geometries(),
+		gpu.GPUModel_H100_SXM5_80GB: h100Profiles80gb.geometries(),
+		gpu.GPUModel_H100_PCIe:      h100Profiles80gb.geometries(),
+		gpu.GPUModel_H100_NVL:       h100Profiles94gb.geometries(),
+		gpu.GPUModel_H200:           h200Profiles141gb.geometries(),
 	}
 )
 
+var (
+	a100Profiles40gb = sevenSliceProfiles{
+		oneSlice:             Profile1g5gb,
+		oneSliceMe:           Profile1g5gbMe,
+		oneSliceDoubleMemory: Profile1g10gb,
+		twoSlices:            Profile2g10gb,
+		threeSlices:          Profile3g20gb,
+		fourSlices:           Profile4g20gb,
+		sevenSlices:          Profile7g40gb,
+	}
+	a100Profiles80gb = sevenSliceProfiles{
+		oneSlice:             Profile1g10gb,
+		oneSliceMe:           Profile1g10gbMe,
+		oneSliceDoubleMemory: Profile1g20gb,
+		twoSlices:            Profile2g20gb,
+		threeSlices:          Profile3g40gb,
+		fourSlices:           Profile4g40gb,
+		sevenSlices:          Profile7g80gb,
+	}
+	h100Profiles80gb = a100Profiles80gb
+	h100Profiles94gb = sevenSliceProfiles{
+		oneSlice:             Profile1g12gb,
+		oneSliceMe:           Profile1g12gbMe,
+		oneSliceDoubleMemory: Profile1g24gb,
+		twoSlices:            Profile2g24gb,
+		threeSlices:          Profile3g47gb,
+		fourSlices:           Profile4g47gb,
+		sevenSlices:          Profile7g94gb,
+	}
+	h200Profiles141gb = sevenSliceProfiles{
+		oneSlice:             Profile1g18gb,
+		oneSliceMe:           Profile1g18gbMe,
+		oneSliceDoubleMemory: Profile1g35gb,
+		twoSlices:            Profile2g35gb,
+		threeSlices:          Profile3g71gb,
+		fourSlices:           Profile4g71gb,
+		sevenSlices:          Profile7g141gb,
+	}
+)
+
+// sevenSliceProfiles are the MIG profiles of the GPUs with 7 compute slices and 8 memory slices,
+// such as A100, H100 and H200, which differ only in the amount of memory of each slice
+type sevenSliceProfiles struct {
+	oneSlice             ProfileName
+	oneSliceMe           ProfileName
+	oneSliceDoubleMemory ProfileName
+	twoSlices            ProfileName
+	threeSlices          ProfileName
+	fourSlices           ProfileName
+	sevenSlices          ProfileName
+}
+
+// geometries returns the MIG geometries allowed by the GPUs with the provided profiles
+func (p sevenSliceProfiles) geometries() []gpu.Geometry {
+	return []gpu.Geometry{
+		{p.sevenSlices: 1},
+		{p.fourSlices: 1, p.twoSlices: 1, p.oneSlice: 1},
+		{p.fourSlices: 1, p.oneSlice: 3},
+		{p.threeSlices: 2},
+		{p.threeSlices: 1, p.twoSlices: 1, p.oneSlice: 1},
+		{p.threeSlices: 1, p.oneSlice: 3},
+		{p.threeSlices: 1, p.twoSlices: 2},
+		{p.threeSlices: 1, p.twoSlices: 1, p.oneSlice: 2},
+		{p.twoSlices: 3, p.oneSlice: 1},
+		{p.twoSlices: 2, p.oneSlice: 3},
+		{p.twoSlices: 1, p.oneSlice: 5},
+		{p.oneSlice: 7},
+		{p.oneSliceDoubleMemory: 4},
+		{p.oneSliceMe: 1, p.oneSlice: 6},
+	}
+}
+
 func SetKnownGeometries(configs map[gpu.Model][]gpu.Geometry) error {
 	if err := ValidateConfigs(configs); err != nil {
 		return err
@@ -172,7 +250,7 @@ func ValidateConfigs(configs map[gpu.Model][]gpu.Geometry) error {
 				if !ok {
 					return fmt.Errorf("invalid profile type %T, expected MIG profile name", profile)
 				}
-				if !migProfile.isValid() {
+				if !migProfile.IsValid() {
 					return fmt.Errorf("invalid profile %s", profile)
 				}
 				if quantity < 1 {
@@ -18,6 +18,7 @@ package mig
 
 import (
 	"fmt"
+	"github.com/google/go-cmp/cmp"
 	"github.com/nebuly-ai/nos/pkg/gpu"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
@@ -36,6 +37,14 @@ type Node struct {
 // - GPU product ("nvidia.com/gpu.product")
 // - GPU count ("nvidia.com/gpu.count")
 //
+// The MIG geometries allowed by each GPU are the ones discovered by the MIG agent and exposed through the
+// annotation v1alpha1.AnnotationAllowedMigGeometries. GPUs not included in the annotation allow the
+// geometries known for their model.
+//
+// If the MIG agent reported through the annotation v1alpha1.AnnotationMigPartitioningFailure that it could not
+// apply a geometry to a GPU, that geometry is not allowed for the GPU, so that the same partitioning is not
+// retried until the agent successfully applies another one.
+//
 // If the v1.Node provided as arg does not have the GPU Product label, returned node will not contain any mig.GPU.
 func NewNode(n framework.NodeInfo) (Node, error) {
 	if n.Node() == nil {
@@ -65,6 +74,33 @@ func NewNode(n framework.NodeInfo) (Node, error) {
 func extractGPUs(node v1.Node, gpuModel gpu.Model, gpuCount int) ([]GPU, error) {
 	result := make([]GPU, 0)
 
+	// Discovered allowed geometries: if the annotation is malformed, fall back to the known ones
+	discoveredGeometries, err := ParseAllowedGeometriesAnnotation(node)
+	if err != nil {
+		discoveredGeometries = map[int][]gpu.Geometry{}
+	}
+	// Geometries that the MIG agent failed to apply: if the annotation is malformed, ignore it
+	failedGeometries := map[int]gpu.Geometry{}
+	if failure, err := ParsePartitioningFailureAnnotation(node); err == nil && failure != nil {
+		failedGeometries = failure.Geometries
+	}
+	var newGPU = func(index int, used, free map[ProfileName]int) (GPU, error) {
+		var g GPU
+		var err error
+		if geometries := discoveredGeometries[index]; len(geometries) > 0 {
+			g, err = NewGPUWithAllowedGeometries(gpuModel, index, geometries, used, free)
+		} else {
+			g, err = NewGPU(gpuModel, index, used, free)
+		}
+		if err != nil {
+			return GPU{}, err
+		}
+		if failed, ok := failedGeometries[index]; ok {
+			g.allowedMigGeometries = excludeGeometry(g.allowedMigGeometries, failed)
+		}
+		return g, nil
+	}
+
 	// Init GPUs from annotation
 	statusAnnotations, _ := gpu.ParseNodeAnnotations(node)
 	for gpuIndex, gpuAnnotations := range statusAnnotations.GroupByGpuIndex() {
@@ -79,7 +115,7 @@ func extractGPUs(node v1.Node, gpuModel gpu.Model, gpuCount int) ([]GPU, error)
 				freeMigDevices[profileName] = a.Quantity
 			}
 		}
-		g, err := NewGPU(gpuModel, gpuIndex, usedMigDevices, freeMigDevices)
+		g, err := newGPU(gpuIndex, usedMigDevices, freeMigDevices)
 		if err != nil {
 			return nil, err
 		}
@@ -89,7 +125,7 @@ func extractGPUs(node v1.Node, gpuModel gpu.Model, gpuCount int) ([]GPU, error)
 	// Add missing GPUs not included in node annotations (e.g. GPUs with MIG enabled but without any MIG device)
 	nGpus := len(result)
 	for i := nGpus; i < gpuCount; i++ {
-		g, err := NewGPU(gpuModel, i, make(map[ProfileName]int), make(map[ProfileName]int))
+		g, err := newGPU(i, make(map[ProfileName]int), make(map[ProfileName]int))
 		if err != nil {
 			return nil, err
 		}
@@ -99,6 +135,21 @@ func extractGPUs(node v1.Node, gpuModel gpu.Model, gpuCount int) ([]GPU, error)
 	return result, nil
 }
 
+// excludeGeometry returns the geometries provided as argument without the excluded one. The geometries are
+// returned unchanged if the excluded one is the only geometry they contain.
+func excludeGeometry(geometries []gpu.Geometry, excluded gpu.Geometry) []gpu.Geometry {
+	res := make([]gpu.Geometry, 0, len(geometries))
+	for _, g := range geometries {
+		if !cmp.Equal(g, excluded) {
+			res = append(res, g)
+		}
+	}
+	if len(res) == 0 {
+		return geometries
+	}
+	return res
+}
+
 func (n *Node) GetName() string {
 	return n.Name
 }
@@ -176,6 +227,23 @@ func (n *Node) UpdateGeometryFor(slices map[gpu.Slice]int) (bool, error) {
 	return anyGpuUpdated, nil
 }
 
+// Compact merges the free MIG capacity of each GPU of the node into the fewest possible MIG devices,
+// without deleting any used device.
+//
+// The method returns true if it updates the MIG geometry of any GPU, false otherwise.
+func (n *Node) Compact() bool {
+	var anyGpuUpdated bool
+	for i := range n.GPUs {
+		if n.GPUs[i].Compact() {
+			anyGpuUpdated = true
+		}
+	}
+	if anyGpuUpdated {
+		n.nodeInfo.Allocatable.ScalarResources = n.computeScalarResources()
+	}
+	return anyGpuUpdated
+}
+
 func (n *Node) computeScalarResources() map[v1.ResourceName]int64 {
 	res := make(map[v1.ResourceName]int64)
 
@@ -201,14 +269,30 @@ func (n *Node) computeScalarResources() map[v1.ResourceName]int64 {
 func (n *Node) AddPod(pod v1.Pod) error {
 	for _, g := range n.GPUs {
 		if err := g.AddPod(pod); err == nil {
-			nodeInfo := n.NodeInfo()
-			nodeInfo.AddPod(&pod)
+			n.nodeInfo.AddPod(&pod)
 			return nil
 		}
 	}
 	return fmt.Errorf("not enough free MIG devices")
 }
 
+// RemovePod removes a Pod from the node by releasing the MIG devices used by the Pod, which become free devices
+// of the first GPU using enough devices of the profiles requested by the Pod.
+//
+// RemovePod returns an error if none of the GPUs of the node uses enough MIG devices for the Pod.
+func (n *Node) RemovePod(pod v1.Pod) error {
+	if err := n.nodeInfo.RemovePod(&pod); err != nil {
+		return err
+	}
+	for _, g := range n.GPUs {
+		if err := g.RemovePod(pod); err == nil {
+			return nil
+		}
+	}
+	n.nodeInfo.AddPod(&pod)
+	return fmt.Errorf("pod %s/%s does not use any MIG device of the node", pod.Namespace, pod.Name)
+}
+
 func (n *Node) Clone() interface{} {
 	cloned := Node{
 		Name:     n.GetName(),
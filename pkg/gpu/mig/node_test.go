@@ -122,6 +122,133 @@ func TestNewNode(t *testing.T) {
 			},
 			expectedError: false,
 		},
+		{
+			name: "Node with discovered allowed geometries: GPUs not in the annotation use known geometries",
+			node: v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-node",
+					Annotations: map[string]string{
+						v1alpha1.AnnotationAllowedMigGeometries: `{"0":[{"1g.6gb":4},{"2g.12gb":2}]}`,
+					},
+					Labels: map[string]string{
+						constant.LabelNvidiaProduct: string(gpu.GPUModel_A30),
+						constant.LabelNvidiaCount:   strconv.Itoa(2),
+					},
+				},
+			},
+			expectedNode: Node{
+				Name: "test-node",
+				GPUs: []GPU{
+					{
+						index: 0,
+						model: gpu.GPUModel_A30,
+						allowedMigGeometries: []gpu.Geometry{
+							{Profile1g6gb: 4},
+							{Profile2g12gb: 2},
+						},
+						usedMigDevices: map[ProfileName]int{},
+						freeMigDevices: map[ProfileName]int{},
+					},
+					{
+						index:                1,
+						model:                gpu.GPUModel_A30,
+						allowedMigGeometries: GetKnownGeometries()[gpu.GPUModel_A30],
+						usedMigDevices:       map[ProfileName]int{},
+						freeMigDevices:       map[ProfileName]int{},
+					},
+				},
+			},
+		},
+		{
+			name: "Node with malformed allowed geometries annotation: GPUs use known geometries",
+			node: v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-node",
+					Annotations: map[string]string{
+						v1alpha1.AnnotationAllowedMigGeometries: `{"0":[{"foo":4}]}`,
+					},
+					Labels: map[string]string{
+						constant.LabelNvidiaProduct: string(gpu.GPUModel_A30),
+						constant.LabelNvidiaCount:   strconv.Itoa(1),
+					},
+				},
+			},
+			expectedNode: Node{
+				Name: "test-node",
+				GPUs: []GPU{
+					{
+						index:                0,
+						model:                gpu.GPUModel_A30,
+						allowedMigGeometries: GetKnownGeometries()[gpu.GPUModel_A30],
+						usedMigDevices:       map[ProfileName]int{},
+						freeMigDevices:       map[ProfileName]int{},
+					},
+				},
+			},
+		},
+		{
+			name: "Node with MIG partitioning failure: failed geometries are not allowed",
+			node: v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-node",
+					Annotations: map[string]string{
+						v1alpha1.AnnotationAllowedMigGeometries:   `{"0":[{"1g.6gb":4},{"2g.12gb":2}],"1":[{"4g.24gb":1}]}`,
+						v1alpha1.AnnotationMigPartitioningFailure: `{"planId":"plan-1","geometries":{"0":{"2g.12gb":2},"1":{"4g.24gb":1}},"rolledBack":true}`,
+					},
+					Labels: map[string]string{
+						constant.LabelNvidiaProduct: string(gpu.GPUModel_A30),
+						constant.LabelNvidiaCount:   strconv.Itoa(2),
+					},
+				},
+			},
+			expectedNode: Node{
+				Name: "test-node",
+				GPUs: []GPU{
+					{
+						index:                0,
+						model:                gpu.GPUModel_A30,
+						allowedMigGeometries: []gpu.Geometry{{Profile1g6gb: 4}},
+						usedMigDevices:       map[ProfileName]int{},
+						freeMigDevices:       map[ProfileName]int{},
+					},
+					{
+						// the failed geometry is the only allowed one, so it is kept
+						index:                1,
+						model:                gpu.GPUModel_A30,
+						allowedMigGeometries: []gpu.Geometry{{Profile4g24gb: 1}},
+						usedMigDevices:       map[ProfileName]int{},
+						freeMigDevices:       map[ProfileName]int{},
+					},
+				},
+			},
+		},
+		{
+			name: "Node with malformed MIG partitioning failure annotation: annotation is ignored",
+			node: v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-node",
+					Annotations: map[string]string{
+						v1alpha1.AnnotationMigPartitioningFailure: `{"geometries":{"0":{"foo":1}}}`,
+					},
+					Labels: map[string]string{
+						constant.LabelNvidiaProduct: string(gpu.GPUModel_A30),
+						constant.LabelNvidiaCount:   strconv.Itoa(1),
+					},
+				},
+			},
+			expectedNode: Node{
+				Name: "test-node",
+				GPUs: []GPU{
+					{
+						index:                0,
+						model:                gpu.GPUModel_A30,
+						allowedMigGeometries: GetKnownGeometries()[gpu.GPUModel_A30],
+						usedMigDevices:       map[ProfileName]int{},
+						freeMigDevices:       map[ProfileName]int{},
+					},
+				},
+			},
+		},
 		{
 			name: "Node with MIG-enabled GPUs, but without any MIG profile created",
 			node: v1.Node{
@@ -633,3 +760,157 @@ func TestNode__Clone(t *testing.T) {
 	}
 
 }
+
+func TestNode__Compact(t *testing.T) {
+	testCases := []struct {
+		name                    string
+		nodeGPUs                []GPU
+		expectedUpdated         bool
+		expectedGeometry        map[gpu.Slice]int
+		expectedScalarResources map[v1.ResourceName]int64
+	}{
+		{
+			name:             "Node without GPUs",
+			nodeGPUs:         make([]GPU, 0),
+			expectedUpdated:  false,
+			expectedGeometry: make(map[gpu.Slice]int),
+		},
+		{
+			name: "Node with GPUs already compacted",
+			nodeGPUs: []GPU{
+				NewGpuOrPanic(gpu.GPUModel_A30, 0, map[ProfileName]int{Profile2g12gb: 2}, make(map[ProfileName]int)),
+				NewGpuOrPanic(gpu.GPUModel_A30, 1, make(map[ProfileName]int), map[ProfileName]int{Profile4g24gb: 1}),
+			},
+			expectedUpdated: false,
+			expectedGeometry: map[gpu.Slice]int{
+				Profile2g12gb: 2,
+				Profile4g24gb: 1,
+			},
+		},
+		{
+			name: "Node with a GPU with scattered free devices, should compact only that GPU",
+			nodeGPUs: []GPU{
+				NewGpuOrPanic(gpu.GPUModel_A30, 0, map[ProfileName]int{Profile2g12gb: 2}, make(map[ProfileName]int)),
+				NewGpuOrPanic(gpu.GPUModel_A30, 1, make(map[ProfileName]int), map[ProfileName]int{Profile1g6gb: 4}),
+			},
+			expectedUpdated: true,
+			expectedGeometry: map[gpu.Slice]int{
+				Profile2g12gb: 2,
+				Profile4g24gb: 1,
+			},
+			expectedScalarResources: map[v1.ResourceName]int64{
+				Profile2g12gb.AsResourceName(): 2,
+				Profile4g24gb.AsResourceName(): 1,
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			node := Node{Name: "test", GPUs: tt.nodeGPUs, nodeInfo: *framework.NewNodeInfo()}
+			updated := node.Compact()
+			assert.Equal(t, tt.expectedUpdated, updated)
+			assert.Equal(t, tt.expectedGeometry, node.Geometry())
+			if tt.expectedUpdated {
+				assert.Equal(t, tt.expectedScalarResources, node.NodeInfo().Allocatable.ScalarResources)
+			}
+		})
+	}
+}
+
+func TestNode_RemovePod(t *testing.T) {
+	pod := factory.BuildPod("ns-1", "pd-1").WithUID("pd-1").WithContainer(
+		factory.BuildContainer("c-1", "foo").
+			WithCPUMilliRequest(1000).
+			WithScalarResourceRequest(Profile1g10gb.AsResourceName(), 1).
+			Get(),
+	).Get()
+	otherPod := factory.BuildPod("ns-1", "pd-2").WithUID("pd-2").WithContainer(
+		factory.BuildContainer("c-1", "foo").
+			WithScalarResourceRequest(Profile2g20gb.AsResourceName(), 1).
+			Get(),
+	).Get()
+
+	testCases := []struct {
+		name                       string
+		node                       v1.Node
+		nodePods                   []v1.Pod
+		pod                        v1.Pod
+		expectedRequestedResources framework.Resource
+		expectedUsedSlices         map[gpu.Slice]int
+		expectedFreeSlices         map[gpu.Slice]int
+		expectedErr                bool
+	}{
+		{
+			name: "Removing a pod should update node info and release used GPU slices",
+			node: factory.BuildNode("node-1").
+				WithLabels(map[string]string{
+					constant.LabelNvidiaProduct: gpu.GPUModel_A100_PCIe_80GB.String(),
+					constant.LabelNvidiaCount:   "1",
+				}).
+				WithAnnotations(map[string]string{
+					fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, Profile1g10gb, resource.StatusUsed): "1",
+					fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, Profile1g10gb, resource.StatusFree): "2",
+				}).Get(),
+			nodePods: []v1.Pod{pod},
+			pod:      pod,
+			expectedRequestedResources: framework.Resource{
+				ScalarResources: map[v1.ResourceName]int64{
+					Profile1g10gb.AsResourceName(): 0,
+				},
+			},
+			expectedUsedSlices: map[gpu.Slice]int{},
+			expectedFreeSlices: map[gpu.Slice]int{
+				Profile1g10gb: 3,
+			},
+		},
+		{
+			name: "Pod does not use any MIG device of the node, should return error",
+			node: factory.BuildNode("node-1").
+				WithLabels(map[string]string{
+					constant.LabelNvidiaProduct: gpu.GPUModel_A100_PCIe_80GB.String(),
+					constant.LabelNvidiaCount:   "1",
+				}).
+				WithAnnotations(map[string]string{
+					fmt.Sprintf(v1alpha1.AnnotationGpuStatusFormat, 0, Profile1g10gb, resource.StatusUsed): "1",
+				}).Get(),
+			nodePods:    []v1.Pod{pod},
+			pod:         otherPod,
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeInfo := framework.NewNodeInfo()
+			nodeInfo.SetNode(&tt.node)
+			for i := range tt.nodePods {
+				nodeInfo.AddPod(&tt.nodePods[i])
+			}
+			n, err := NewNode(*nodeInfo)
+			assert.NoError(t, err)
+
+			err = n.RemovePod(tt.pod)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			var freeSlices = make(map[gpu.Slice]int)
+			var usedSlices = make(map[gpu.Slice]int)
+			for _, g := range n.GPUs {
+				for p, q := range g.GetUsedMigDevices() {
+					usedSlices[p] += q
+				}
+				for p, q := range g.GetFreeMigDevices() {
+					freeSlices[p] += q
+				}
+			}
+			assert.Equal(t, tt.expectedRequestedResources, *n.NodeInfo().Requested)
+			assert.Equal(t, tt.expectedUsedSlices, usedSlices)
+			assert.Equal(t, tt.expectedFreeSlices, freeSlices)
+			assert.Empty(t, n.NodeInfo().Pods)
+		})
+	}
+}
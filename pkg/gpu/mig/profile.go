@@ -44,17 +44,37 @@ const (
 	Profile3g40gb ProfileName = "3g.40gb"
 	Profile4g40gb ProfileName = "4g.40gb"
 	Profile7g79gb ProfileName = "7g.79gb"
+
+	Profile1g5gbMe  ProfileName = "1g.5gb+me"
+	Profile1g10gbMe ProfileName = "1g.10gb+me"
+	Profile1g20gb   ProfileName = "1g.20gb"
+	Profile7g80gb   ProfileName = "7g.80gb"
+	Profile1g12gb   ProfileName = "1g.12gb"
+	Profile1g12gbMe ProfileName = "1g.12gb+me"
+	Profile1g24gb   ProfileName = "1g.24gb"
+	Profile2g24gb   ProfileName = "2g.24gb"
+	Profile3g47gb   ProfileName = "3g.47gb"
+	Profile4g47gb   ProfileName = "4g.47gb"
+	Profile7g94gb   ProfileName = "7g.94gb"
+	Profile1g18gb   ProfileName = "1g.18gb"
+	Profile1g18gbMe ProfileName = "1g.18gb+me"
+	Profile1g35gb   ProfileName = "1g.35gb"
+	Profile2g35gb   ProfileName = "2g.35gb"
+	Profile3g71gb   ProfileName = "3g.71gb"
+	Profile4g71gb   ProfileName = "4g.71gb"
+	Profile7g141gb  ProfileName = "7g.141gb"
 )
 
 var (
-	migProfileRegex = regexp.MustCompile(constant.RegexNvidiaMigProfile)
+	migProfileRegex = regexp.MustCompile("^" + constant.RegexNvidiaMigProfile + "$")
 	migGiRegex      = regexp.MustCompile(`\d+g`)
 	migMemoryRegex  = regexp.MustCompile(`\d+gb`)
 )
 
 type ProfileName string
 
-func (p ProfileName) isValid() bool {
+// IsValid returns true if the profile name has the format of a valid MIG profile (e.g. 1g.10gb or 1g.10gb+me)
+func (p ProfileName) IsValid() bool {
 	return migProfileRegex.MatchString(string(p))
 }
 
@@ -62,8 +82,15 @@ func (p ProfileName) String() string {
 	return string(p)
 }
 
+// AsResourceName returns the name of the resource exposed by the NVIDIA device plugin for the profile.
+// Since resource names cannot contain "+", the attributes of the profile are appended to the
+// resource name with "." instead.
+//
+// Example:
+//
+//	1g.10gb+me => nvidia.com/mig-1g.10gb.me
 func (p ProfileName) AsResourceName() v1.ResourceName {
-	resourceNameStr := fmt.Sprintf("%s%s", constant.NvidiaMigResourcePrefix, p)
+	resourceNameStr := fmt.Sprintf("%s%s", constant.NvidiaMigResourcePrefix, strings.ReplaceAll(string(p), "+", "."))
 	return v1.ResourceName(resourceNameStr)
 }
 
@@ -81,6 +108,20 @@ func (p ProfileName) getGiSlices() int {
 	return asInt
 }
 
+// GetAttributes returns the attributes appended to the name of the profile, such as "me" for the
+// profiles including media extensions. It returns an empty slice if the profile has no attributes.
+//
+// Example:
+//
+//	1g.10gb+me => [me]
+func (p ProfileName) GetAttributes() []string {
+	parts := strings.Split(string(p), "+")
+	return parts[1:]
+}
+
+// SmallerThan returns true if the profile is smaller than the other one. Profiles with the same
+// memory and GPU instance slices are compared by their attributes: the profile without
+// attributes is smaller than the ones with attributes.
 func (p ProfileName) SmallerThan(other gpu.Slice) bool {
 	otherMig, ok := other.(ProfileName)
 	if !ok {
@@ -92,6 +133,9 @@ func (p ProfileName) SmallerThan(other gpu.Slice) bool {
 	if p.getGiSlices() < otherMig.getGiSlices() {
 		return true
 	}
+	if p.getMemorySlices() == otherMig.getMemorySlices() && p.getGiSlices() == otherMig.getGiSlices() {
+		return len(p.GetAttributes()) < len(otherMig.GetAttributes())
+	}
 	return false
 }
 
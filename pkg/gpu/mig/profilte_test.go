@@ -18,6 +18,8 @@ package mig
 
 import (
 	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"testing"
 )
 
@@ -29,6 +31,54 @@ func TestProfileName__getGiSlices(t *testing.T) {
 	assert.Equal(t, 3, Profile3g20gb.getGiSlices())
 }
 
+func TestProfileName__IsValid(t *testing.T) {
+	testCases := []struct {
+		profile  ProfileName
+		expected bool
+	}{
+		{profile: "", expected: false},
+		{profile: "1g", expected: false},
+		{profile: "10gb", expected: false},
+		{profile: "foo-1g.10gb", expected: false},
+		{profile: "1g.10gb+", expected: false},
+		{profile: Profile1g10gb, expected: true},
+		{profile: Profile7g141gb, expected: true},
+		{profile: Profile1g10gbMe, expected: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.profile.String(), func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.profile.IsValid())
+		})
+	}
+}
+
+func TestProfileName__GetAttributes(t *testing.T) {
+	assert.Empty(t, Profile1g10gb.GetAttributes())
+	assert.Equal(t, []string{"me"}, Profile1g10gbMe.GetAttributes())
+	assert.Equal(t, 10, Profile1g10gbMe.getMemorySlices())
+	assert.Equal(t, 1, Profile1g10gbMe.getGiSlices())
+}
+
+func TestProfileName__AsResourceName(t *testing.T) {
+	assert.Equal(t, v1.ResourceName("nvidia.com/mig-1g.10gb"), Profile1g10gb.AsResourceName())
+	assert.Equal(t, v1.ResourceName("nvidia.com/mig-1g.10gb.me"), Profile1g10gbMe.AsResourceName())
+
+	// The resource names of all the known profiles must be valid and must map back to the profiles
+	for model, geometries := range GetKnownGeometries() {
+		for _, geometry := range geometries {
+			for slice := range geometry {
+				profile := slice.(ProfileName)
+				resourceName := profile.AsResourceName()
+				assert.Empty(t, validation.IsQualifiedName(string(resourceName)), "model %s, profile %s", model, profile)
+				extracted, err := ExtractProfileName(resourceName)
+				assert.NoError(t, err)
+				assert.Equal(t, profile, extracted)
+			}
+		}
+	}
+}
+
 func TestProfileList__GroupByGpuIndex(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -110,6 +160,18 @@ func TestProfileName__GreaterThan(t *testing.T) {
 			other:    Profile1g10gb,
 			expected: true,
 		},
+		{
+			name:     "Same Gi and memory, other has attributes",
+			profile:  Profile1g10gb,
+			other:    Profile1g10gbMe,
+			expected: true,
+		},
+		{
+			name:     "Same Gi and memory, profile has attributes",
+			profile:  Profile1g10gbMe,
+			other:    Profile1g10gb,
+			expected: false,
+		},
 	}
 
 	for _, tt := range testCases {
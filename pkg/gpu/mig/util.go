@@ -28,7 +28,7 @@ import (
 )
 
 var (
-	resourceRegexp        = regexp.MustCompile(constant.RegexNvidiaMigResource)
+	resourceRegexp        = regexp.MustCompile("^" + constant.RegexNvidiaMigResource + "$")
 	migDeviceMemoryRegexp = regexp.MustCompile(constant.RegexNvidiaMigFormatMemory)
 	numberRegexp          = regexp.MustCompile(`\d+`)
 )
@@ -38,17 +38,19 @@ func IsNvidiaMigDevice(resourceName v1.ResourceName) bool {
 }
 
 // ExtractProfileName extracts the Name of the MIG profile from the provided resource Name, and returns an error
-// if the resource Name is not a valid NVIDIA MIG resource.
+// if the resource Name is not a valid NVIDIA MIG resource. It is the inverse of ProfileName.AsResourceName.
 //
 // Example:
 //
 //	nvidia.com/mig-1g.10gb => 1g.10gb
+//	nvidia.com/mig-1g.10gb.me => 1g.10gb+me
 func ExtractProfileName(resourceName v1.ResourceName) (ProfileName, error) {
 	if isMigResource := resourceRegexp.MatchString(string(resourceName)); !isMigResource {
 		return "", fmt.Errorf("invalid input string, required format is %s", resourceRegexp.String())
 	}
-	name := strings.TrimPrefix(string(resourceName), "nvidia.com/mig-")
-	return ProfileName(name), nil
+	name := strings.TrimPrefix(string(resourceName), constant.NvidiaMigResourcePrefix)
+	attributesIndex := strings.Index(name, "gb") + len("gb")
+	return ProfileName(name[:attributesIndex] + strings.ReplaceAll(name[attributesIndex:], ".", "+")), nil
 }
 
 // ExtractProfileNameStr extracts the Name of the MIG profile from the provided resource Name, and returns an error
@@ -125,8 +127,7 @@ func GroupDevicesByMigProfile(l gpu.DeviceList) map[Profile]gpu.DeviceList {
 func AsResources(g gpu.Geometry) map[v1.ResourceName]int {
 	res := make(map[v1.ResourceName]int)
 	for p, v := range g {
-		resourceName := v1.ResourceName(fmt.Sprintf("%s%s", constant.NvidiaMigResourcePrefix, p))
-		res[resourceName] += v
+		res[ProfileName(p.String()).AsResourceName()] += v
 	}
 	return res
 }
@@ -52,6 +52,21 @@ func TestIsNvidiaMigDevice(t *testing.T) {
 			resourceName: "nvidia.com/mig-1g.1gb",
 			expected:     true,
 		},
+		{
+			name:         "Valid NVIDIA MIG with attributes",
+			resourceName: "nvidia.com/mig-1g.10gb.me",
+			expected:     true,
+		},
+		{
+			name:         "NVIDIA MIG with attributes separated by +",
+			resourceName: "nvidia.com/mig-1g.10gb+me",
+			expected:     false,
+		},
+		{
+			name:         "NVIDIA MIG with trailing characters",
+			resourceName: "nvidia.com/mig-1g.10gb-foo",
+			expected:     false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -61,6 +76,43 @@ func TestIsNvidiaMigDevice(t *testing.T) {
 	}
 }
 
+func TestExtractProfileName(t *testing.T) {
+	tests := []struct {
+		name          string
+		resourceName  v1.ResourceName
+		errorExpected bool
+		expected      ProfileName
+	}{
+		{
+			name:          "Generic resource",
+			resourceName:  "nvidia.com/gpu",
+			errorExpected: true,
+		},
+		{
+			name:         "Valid NVIDIA MIG",
+			resourceName: "nvidia.com/mig-1g.10gb",
+			expected:     Profile1g10gb,
+		},
+		{
+			name:         "Valid NVIDIA MIG with attributes",
+			resourceName: "nvidia.com/mig-1g.10gb.me",
+			expected:     Profile1g10gbMe,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := ExtractProfileName(tt.resourceName)
+			if tt.errorExpected {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, profile)
+		})
+	}
+}
+
 func TestExtractMemoryGBFromMigDevice(t *testing.T) {
 	tests := []struct {
 		name          string
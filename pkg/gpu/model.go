@@ -26,4 +26,10 @@ const (
 	GPUModel_A30            Model = "A30"
 	GPUModel_A100_SXM4_40GB Model = "NVIDIA-A100-40GB-SXM4"
 	GPUModel_A100_PCIe_80GB Model = "NVIDIA-A100-80GB-PCIe"
+	GPUModel_A100_PCIe_40GB Model = "NVIDIA-A100-PCIE-40GB"
+	GPUModel_A100_SXM4_80GB Model = "NVIDIA-A100-SXM4-80GB"
+	GPUModel_H100_SXM5_80GB Model = "NVIDIA-H100-80GB-HBM3"
+	GPUModel_H100_PCIe      Model = "NVIDIA-H100-PCIe"
+	GPUModel_H100_NVL       Model = "NVIDIA-H100-NVL"
+	GPUModel_H200           Model = "NVIDIA-H200"
 )
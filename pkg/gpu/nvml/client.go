@@ -26,6 +26,7 @@ import (
 	"github.com/nebuly-ai/nos/pkg/util"
 	nvlibdevice "gitlab.com/nvidia/cloud-native/go-nvlib/pkg/nvlib/device"
 	nvlibNvml "gitlab.com/nvidia/cloud-native/go-nvlib/pkg/nvml"
+	"math"
 )
 
 type clientImpl struct {
@@ -182,7 +183,7 @@ func (c *clientImpl) DeleteMigDevice(id string) gpu.Error {
 	}
 	gi, ret := parentGpu.GetGpuInstanceById(giId)
 	if ret == nvlibNvml.ERROR_NOT_FOUND {
-		return gpu.NotFoundErr.Errorf("GPU instance %s not found", giId)
+		return gpu.NotFoundErr.Errorf("GPU instance %d not found", giId)
 	}
 	if ret != nvlibNvml.SUCCESS {
 		return gpu.GenericErr.Errorf("error getting GPU Instance %d: %s", giId, ret.Error())
@@ -222,6 +223,12 @@ func (c *clientImpl) DeleteMigDevice(id string) gpu.Error {
 	return nil
 }
 
+// CreateMigDevices creates on the GPU with the index provided as argument a MIG device for each of the
+// MIG profiles provided as argument.
+//
+// Since the creation of a GPU instance can fail depending on where the existing ones are placed, the
+// placement of each GPU instance is computed upfront from the possible placements reported by NVML,
+// and the GPU instances are then created with their explicit placement.
 func (c *clientImpl) CreateMigDevices(migProfileNames []string, gpuIndex int) gpu.Error {
 	r := nvml.Init()
 	if r != nvml.SUCCESS {
@@ -240,103 +247,141 @@ func (c *clientImpl) CreateMigDevices(migProfileNames []string, gpuIndex int) gp
 	}
 
 	// Check if GPU is MIG-enabled
-	d, ret := c.nvmlClient.DeviceGetHandleByIndex(gpuIndex)
-	if ret == nvlibNvml.ERROR_NOT_FOUND {
+	d, ret := nvml.DeviceGetHandleByIndex(gpuIndex)
+	if ret == nvml.ERROR_NOT_FOUND {
 		return gpu.NotFoundErr.Errorf("GPU with index %d not found", gpuIndex)
 	}
-	if ret != nvlibNvml.SUCCESS {
-		return gpu.GenericErr.Errorf("error getting GPU with index %d: %s", gpuIndex, ret.Error())
+	if ret != nvml.SUCCESS {
+		return gpu.GenericErr.Errorf("error getting GPU with index %d: %s", gpuIndex, nvml.ErrorString(ret))
 	}
-	device, err := c.nvlibClient.NewDevice(d)
-	if err != nil {
-		return gpu.GenericErr.Errorf("error getting GPU with index %d: %s", gpuIndex, ret.Error())
+	migMode, _, ret := d.GetMigMode()
+	if ret == nvml.ERROR_NOT_SUPPORTED || (ret == nvml.SUCCESS && migMode != nvml.DEVICE_MIG_ENABLE) {
+		return gpu.GenericErr.Errorf("MIG is not enabled on GPU with index %d", gpuIndex)
+	}
+	if ret != nvml.SUCCESS {
+		return gpu.GenericErr.Errorf("error getting MIG mode of GPU with index %d: %s", gpuIndex, nvml.ErrorString(ret))
 	}
-	isMigCapable, err := device.IsMigCapable()
+
+	// Compute the placement of the GPU instances
+	giProfileInfos := make([]nvml.GpuInstanceProfileInfo, 0, len(mps))
+	possiblePlacements := make([][]GpuInstancePlacement, 0, len(mps))
+	for _, mp := range mps {
+		giProfileInfo, ret := d.GetGpuInstanceProfileInfo(mp.GetInfo().GIProfileID)
+		if ret != nvml.SUCCESS {
+			return gpu.GenericErr.Errorf("error getting GPU instance profile info: %s", nvml.ErrorString(ret))
+		}
+		placements, ret := d.GetGpuInstancePossiblePlacements(&giProfileInfo)
+		if ret != nvml.SUCCESS {
+			return gpu.GenericErr.Errorf("error getting possible placements of MIG profile %s: %s", mp, nvml.ErrorString(ret))
+		}
+		giProfileInfos = append(giProfileInfos, giProfileInfo)
+		possiblePlacements = append(possiblePlacements, toGpuInstancePlacements(placements))
+	}
+	occupied, err := getOccupiedPlacements(d)
 	if err != nil {
-		return gpu.NewGenericError(err)
+		return err
 	}
-	if !isMigCapable {
-		return gpu.GenericErr.Errorf("MIG is not enabled on GPU with index %d", gpuIndex)
+	placements, ok := computePlacements(possiblePlacements, occupied)
+	if !ok {
+		return gpu.GenericErr.Errorf(
+			"could not create MIG profiles %v: GPU %d does not have enough free space for placing them",
+			migProfileNames,
+			gpuIndex,
+		)
 	}
+	c.logger.V(1).Info("computed placements of MIG profiles", "profiles", migProfileNames, "placements", placements)
 
-	// Function for destroying the CIs and GIs created while trying MIG profiles permutations
-	cleanup := func(gis []nvlibNvml.GpuInstance, cis []nvlibNvml.ComputeInstance) error {
+	// Function for destroying the CIs and GIs created if any of the MIG profiles cannot be created
+	createdGIs := make([]nvml.GpuInstance, 0)
+	createdCIs := make([]nvml.ComputeInstance, 0)
+	cleanup := func() {
 		c.logger.V(1).Info("cleaning up created resources")
-		var anyErrorCleaningUp bool
-		// cleanup compute instances
-		for _, ci := range cis {
-			if ret = ci.Destroy(); ret != nvlibNvml.SUCCESS {
-				c.logger.Error(gpu.GenericErr.Errorf(ret.Error()), "error deleting compute instance")
-				anyErrorCleaningUp = true
+		for _, ci := range createdCIs {
+			if ret := ci.Destroy(); ret != nvml.SUCCESS {
+				c.logger.Error(gpu.GenericErr.Errorf(nvml.ErrorString(ret)), "error deleting compute instance")
 			}
 		}
-		// cleanup GPU instances
-		for _, gi := range gis {
-			if ret = gi.Destroy(); ret != nvlibNvml.SUCCESS {
-				c.logger.Error(gpu.GenericErr.Errorf(ret.Error()), "error deleting GPU instance")
-				anyErrorCleaningUp = true
+		for _, gi := range createdGIs {
+			if ret := gi.Destroy(); ret != nvml.SUCCESS {
+				c.logger.Error(gpu.GenericErr.Errorf(nvml.ErrorString(ret)), "error deleting GPU instance")
 			}
 		}
-		if anyErrorCleaningUp {
-			return fmt.Errorf("error cleaning up created resources, some resources might not have been deleted")
+	}
+
+	// Create MIG profiles
+	for i, mp := range mps {
+		// Create GPU Instance
+		placement := nvml.GpuInstancePlacement{
+			Start: uint32(placements[i].Start),
+			Size:  uint32(placements[i].Size),
 		}
-		return nil
+		gi, ret := d.CreateGpuInstanceWithPlacement(&giProfileInfos[i], &placement)
+		if ret != nvml.SUCCESS {
+			cleanup()
+			return gpu.GenericErr.Errorf(
+				"could not create GPU instance of MIG profile %s at placement %v: %s",
+				mp,
+				placements[i],
+				nvml.ErrorString(ret),
+			)
+		}
+		c.logger.V(1).Info("created GPU Instance", "GpuInstanceID", mp.GetInfo().GIProfileID, "placement", placements[i])
+		createdGIs = append(createdGIs, gi)
+
+		// Create Compute Instance
+		ciProfileInfo, ret := gi.GetComputeInstanceProfileInfo(mp.GetInfo().CIProfileID, mp.GetInfo().CIEngProfileID)
+		if ret != nvml.SUCCESS {
+			cleanup()
+			return gpu.GenericErr.Errorf("error getting compute instance profile info: %s", nvml.ErrorString(ret))
+		}
+		ci, ret := gi.CreateComputeInstance(&ciProfileInfo)
+		if ret != nvml.SUCCESS {
+			cleanup()
+			return gpu.GenericErr.Errorf("could not create compute instance of MIG profile %s: %s", mp, nvml.ErrorString(ret))
+		}
+		c.logger.V(1).Info("created compute Instance", "ComputeInstanceId", mp.GetInfo().CIProfileID)
+		createdCIs = append(createdCIs, ci)
 	}
 
-	// Iterate permutations until success
-	// (MIG profile creation success depends on the order on which they are created)
-	var anyPermutationApplied bool
-	var nAttempts int
-	var maxAttempts = 20
-	err = util.IterPermutations(mps, func(mps []nvlibdevice.MigProfile) (bool, error) {
-		// TODO: optimize permutation search instead of trying all of them and limiting the max attempts
-		if nAttempts > maxAttempts {
-			return false, fmt.Errorf("could not find a valid permutation for creating MIG profiles: too many attempts")
-		}
-		c.logger.V(1).Info("trying to create MIG profiles", "permutation", mps)
-		nAttempts++
-		createdGIs := make([]nvlibNvml.GpuInstance, 0)
-		createdCIs := make([]nvlibNvml.ComputeInstance, 0)
-		for _, mp := range mps {
-			// Create GPU Instance
-			giProfileInfo, ret := device.GetGpuInstanceProfileInfo(mp.GetInfo().GIProfileID)
-			if ret != nvlibNvml.SUCCESS {
-				return false, gpu.GenericErr.Errorf("error getting GPU instance profile info: %s", ret.Error())
-			}
-			gi, ret := device.CreateGpuInstance(&giProfileInfo)
-			if ret != nvlibNvml.SUCCESS {
-				c.logger.V(1).Info("could not create GPU instance", "error", ret.Error())
-				return true, cleanup(createdGIs, createdCIs)
-			}
-			c.logger.V(1).Info("created GPU Instance", "GpuInstanceID", mp.GetInfo().GIProfileID)
-			createdGIs = append(createdGIs, gi)
+	c.logger.V(1).Info("MIG profiles successfully created", "profiles", migProfileNames)
+	return nil
+}
 
-			// Create Compute Instance
-			ciProfileInfo, ret := gi.GetComputeInstanceProfileInfo(mp.GetInfo().CIProfileID, mp.GetInfo().CIEngProfileID)
-			if ret != nvlibNvml.SUCCESS {
-				return false, gpu.GenericErr.Errorf("error getting compute instance profile info: %s", ret.Error())
-			}
-			ci, ret := gi.CreateComputeInstance(&ciProfileInfo)
-			if ret != nvlibNvml.SUCCESS {
-				c.logger.V(1).Info("could not create compute instance", "error", ret.Error())
-				return true, cleanup(createdGIs, createdCIs)
+// getOccupiedPlacements returns the placements of the GPU instances existing on the GPU provided as argument
+func getOccupiedPlacements(d nvml.Device) ([]GpuInstancePlacement, gpu.Error) {
+	res := make([]GpuInstancePlacement, 0)
+	for profileId := 0; profileId < nvml.GPU_INSTANCE_PROFILE_COUNT; profileId++ {
+		info, ret := d.GetGpuInstanceProfileInfo(profileId)
+		if ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_INVALID_ARGUMENT {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return nil, gpu.GenericErr.Errorf("error getting GPU instance profile info: %s", nvml.ErrorString(ret))
+		}
+		gis, ret := d.GetGpuInstances(&info)
+		if ret != nvml.SUCCESS {
+			return nil, gpu.GenericErr.Errorf("error getting GPU instances of profile %d: %s", profileId, nvml.ErrorString(ret))
+		}
+		for _, gi := range gis {
+			giInfo, ret := gi.GetInfo()
+			if ret != nvml.SUCCESS {
+				return nil, gpu.GenericErr.Errorf("error getting GPU instance info: %s", nvml.ErrorString(ret))
 			}
-			c.logger.V(1).Info("created compute Instance", "ComputeInstanceId", mp.GetInfo().CIProfileID)
-			createdCIs = append(createdCIs, ci)
+			res = append(res, toGpuInstancePlacements([]nvml.GpuInstancePlacement{giInfo.Placement})...)
 		}
-		// all MIG profiles of the permutation have been created, stop iterating
-		anyPermutationApplied = true
-		c.logger.V(1).Info("MIG profiles successfully created", "permutations", mps)
-		return false, nil
-	})
-
-	if err != nil {
-		return gpu.GenericErr.Errorf("error while applying permutations: %s", err)
 	}
-	if !anyPermutationApplied {
-		return gpu.GenericErr.Errorf("could not create MIG profiles: could not find any valid permutation")
+	return res, nil
+}
+
+func toGpuInstancePlacements(placements []nvml.GpuInstancePlacement) []GpuInstancePlacement {
+	res := make([]GpuInstancePlacement, 0, len(placements))
+	for _, p := range placements {
+		res = append(res, GpuInstancePlacement{
+			Start: int(p.Start),
+			Size:  int(p.Size),
+		})
 	}
-	return nil
+	return res
 }
 
 // GetMigEnabledGPUs returns the indexes of the GPUs that have MIG mode enabled
@@ -453,6 +498,100 @@ func (c *clientImpl) DeleteAllMigDevicesExcept(migDeviceIds []string) error {
 	return nil
 }
 
+// GetGpuInstanceProfiles returns the GPU instance profiles supported by the GPU with the index
+// provided as argument, together with their possible placements on the GPU.
+func (c *clientImpl) GetGpuInstanceProfiles(gpuIndex int) ([]GpuInstanceProfile, gpu.Error) {
+	r := nvml.Init()
+	if r != nvml.SUCCESS {
+		return nil, gpu.GenericErr.Errorf("error initializing nvml client: %s", nvml.ErrorString(r))
+	}
+	defer nvml.Shutdown()
+
+	d, ret := nvml.DeviceGetHandleByIndex(gpuIndex)
+	if ret == nvml.ERROR_NOT_FOUND {
+		return nil, gpu.NotFoundErr.Errorf("GPU with index %d not found", gpuIndex)
+	}
+	if ret != nvml.SUCCESS {
+		return nil, gpu.GenericErr.Errorf("error getting GPU with index %d: %s", gpuIndex, nvml.ErrorString(ret))
+	}
+	memory, ret := d.GetMemoryInfo()
+	if ret != nvml.SUCCESS {
+		return nil, gpu.GenericErr.Errorf("error getting memory info of GPU %d: %s", gpuIndex, nvml.ErrorString(ret))
+	}
+
+	infos := make([]nvml.GpuInstanceProfileInfo, 0)
+	placements := make([][]nvml.GpuInstancePlacement, 0)
+	for profileId := 0; profileId < nvml.GPU_INSTANCE_PROFILE_COUNT; profileId++ {
+		info, ret := d.GetGpuInstanceProfileInfo(profileId)
+		if ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_INVALID_ARGUMENT {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return nil, gpu.GenericErr.Errorf("error getting GPU instance profile info: %s", nvml.ErrorString(ret))
+		}
+		profilePlacements, ret := d.GetGpuInstancePossiblePlacements(&info)
+		if ret != nvml.SUCCESS {
+			return nil, gpu.GenericErr.Errorf(
+				"error getting possible placements of GPU instance profile %d: %s",
+				profileId,
+				nvml.ErrorString(ret),
+			)
+		}
+		infos = append(infos, info)
+		placements = append(placements, profilePlacements)
+	}
+
+	res := make([]GpuInstanceProfile, 0, len(infos))
+	for i, name := range gpuInstanceProfileNames(infos, memory.Total) {
+		profile := GpuInstanceProfile{
+			Name:         name,
+			MaxInstances: int(infos[i].InstanceCount),
+			Placements:   toGpuInstancePlacements(placements[i]),
+		}
+		c.logger.V(3).Info("found GPU instance profile", "GPUIndex", gpuIndex, "profile", profile)
+		res = append(res, profile)
+	}
+
+	return res, nil
+}
+
+// gpuInstanceProfileNames returns the names of the GPU instance profiles provided as argument in the
+// format used by the NVIDIA device plugin (e.g. 1g.10gb), computing the memory in GB as fraction of
+// the total memory of the GPU in the same way as go-nvlib does.
+//
+// Profiles with the same slices and memory differ only by their media engines: the ones
+// with more media engines than the others get the media extensions attribute (e.g. 1g.10gb+me).
+func gpuInstanceProfileNames(infos []nvml.GpuInstanceProfileInfo, totalMemoryBytes uint64) []string {
+	const fracDenominator = 8
+	const oneMB = 1024 * 1024
+	const oneGB = 1024 * 1024 * 1024
+	totalMemGB := float64((totalMemoryBytes + oneGB - 1) / oneGB)
+
+	names := make([]string, len(infos))
+	minMediaEngines := make(map[string]uint32)
+	for i, info := range infos {
+		fractionalGpuMem := (float64(info.MemorySizeMB) * oneMB) / float64(totalMemoryBytes)
+		fractionalGpuMem = math.Ceil(fractionalGpuMem*fracDenominator) / fracDenominator
+		memoryGB := int(math.Round(fractionalGpuMem * totalMemGB))
+		names[i] = fmt.Sprintf("%dg.%dgb", info.SliceCount, memoryGB)
+		if engines, ok := minMediaEngines[names[i]]; !ok || countMediaEngines(info) < engines {
+			minMediaEngines[names[i]] = countMediaEngines(info)
+		}
+	}
+	for i, info := range infos {
+		if countMediaEngines(info) > minMediaEngines[names[i]] {
+			names[i] += "+" + nvlibdevice.AttributeMediaExtensions
+		}
+	}
+	return names
+}
+
+// countMediaEngines returns the number of media engines (decoders, encoders, JPEG and OFA engines)
+// of the GPU instance profile
+func countMediaEngines(info nvml.GpuInstanceProfileInfo) uint32 {
+	return info.DecoderCount + info.EncoderCount + info.JpegCount + info.OfaCount
+}
+
 func visitGpuInstances(device nvlibdevice.Device, f func(ci nvlibNvml.GpuInstance) error) error {
 	for i := 0; i < nvlibNvml.GPU_INSTANCE_PROFILE_COUNT; i++ {
 		profile, ret := device.GetGpuInstanceProfileInfo(i)
@@ -0,0 +1,44 @@
+//go:build nvml
+
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nvml
+
+import (
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGpuInstanceProfileNames(t *testing.T) {
+	// GPU instance profiles of an A100 40GB
+	const totalMemoryBytes = 40960 * 1024 * 1024
+	infos := []nvml.GpuInstanceProfileInfo{
+		{SliceCount: 1, MemorySizeMB: 4864},
+		{SliceCount: 2, MemorySizeMB: 9856, DecoderCount: 1},
+		{SliceCount: 3, MemorySizeMB: 19968, DecoderCount: 2},
+		{SliceCount: 4, MemorySizeMB: 19968, DecoderCount: 2},
+		{SliceCount: 7, MemorySizeMB: 40192, DecoderCount: 5, JpegCount: 1, OfaCount: 1},
+		{SliceCount: 1, MemorySizeMB: 4864, DecoderCount: 1, JpegCount: 1, OfaCount: 1},
+	}
+
+	assert.Equal(
+		t,
+		[]string{"1g.5gb", "2g.10gb", "3g.20gb", "4g.20gb", "7g.40gb", "1g.5gb+me"},
+		gpuInstanceProfileNames(infos, totalMemoryBytes),
+	)
+}
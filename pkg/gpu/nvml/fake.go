@@ -0,0 +1,239 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nvml
+
+import (
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/util"
+	"sort"
+	"sync"
+)
+
+// FakeMigDevice is a MIG device simulated by FakeClient
+type FakeMigDevice struct {
+	// Id is the UUID of the MIG device
+	Id string
+	// GpuIndex is the index of the GPU the MIG device belongs to
+	GpuIndex int
+	// ProfileName is the name of the MIG profile of the device (e.g. 1g.10gb)
+	ProfileName string
+	// Placement is the position of the GPU instance of the device on the GPU
+	Placement GpuInstancePlacement
+}
+
+type fakeGpu struct {
+	id       string
+	profiles []GpuInstanceProfile
+	devices  []FakeMigDevice
+}
+
+// FakeClient is a Client that simulates in memory the MIG-enabled GPUs of a node, without requiring
+// NVML or any physical GPU. It enforces the possible placements and the max number of instances of
+// each MIG profile of the simulated GPU model, so creating MIG devices fails in the same cases it
+// would fail on real GPUs.
+type FakeClient struct {
+	mu       sync.Mutex
+	gpus     []*fakeGpu
+	nCreated int
+}
+
+var _ Client = &FakeClient{}
+
+// NewFakeClient returns a FakeClient simulating gpuCount MIG-enabled GPUs of the model provided as argument,
+// without any MIG device.
+func NewFakeClient(model gpu.Model, gpuCount int) (*FakeClient, error) {
+	profiles, ok := fakeGpuInstanceProfiles[model]
+	if !ok {
+		return nil, fmt.Errorf("GPU model %q cannot be simulated", model)
+	}
+	if gpuCount < 1 {
+		return nil, fmt.Errorf("the number of simulated GPUs must be greater than zero")
+	}
+	c := &FakeClient{gpus: make([]*fakeGpu, gpuCount)}
+	for i := range c.gpus {
+		c.gpus[i] = &fakeGpu{
+			id:       fmt.Sprintf("GPU-fake-%d", i),
+			profiles: profiles,
+			devices:  make([]FakeMigDevice, 0),
+		}
+	}
+	return c, nil
+}
+
+// GetGpuIds returns the UUID of each simulated GPU, ordered by GPU index
+func (c *FakeClient) GetGpuIds() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make([]string, 0, len(c.gpus))
+	for _, g := range c.gpus {
+		res = append(res, g.id)
+	}
+	return res
+}
+
+// GetMigDevices returns the MIG devices existing on the simulated GPUs, ordered by GPU index and placement
+func (c *FakeClient) GetMigDevices() []FakeMigDevice {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make([]FakeMigDevice, 0)
+	for _, g := range c.gpus {
+		res = append(res, g.devices...)
+	}
+	return res
+}
+
+func (c *FakeClient) GetGpuIndex(gpuId string) (int, gpu.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, g := range c.gpus {
+		if g.id == gpuId {
+			return i, nil
+		}
+	}
+	return 0, gpu.NotFoundErr.Errorf("GPU %s not found", gpuId)
+}
+
+func (c *FakeClient) GetMigDeviceGpuIndex(migDeviceId string) (int, gpu.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, g := range c.gpus {
+		for _, d := range g.devices {
+			if d.Id == migDeviceId {
+				return i, nil
+			}
+		}
+	}
+	return 0, gpu.NotFoundErr.Errorf("MIG device %s not found", migDeviceId)
+}
+
+func (c *FakeClient) DeleteMigDevice(id string) gpu.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, g := range c.gpus {
+		for i, d := range g.devices {
+			if d.Id == id {
+				g.devices = append(g.devices[:i], g.devices[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return gpu.NotFoundErr.Errorf("MIG device %s not found", id)
+}
+
+// CreateMigDevices creates on the simulated GPU a MIG device for each of the profiles provided as argument.
+// Either all the devices are created or none of them.
+func (c *FakeClient) CreateMigDevices(migProfileNames []string, gpuIndex int) gpu.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gpuIndex < 0 || gpuIndex >= len(c.gpus) {
+		return gpu.NotFoundErr.Errorf("GPU with index %d not found", gpuIndex)
+	}
+	g := c.gpus[gpuIndex]
+
+	// Check the max number of instances of each profile
+	nInstances := make(map[string]int)
+	for _, d := range g.devices {
+		nInstances[d.ProfileName]++
+	}
+	possiblePlacements := make([][]GpuInstancePlacement, 0, len(migProfileNames))
+	for _, name := range migProfileNames {
+		profile, ok := g.getProfile(name)
+		if !ok {
+			return gpu.GenericErr.Errorf("invalid MIG profile: %s", name)
+		}
+		nInstances[name]++
+		if nInstances[name] > profile.MaxInstances {
+			return gpu.GenericErr.Errorf(
+				"could not create MIG profiles %v: at most %d instances of %s are allowed",
+				migProfileNames,
+				profile.MaxInstances,
+				name,
+			)
+		}
+		possiblePlacements = append(possiblePlacements, profile.Placements)
+	}
+
+	// Place the new devices
+	occupied := make([]GpuInstancePlacement, 0, len(g.devices))
+	for _, d := range g.devices {
+		occupied = append(occupied, d.Placement)
+	}
+	placements, ok := computePlacements(possiblePlacements, occupied)
+	if !ok {
+		return gpu.GenericErr.Errorf(
+			"could not create MIG profiles %v: GPU %d does not have enough free space for placing them",
+			migProfileNames,
+			gpuIndex,
+		)
+	}
+	for i, name := range migProfileNames {
+		c.nCreated++
+		g.devices = append(g.devices, FakeMigDevice{
+			Id:          fmt.Sprintf("MIG-fake-%d-%d", gpuIndex, c.nCreated),
+			GpuIndex:    gpuIndex,
+			ProfileName: name,
+			Placement:   placements[i],
+		})
+	}
+	sort.SliceStable(g.devices, func(i, j int) bool {
+		return g.devices[i].Placement.Start < g.devices[j].Placement.Start
+	})
+
+	return nil
+}
+
+func (c *FakeClient) GetMigEnabledGPUs() ([]int, gpu.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make([]int, 0, len(c.gpus))
+	for i := range c.gpus {
+		res = append(res, i)
+	}
+	return res, nil
+}
+
+func (c *FakeClient) DeleteAllMigDevicesExcept(migDeviceIds []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, g := range c.gpus {
+		g.devices = util.Filter(g.devices, func(d FakeMigDevice) bool {
+			return util.InSlice(d.Id, migDeviceIds)
+		})
+	}
+	return nil
+}
+
+func (c *FakeClient) GetGpuInstanceProfiles(gpuIndex int) ([]GpuInstanceProfile, gpu.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gpuIndex < 0 || gpuIndex >= len(c.gpus) {
+		return nil, gpu.NotFoundErr.Errorf("GPU with index %d not found", gpuIndex)
+	}
+	res := make([]GpuInstanceProfile, len(c.gpus[gpuIndex].profiles))
+	copy(res, c.gpus[gpuIndex].profiles)
+	return res, nil
+}
+
+func (g *fakeGpu) getProfile(name string) (GpuInstanceProfile, bool) {
+	for _, p := range g.profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return GpuInstanceProfile{}, false
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nvml
+
+import "github.com/nebuly-ai/nos/pkg/gpu"
+
+// fakeGpuInstanceProfiles are the GPU instance profiles of the GPU models that can be simulated by FakeClient,
+// with the same max instances and placements reported by NVML on the real GPUs
+var fakeGpuInstanceProfiles = map[gpu.Model][]GpuInstanceProfile{
+	gpu.GPUModel_A30: {
+		newFakeProfile("1g.6gb", 4, 1, 0, 1, 2, 3),
+		newFakeProfile("1g.6gb+me", 1, 1, 0, 1, 2, 3),
+		newFakeProfile("2g.12gb", 2, 2, 0, 2),
+		newFakeProfile("2g.12gb+me", 1, 2, 0, 2),
+		newFakeProfile("4g.24gb", 1, 4, 0),
+	},
+	gpu.GPUModel_A100_SXM4_40GB: sevenSliceFakeProfiles("1g.5gb", "1g.10gb", "2g.10gb", "3g.20gb", "4g.20gb", "7g.40gb"),
+	gpu.GPUModel_A100_PCIe_40GB: sevenSliceFakeProfiles("1g.5gb", "1g.10gb", "2g.10gb", "3g.20gb", "4g.20gb", "7g.40gb"),
+	gpu.GPUModel_A100_PCIe_80GB: sevenSliceFakeProfiles("1g.10gb", "1g.20gb", "2g.20gb", "3g.40gb", "4g.40gb", "7g.80gb"),
+	gpu.GPUModel_A100_SXM4_80GB: sevenSliceFakeProfiles("1g.10gb", "1g.20gb", "2g.20gb", "3g.40gb", "4g.40gb", "7g.80gb"),
+	gpu.GPUModel_H100_SXM5_80GB: sevenSliceFakeProfiles("1g.10gb", "1g.20gb", "2g.20gb", "3g.40gb", "4g.40gb", "7g.80gb"),
+	gpu.GPUModel_H100_PCIe:      sevenSliceFakeProfiles("1g.10gb", "1g.20gb", "2g.20gb", "3g.40gb", "4g.40gb", "7g.80gb"),
+	gpu.GPUModel_H100_NVL:       sevenSliceFakeProfiles("1g.12gb", "1g.24gb", "2g.24gb", "3g.47gb", "4g.47gb", "7g.94gb"),
+	gpu.GPUModel_H200:           sevenSliceFakeProfiles("1g.18gb", "1g.35gb", "2g.35gb", "3g.71gb", "4g.71gb", "7g.141gb"),
+}
+
+// sevenSliceFakeProfiles returns the GPU instance profiles of the GPUs with 7 compute slices
+// and 8 memory slices, such as A100, H100 and H200
+func sevenSliceFakeProfiles(oneSlice, oneSliceDoubleMemory, twoSlices, threeSlices, fourSlices, sevenSlices string) []GpuInstanceProfile {
+	return []GpuInstanceProfile{
+		newFakeProfile(oneSlice, 7, 1, 0, 1, 2, 3, 4, 5, 6),
+		newFakeProfile(oneSlice+"+me", 1, 1, 0, 1, 2, 3, 4, 5, 6),
+		newFakeProfile(oneSliceDoubleMemory, 4, 2, 0, 2, 4, 6),
+		newFakeProfile(twoSlices, 3, 2, 0, 2, 4),
+		newFakeProfile(threeSlices, 2, 4, 0, 4),
+		newFakeProfile(fourSlices, 1, 4, 0),
+		newFakeProfile(sevenSlices, 1, 8, 0),
+	}
+}
+
+func newFakeProfile(name string, maxInstances int, size int, starts ...int) GpuInstanceProfile {
+	placements := make([]GpuInstancePlacement, 0, len(starts))
+	for _, s := range starts {
+		placements = append(placements, GpuInstancePlacement{Start: s, Size: size})
+	}
+	return GpuInstanceProfile{
+		Name:         name,
+		MaxInstances: maxInstances,
+		Placements:   placements,
+	}
+}
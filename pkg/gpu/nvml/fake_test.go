@@ -0,0 +1,166 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nvml_test
+
+import (
+	"github.com/nebuly-ai/nos/pkg/gpu"
+	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/gpu/nvml"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewFakeClient(t *testing.T) {
+	_, err := nvml.NewFakeClient("unknown", 1)
+	assert.Error(t, err)
+
+	_, err = nvml.NewFakeClient(gpu.GPUModel_A30, 0)
+	assert.Error(t, err)
+
+	c, err := nvml.NewFakeClient(gpu.GPUModel_A30, 2)
+	assert.NoError(t, err)
+	indexes, err := c.GetMigEnabledGPUs()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, indexes)
+	for i, id := range c.GetGpuIds() {
+		index, err := c.GetGpuIndex(id)
+		assert.NoError(t, err)
+		assert.Equal(t, i, index)
+	}
+}
+
+func TestFakeClient__CreateMigDevices(t *testing.T) {
+	testCases := []struct {
+		name             string
+		existing         []string
+		toCreate         []string
+		gpuIndex         int
+		errExpected      bool
+		notFoundExpected bool
+		expectedProfiles []string
+	}{
+		{
+			name:             "GPU not found",
+			toCreate:         []string{"1g.5gb"},
+			gpuIndex:         1,
+			errExpected:      true,
+			notFoundExpected: true,
+		},
+		{
+			name:        "Unknown profile",
+			toCreate:    []string{"1g.6gb"},
+			errExpected: true,
+		},
+		{
+			name:        "Too many instances of the same profile",
+			toCreate:    []string{"3g.20gb", "3g.20gb", "3g.20gb"},
+			errExpected: true,
+		},
+		{
+			name:             "Not enough space, existing devices should not be changed",
+			existing:         []string{"4g.20gb"},
+			toCreate:         []string{"3g.20gb", "1g.5gb"},
+			errExpected:      true,
+			expectedProfiles: []string{"4g.20gb"},
+		},
+		{
+			name:             "Devices are placed around the existing ones",
+			existing:         []string{"3g.20gb"},
+			toCreate:         []string{"1g.5gb", "2g.10gb"},
+			expectedProfiles: []string{"3g.20gb", "2g.10gb", "1g.5gb"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := nvml.NewFakeClient(gpu.GPUModel_A100_PCIe_40GB, 1)
+			assert.NoError(t, err)
+			if len(tt.existing) > 0 {
+				assert.NoError(t, c.CreateMigDevices(tt.existing, 0))
+			}
+
+			err = c.CreateMigDevices(tt.toCreate, tt.gpuIndex)
+			if tt.errExpected {
+				assert.Error(t, err)
+				assert.Equal(t, tt.notFoundExpected, gpu.IsNotFound(err))
+			} else {
+				assert.NoError(t, err)
+			}
+
+			profiles := make([]string, 0)
+			for _, d := range c.GetMigDevices() {
+				profiles = append(profiles, d.ProfileName)
+				gpuIndex, err := c.GetMigDeviceGpuIndex(d.Id)
+				assert.NoError(t, err)
+				assert.Equal(t, d.GpuIndex, gpuIndex)
+			}
+			if len(tt.expectedProfiles) == 0 {
+				assert.Empty(t, profiles)
+			} else {
+				assert.Equal(t, tt.expectedProfiles, profiles)
+			}
+		})
+	}
+}
+
+func TestFakeClient__DeleteMigDevices(t *testing.T) {
+	c, err := nvml.NewFakeClient(gpu.GPUModel_A30, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, c.CreateMigDevices([]string{"1g.6gb", "1g.6gb", "2g.12gb"}, 0))
+	devices := c.GetMigDevices()
+	assert.Len(t, devices, 3)
+
+	assert.NoError(t, c.DeleteMigDevice(devices[0].Id))
+	assert.True(t, gpu.IsNotFound(c.DeleteMigDevice(devices[0].Id)))
+	assert.Len(t, c.GetMigDevices(), 2)
+
+	assert.NoError(t, c.DeleteAllMigDevicesExcept([]string{devices[2].Id}))
+	assert.Equal(t, []nvml.FakeMigDevice{devices[2]}, c.GetMigDevices())
+
+	_, err = c.GetMigDeviceGpuIndex(devices[1].Id)
+	assert.True(t, gpu.IsNotFound(err))
+}
+
+func TestFakeClient__GetGpuInstanceProfiles(t *testing.T) {
+	models := []gpu.Model{
+		gpu.GPUModel_A30,
+		gpu.GPUModel_A100_SXM4_40GB,
+		gpu.GPUModel_A100_SXM4_80GB,
+		gpu.GPUModel_H100_SXM5_80GB,
+		gpu.GPUModel_H100_NVL,
+		gpu.GPUModel_H200,
+	}
+	for _, model := range models {
+		t.Run(string(model), func(t *testing.T) {
+			c, err := nvml.NewFakeClient(model, 1)
+			assert.NoError(t, err)
+			profiles, err := c.GetGpuInstanceProfiles(0)
+			assert.NoError(t, err)
+
+			// The geometries allowed by the simulated GPU must include all the known ones
+			allowed := make(map[string]bool)
+			for _, g := range mig.ComputeAllowedGeometries(profiles) {
+				allowed[g.Id()] = true
+			}
+			known, ok := mig.GetAllowedGeometries(model)
+			assert.True(t, ok)
+			for _, g := range known {
+				assert.True(t, allowed[g.Id()], "geometry %s is not allowed", g)
+			}
+		})
+	}
+}
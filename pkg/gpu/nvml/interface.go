@@ -32,4 +32,23 @@ type Client interface {
 	GetMigEnabledGPUs() ([]int, gpu.Error)
 
 	DeleteAllMigDevicesExcept(migDeviceIds []string) error
+
+	GetGpuInstanceProfiles(gpuIndex int) ([]GpuInstanceProfile, gpu.Error)
+}
+
+// GpuInstanceProfile is a GPU instance profile supported by a GPU, together with
+// the positions of the GPU on which its instances can be placed.
+type GpuInstanceProfile struct {
+	// Name is the name of the profile (e.g. 1g.10gb, 1g.10gb+me)
+	Name string
+	// MaxInstances is the max number of instances of the profile that can exist on the GPU at the same time
+	MaxInstances int
+	// Placements are the possible placements of the profile instances on the GPU
+	Placements []GpuInstancePlacement
+}
+
+// GpuInstancePlacement is a range of memory slices of a GPU that can be occupied by a GPU instance
+type GpuInstancePlacement struct {
+	Start int
+	Size  int
 }
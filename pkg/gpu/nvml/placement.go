@@ -0,0 +1,117 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nvml
+
+import (
+	"github.com/nebuly-ai/nos/pkg/util"
+	"sort"
+)
+
+// computePlacements assigns to each GPU instance that has to be created one of its possible placements,
+// provided as argument in the same order as the GPU instances, so that the placements do not overlap
+// with each other or with the placements already occupied on the GPU.
+//
+// The GPU instances are placed starting from the largest ones, which have fewer possible placements, and
+// the possible placements of each instance are tried in ascending order of start. If a GPU instance cannot
+// be placed, the function backtracks and tries the next placement of the previous instances, so the result
+// is deterministic and a solution is always found if it exists.
+//
+// The function returns the placement of each GPU instance, in the same order as the GPU instances provided
+// as argument, and false if no valid placement exists.
+func computePlacements(possiblePlacements [][]GpuInstancePlacement, occupied []GpuInstancePlacement) ([]GpuInstancePlacement, bool) {
+	// Compute the number of memory slices of the GPU
+	var nSlices int
+	for _, p := range occupied {
+		nSlices = util.Max(nSlices, p.Start+p.Size)
+	}
+	for _, placements := range possiblePlacements {
+		for _, p := range placements {
+			nSlices = util.Max(nSlices, p.Start+p.Size)
+		}
+	}
+	used := make([]bool, nSlices)
+	for _, p := range occupied {
+		for i := p.Start; i < p.Start+p.Size; i++ {
+			used[i] = true
+		}
+	}
+
+	// Sort GPU instances: largest first, then the ones with fewer possible placements
+	order := make([]int, len(possiblePlacements))
+	candidates := make([][]GpuInstancePlacement, len(possiblePlacements))
+	for i, placements := range possiblePlacements {
+		order[i] = i
+		candidates[i] = make([]GpuInstancePlacement, len(placements))
+		copy(candidates[i], placements)
+		sort.SliceStable(candidates[i], func(a, b int) bool {
+			return candidates[i][a].Start < candidates[i][b].Start
+		})
+	}
+	size := func(i int) int {
+		var res int
+		for _, p := range candidates[i] {
+			res = util.Max(res, p.Size)
+		}
+		return res
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if size(order[a]) != size(order[b]) {
+			return size(order[a]) > size(order[b])
+		}
+		return len(candidates[order[a]]) < len(candidates[order[b]])
+	})
+
+	fits := func(p GpuInstancePlacement) bool {
+		for i := p.Start; i < p.Start+p.Size; i++ {
+			if used[i] {
+				return false
+			}
+		}
+		return true
+	}
+	setUsed := func(p GpuInstancePlacement, value bool) {
+		for i := p.Start; i < p.Start+p.Size; i++ {
+			used[i] = value
+		}
+	}
+
+	res := make([]GpuInstancePlacement, len(possiblePlacements))
+	var place func(n int) bool
+	place = func(n int) bool {
+		if n == len(order) {
+			return true
+		}
+		i := order[n]
+		for _, p := range candidates[i] {
+			if !fits(p) {
+				continue
+			}
+			setUsed(p, true)
+			res[i] = p
+			if place(n + 1) {
+				return true
+			}
+			setUsed(p, false)
+		}
+		return false
+	}
+	if !place(0) {
+		return nil, false
+	}
+
+	return res, true
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nvml
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// Possible placements of the GPU instance profiles of an A100 40GB
+var (
+	placements1g = []GpuInstancePlacement{
+		{Start: 0, Size: 1}, {Start: 1, Size: 1}, {Start: 2, Size: 1}, {Start: 3, Size: 1},
+		{Start: 4, Size: 1}, {Start: 5, Size: 1}, {Start: 6, Size: 1},
+	}
+	placements2g = []GpuInstancePlacement{{Start: 0, Size: 2}, {Start: 2, Size: 2}, {Start: 4, Size: 2}}
+	placements3g = []GpuInstancePlacement{{Start: 4, Size: 4}, {Start: 0, Size: 4}}
+	placements4g = []GpuInstancePlacement{{Start: 0, Size: 4}}
+	placements7g = []GpuInstancePlacement{{Start: 0, Size: 8}}
+)
+
+func TestComputePlacements(t *testing.T) {
+	testCases := []struct {
+		name               string
+		possiblePlacements [][]GpuInstancePlacement
+		occupied           []GpuInstancePlacement
+		expected           []GpuInstancePlacement
+		expectedOk         bool
+	}{
+		{
+			name:               "No GPU instances",
+			possiblePlacements: [][]GpuInstancePlacement{},
+			expected:           []GpuInstancePlacement{},
+			expectedOk:         true,
+		},
+		{
+			name:               "Single GPU instance on empty GPU",
+			possiblePlacements: [][]GpuInstancePlacement{placements7g},
+			expected:           []GpuInstancePlacement{{Start: 0, Size: 8}},
+			expectedOk:         true,
+		},
+		{
+			name:               "Larger instances are placed first, regardless of the order of the input",
+			possiblePlacements: [][]GpuInstancePlacement{placements1g, placements4g, placements2g},
+			expected: []GpuInstancePlacement{
+				{Start: 6, Size: 1},
+				{Start: 0, Size: 4},
+				{Start: 4, Size: 2},
+			},
+			expectedOk: true,
+		},
+		{
+			name: "Should backtrack when the first placement of an instance does not leave enough space",
+			possiblePlacements: [][]GpuInstancePlacement{
+				placements1g, placements1g, placements1g, placements1g, placements3g,
+			},
+			expected: []GpuInstancePlacement{
+				{Start: 0, Size: 1},
+				{Start: 1, Size: 1},
+				{Start: 2, Size: 1},
+				{Start: 3, Size: 1},
+				{Start: 4, Size: 4},
+			},
+			expectedOk: true,
+		},
+		{
+			name:               "Should take into account occupied placements",
+			possiblePlacements: [][]GpuInstancePlacement{placements2g, placements1g, placements2g},
+			occupied:           []GpuInstancePlacement{{Start: 4, Size: 2}},
+			expected: []GpuInstancePlacement{
+				{Start: 0, Size: 2},
+				{Start: 6, Size: 1},
+				{Start: 2, Size: 2},
+			},
+			expectedOk: true,
+		},
+		{
+			name:               "Occupied placements prevent creating the instance",
+			possiblePlacements: [][]GpuInstancePlacement{placements4g},
+			occupied:           []GpuInstancePlacement{{Start: 0, Size: 1}},
+			expectedOk:         false,
+		},
+		{
+			name:               "Not enough space for all the instances",
+			possiblePlacements: [][]GpuInstancePlacement{placements3g, placements3g, placements1g},
+			expectedOk:         false,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			placements, ok := computePlacements(tt.possiblePlacements, tt.occupied)
+			assert.Equal(t, tt.expectedOk, ok)
+			if tt.expectedOk {
+				assert.Equal(t, tt.expected, placements)
+			}
+		})
+	}
+}
@@ -109,6 +109,16 @@ func IsMpsPartitioningEnabled(node v1.Node) bool {
 	return partitioningKind == PartitioningKindMps.String()
 }
 
+// IsHybridPartitioningEnabled returns true if the node is enabled for
+// automatic hybrid GPU partitioning, namely both MIG and MPS, false otherwise
+func IsHybridPartitioningEnabled(node v1.Node) bool {
+	partitioningKind, ok := node.Labels[v1alpha1.LabelGpuPartitioning]
+	if !ok {
+		return false
+	}
+	return partitioningKind == PartitioningKindHybrid.String()
+}
+
 func GetPartitioningKind(node v1.Node) (PartitioningKind, bool) {
 	partitioningKindStr, ok := node.Labels[v1alpha1.LabelGpuPartitioning]
 	if !ok {
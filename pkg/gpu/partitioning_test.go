@@ -96,6 +96,41 @@ func TestIsMpsSlicingPartitioningEnabled(t *testing.T) {
 	}
 }
 
+func TestIsHybridPartitioningEnabled(t *testing.T) {
+	testCases := []struct {
+		name     string
+		node     v1.Node
+		expected bool
+	}{
+		{
+			name:     "Node without partitioning label",
+			node:     factory.BuildNode("node-1").Get(),
+			expected: false,
+		},
+		{
+			name: "Node with partitioning label, but not hybrid",
+			node: factory.BuildNode("node-1").WithLabels(map[string]string{
+				v1alpha1.LabelGpuPartitioning: gpu.PartitioningKindMps.String(),
+			}).Get(),
+			expected: false,
+		},
+		{
+			name: "Node with partitioning label, hybrid",
+			node: factory.BuildNode("node-1").WithLabels(map[string]string{
+				v1alpha1.LabelGpuPartitioning: gpu.PartitioningKindHybrid.String(),
+			}).Get(),
+			expected: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			enabled := gpu.IsHybridPartitioningEnabled(tt.node)
+			assert.Equal(t, tt.expected, enabled)
+		})
+	}
+}
+
 func TestGetPartitioningKind(t *testing.T) {
 	testCases := []struct {
 		name       string
@@ -18,10 +18,12 @@ package slicing
 
 import (
 	"context"
+	"github.com/nebuly-ai/nos/pkg/constant"
 	"github.com/nebuly-ai/nos/pkg/gpu"
 	"github.com/nebuly-ai/nos/pkg/gpu/nvml"
 	"github.com/nebuly-ai/nos/pkg/resource"
 	"github.com/nebuly-ai/nos/pkg/util"
+	"strings"
 )
 
 type tsClient struct {
@@ -60,10 +62,7 @@ func (c tsClient) GetUsedDevices(ctx context.Context) (gpu.DeviceList, gpu.Error
 		return nil, gpu.NewGenericError(err)
 	}
 	// Consider only NVIDIA GPUs
-	var isNvidiaResource = func(d resource.Device) bool {
-		return d.IsNvidiaResource()
-	}
-	usedGpus := util.Filter(usedResources, isNvidiaResource)
+	usedGpus := util.Filter(usedResources, isNvidiaGpuResource)
 	// Convert to gpu.DeviceList
 	return c.toGpuDeviceList(usedGpus)
 }
@@ -75,14 +74,17 @@ func (c tsClient) GetAllocatableDevices(ctx context.Context) (gpu.DeviceList, gp
 		return nil, gpu.NewGenericError(err)
 	}
 	// Consider only NVIDIA GPUs
-	var isNvidiaResource = func(d resource.Device) bool {
-		return d.IsNvidiaResource()
-	}
-	allocatableGPUs := util.Filter(allocatableResources, isNvidiaResource)
+	allocatableGPUs := util.Filter(allocatableResources, isNvidiaGpuResource)
 	// Extract MIG devices
 	return c.toGpuDeviceList(allocatableGPUs)
 }
 
+// isNvidiaGpuResource returns true if the device is an NVIDIA GPU or GPU slice. MIG devices, which are
+// exposed on nodes with hybrid partitioning, are excluded since they are managed by the MIG agent.
+func isNvidiaGpuResource(d resource.Device) bool {
+	return d.IsNvidiaResource() && !strings.HasPrefix(d.ResourceName.String(), constant.NvidiaMigResourcePrefix)
+}
+
 func (c tsClient) toGpuDeviceList(resources []resource.Device) (gpu.DeviceList, gpu.Error) {
 	var res = make(gpu.DeviceList, len(resources))
 	for i, r := range resources {
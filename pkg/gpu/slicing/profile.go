@@ -28,7 +28,8 @@ import (
 
 var (
 	profileNamePrefix = fmt.Sprintf("%s-", constant.ResourceNvidiaGPU.String())
-	resourceRegexp    = regexp.MustCompile(`nvidia\.com/gpu-\d+gb`)
+	resourceRegexp    = regexp.MustCompile(constant.RegexNvidiaMpsResource)
+	profileRegexp     = regexp.MustCompile(`^\d+gb$`)
 )
 
 type ProfileName string
@@ -41,6 +42,11 @@ func (p ProfileName) SmallerThan(other gpu.Slice) bool {
 	return p.GetMemorySizeGB() < otherProfile.GetMemorySizeGB()
 }
 
+// IsValid returns true if the profile name has the format of a valid slicing profile (e.g. 10gb)
+func (p ProfileName) IsValid() bool {
+	return profileRegexp.MatchString(string(p))
+}
+
 func (p ProfileName) String() string {
 	return string(p)
 }
@@ -94,3 +94,38 @@ func TestProfileName__SmallerThan(t *testing.T) {
 		})
 	}
 }
+
+func TestProfileName__IsValid(t *testing.T) {
+	testCases := []struct {
+		name        string
+		profileName slicing.ProfileName
+		expected    bool
+	}{
+		{
+			name:        "Empty profile",
+			profileName: "",
+			expected:    false,
+		},
+		{
+			name:        "MIG profile",
+			profileName: "1g.10gb",
+			expected:    false,
+		},
+		{
+			name:        "Resource name instead of profile",
+			profileName: "nvidia.com/gpu-10gb",
+			expected:    false,
+		},
+		{
+			name:        "Valid profile",
+			profileName: "10gb",
+			expected:    true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.profileName.IsValid())
+		})
+	}
+}
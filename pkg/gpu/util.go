@@ -57,7 +57,13 @@ func GetCount(node v1.Node) (int, error) {
 
 // GetMemoryGB returns the amount of memory GB of the GPUs on the node.
 func GetMemoryGB(node v1.Node) (int, error) {
-	memoryStr, ok := node.Labels[constant.LabelNvidiaMemory]
+	return GetMemoryGBFromLabels(node.Labels)
+}
+
+// GetMemoryGBFromLabels returns the amount of memory GB of the GPUs identified by the labels
+// provided as argument, which can be either the labels of a node or the node selector of a pod.
+func GetMemoryGBFromLabels(labels map[string]string) (int, error) {
+	memoryStr, ok := labels[constant.LabelNvidiaMemory]
 	if !ok {
 		return 0, fmt.Errorf(
 			"cannot get GPU Memory GB from node labels, missing label %s",
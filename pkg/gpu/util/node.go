@@ -0,0 +1,61 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NodeGetter returns the node with the name provided as argument
+type NodeGetter interface {
+	GetNode(name string) (v1.Node, error)
+}
+
+// NewClientNodeGetter returns a NodeGetter that gets the nodes through the client provided as argument
+func NewClientNodeGetter(c client.Reader) NodeGetter {
+	return clientNodeGetter{c: c}
+}
+
+type clientNodeGetter struct {
+	c client.Reader
+}
+
+func (g clientNodeGetter) GetNode(name string) (v1.Node, error) {
+	var node v1.Node
+	err := g.c.Get(context.Background(), client.ObjectKey{Name: name}, &node)
+	return node, err
+}
+
+// NewListerNodeGetter returns a NodeGetter that gets the nodes from the lister provided as argument
+func NewListerNodeGetter(lister corelisters.NodeLister) NodeGetter {
+	return listerNodeGetter{lister: lister}
+}
+
+type listerNodeGetter struct {
+	lister corelisters.NodeLister
+}
+
+func (g listerNodeGetter) GetNode(name string) (v1.Node, error) {
+	node, err := g.lister.Get(name)
+	if err != nil {
+		return v1.Node{}, err
+	}
+	return *node, nil
+}
@@ -19,14 +19,24 @@ package util
 import (
 	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/gpu"
 	"github.com/nebuly-ai/nos/pkg/gpu/mig"
+	"github.com/nebuly-ai/nos/pkg/gpu/slicing"
 	"github.com/nebuly-ai/nos/pkg/resource"
 	v1 "k8s.io/api/core/v1"
 	k8sresource "k8s.io/apimachinery/pkg/api/resource"
 )
 
 type ResourceCalculator struct {
+	// NvidiaGPUDeviceMemoryGB is the GPU memory of each nvidia.com/gpu resource, used when
+	// the model and the memory of the GPUs requested by a pod cannot be determined
 	NvidiaGPUDeviceMemoryGB int64
+	// NvidiaGPUModelsMemoryGB is the GPU memory of each nvidia.com/gpu resource for each GPU model,
+	// where the keys are the values of the label "nvidia.com/gpu.product"
+	NvidiaGPUModelsMemoryGB map[string]int64
+	// Nodes is used for looking up the node to which pods are assigned. If nil, the GPU memory
+	// is determined only from the node selector of the pods.
+	Nodes NodeGetter
 }
 
 // ComputePodRequest returns a v1.ResourceList that covers the largest
@@ -61,18 +71,40 @@ func (r ResourceCalculator) ComputePodRequest(pod v1.Pod) v1.ResourceList {
 	res := resource.ComputePodRequest(pod)
 
 	// add required GPU memory resource
-	gpuMemory := r.ComputeRequiredGPUMemoryGB(res)
+	gpuMemory := r.computeRequiredGPUMemoryGB(res, r.getNvidiaGPUMemoryGB(pod))
 	res[v1alpha1.ResourceGPUMemory] = *k8sresource.NewQuantity(gpuMemory, k8sresource.DecimalSI)
 
 	return res
 }
 
+// ComputeNodeAllocatable returns the allocatable resources of the node provided as argument, including
+// the GPU memory provided by its nvidia.com/gpu, MIG and MPS resources
+func (r ResourceCalculator) ComputeNodeAllocatable(node v1.Node) v1.ResourceList {
+	res := node.Status.Allocatable.DeepCopy()
+	if res == nil {
+		res = make(v1.ResourceList)
+	}
+	nvidiaGPUMemoryGB, ok := r.getNvidiaGPUMemoryGBFromLabels(node.Labels)
+	if !ok {
+		nvidiaGPUMemoryGB = r.NvidiaGPUDeviceMemoryGB
+	}
+	gpuMemory := r.computeRequiredGPUMemoryGB(res, nvidiaGPUMemoryGB)
+	res[v1alpha1.ResourceGPUMemory] = *k8sresource.NewQuantity(gpuMemory, k8sresource.DecimalSI)
+	return res
+}
+
+// ComputeRequiredGPUMemoryGB returns the GPU memory required by the resources provided as argument,
+// considering NvidiaGPUDeviceMemoryGB as the memory of each nvidia.com/gpu resource
 func (r ResourceCalculator) ComputeRequiredGPUMemoryGB(resourceList v1.ResourceList) int64 {
+	return r.computeRequiredGPUMemoryGB(resourceList, r.NvidiaGPUDeviceMemoryGB)
+}
+
+func (r ResourceCalculator) computeRequiredGPUMemoryGB(resourceList v1.ResourceList, nvidiaGPUMemoryGB int64) int64 {
 	var totalRequiredGB int64
 
 	for resourceName, quantity := range resourceList {
 		if resourceName == constant.ResourceNvidiaGPU {
-			totalRequiredGB += r.NvidiaGPUDeviceMemoryGB * quantity.Value()
+			totalRequiredGB += nvidiaGPUMemoryGB * quantity.Value()
 			continue
 		}
 		if mig.IsNvidiaMigDevice(resourceName) {
@@ -80,7 +112,44 @@ func (r ResourceCalculator) ComputeRequiredGPUMemoryGB(resourceList v1.ResourceL
 			totalRequiredGB += migMemory * quantity.Value()
 			continue
 		}
+		if slicing.IsGpuSlice(resourceName) {
+			profileName, _ := slicing.ExtractProfileName(resourceName)
+			totalRequiredGB += int64(profileName.GetMemorySizeGB()) * quantity.Value()
+			continue
+		}
 	}
 
 	return totalRequiredGB
 }
+
+// getNvidiaGPUMemoryGB returns the GPU memory of each nvidia.com/gpu resource requested by the pod.
+// The memory is derived from the GPUs of the node to which the pod is assigned or, if the pod is not
+// assigned to any node yet, from its node selector. If neither of them identifies the GPUs, the
+// default NvidiaGPUDeviceMemoryGB is returned.
+func (r ResourceCalculator) getNvidiaGPUMemoryGB(pod v1.Pod) int64 {
+	if pod.Spec.NodeName != "" && r.Nodes != nil {
+		if node, err := r.Nodes.GetNode(pod.Spec.NodeName); err == nil {
+			if memory, ok := r.getNvidiaGPUMemoryGBFromLabels(node.Labels); ok {
+				return memory
+			}
+		}
+	}
+	if memory, ok := r.getNvidiaGPUMemoryGBFromLabels(pod.Spec.NodeSelector); ok {
+		return memory
+	}
+	return r.NvidiaGPUDeviceMemoryGB
+}
+
+// getNvidiaGPUMemoryGBFromLabels returns the GPU memory of the GPUs identified by the labels provided as argument.
+// The memory configured for the GPU model takes precedence over the one exposed by the labels.
+func (r ResourceCalculator) getNvidiaGPUMemoryGBFromLabels(labels map[string]string) (int64, bool) {
+	if model, ok := labels[constant.LabelNvidiaProduct]; ok {
+		if memory, ok := r.NvidiaGPUModelsMemoryGB[model]; ok {
+			return memory, true
+		}
+	}
+	if memory, err := gpu.GetMemoryGBFromLabels(labels); err == nil && memory > 0 {
+		return int64(memory), true
+	}
+	return 0, false
+}
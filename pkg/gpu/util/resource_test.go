@@ -17,10 +17,14 @@
 package util
 
 import (
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/constant"
+	"github.com/nebuly-ai/nos/pkg/test/factory"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"testing"
 )
 
@@ -57,6 +61,15 @@ func TestResourceCalculator_ComputeRequiredGPUMemoryGB(t *testing.T) {
 			},
 			expected: nvidiaDeviceGPUMemoryGB*2 + 32*3,
 		},
+		{
+			name: "Resource list contains NVIDIA GPU, MIG and MPS resources",
+			resourceList: v1.ResourceList{
+				constant.ResourceNvidiaGPU:                *resource.NewQuantity(1, resource.DecimalSI),
+				v1.ResourceName("nvidia.com/mig-1g.10gb"): *resource.NewQuantity(2, resource.DecimalSI),
+				v1.ResourceName("nvidia.com/gpu-5gb"):     *resource.NewQuantity(3, resource.DecimalSI),
+			},
+			expected: nvidiaDeviceGPUMemoryGB + 10*2 + 5*3,
+		},
 	}
 
 	resourceCalculator := ResourceCalculator{
@@ -69,3 +82,139 @@ func TestResourceCalculator_ComputeRequiredGPUMemoryGB(t *testing.T) {
 		})
 	}
 }
+
+func TestResourceCalculator_ComputePodRequest__GPUMemory(t *testing.T) {
+	const nvidiaDeviceGPUMemoryGB = 8
+	nodeWithKnownModel := v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-1",
+			Labels: map[string]string{
+				constant.LabelNvidiaProduct: "NVIDIA-A100-SXM4-80GB",
+				constant.LabelNvidiaMemory:  "81920",
+			},
+		},
+	}
+	nodeWithUnknownModel := v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-2",
+			Labels: map[string]string{
+				constant.LabelNvidiaProduct: "Tesla-T4",
+				constant.LabelNvidiaMemory:  "16000",
+			},
+		},
+	}
+	nodeWithoutLabels := v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-3",
+		},
+	}
+
+	tests := []struct {
+		name         string
+		nodeName     string
+		nodeSelector map[string]string
+		expected     int64
+	}{
+		{
+			name:     "Pod not assigned to any node and without node selector, should use default memory",
+			expected: nvidiaDeviceGPUMemoryGB * 2,
+		},
+		{
+			name:     "Pod assigned to node with GPU model included in the models memory",
+			nodeName: nodeWithKnownModel.Name,
+			expected: 80 * 2,
+		},
+		{
+			name:     "Pod assigned to node with GPU model not included in the models memory, should use memory label",
+			nodeName: nodeWithUnknownModel.Name,
+			expected: 16 * 2,
+		},
+		{
+			name:     "Pod assigned to node without GPU labels, should use default memory",
+			nodeName: nodeWithoutLabels.Name,
+			expected: nvidiaDeviceGPUMemoryGB * 2,
+		},
+		{
+			name:     "Pod assigned to node that does not exist, should use default memory",
+			nodeName: "not-found",
+			expected: nvidiaDeviceGPUMemoryGB * 2,
+		},
+		{
+			name: "Pod not assigned to any node, should use node selector",
+			nodeSelector: map[string]string{
+				constant.LabelNvidiaProduct: "NVIDIA-A100-SXM4-80GB",
+			},
+			expected: 80 * 2,
+		},
+		{
+			name:     "Node labels take precedence over node selector",
+			nodeName: nodeWithUnknownModel.Name,
+			nodeSelector: map[string]string{
+				constant.LabelNvidiaProduct: "NVIDIA-A100-SXM4-80GB",
+			},
+			expected: 16 * 2,
+		},
+	}
+
+	resourceCalculator := ResourceCalculator{
+		NvidiaGPUDeviceMemoryGB: nvidiaDeviceGPUMemoryGB,
+		NvidiaGPUModelsMemoryGB: map[string]int64{
+			"NVIDIA-A100-SXM4-80GB": 80,
+		},
+		Nodes: NewClientNodeGetter(
+			fake.NewClientBuilder().WithObjects(&nodeWithKnownModel, &nodeWithUnknownModel, &nodeWithoutLabels).Build(),
+		),
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := factory.BuildPod("ns-1", "pd-1").
+				WithContainer(
+					factory.BuildContainer("c1", "test").
+						WithScalarResourceRequest(constant.ResourceNvidiaGPU, 2).
+						Get(),
+				).
+				Get()
+			pod.Spec.NodeName = tt.nodeName
+			pod.Spec.NodeSelector = tt.nodeSelector
+			request := resourceCalculator.ComputePodRequest(pod)
+			gpuMemory := request[v1alpha1.ResourceGPUMemory]
+			assert.Equal(t, tt.expected, gpuMemory.Value())
+		})
+	}
+}
+
+func TestResourceCalculator_ComputeNodeAllocatable(t *testing.T) {
+	resourceCalculator := ResourceCalculator{
+		NvidiaGPUDeviceMemoryGB: 8,
+		NvidiaGPUModelsMemoryGB: map[string]int64{
+			"NVIDIA-A100-SXM4-80GB": 80,
+		},
+	}
+	node := v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-1",
+			Labels: map[string]string{
+				constant.LabelNvidiaProduct: "NVIDIA-A100-SXM4-80GB",
+			},
+		},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:                            *resource.NewMilliQuantity(4000, resource.DecimalSI),
+				constant.ResourceNvidiaGPU:                *resource.NewQuantity(1, resource.DecimalSI),
+				v1.ResourceName("nvidia.com/mig-1g.10gb"): *resource.NewQuantity(2, resource.DecimalSI),
+				v1.ResourceName("nvidia.com/gpu-20gb"):    *resource.NewQuantity(2, resource.DecimalSI),
+			},
+		},
+	}
+
+	allocatable := resourceCalculator.ComputeNodeAllocatable(node)
+	gpuMemory := allocatable[v1alpha1.ResourceGPUMemory]
+	cpu := allocatable[v1.ResourceCPU]
+	assert.Equal(t, int64(80+2*10+2*20), gpuMemory.Value())
+	assert.Equal(t, int64(4000), cpu.MilliValue())
+
+	node.Labels = nil
+	allocatable = resourceCalculator.ComputeNodeAllocatable(node)
+	gpuMemory = allocatable[v1alpha1.ResourceGPUMemory]
+	assert.Equal(t, int64(8+2*10+2*20), gpuMemory.Value())
+}
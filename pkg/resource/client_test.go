@@ -0,0 +1,120 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resource_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/resource"
+	"github.com/nebuly-ai/nos/pkg/test/kubelet"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/kubelet/apis/podresources"
+	"testing"
+	"time"
+)
+
+func newPodResourcesServer(t *testing.T) *kubelet.PodResourcesServer {
+	server := kubelet.NewPodResourcesServer(t.TempDir())
+	assert.NoError(t, server.Start())
+	t.Cleanup(server.Stop)
+	return server
+}
+
+func newClient(t *testing.T, server *kubelet.PodResourcesServer, maxMsgSize int) resource.Client {
+	lister, conn, err := podresources.GetV1Client(server.Endpoint(), time.Second, maxMsgSize)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return resource.NewClient(lister)
+}
+
+func TestClient__GetAllocatableDevices(t *testing.T) {
+	server := newPodResourcesServer(t)
+	server.SetAllocatableDevices(
+		resource.Device{ResourceName: "nvidia.com/mig-1g.10gb", DeviceId: "1"},
+		resource.Device{ResourceName: "nvidia.com/mig-1g.10gb", DeviceId: "2"},
+		resource.Device{ResourceName: "nvidia.com/mig-3g.40gb", DeviceId: "3"},
+	)
+	client := newClient(t, server, 1024*1024)
+
+	devices, err := client.GetAllocatableDevices(context.Background())
+	assert.NoError(t, err)
+	assert.ElementsMatch(
+		t,
+		[]resource.Device{
+			{ResourceName: "nvidia.com/mig-1g.10gb", DeviceId: "1", Status: resource.StatusUnknown},
+			{ResourceName: "nvidia.com/mig-1g.10gb", DeviceId: "2", Status: resource.StatusUnknown},
+			{ResourceName: "nvidia.com/mig-3g.40gb", DeviceId: "3", Status: resource.StatusUnknown},
+		},
+		devices,
+	)
+}
+
+func TestClient__GetUsedDevices(t *testing.T) {
+	server := newPodResourcesServer(t)
+	pod1 := types.NamespacedName{Namespace: "ns-1", Name: "pod-1"}
+	pod2 := types.NamespacedName{Namespace: "ns-1", Name: "pod-2"}
+	server.SetUsedDevices(pod1, "c1", resource.Device{ResourceName: "nvidia.com/mig-1g.10gb", DeviceId: "1"})
+	server.SetUsedDevices(pod1, "c2", resource.Device{ResourceName: "nvidia.com/mig-3g.40gb", DeviceId: "3"})
+	server.SetUsedDevices(pod2, "c1", resource.Device{ResourceName: "nvidia.com/mig-1g.10gb", DeviceId: "2"})
+	// pod-2 terminated
+	server.SetUsedDevices(pod2, "c1")
+	client := newClient(t, server, 1024*1024)
+
+	devices, err := client.GetUsedDevices(context.Background())
+	assert.NoError(t, err)
+	assert.ElementsMatch(
+		t,
+		[]resource.Device{
+			{ResourceName: "nvidia.com/mig-1g.10gb", DeviceId: "1", Status: resource.StatusUsed},
+			{ResourceName: "nvidia.com/mig-3g.40gb", DeviceId: "3", Status: resource.StatusUsed},
+		},
+		devices,
+	)
+}
+
+func TestClient__SlowKubelet(t *testing.T) {
+	server := newPodResourcesServer(t)
+	server.SetResponseDelay(time.Second)
+	client := newClient(t, server, 1024*1024)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.GetAllocatableDevices(ctx)
+	assert.Error(t, err)
+	_, err = client.GetUsedDevices(ctx)
+	assert.Error(t, err)
+}
+
+func TestClient__MessageTooLarge(t *testing.T) {
+	server := newPodResourcesServer(t)
+	devices := make([]resource.Device, 0)
+	for i := 0; i < 1000; i++ {
+		devices = append(devices, resource.Device{
+			ResourceName: "nvidia.com/mig-1g.10gb",
+			DeviceId:     fmt.Sprintf("MIG-%d", i),
+		})
+	}
+	server.SetAllocatableDevices(devices...)
+
+	_, err := newClient(t, server, 1024).GetAllocatableDevices(context.Background())
+	assert.Error(t, err)
+
+	res, err := newClient(t, server, 1024*1024).GetAllocatableDevices(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, res, 1000)
+}
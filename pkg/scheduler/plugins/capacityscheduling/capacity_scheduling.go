@@ -27,6 +27,7 @@ import (
 	podutil "github.com/nebuly-ai/nos/pkg/util/pod"
 	"sort"
 	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	policy "k8s.io/api/policy/v1"
@@ -84,8 +85,10 @@ type ElasticQuotaSnapshotState struct {
 
 // Clone the ElasticQuotaSnapshot state.
 func (s *ElasticQuotaSnapshotState) Clone() framework.StateData {
+	elasticQuotaInfos := s.elasticQuotaInfos.clone()
+	elasticQuotaInfos.indexHierarchy()
 	return &ElasticQuotaSnapshotState{
-		elasticQuotaInfos: s.elasticQuotaInfos.clone(),
+		elasticQuotaInfos: elasticQuotaInfos,
 	}
 }
 
@@ -117,6 +120,7 @@ func New(obj runtime.Object, handle framework.Handle) (framework.Plugin, error)
 	}
 
 	klog.Info("using nvidiaGpuResourceMemoryGB=", args.NvidiaGpuResourceMemoryGB)
+	klog.Info("using nvidiaGpuModelsMemoryGB=", args.NvidiaGpuModelsMemoryGB)
 
 	c := &CapacityScheduling{
 		fh:                handle,
@@ -125,6 +129,8 @@ func New(obj runtime.Object, handle framework.Handle) (framework.Plugin, error)
 		pdbLister:         getPDBLister(handle.SharedInformerFactory()),
 		resourceCalculator: &gpu_util.ResourceCalculator{
 			NvidiaGPUDeviceMemoryGB: args.NvidiaGpuResourceMemoryGB,
+			NvidiaGPUModelsMemoryGB: args.NvidiaGpuModelsMemoryGB,
+			Nodes:                   gpu_util.NewListerNodeGetter(handle.SharedInformerFactory().Core().V1().Nodes().Lister()),
 		},
 	}
 
@@ -190,6 +196,7 @@ func (c *CapacityScheduling) EventsToRegister() []framework.ClusterEvent {
 func (c *CapacityScheduling) PreFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod) (*framework.PreFilterResult, *framework.Status) {
 	// TODO improve the efficiency of taking snapshot
 	// e.g. use a two-pointer data structure to only copy the updated EQs when necessary.
+	c.refreshElasticQuotaLimits(time.Now())
 	snapshotElasticQuota := c.snapshotElasticQuota()
 	req := c.resourceCalculator.ComputePodRequest(*pod)
 	podReq := resource.FromListToFramework(req)
@@ -254,13 +261,13 @@ func (c *CapacityScheduling) PreFilter(ctx context.Context, state *framework.Cyc
 	}
 	state.Write(preFilterStateKey, preFilterState)
 
-	if eq.usedOverMaxWith(nominatedPodsReqInEQWithPodReq) {
+	if overMaxEq := elasticQuotaInfos.UsedOverMaxWith(pod.Namespace, nominatedPodsReqInEQWithPodReq); overMaxEq != nil {
 		msg := fmt.Sprintf(
 			"Pod %v/%v is rejected in PreFilter because quota %v/%v is more than Max",
 			pod.Namespace,
 			pod.Name,
-			eq.ResourceNamespace,
-			eq.ResourceName,
+			overMaxEq.ResourceNamespace,
+			overMaxEq.ResourceName,
 		)
 		return nil, framework.NewStatus(framework.Unschedulable, msg)
 	}
@@ -346,7 +353,7 @@ func (c *CapacityScheduling) Reserve(ctx context.Context, state *framework.Cycle
 
 	elasticQuotaInfo := c.elasticQuotaInfos[pod.Namespace]
 	if elasticQuotaInfo != nil {
-		err := elasticQuotaInfo.addPodIfNotPresent(pod)
+		err := elasticQuotaInfo.addPodIfNotPresent(withNodeName(pod, nodeName))
 		if err != nil {
 			klog.ErrorS(err, "Failed to add Pod to its associated elasticQuota", "pod", klog.KObj(pod))
 			return framework.NewStatus(framework.Error, err.Error())
@@ -361,13 +368,24 @@ func (c *CapacityScheduling) Unreserve(ctx context.Context, state *framework.Cyc
 
 	elasticQuotaInfo := c.elasticQuotaInfos[pod.Namespace]
 	if elasticQuotaInfo != nil {
-		err := elasticQuotaInfo.deletePodIfPresent(pod)
+		err := elasticQuotaInfo.deletePodIfPresent(withNodeName(pod, nodeName))
 		if err != nil {
 			klog.ErrorS(err, "Failed to delete Pod from its associated elasticQuota", "pod", klog.KObj(pod))
 		}
 	}
 }
 
+// withNodeName returns a copy of the pod assigned to the node provided as argument, so that the
+// GPU memory requested by the pod is computed in the same way before and after the pod is bound
+func withNodeName(pod *v1.Pod, nodeName string) *v1.Pod {
+	if pod.Spec.NodeName == nodeName {
+		return pod
+	}
+	res := pod.DeepCopy()
+	res.Spec.NodeName = nodeName
+	return res
+}
+
 type preemptor struct {
 	fh    framework.Handle
 	state *framework.CycleState
@@ -543,19 +561,19 @@ func (p *preemptor) SelectVictimsOnNode(
 				// If pod_namespace != potential_victim_namespace than we check
 				// whether the preemptor EQ has guaranteed overquotas available,
 				// and we select as potential victims over-quota pods in other namespaces where
-				// UsedOverquotas > GuaranteedOverquotas
+				// UsedOverquotas > GuaranteedOverquotas.
+				// With hierarchical quotas, the check is performed on every level of the hierarchy below
+				// the lowest common ancestor of the two quotas, namely the levels at which they compete
+				// for the same resources.
 				if pvPi.Pod.Namespace == pod.Namespace {
 					continue
 				}
 				if !podutil.IsOverQuota(*pvPi.Pod) {
 					continue
 				}
-				guaranteeedOverquotas, _ := elasticQuotaInfos.GetGuaranteedOverquotas(pod.Namespace)
-				minPlusGuaranteeedOverquotas := resource.Sum(*guaranteeedOverquotas, *preemptorElasticQuotaInfo.Min)
-				if preemptorElasticQuotaInfo.usedLteWith(&minPlusGuaranteeedOverquotas, &nominatedPodsReqInEQWithPodReq) {
-					pvGuaranteedOverquotas, _ := elasticQuotaInfos.GetGuaranteedOverquotas(pvPi.Pod.Namespace)
-					pvMinPlusGuaranteedOverquotas := resource.Sum(*pvGuaranteedOverquotas, *pvEqInfo.Min)
-					if pvEqInfo.usedOver(&pvMinPlusGuaranteedOverquotas) {
+				preemptorPath, pvPath := elasticQuotaInfos.getDivergentPaths(preemptorElasticQuotaInfo, pvEqInfo)
+				if elasticQuotaInfos.usedLteGuaranteedWith(preemptorPath, &nominatedPodsReqInEQWithPodReq) {
+					if elasticQuotaInfos.usedOverGuaranteed(pvPath) {
 						potentialVictims = append(potentialVictims, pvPi)
 						if err := removePod(pvPi); err != nil {
 							return nil, 0, framework.AsStatus(err)
@@ -570,7 +588,8 @@ func (p *preemptor) SelectVictimsOnNode(
 				// will be chosen from Quotas that allocates more resources
 				// than its min, i.e., borrowing resources from other
 				// Quotas. Only Pods marked as "overquota" can be preempted.
-				if pvPi.Pod.Namespace != pod.Namespace && pvEqInfo.usedOverMin() {
+				_, pvPath := elasticQuotaInfos.getDivergentPaths(preemptorElasticQuotaInfo, pvEqInfo)
+				if pvPi.Pod.Namespace != pod.Namespace && elasticQuotaInfos.usedOverMin(pvPath) {
 					if podutil.IsOverQuota(*pvPi.Pod) {
 						potentialVictims = append(potentialVictims, pvPi)
 						if err := removePod(pvPi); err != nil {
@@ -615,7 +634,7 @@ func (p *preemptor) SelectVictimsOnNode(
 	// after removing all the lower priority pods,
 	// we are almost done and this node is not suitable for preemption.
 	if preemptorWithElasticQuota {
-		if preemptorElasticQuotaInfo.usedOverMaxWith(&podReq) {
+		if elasticQuotaInfos.UsedOverMaxWith(pod.Namespace, &podReq) != nil {
 			return nil, 0, framework.NewStatus(framework.Unschedulable, "max quota exceeded")
 		}
 		if elasticQuotaInfos.AggregatedUsedOverMinWith(podReq) {
@@ -646,7 +665,7 @@ func (p *preemptor) SelectVictimsOnNode(
 			klog.V(5).InfoS("Found a potential preemption victim on node", "pod", klog.KObj(pi.Pod), "node", klog.KObj(nodeInfo.Node()))
 		}
 
-		if preemptorWithElasticQuota && (preemptorElasticQuotaInfo.usedOverMaxWith(&nominatedPodsReqInEQWithPodReq) || elasticQuotaInfos.AggregatedUsedOverMinWith(nominatedPodsReqWithPodReq)) {
+		if preemptorWithElasticQuota && (elasticQuotaInfos.UsedOverMaxWith(pod.Namespace, &nominatedPodsReqInEQWithPodReq) != nil || elasticQuotaInfos.AggregatedUsedOverMinWith(nominatedPodsReqWithPodReq)) {
 			if err := removePod(pi); err != nil {
 				return false, err
 			}
@@ -723,6 +742,38 @@ func (c *CapacityScheduling) deleteElasticQuotaInfo(obj interface{}) {
 	c.elasticQuotaInfos.Delete(eqInfo)
 }
 
+// refreshElasticQuotaLimits sets the Min and Max of the ElasticQuotaInfos whose limits expired to the
+// limits in effect at the time provided as argument, so that the time windows start and end at the
+// right time even if the ElasticQuotas do not change
+func (c *CapacityScheduling) refreshElasticQuotaLimits(now time.Time) {
+	if !c.hasExpiredElasticQuotaLimits(now) {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	for _, eqInfo := range c.elasticQuotaInfos.distinct() {
+		if !eqInfo.limitsExpired(now) {
+			continue
+		}
+		if err := eqInfo.applyTimeWindows(now); err != nil {
+			klog.ErrorS(err, "unable to apply time windows", "namespace", eqInfo.ResourceNamespace, "name", eqInfo.ResourceName)
+		}
+	}
+}
+
+// hasExpiredElasticQuotaLimits returns true if the limits of any ElasticQuotaInfo
+// may not be in effect anymore at the time provided as argument
+func (c *CapacityScheduling) hasExpiredElasticQuotaLimits(now time.Time) bool {
+	c.RLock()
+	defer c.RUnlock()
+	for _, eqInfo := range c.elasticQuotaInfos {
+		if eqInfo.limitsExpired(now) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *CapacityScheduling) addPod(obj interface{}) {
 	pod := obj.(*v1.Pod)
 
@@ -805,6 +856,7 @@ func (c *CapacityScheduling) snapshotElasticQuota() *ElasticQuotaSnapshotState {
 	defer c.RUnlock()
 
 	elasticQuotaInfosDeepCopy := c.elasticQuotaInfos.clone()
+	elasticQuotaInfosDeepCopy.indexHierarchy()
 	return &ElasticQuotaSnapshotState{
 		elasticQuotaInfos: elasticQuotaInfosDeepCopy,
 	}
@@ -27,11 +27,13 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"sort"
 	"testing"
+	"time"
 
 	gocmp "github.com/google/go-cmp/cmp"
 
 	"k8s.io/api/core/v1"
 	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	apiruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/informers"
 	clientsetfake "k8s.io/client-go/kubernetes/fake"
@@ -439,6 +441,240 @@ func TestDryRunPreemption(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "hierarchical preemption - the parent of the victim's quota does not borrow quotas",
+			pod:  makePod("t1-p", "ns3", 100, 0, 0, highPriority, "", "t1-p", false),
+			pods: []*v1.Pod{
+				makePod("t1-p1", "ns2", 50, 0, 0, midPriority, "t1-p1", "node-a", false),
+				makePod("t1-p2", "ns2", 50, 0, 0, midPriority, "t1-p2", "node-a", true),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(map[v1.ResourceName]string{v1.ResourceMemory: "150"}).Obj(),
+			},
+			elasticQuotas: map[string]*ElasticQuotaInfo{
+				"org-a": {
+					ResourceName:      "eq",
+					ResourceNamespace: "org-a",
+					ResourceKind:      v1alpha1.KindElasticQuota,
+					Namespaces:        sets.NewString("org-a"),
+					Min: &framework.Resource{
+						Memory: 100,
+					},
+					Used: &framework.Resource{},
+				},
+				"ns1": {
+					ResourceName:      "eq",
+					ResourceNamespace: "ns1",
+					ResourceKind:      v1alpha1.KindElasticQuota,
+					Parent:            &v1alpha1.ElasticQuotaReference{Kind: v1alpha1.KindElasticQuota, Namespace: "org-a", Name: "eq"},
+					Namespaces:        sets.NewString("ns1"),
+					Min: &framework.Resource{
+						Memory: 50,
+					},
+					Used: &framework.Resource{},
+				},
+				"ns2": {
+					ResourceName:      "eq",
+					ResourceNamespace: "ns2",
+					ResourceKind:      v1alpha1.KindElasticQuota,
+					Parent:            &v1alpha1.ElasticQuotaReference{Kind: v1alpha1.KindElasticQuota, Namespace: "org-a", Name: "eq"},
+					Namespaces:        sets.NewString("ns2"),
+					Min: &framework.Resource{
+						Memory: 50,
+					},
+					Used: &framework.Resource{
+						Memory: 100, // borrowing from ns1
+					},
+				},
+				"ns3": {
+					ResourceName:      "eq",
+					ResourceNamespace: "ns3",
+					ResourceKind:      v1alpha1.KindElasticQuota,
+					Namespaces:        sets.NewString("ns3"),
+					Min: &framework.Resource{
+						Memory: 200,
+					},
+					Used: &framework.Resource{},
+				},
+			},
+			nodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			want: []preemption.Candidate{},
+		},
+		{
+			name: "hierarchical preemption - the parent of the victim's quota borrows quotas",
+			pod:  makePod("t1-p", "ns3", 100, 0, 0, highPriority, "", "t1-p", false),
+			pods: []*v1.Pod{
+				makePod("t1-p1", "ns2", 50, 0, 0, midPriority, "t1-p1", "node-a", false),
+				makePod("t1-p2", "ns2", 50, 0, 0, midPriority, "t1-p2", "node-a", true),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(map[v1.ResourceName]string{v1.ResourceMemory: "150"}).Obj(),
+			},
+			elasticQuotas: map[string]*ElasticQuotaInfo{
+				"org-a": {
+					ResourceName:      "eq",
+					ResourceNamespace: "org-a",
+					ResourceKind:      v1alpha1.KindElasticQuota,
+					Namespaces:        sets.NewString("org-a"),
+					Min: &framework.Resource{
+						Memory: 60,
+					},
+					Used: &framework.Resource{},
+				},
+				"ns1": {
+					ResourceName:      "eq",
+					ResourceNamespace: "ns1",
+					ResourceKind:      v1alpha1.KindElasticQuota,
+					Parent:            &v1alpha1.ElasticQuotaReference{Kind: v1alpha1.KindElasticQuota, Namespace: "org-a", Name: "eq"},
+					Namespaces:        sets.NewString("ns1"),
+					Min: &framework.Resource{
+						Memory: 30,
+					},
+					Used: &framework.Resource{},
+				},
+				"ns2": {
+					ResourceName:      "eq",
+					ResourceNamespace: "ns2",
+					ResourceKind:      v1alpha1.KindElasticQuota,
+					Parent:            &v1alpha1.ElasticQuotaReference{Kind: v1alpha1.KindElasticQuota, Namespace: "org-a", Name: "eq"},
+					Namespaces:        sets.NewString("ns2"),
+					Min: &framework.Resource{
+						Memory: 30,
+					},
+					Used: &framework.Resource{
+						Memory: 100, // borrowing from ns1
+					},
+				},
+				"ns3": {
+					ResourceName:      "eq",
+					ResourceNamespace: "ns3",
+					ResourceKind:      v1alpha1.KindElasticQuota,
+					Namespaces:        sets.NewString("ns3"),
+					Min: &framework.Resource{
+						Memory: 200,
+					},
+					Used: &framework.Resource{},
+				},
+			},
+			nodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			want: []preemption.Candidate{
+				&candidate{
+					victims: &extenderv1.Victims{
+						Pods: []*v1.Pod{
+							makePod("t1-p2", "ns2", 50, 0, 0, midPriority, "t1-p2", "node-a", true),
+						},
+						NumPDBViolations: 0,
+					},
+					name: "node-a",
+				},
+			},
+		},
+		{
+			name: "cross-namespace preemption - guaranteed overquotas proportional to min",
+			pod:  makePod("t1-p", "ns1", 50, 0, 0, highPriority, "", "t1-p", false),
+			pods: []*v1.Pod{
+				makePod("t1-p1", "ns1", 50, 0, 0, highPriority, "t1-p1", "node-a", false),
+				makePod("t1-p2", "ns2", 50, 0, 0, midPriority, "t1-p2", "node-a", false),
+				makePod("t1-p3", "ns2", 50, 0, 0, midPriority, "t1-p3", "node-a", true),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(map[v1.ResourceName]string{v1.ResourceMemory: "150"}).Obj(),
+			},
+			elasticQuotas: map[string]*ElasticQuotaInfo{
+				"ns1": {
+					Namespaces: sets.NewString("ns1"),
+					Min: &framework.Resource{
+						Memory: 50,
+					},
+					Used: &framework.Resource{
+						Memory: 50,
+					},
+				},
+				"ns2": {
+					Namespaces: sets.NewString("ns2"),
+					Min: &framework.Resource{
+						Memory: 50,
+					},
+					Used: &framework.Resource{
+						Memory: 100,
+					},
+				},
+				"ns3": {
+					Namespaces: sets.NewString("ns3"),
+					Min: &framework.Resource{
+						Memory: 100,
+					},
+					Used: &framework.Resource{
+						Memory: 0,
+					},
+				},
+			},
+			nodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			want: []preemption.Candidate{},
+		},
+		{
+			name: "cross-namespace preemption - guaranteed overquotas proportional to weights",
+			pod:  makePod("t1-p", "ns1", 50, 0, 0, highPriority, "", "t1-p", false),
+			pods: []*v1.Pod{
+				makePod("t1-p1", "ns1", 50, 0, 0, highPriority, "t1-p1", "node-a", false),
+				makePod("t1-p2", "ns2", 50, 0, 0, midPriority, "t1-p2", "node-a", false),
+				makePod("t1-p3", "ns2", 50, 0, 0, midPriority, "t1-p3", "node-a", true),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(map[v1.ResourceName]string{v1.ResourceMemory: "150"}).Obj(),
+			},
+			elasticQuotas: map[string]*ElasticQuotaInfo{
+				"ns1": {
+					Namespaces: sets.NewString("ns1"),
+					Weight:     3,
+					Min: &framework.Resource{
+						Memory: 50,
+					},
+					Used: &framework.Resource{
+						Memory: 50,
+					},
+				},
+				"ns2": {
+					Namespaces: sets.NewString("ns2"),
+					Weight:     1,
+					Min: &framework.Resource{
+						Memory: 50,
+					},
+					Used: &framework.Resource{
+						Memory: 100,
+					},
+				},
+				"ns3": {
+					Namespaces: sets.NewString("ns3"),
+					Min: &framework.Resource{
+						Memory: 100,
+					},
+					Used: &framework.Resource{
+						Memory: 0,
+					},
+				},
+			},
+			nodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			want: []preemption.Candidate{
+				&candidate{
+					victims: &extenderv1.Victims{
+						Pods: []*v1.Pod{
+							makePod("t1-p3", "ns2", 50, 0, 0, midPriority, "t1-p3", "node-a", true),
+						},
+						NumPDBViolations: 0,
+					},
+					name: "node-a",
+				},
+			},
+		},
 	}
 
 	resourceCalculator := util.ResourceCalculator{
@@ -702,3 +938,48 @@ func TestElasticQuotaInfos_Update(t *testing.T) {
 		})
 	}
 }
+
+func TestCapacityScheduling_RefreshElasticQuotaLimits(t *testing.T) {
+	specMin := v1.ResourceList{v1.ResourceCPU: k8sresource.MustParse("2")}
+	compositeEqInfo := &ElasticQuotaInfo{
+		ResourceName:      "ceq",
+		ResourceNamespace: "ns-1",
+		ResourceKind:      v1alpha1.KindCompositeElasticQuota,
+		Namespaces:        sets.NewString("ns-1", "ns-2"),
+		pods:              sets.NewString(),
+		Min:               framework.NewResource(specMin),
+		Max:               framework.NewResource(nil),
+		Used:              framework.NewResource(nil),
+		TimeWindows: []v1alpha1.ElasticQuotaTimeWindow{
+			{
+				Name:     "weekend",
+				Schedule: "0 0 * * sat",
+				Duration: metav1.Duration{Duration: 48 * time.Hour},
+				Min:      v1.ResourceList{v1.ResourceCPU: k8sresource.MustParse("6")},
+			},
+		},
+		specMin: specMin,
+	}
+	c := &CapacityScheduling{elasticQuotaInfos: NewElasticQuotaInfos()}
+	c.elasticQuotaInfos.Add(compositeEqInfo)
+	c.elasticQuotaInfos.Add(newHierarchicalEqInfo("ns-3", "", 1000, 0))
+	assert.NoError(t, compositeEqInfo.applyTimeWindows(time.Date(2023, 1, 6, 10, 0, 0, 0, time.UTC))) // Friday
+
+	c.refreshElasticQuotaLimits(time.Date(2023, 1, 6, 23, 0, 0, 0, time.UTC)) // Friday, before the window starts
+	assert.Equal(t, int64(2000), c.elasticQuotaInfos["ns-1"].Min.MilliCPU)
+
+	c.refreshElasticQuotaLimits(time.Date(2023, 1, 7, 10, 0, 0, 0, time.UTC)) // Saturday
+	assert.Equal(t, int64(6000), c.elasticQuotaInfos["ns-1"].Min.MilliCPU)
+	assert.Equal(t, int64(6000), c.elasticQuotaInfos["ns-2"].Min.MilliCPU)
+	assert.Equal(t, int64(1000), c.elasticQuotaInfos["ns-3"].Min.MilliCPU)
+
+	// Limits that did not expire yet are not recomputed
+	c.elasticQuotaInfos["ns-1"].Min.MilliCPU = 5000
+	c.refreshElasticQuotaLimits(time.Date(2023, 1, 8, 10, 0, 0, 0, time.UTC)) // Sunday
+	assert.Equal(t, int64(5000), c.elasticQuotaInfos["ns-1"].Min.MilliCPU)
+
+	c.refreshElasticQuotaLimits(time.Date(2023, 1, 9, 10, 0, 0, 0, time.UTC)) // Monday
+	assert.Equal(t, int64(2000), c.elasticQuotaInfos["ns-1"].Min.MilliCPU)
+	assert.Equal(t, int64(2000), c.elasticQuotaInfos["ns-2"].Min.MilliCPU)
+	assert.False(t, c.elasticQuotaInfos["ns-1"].MaxEnforced)
+}
@@ -19,12 +19,14 @@ package capacityscheduling
 
 import (
 	"fmt"
+	"github.com/nebuly-ai/nos/pkg/api/nos.nebuly.com/v1alpha1"
 	"github.com/nebuly-ai/nos/pkg/resource"
 	"github.com/nebuly-ai/nos/pkg/util"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"math"
+	"time"
 )
 
 // ElasticQuotaInfos associates namespaces with the respective ElasticQuotaInfo that defines its quota
@@ -36,12 +38,17 @@ func NewElasticQuotaInfos() ElasticQuotaInfos {
 
 func (e ElasticQuotaInfos) clone() ElasticQuotaInfos {
 	elasticQuotas := make(ElasticQuotaInfos)
+	// CompositeElasticQuotas are shared by multiple namespaces, so we clone
+	// each ElasticQuotaInfo only once for preserving the sharing
+	clones := make(map[*ElasticQuotaInfo]*ElasticQuotaInfo)
 	for key, elasticQuotaInfo := range e {
-		elasticQuotas[key] = elasticQuotaInfo.clone()
+		if _, ok := clones[elasticQuotaInfo]; !ok {
+			clones[elasticQuotaInfo] = elasticQuotaInfo.clone()
+		}
+		elasticQuotas[key] = clones[elasticQuotaInfo]
 	}
 	return elasticQuotas
 }
-
 func (e ElasticQuotaInfos) Delete(eqInfo *ElasticQuotaInfo) {
 	for _, ns := range eqInfo.Namespaces.List() {
 		delete(e, ns)
@@ -71,42 +78,86 @@ func (e ElasticQuotaInfos) Add(eqInfo *ElasticQuotaInfo) {
 	}
 }
 
+// AggregatedUsedOverMinWith returns true if the total resources used by all the ElasticQuotas plus the
+// pod request exceed the sum of the Min of the roots of the hierarchies of ElasticQuotas.
+// The Min of ElasticQuotas with a parent is not taken into account, since it is a share of the Min of the parent.
 func (e ElasticQuotaInfos) AggregatedUsedOverMinWith(podRequest framework.Resource) bool {
-	min := e.getAggregatedMin()
+	min := e.GetAggregatedMin()
 	used := e.getAggregatedUsed()
 	used.Add(resource.FromFrameworkToList(podRequest))
 	return greaterThan(used, min)
 }
 
+// UsedOverMaxWith checks the Max of the ElasticQuota of the namespace and of all its ancestors, and returns
+// the first ElasticQuota whose total used resources plus the pod request exceed its Max.
+// If none of them exceeds its Max, it returns nil.
+func (e ElasticQuotaInfos) UsedOverMaxWith(namespace string, podRequest *framework.Resource) *ElasticQuotaInfo {
+	eqInfo, ok := e[namespace]
+	if !ok {
+		return nil
+	}
+	for _, info := range e.getPath(eqInfo) {
+		if !info.MaxEnforced {
+			continue
+		}
+		used := e.getTotalUsed(info)
+		if sumGreaterThan(podRequest, &used, info.Max) {
+			return info
+		}
+	}
+	return nil
+}
+
+// GetGuaranteedOverquotas returns the over-quotas guaranteed to the ElasticQuota of the namespace, namely the
+// share of the unused Min of its siblings and of the over-quotas guaranteed to its parent that it can use
+// in addition to its own Min. The share is proportional to the Min of the ElasticQuota.
 func (e ElasticQuotaInfos) GetGuaranteedOverquotas(elasticQuota string) (*framework.Resource, error) {
 	eqInfo, ok := e[elasticQuota]
 	if !ok {
 		return nil, fmt.Errorf("elastic quota %q not present in elastic quota infos", elasticQuota)
 	}
+	result := e.getGuaranteedOverquotas(eqInfo)
+	return &result, nil
+}
 
+func (e ElasticQuotaInfos) getGuaranteedOverquotas(eqInfo *ElasticQuotaInfo) framework.Resource {
 	var result = framework.NewResource(nil)
 	percentages := e.getGuaranteedOverquotasPercentages(eqInfo)
-	aggregatedOverquotas := e.getAggregatedOverquotas()
+	parent := e.getParent(eqInfo)
+	availableOverquotas := e.getOverquotas(e.getChildren(parent))
+	if parent != nil {
+		availableOverquotas = resource.Sum(availableOverquotas, e.getGuaranteedOverquotas(parent))
+	}
 
-	result.MilliCPU = int64(math.Floor(float64(aggregatedOverquotas.MilliCPU) * percentages[v1.ResourceCPU]))
-	result.Memory = int64(math.Floor(float64(aggregatedOverquotas.Memory) * percentages[v1.ResourceMemory]))
-	result.AllowedPodNumber = int(math.Floor(float64(aggregatedOverquotas.AllowedPodNumber) * percentages[v1.ResourcePods]))
-	result.EphemeralStorage = int64(math.Floor(float64(aggregatedOverquotas.EphemeralStorage) * percentages[v1.ResourceEphemeralStorage]))
+	result.MilliCPU = int64(math.Floor(float64(availableOverquotas.MilliCPU) * percentages[v1.ResourceCPU]))
+	result.Memory = int64(math.Floor(float64(availableOverquotas.Memory) * percentages[v1.ResourceMemory]))
+	result.AllowedPodNumber = int(math.Floor(float64(availableOverquotas.AllowedPodNumber) * percentages[v1.ResourcePods]))
+	result.EphemeralStorage = int64(math.Floor(float64(availableOverquotas.EphemeralStorage) * percentages[v1.ResourceEphemeralStorage]))
 
-	for r, v := range aggregatedOverquotas.ScalarResources {
+	for r, v := range availableOverquotas.ScalarResources {
 		result.SetScalar(r, int64(math.Floor(float64(v)*percentages[r])))
 	}
 
-	return result, nil
+	return *result
 }
 
+// getGuaranteedOverquotasPercentages returns, for each resource, the ratio between the Min of the
+// ElasticQuotaInfo and the sum of the Min of the ElasticQuotaInfos sharing its same parent.
+//
+// If any of the ElasticQuotaInfos sharing the same parent specifies a weight, then the ratio is computed
+// using their weights instead of their Min.
 func (e ElasticQuotaInfos) getGuaranteedOverquotasPercentages(eqInfo *ElasticQuotaInfo) map[v1.ResourceName]float64 {
+	siblings := e.getChildren(e.getParent(eqInfo))
+	if isWeighted(siblings) {
+		return e.getWeightedGuaranteedOverquotasPercentages(eqInfo, siblings)
+	}
+
 	var result = make(map[v1.ResourceName]float64)
 	if eqInfo.Min == nil {
 		return result
 	}
 
-	var totalMin = resource.FromFrameworkToList(*e.getAggregatedMin())
+	var totalMin = resource.FromFrameworkToList(getMin(siblings))
 	for r, m := range resource.FromFrameworkToList(*eqInfo.Min) {
 		t := totalMin[r]
 		var p float64
@@ -118,6 +169,22 @@ func (e ElasticQuotaInfos) getGuaranteedOverquotasPercentages(eqInfo *ElasticQuo
 	return result
 }
 
+// getWeightedGuaranteedOverquotasPercentages returns, for each resource, the ratio between the weight of the
+// ElasticQuotaInfo and the sum of the weights of the ElasticQuotaInfos provided as argument
+func (e ElasticQuotaInfos) getWeightedGuaranteedOverquotasPercentages(eqInfo *ElasticQuotaInfo, siblings []*ElasticQuotaInfo) map[v1.ResourceName]float64 {
+	var result = make(map[v1.ResourceName]float64)
+	var totalWeight int64
+	for _, sibling := range siblings {
+		totalWeight += int64(sibling.getWeight())
+	}
+	p := float64(eqInfo.getWeight()) / float64(totalWeight)
+	// The over-quotas can include any resource specified in the Min of the ElasticQuotas
+	for r := range resource.FromFrameworkToList(getMin(e.distinct())) {
+		result[r] = p
+	}
+	return result
+}
+
 // getAggregatedOverquotas returns the total amount of quotas that can be used as "over-quotas", namely
 // the quotas that ElasticQuotas can use for hosting a Pod over their Min limits.
 //
@@ -142,35 +209,241 @@ func (e ElasticQuotaInfos) getGuaranteedOverquotasPercentages(eqInfo *ElasticQuo
 //			cpu: 50m
 //
 // Tot. available overquotas = 50m + 150m = 200m (150m of these quotas are already being used by ElasticQuota A)
+//
+// Only the roots of the hierarchies of ElasticQuotas are taken into account: the over-quotas available
+// to the children of an ElasticQuota are computed by getGuaranteedOverquotas.
 func (e ElasticQuotaInfos) getAggregatedOverquotas() framework.Resource {
+	return e.getOverquotas(e.getChildren(nil))
+}
+
+// getOverquotas returns the sum of the unused Min of the ElasticQuotaInfos provided as argument
+func (e ElasticQuotaInfos) getOverquotas(eqInfos []*ElasticQuotaInfo) framework.Resource {
 	var result = framework.Resource{}
-	for _, eqInfo := range e {
-		unused := resource.SubtractNonNegative(*eqInfo.Min, *eqInfo.Used)
+	for _, eqInfo := range eqInfos {
+		if eqInfo.Min == nil {
+			continue
+		}
+		unused := resource.SubtractNonNegative(*eqInfo.Min, e.getTotalUsed(eqInfo))
 		result = resource.Sum(result, unused)
 	}
 	return result
 }
 
-func (e ElasticQuotaInfos) getAggregatedMin() *framework.Resource {
-	var totalMin = framework.Resource{}
-	for _, eqi := range e {
-		if eqi.Min == nil {
-			continue
-		}
-		totalMin = resource.Sum(totalMin, *eqi.Min)
-	}
+// GetAggregatedMin returns the sum of the Min of the ElasticQuotaInfos that do not have any parent.
+// The Min of the other ElasticQuotaInfos is not considered, since it is part of the Min of their ancestors.
+func (e ElasticQuotaInfos) GetAggregatedMin() *framework.Resource {
+	totalMin := getMin(e.getChildren(nil))
 	return &totalMin
 }
 
 func (e ElasticQuotaInfos) getAggregatedUsed() *framework.Resource {
 	var totalUsed = framework.Resource{}
-	for _, eqi := range e {
-		if eqi.Used == nil {
+	for _, eqi := range e.getChildren(nil) {
+		totalUsed = resource.Sum(totalUsed, e.getTotalUsed(eqi))
+	}
+	return &totalUsed
+}
+
+// getTotalUsed returns the resources used by the pods in the namespaces of the ElasticQuotaInfo
+// plus the resources used by all its descendants.
+func (e ElasticQuotaInfos) getTotalUsed(eqInfo *ElasticQuotaInfo) framework.Resource {
+	var totalUsed = framework.Resource{}
+	if eqInfo.Used != nil {
+		totalUsed = resource.Sum(totalUsed, *eqInfo.Used)
+	}
+	for _, child := range e.getChildren(eqInfo) {
+		totalUsed = resource.Sum(totalUsed, e.getTotalUsed(child))
+	}
+	return totalUsed
+}
+
+// getParent returns the ElasticQuotaInfo referenced as parent by the ElasticQuotaInfo provided as argument.
+//
+// It returns nil if the ElasticQuotaInfo has no parent, if its parent does not exist or
+// if following its ancestors leads back to the ElasticQuotaInfo itself, so that
+// parent references forming a cycle are ignored.
+func (e ElasticQuotaInfos) getParent(eqInfo *ElasticQuotaInfo) *ElasticQuotaInfo {
+	if eqInfo.hierarchy != nil {
+		return eqInfo.hierarchy.parent
+	}
+	return e.findParent(eqInfo, e.lookupParent)
+}
+
+// findParent returns the parent of the ElasticQuotaInfo, using the function provided as argument
+// for looking up the ElasticQuotaInfo referenced as parent by each ElasticQuotaInfo
+func (e ElasticQuotaInfos) findParent(eqInfo *ElasticQuotaInfo, lookupParent func(*ElasticQuotaInfo) *ElasticQuotaInfo) *ElasticQuotaInfo {
+	parent := lookupParent(eqInfo)
+	visited := map[*ElasticQuotaInfo]bool{}
+	for ancestor := parent; ancestor != nil && !visited[ancestor]; ancestor = lookupParent(ancestor) {
+		if ancestor == eqInfo {
+			return nil
+		}
+		visited[ancestor] = true
+	}
+	return parent
+}
+
+func (e ElasticQuotaInfos) lookupParent(eqInfo *ElasticQuotaInfo) *ElasticQuotaInfo {
+	if eqInfo.Parent == nil {
+		return nil
+	}
+	for _, info := range e {
+		if info.isReferencedBy(*eqInfo.Parent) {
+			return info
+		}
+	}
+	return nil
+}
+
+// getChildren returns the ElasticQuotaInfos whose parent is the ElasticQuotaInfo provided as argument.
+// If the argument is nil, it returns the roots of the hierarchies, namely the ElasticQuotaInfos without parent.
+func (e ElasticQuotaInfos) getChildren(parent *ElasticQuotaInfo) []*ElasticQuotaInfo {
+	if parent != nil && parent.hierarchy != nil {
+		return parent.hierarchy.children
+	}
+	var result = make([]*ElasticQuotaInfo, 0)
+	for _, info := range e.distinct() {
+		if info != parent && e.getParent(info) == parent {
+			result = append(result, info)
+		}
+	}
+	return result
+}
+
+// getPath returns the ElasticQuotaInfo provided as argument followed by its ancestors,
+// from its parent up to the root of its hierarchy.
+func (e ElasticQuotaInfos) getPath(eqInfo *ElasticQuotaInfo) []*ElasticQuotaInfo {
+	var result = []*ElasticQuotaInfo{eqInfo}
+	for parent := e.getParent(eqInfo); parent != nil; parent = e.getParent(parent) {
+		result = append(result, parent)
+	}
+	return result
+}
+
+// getDivergentPaths returns the paths of the two ElasticQuotaInfos provided as arguments without their
+// common ancestors, namely the ElasticQuotaInfos competing with each other for the same resources.
+// If the two ElasticQuotaInfos are the same, both the returned paths are empty.
+func (e ElasticQuotaInfos) getDivergentPaths(x, y *ElasticQuotaInfo) ([]*ElasticQuotaInfo, []*ElasticQuotaInfo) {
+	xPath, yPath := e.getPath(x), e.getPath(y)
+	for len(xPath) > 0 && len(yPath) > 0 && xPath[len(xPath)-1] == yPath[len(yPath)-1] {
+		xPath = xPath[:len(xPath)-1]
+		yPath = yPath[:len(yPath)-1]
+	}
+	return xPath, yPath
+}
+
+// usedLteGuaranteedWith returns true if, for each ElasticQuotaInfo provided as argument, its total used
+// resources plus the pod request are less than or equal to its Min plus its guaranteed over-quotas.
+func (e ElasticQuotaInfos) usedLteGuaranteedWith(eqInfos []*ElasticQuotaInfo, podRequest *framework.Resource) bool {
+	for _, info := range eqInfos {
+		used := e.getTotalUsed(info)
+		guaranteed := resource.Sum(getMin([]*ElasticQuotaInfo{info}), e.getGuaranteedOverquotas(info))
+		if !sumLessThanEqual(podRequest, &used, &guaranteed) {
+			return false
+		}
+	}
+	return true
+}
+
+// usedOverGuaranteed returns true if all the ElasticQuotaInfos provided as argument use more resources than
+// their Min plus their guaranteed over-quotas. It returns false if no ElasticQuotaInfo is provided.
+func (e ElasticQuotaInfos) usedOverGuaranteed(eqInfos []*ElasticQuotaInfo) bool {
+	if len(eqInfos) == 0 {
+		return false
+	}
+	for _, info := range eqInfos {
+		used := e.getTotalUsed(info)
+		guaranteed := resource.Sum(getMin([]*ElasticQuotaInfo{info}), e.getGuaranteedOverquotas(info))
+		if !greaterThan(&used, &guaranteed) {
+			return false
+		}
+	}
+	return true
+}
+
+// usedOverMin returns true if all the ElasticQuotaInfos provided as argument use more resources than
+// their Min. It returns false if no ElasticQuotaInfo is provided.
+func (e ElasticQuotaInfos) usedOverMin(eqInfos []*ElasticQuotaInfo) bool {
+	if len(eqInfos) == 0 {
+		return false
+	}
+	for _, info := range eqInfos {
+		used := e.getTotalUsed(info)
+		min := getMin([]*ElasticQuotaInfo{info})
+		if !greaterThan(&used, &min) {
+			return false
+		}
+	}
+	return true
+}
+
+// indexHierarchy stores in each ElasticQuotaInfo its parent and its children, so that navigating
+// the hierarchies of ElasticQuotaInfos does not require scanning all of them at each step.
+// Since the index is not updated when ElasticQuotaInfos are added, updated or deleted, it must be
+// built only on snapshots, whose ElasticQuotaInfos never change.
+func (e ElasticQuotaInfos) indexHierarchy() {
+	infos := e.distinct()
+	byReference := make(map[v1alpha1.ElasticQuotaReference]*ElasticQuotaInfo, len(infos))
+	for _, info := range infos {
+		ref := v1alpha1.ElasticQuotaReference{Kind: info.ResourceKind, Namespace: info.ResourceNamespace, Name: info.ResourceName}
+		byReference[ref] = info
+	}
+	lookupParent := func(eqInfo *ElasticQuotaInfo) *ElasticQuotaInfo {
+		if eqInfo.Parent == nil {
+			return nil
+		}
+		return byReference[*eqInfo.Parent]
+	}
+
+	parents := make(map[*ElasticQuotaInfo]*ElasticQuotaInfo, len(infos))
+	for _, info := range infos {
+		parents[info] = e.findParent(info, lookupParent)
+	}
+	for _, info := range infos {
+		info.hierarchy = &hierarchyIndex{parent: parents[info], children: make([]*ElasticQuotaInfo, 0)}
+	}
+	for _, info := range infos {
+		if parent := parents[info]; parent != nil {
+			parent.hierarchy.children = append(parent.hierarchy.children, info)
+		}
+	}
+}
+
+// distinct returns the ElasticQuotaInfos without duplicates, since the same ElasticQuotaInfo
+// is associated with all the namespaces of a CompositeElasticQuota
+func (e ElasticQuotaInfos) distinct() []*ElasticQuotaInfo {
+	var result = make([]*ElasticQuotaInfo, 0, len(e))
+	var seen = make(map[*ElasticQuotaInfo]bool, len(e))
+	for _, info := range e {
+		if info == nil || seen[info] {
 			continue
 		}
-		totalUsed = resource.Sum(totalUsed, *eqi.Used)
+		seen[info] = true
+		result = append(result, info)
 	}
-	return &totalUsed
+	return result
+}
+
+// isWeighted returns true if any of the ElasticQuotaInfos provided as argument specifies a weight
+func isWeighted(eqInfos []*ElasticQuotaInfo) bool {
+	for _, eqInfo := range eqInfos {
+		if eqInfo.Weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// getMin returns the sum of the Min of the ElasticQuotaInfos provided as argument
+func getMin(eqInfos []*ElasticQuotaInfo) framework.Resource {
+	var totalMin = framework.Resource{}
+	for _, eqi := range eqInfos {
+		if eqi.Min == nil {
+			continue
+		}
+		totalMin = resource.Sum(totalMin, *eqi.Min)
+	}
+	return totalMin
 }
 
 // ElasticQuotaInfo wraps ElasticQuotas and CompositeElasticQuotas adding additional information and utility methods.
@@ -182,6 +455,25 @@ type ElasticQuotaInfo struct {
 	// associated to the ElasticQuotaInfo belongs to
 	ResourceNamespace string
 
+	// ResourceKind is the kind of the resource (ElasticQuota or CompositeElasticQuota)
+	// associated to the ElasticQuotaInfo
+	ResourceKind string
+	// Parent is the optional reference to the ElasticQuota or CompositeElasticQuota from which
+	// the ElasticQuotaInfo borrows resources before borrowing them from the rest of the cluster
+	Parent *v1alpha1.ElasticQuotaReference
+	// Weight is the optional weight used for computing the guaranteed over-quotas of the ElasticQuotaInfo.
+	// Zero means that the weight is not specified.
+	Weight int32
+	// TimeWindows are the optional time windows during which the Min and Max specified by the
+	// resource are overridden. Min and Max always contain the limits in effect at the last
+	// time the time windows were applied.
+	TimeWindows []v1alpha1.ElasticQuotaTimeWindow
+	specMin     v1.ResourceList
+	specMax     v1.ResourceList
+	// nextTransition is the time at which the time windows must be applied again since
+	// the limits in effect may change, or the zero time if they never change
+	nextTransition time.Time
+
 	Namespaces         sets.String
 	pods               sets.String
 	Min                *framework.Resource
@@ -189,6 +481,51 @@ type ElasticQuotaInfo struct {
 	Used               *framework.Resource
 	MaxEnforced        bool
 	resourceCalculator resource.Calculator
+	// hierarchy is the index of the parent and of the children of the ElasticQuotaInfo,
+	// nil if the hierarchy has not been indexed
+	hierarchy *hierarchyIndex
+}
+
+// hierarchyIndex stores the parent and the children of an ElasticQuotaInfo
+type hierarchyIndex struct {
+	parent   *ElasticQuotaInfo
+	children []*ElasticQuotaInfo
+}
+
+// isReferencedBy returns true if the resource associated to the ElasticQuotaInfo is the one
+// identified by the reference provided as argument
+func (e *ElasticQuotaInfo) isReferencedBy(ref v1alpha1.ElasticQuotaReference) bool {
+	return e.ResourceKind == ref.Kind && e.ResourceNamespace == ref.Namespace && e.ResourceName == ref.Name
+}
+
+// getWeight returns the weight of the ElasticQuotaInfo, or 1 if the weight is not specified
+func (e *ElasticQuotaInfo) getWeight() int32 {
+	if e.Weight > 0 {
+		return e.Weight
+	}
+	return 1
+}
+
+// applyTimeWindows sets Min and Max to the limits in effect at the time provided as argument according
+// to the time windows of the ElasticQuotaInfo. Invalid time windows are ignored and reported in the
+// returned error.
+func (e *ElasticQuotaInfo) applyTimeWindows(t time.Time) error {
+	if len(e.TimeWindows) == 0 {
+		return nil
+	}
+	spec := v1alpha1.ElasticQuotaSpec{Min: e.specMin, Max: e.specMax, TimeWindows: e.TimeWindows}
+	limits, err := spec.GetLimits(t)
+	e.Min = framework.NewResource(limits.Min)
+	e.Max = framework.NewResource(limits.Max)
+	e.MaxEnforced = limits.Max != nil
+	e.nextTransition = limits.NextTransition
+	return err
+}
+
+// limitsExpired returns true if the limits applied according to the time windows of the ElasticQuotaInfo
+// may not be in effect anymore at the time provided as argument
+func (e *ElasticQuotaInfo) limitsExpired(t time.Time) bool {
+	return !e.nextTransition.IsZero() && !t.Before(e.nextTransition)
 }
 
 func (e *ElasticQuotaInfo) reserveResource(request framework.Resource) {
@@ -242,12 +579,21 @@ func (e *ElasticQuotaInfo) clone() *ElasticQuotaInfo {
 	newEQInfo := &ElasticQuotaInfo{
 		ResourceName:       e.ResourceName,
 		ResourceNamespace:  e.ResourceNamespace,
+		ResourceKind:       e.ResourceKind,
+		Weight:             e.Weight,
+		TimeWindows:        e.TimeWindows,
+		specMin:            e.specMin,
+		specMax:            e.specMax,
+		nextTransition:     e.nextTransition,
 		pods:               sets.NewString(),
 		Namespaces:         sets.NewString(),
 		MaxEnforced:        e.MaxEnforced,
 		resourceCalculator: e.resourceCalculator,
 	}
 
+	if e.Parent != nil {
+		newEQInfo.Parent = e.Parent.DeepCopy()
+	}
 	if e.Min != nil {
 		newEQInfo.Min = e.Min.Clone()
 	}
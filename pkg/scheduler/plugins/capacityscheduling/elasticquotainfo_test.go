@@ -24,10 +24,12 @@ import (
 	"github.com/nebuly-ai/nos/pkg/resource"
 	"github.com/stretchr/testify/assert"
 	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"math"
 	"reflect"
 	"testing"
+	"time"
 
 	"k8s.io/api/core/v1"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
@@ -721,7 +723,7 @@ func TestElasticQuotaInfos_getAggregatedOverquotas(t *testing.T) {
 			assert.Equal(t, tt.expected, actual)
 
 			// aggregated_overquotas must be <= aggregated_min
-			aggregatedMin := tt.elasticQuotaInfos.getAggregatedMin()
+			aggregatedMin := tt.elasticQuotaInfos.GetAggregatedMin()
 			assert.LessOrEqual(t, actual.MilliCPU, aggregatedMin.MilliCPU)
 			assert.LessOrEqual(t, actual.Memory, aggregatedMin.Memory)
 			assert.LessOrEqual(t, actual.EphemeralStorage, aggregatedMin.EphemeralStorage)
@@ -879,3 +881,278 @@ func TestElasticQuotaInfos_AggregatedUsedOverMinWith(t *testing.T) {
 		})
 	}
 }
+
+func newHierarchicalEqInfo(namespace string, parentNamespace string, min, used int64) *ElasticQuotaInfo {
+	eqInfo := &ElasticQuotaInfo{
+		ResourceName:      "eq",
+		ResourceNamespace: namespace,
+		ResourceKind:      v1alpha1.KindElasticQuota,
+		Namespaces:        sets.NewString(namespace),
+		pods:              sets.NewString(),
+		Min:               &framework.Resource{MilliCPU: min},
+		Max:               &framework.Resource{},
+		Used:              &framework.Resource{MilliCPU: used},
+	}
+	if parentNamespace != "" {
+		eqInfo.Parent = &v1alpha1.ElasticQuotaReference{
+			Kind:      v1alpha1.KindElasticQuota,
+			Namespace: parentNamespace,
+			Name:      "eq",
+		}
+	}
+	return eqInfo
+}
+
+func newHierarchicalEqInfos(eqInfos ...*ElasticQuotaInfo) ElasticQuotaInfos {
+	res := NewElasticQuotaInfos()
+	for _, eqInfo := range eqInfos {
+		res.Add(eqInfo)
+	}
+	return res
+}
+
+func TestElasticQuotaInfos_Hierarchy(t *testing.T) {
+	t.Run("Parent references to missing quotas and cycles are ignored", func(t *testing.T) {
+		infos := newHierarchicalEqInfos(
+			newHierarchicalEqInfo("org", "", 10, 0),
+			newHierarchicalEqInfo("team-a", "org", 5, 0),
+			newHierarchicalEqInfo("team-b", "missing", 5, 0),
+			newHierarchicalEqInfo("cycle-1", "cycle-2", 5, 0),
+			newHierarchicalEqInfo("cycle-2", "cycle-1", 5, 0),
+			newHierarchicalEqInfo("cycle-child", "cycle-1", 5, 0),
+		)
+		assert.Equal(t, infos["org"], infos.getParent(infos["team-a"]))
+		assert.Nil(t, infos.getParent(infos["org"]))
+		assert.Nil(t, infos.getParent(infos["team-b"]))
+		assert.Nil(t, infos.getParent(infos["cycle-1"]))
+		assert.Nil(t, infos.getParent(infos["cycle-2"]))
+		assert.Equal(t, infos["cycle-1"], infos.getParent(infos["cycle-child"]))
+		assert.ElementsMatch(
+			t,
+			[]*ElasticQuotaInfo{infos["org"], infos["team-b"], infos["cycle-1"], infos["cycle-2"]},
+			infos.getChildren(nil),
+		)
+	})
+
+	t.Run("Total used includes the used of all the descendants", func(t *testing.T) {
+		infos := newHierarchicalEqInfos(
+			newHierarchicalEqInfo("org", "", 10, 1),
+			newHierarchicalEqInfo("dept", "org", 5, 2),
+			newHierarchicalEqInfo("team-a", "dept", 2, 3),
+			newHierarchicalEqInfo("team-b", "dept", 2, 4),
+		)
+		assert.Equal(t, int64(10), infos.getTotalUsed(infos["org"]).MilliCPU)
+		assert.Equal(t, int64(9), infos.getTotalUsed(infos["dept"]).MilliCPU)
+		assert.Equal(t, int64(3), infos.getTotalUsed(infos["team-a"]).MilliCPU)
+	})
+
+	t.Run("Divergent paths exclude common ancestors", func(t *testing.T) {
+		infos := newHierarchicalEqInfos(
+			newHierarchicalEqInfo("org-a", "", 10, 0),
+			newHierarchicalEqInfo("org-b", "", 10, 0),
+			newHierarchicalEqInfo("team-a1", "org-a", 5, 0),
+			newHierarchicalEqInfo("team-a2", "org-a", 5, 0),
+			newHierarchicalEqInfo("team-b1", "org-b", 5, 0),
+		)
+		x, y := infos.getDivergentPaths(infos["team-a1"], infos["team-a2"])
+		assert.Equal(t, []*ElasticQuotaInfo{infos["team-a1"]}, x)
+		assert.Equal(t, []*ElasticQuotaInfo{infos["team-a2"]}, y)
+
+		x, y = infos.getDivergentPaths(infos["team-a1"], infos["team-b1"])
+		assert.Equal(t, []*ElasticQuotaInfo{infos["team-a1"], infos["org-a"]}, x)
+		assert.Equal(t, []*ElasticQuotaInfo{infos["team-b1"], infos["org-b"]}, y)
+
+		x, y = infos.getDivergentPaths(infos["team-a1"], infos["team-a1"])
+		assert.Empty(t, x)
+		assert.Empty(t, y)
+	})
+
+	t.Run("Clone preserves quotas shared by multiple namespaces and parent references", func(t *testing.T) {
+		composite := newHierarchicalEqInfo("org", "", 10, 0)
+		composite.ResourceKind = v1alpha1.KindCompositeElasticQuota
+		composite.Namespaces = sets.NewString("ns-1", "ns-2")
+		infos := newHierarchicalEqInfos(composite, newHierarchicalEqInfo("team", "org", 5, 0))
+
+		cloned := infos.clone()
+		assert.True(t, cloned["ns-1"] == cloned["ns-2"])
+		assert.False(t, cloned["ns-1"] == infos["ns-1"])
+		assert.Equal(t, infos["team"].Parent, cloned["team"].Parent)
+		assert.Len(t, cloned.distinct(), 2)
+	})
+
+	t.Run("Indexed hierarchy is the same as the scanned one", func(t *testing.T) {
+		composite := newHierarchicalEqInfo("org", "", 10, 1)
+		composite.ResourceKind = v1alpha1.KindCompositeElasticQuota
+		composite.Namespaces = sets.NewString("ns-1", "ns-2")
+		team := newHierarchicalEqInfo("team", "", 5, 2)
+		team.Parent = &v1alpha1.ElasticQuotaReference{Kind: v1alpha1.KindCompositeElasticQuota, Namespace: "org", Name: "eq"}
+		scanned := newHierarchicalEqInfos(
+			composite,
+			team,
+			newHierarchicalEqInfo("sub-team", "team", 2, 3),
+			newHierarchicalEqInfo("missing-parent", "missing", 5, 4),
+			newHierarchicalEqInfo("cycle-1", "cycle-2", 5, 5),
+			newHierarchicalEqInfo("cycle-2", "cycle-1", 5, 6),
+			newHierarchicalEqInfo("cycle-child", "cycle-1", 5, 7),
+		)
+		indexed := scanned.clone()
+		indexed.indexHierarchy()
+
+		assert.Len(t, indexed.getChildren(nil), len(scanned.getChildren(nil)))
+		for ns, info := range scanned {
+			assert.Nil(t, info.hierarchy)
+			assert.NotNil(t, indexed[ns].hierarchy)
+			if parent := scanned.getParent(info); parent != nil {
+				assert.Equal(t, parent.ResourceNamespace, indexed.getParent(indexed[ns]).ResourceNamespace, ns)
+			} else {
+				assert.Nil(t, indexed.getParent(indexed[ns]), ns)
+			}
+			assert.Len(t, indexed.getChildren(indexed[ns]), len(scanned.getChildren(info)), ns)
+			assert.Equal(t, scanned.getTotalUsed(info), indexed.getTotalUsed(indexed[ns]), ns)
+		}
+		assert.Equal(t, int64(6), indexed.getTotalUsed(indexed["ns-1"]).MilliCPU)
+		assert.Equal(t, scanned.GetAggregatedMin(), indexed.GetAggregatedMin())
+		assert.Nil(t, indexed.clone()["ns-1"].hierarchy)
+	})
+}
+
+func TestElasticQuotaInfos_UsedOverMaxWith(t *testing.T) {
+	org := newHierarchicalEqInfo("org", "", 10, 0)
+	org.Max = &framework.Resource{MilliCPU: 20, Memory: math.MaxInt64}
+	org.MaxEnforced = true
+	teamA := newHierarchicalEqInfo("team-a", "org", 5, 8)
+	teamA.Max = &framework.Resource{MilliCPU: 15, Memory: math.MaxInt64}
+	teamA.MaxEnforced = true
+	teamB := newHierarchicalEqInfo("team-b", "org", 5, 10)
+	infos := newHierarchicalEqInfos(org, teamA, teamB)
+
+	// used team-a = 8, used org = 18
+	assert.Nil(t, infos.UsedOverMaxWith("team-a", &framework.Resource{MilliCPU: 2}))
+	assert.Equal(t, org, infos.UsedOverMaxWith("team-a", &framework.Resource{MilliCPU: 3}))
+	assert.Equal(t, org, infos.UsedOverMaxWith("team-b", &framework.Resource{MilliCPU: 3}))
+
+	org.Max.MilliCPU = 100
+	assert.Equal(t, teamA, infos.UsedOverMaxWith("team-a", &framework.Resource{MilliCPU: 8}))
+	assert.Nil(t, infos.UsedOverMaxWith("team-b", &framework.Resource{MilliCPU: 8}))
+	assert.Nil(t, infos.UsedOverMaxWith("not-present", &framework.Resource{MilliCPU: 8}))
+}
+
+func TestElasticQuotaInfos_AggregatedUsedOverMinWith_Hierarchy(t *testing.T) {
+	// The min of the children is part of the min of their parent, so only the min of
+	// the roots must be taken into account
+	infos := newHierarchicalEqInfos(
+		newHierarchicalEqInfo("org-a", "", 10, 0),
+		newHierarchicalEqInfo("team-a1", "org-a", 5, 4),
+		newHierarchicalEqInfo("team-a2", "org-a", 5, 4),
+		newHierarchicalEqInfo("org-b", "", 10, 4),
+	)
+	assert.False(t, infos.AggregatedUsedOverMinWith(framework.Resource{MilliCPU: 8}))
+	assert.True(t, infos.AggregatedUsedOverMinWith(framework.Resource{MilliCPU: 9}))
+}
+
+func TestElasticQuotaInfos_GetGuaranteedOverquotas_Hierarchy(t *testing.T) {
+	infos := newHierarchicalEqInfos(
+		newHierarchicalEqInfo("org-a", "", 40, 0),
+		newHierarchicalEqInfo("team-a1", "org-a", 20, 0),
+		newHierarchicalEqInfo("team-a2", "org-a", 20, 10),
+		newHierarchicalEqInfo("org-b", "", 40, 20),
+	)
+
+	// Roots: unused min = (40 - 10) + (40 - 20) = 50
+	orgA, err := infos.GetGuaranteedOverquotas("org-a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(25), orgA.MilliCPU) // 50 * 40 / 80
+	orgB, err := infos.GetGuaranteedOverquotas("org-b")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(25), orgB.MilliCPU) // 50 * 40 / 80
+
+	// Children of org-a: unused min = 20 + 10, plus the 25 guaranteed to org-a
+	teamA1, err := infos.GetGuaranteedOverquotas("team-a1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(27), teamA1.MilliCPU) // 55 * 20 / 40
+	teamA2, err := infos.GetGuaranteedOverquotas("team-a2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(27), teamA2.MilliCPU) // 55 * 20 / 40
+
+	// team-a2 can borrow up to its min plus its guaranteed over-quotas
+	path := []*ElasticQuotaInfo{infos["team-a2"]}
+	assert.True(t, infos.usedLteGuaranteedWith(path, &framework.Resource{MilliCPU: 37}))
+	assert.False(t, infos.usedLteGuaranteedWith(path, &framework.Resource{MilliCPU: 38}))
+}
+
+func TestElasticQuotaInfos_GetGuaranteedOverquotas_Weights(t *testing.T) {
+	eqA := newHierarchicalEqInfo("ns-a", "", 40, 40)
+	eqA.Weight = 3
+	eqB := newHierarchicalEqInfo("ns-b", "", 40, 40)
+	eqC := newHierarchicalEqInfo("ns-c", "", 80, 0)
+	eqC.Weight = 4
+	infos := newHierarchicalEqInfos(eqA, eqB, eqC)
+
+	// Tot. available over-quotas = 80, shared in proportion to the weights 3, 1 (default) and 4
+	for ns, expected := range map[string]int64{"ns-a": 30, "ns-b": 10, "ns-c": 40} {
+		guaranteedOverquotas, err := infos.GetGuaranteedOverquotas(ns)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, guaranteedOverquotas.MilliCPU, ns)
+	}
+
+	// Weights are applied only among quotas sharing the same parent
+	teamA1 := newHierarchicalEqInfo("team-a1", "ns-c", 40, 0)
+	teamA2 := newHierarchicalEqInfo("team-a2", "ns-c", 20, 0)
+	infos = newHierarchicalEqInfos(eqA, eqB, eqC, teamA1, teamA2)
+	percentages := infos.getGuaranteedOverquotasPercentages(teamA1)
+	assert.InDelta(t, 40.0/60.0, percentages[v1.ResourceCPU], 1e-4)
+	percentages = infos.getGuaranteedOverquotasPercentages(eqB)
+	assert.InDelta(t, 1.0/8.0, percentages[v1.ResourceCPU], 1e-4)
+	assert.InDelta(t, 1.0/8.0, percentages[v1.ResourceMemory], 1e-4)
+}
+
+func TestElasticQuotaInfo_ApplyTimeWindows(t *testing.T) {
+	specMin := v1.ResourceList{v1.ResourceCPU: k8sresource.MustParse("2")}
+	specMax := v1.ResourceList{v1.ResourceCPU: k8sresource.MustParse("4")}
+	eqInfo := &ElasticQuotaInfo{
+		Namespaces: sets.NewString("ns-1"),
+		pods:       sets.NewString(),
+		Min:        framework.NewResource(specMin),
+		Max:        framework.NewResource(specMax),
+		Used:       framework.NewResource(nil),
+		TimeWindows: []v1alpha1.ElasticQuotaTimeWindow{
+			{
+				Name:     "business-hours",
+				Schedule: "0 8 * * *",
+				Duration: metav1.Duration{Duration: 10 * time.Hour},
+				Min:      v1.ResourceList{v1.ResourceCPU: k8sresource.MustParse("1")},
+			},
+			{
+				Name:     "night",
+				Schedule: "0 20 * * *",
+				Duration: metav1.Duration{Duration: 12 * time.Hour},
+				Max:      v1.ResourceList{v1.ResourceCPU: k8sresource.MustParse("8")},
+			},
+		},
+		specMin:     specMin,
+		specMax:     specMax,
+		MaxEnforced: true,
+	}
+
+	assert.NoError(t, eqInfo.applyTimeWindows(time.Date(2023, 1, 2, 9, 0, 0, 0, time.UTC)))
+	assert.Equal(t, int64(1000), eqInfo.Min.MilliCPU)
+	assert.Equal(t, int64(4000), eqInfo.Max.MilliCPU)
+
+	// Clones keep the time windows, so they can be applied again
+	clone := eqInfo.clone()
+	assert.NoError(t, clone.applyTimeWindows(time.Date(2023, 1, 2, 21, 0, 0, 0, time.UTC)))
+	assert.Equal(t, int64(2000), clone.Min.MilliCPU)
+	assert.Equal(t, int64(8000), clone.Max.MilliCPU)
+	assert.Equal(t, int64(1000), eqInfo.Min.MilliCPU)
+
+	// Between the time windows the limits of the spec are restored
+	assert.NoError(t, eqInfo.applyTimeWindows(time.Date(2023, 1, 2, 19, 0, 0, 0, time.UTC)))
+	assert.Equal(t, int64(2000), eqInfo.Min.MilliCPU)
+	assert.Equal(t, int64(4000), eqInfo.Max.MilliCPU)
+	assert.True(t, eqInfo.MaxEnforced)
+
+	// ElasticQuotaInfos without time windows are not changed
+	noWindows := newHierarchicalEqInfo("ns-2", "", 10, 0)
+	assert.NoError(t, noWindows.applyTimeWindows(time.Now()))
+	assert.Equal(t, int64(10), noWindows.Min.MilliCPU)
+}
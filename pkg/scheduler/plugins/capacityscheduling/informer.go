@@ -34,6 +34,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"time"
 )
 
 type filterFunc func(obj interface{}) bool
@@ -266,35 +267,76 @@ func (i ElasticQuotaInfoInformer) fromUnstructuredEqToElasticQuotaInfo(u *unstru
 	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &eq); err != nil {
 		return nil, err
 	}
-	return &ElasticQuotaInfo{
+	return NewElasticQuotaInfoFromElasticQuota(eq, i.resourceCalculator), nil
+}
+
+// fromUnstructuredCompositeEqToElasticQuotaInfo converts an Unstructured object containing a CompositeElasticQuota to
+// an object of type ElasticQuotaInfo
+func (i ElasticQuotaInfoInformer) fromUnstructuredCompositeEqToElasticQuotaInfo(u *unstructured.Unstructured) (*ElasticQuotaInfo, error) {
+	var compositeEq v1alpha1.CompositeElasticQuota
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &compositeEq); err != nil {
+		return nil, err
+	}
+	return NewElasticQuotaInfoFromCompositeElasticQuota(compositeEq, i.resourceCalculator), nil
+}
+
+// NewElasticQuotaInfoFromElasticQuota returns the ElasticQuotaInfo of the ElasticQuota provided as argument,
+// with Min and Max set to the limits in effect at the current time
+func NewElasticQuotaInfoFromElasticQuota(eq v1alpha1.ElasticQuota, resourceCalculator resource.Calculator) *ElasticQuotaInfo {
+	eqInfo := &ElasticQuotaInfo{
 		ResourceName:       eq.Name,
 		ResourceNamespace:  eq.Namespace,
+		ResourceKind:       v1alpha1.KindElasticQuota,
+		Parent:             eq.Spec.Parent,
+		Weight:             getWeight(eq.Spec.Weight),
+		TimeWindows:        eq.Spec.TimeWindows,
+		specMin:            eq.Spec.Min,
+		specMax:            eq.Spec.Max,
 		Namespaces:         sets.NewString(eq.Namespace),
 		pods:               sets.NewString(),
 		Min:                framework.NewResource(eq.Spec.Min),
 		Max:                framework.NewResource(eq.Spec.Max),
 		Used:               framework.NewResource(nil), // used is calculated by the scheduler plugin afterwards
 		MaxEnforced:        eq.Spec.Max != nil,
-		resourceCalculator: i.resourceCalculator,
-	}, nil
+		resourceCalculator: resourceCalculator,
+	}
+	if err := eqInfo.applyTimeWindows(time.Now()); err != nil {
+		klog.ErrorS(err, "unable to apply time windows of ElasticQuota", "namespace", eq.Namespace, "name", eq.Name)
+	}
+	return eqInfo
 }
 
-// fromUnstructuredCompositeEqToElasticQuotaInfo converts an Unstructured object containing a CompositeElasticQuota to
-// an object of type ElasticQuotaInfo
-func (i ElasticQuotaInfoInformer) fromUnstructuredCompositeEqToElasticQuotaInfo(u *unstructured.Unstructured) (*ElasticQuotaInfo, error) {
-	var compositeEq v1alpha1.CompositeElasticQuota
-	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &compositeEq); err != nil {
-		return nil, err
-	}
-	return &ElasticQuotaInfo{
+// NewElasticQuotaInfoFromCompositeElasticQuota returns the ElasticQuotaInfo of the CompositeElasticQuota provided
+// as argument, with Min and Max set to the limits in effect at the current time
+func NewElasticQuotaInfoFromCompositeElasticQuota(compositeEq v1alpha1.CompositeElasticQuota, resourceCalculator resource.Calculator) *ElasticQuotaInfo {
+	eqInfo := &ElasticQuotaInfo{
 		ResourceName:       compositeEq.Name,
 		ResourceNamespace:  compositeEq.Namespace,
+		ResourceKind:       v1alpha1.KindCompositeElasticQuota,
+		Parent:             compositeEq.Spec.Parent,
+		Weight:             getWeight(compositeEq.Spec.Weight),
+		TimeWindows:        compositeEq.Spec.TimeWindows,
+		specMin:            compositeEq.Spec.Min,
+		specMax:            compositeEq.Spec.Max,
 		Namespaces:         sets.NewString(compositeEq.Spec.Namespaces...),
 		pods:               sets.NewString(),
 		Min:                framework.NewResource(compositeEq.Spec.Min),
 		Max:                framework.NewResource(compositeEq.Spec.Max),
 		Used:               framework.NewResource(nil), // used is calculated by the scheduler plugin afterwards
 		MaxEnforced:        compositeEq.Spec.Max != nil,
-		resourceCalculator: i.resourceCalculator,
-	}, nil
+		resourceCalculator: resourceCalculator,
+	}
+	if err := eqInfo.applyTimeWindows(time.Now()); err != nil {
+		klog.ErrorS(err, "unable to apply time windows of CompositeElasticQuota", "namespace", compositeEq.Namespace, "name", compositeEq.Name)
+	}
+	return eqInfo
+}
+
+// getWeight returns the value of the optional weight of an ElasticQuota or CompositeElasticQuota,
+// or 0 if the weight is not specified
+func getWeight(weight *int32) int32 {
+	if weight == nil {
+		return 0
+	}
+	return *weight
 }
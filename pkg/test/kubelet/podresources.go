@@ -0,0 +1,186 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kubelet
+
+import (
+	"context"
+	"fmt"
+	"github.com/nebuly-ai/nos/pkg/resource"
+	"google.golang.org/grpc"
+	"k8s.io/apimachinery/pkg/types"
+	pdrv1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// PodResourcesServer is a stand-in for the PodResources gRPC service exposed by the kubelet, which serves
+// over a unix socket the allocatable devices and the devices used by the containers configured on it.
+//
+// It can be used for testing against realistic gRPC responses the components that read the devices of a
+// node through the kubelet, such as resource.Client.
+type PodResourcesServer struct {
+	pdrv1.UnimplementedPodResourcesListerServer
+
+	mu          sync.Mutex
+	allocatable []resource.Device
+	used        map[types.NamespacedName]map[string][]resource.Device
+	delay       time.Duration
+
+	socket   string
+	server   *grpc.Server
+	listener net.Listener
+}
+
+// NewPodResourcesServer returns a PodResourcesServer that listens on a unix socket created in the
+// directory provided as argument. The server does not serve any device until it is configured.
+func NewPodResourcesServer(dir string) *PodResourcesServer {
+	return &PodResourcesServer{
+		used:   make(map[types.NamespacedName]map[string][]resource.Device),
+		socket: filepath.Join(dir, "kubelet.sock"),
+	}
+}
+
+// Start starts serving the PodResources service on the unix socket of the server
+func (s *PodResourcesServer) Start() error {
+	if err := os.Remove(s.socket); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove socket %s: %v", s.socket, err)
+	}
+	listener, err := net.Listen("unix", s.socket)
+	if err != nil {
+		return fmt.Errorf("unable to listen on socket %s: %v", s.socket, err)
+	}
+	s.listener = listener
+	s.server = grpc.NewServer()
+	pdrv1.RegisterPodResourcesListerServer(s.server, s)
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+	return nil
+}
+
+// Stop stops the server, closing the pending connections
+func (s *PodResourcesServer) Stop() {
+	if s.server != nil {
+		s.server.Stop()
+	}
+}
+
+// Endpoint returns the endpoint of the server, which can be used for creating a client
+// with podresources.GetV1Client
+func (s *PodResourcesServer) Endpoint() string {
+	return "unix://" + s.socket
+}
+
+// SetAllocatableDevices sets the devices returned as allocatable by the server
+func (s *PodResourcesServer) SetAllocatableDevices(devices ...resource.Device) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.allocatable = devices
+}
+
+// SetUsedDevices sets the devices used by the container of the pod provided as argument.
+// Providing no device removes the container.
+func (s *PodResourcesServer) SetUsedDevices(pod types.NamespacedName, container string, devices ...resource.Device) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(devices) == 0 {
+		delete(s.used[pod], container)
+		if len(s.used[pod]) == 0 {
+			delete(s.used, pod)
+		}
+		return
+	}
+	if s.used[pod] == nil {
+		s.used[pod] = make(map[string][]resource.Device)
+	}
+	s.used[pod][container] = devices
+}
+
+// SetResponseDelay sets the time the server waits before answering each request,
+// which can be used for simulating a slow kubelet
+func (s *PodResourcesServer) SetResponseDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = delay
+}
+
+func (s *PodResourcesServer) List(ctx context.Context, _ *pdrv1.ListPodResourcesRequest) (*pdrv1.ListPodResourcesResponse, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := &pdrv1.ListPodResourcesResponse{PodResources: make([]*pdrv1.PodResources, 0, len(s.used))}
+	for pod, containers := range s.used {
+		podResources := &pdrv1.PodResources{
+			Name:       pod.Name,
+			Namespace:  pod.Namespace,
+			Containers: make([]*pdrv1.ContainerResources, 0, len(containers)),
+		}
+		for name, devices := range containers {
+			podResources.Containers = append(podResources.Containers, &pdrv1.ContainerResources{
+				Name:    name,
+				Devices: toContainerDevices(devices),
+			})
+		}
+		res.PodResources = append(res.PodResources, podResources)
+	}
+	return res, nil
+}
+
+func (s *PodResourcesServer) GetAllocatableResources(ctx context.Context, _ *pdrv1.AllocatableResourcesRequest) (*pdrv1.AllocatableResourcesResponse, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &pdrv1.AllocatableResourcesResponse{Devices: toContainerDevices(s.allocatable)}, nil
+}
+
+func (s *PodResourcesServer) wait(ctx context.Context) error {
+	s.mu.Lock()
+	delay := s.delay
+	s.mu.Unlock()
+	if delay == 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// toContainerDevices groups the devices by resource name, as done by the kubelet
+func toContainerDevices(devices []resource.Device) []*pdrv1.ContainerDevices {
+	res := make([]*pdrv1.ContainerDevices, 0)
+	byResourceName := make(map[string]*pdrv1.ContainerDevices)
+	for _, d := range devices {
+		resourceName := d.ResourceName.String()
+		if _, ok := byResourceName[resourceName]; !ok {
+			byResourceName[resourceName] = &pdrv1.ContainerDevices{ResourceName: resourceName}
+			res = append(res, byResourceName[resourceName])
+		}
+		byResourceName[resourceName].DeviceIds = append(byResourceName[resourceName].DeviceIds, d.DeviceId)
+	}
+	return res
+}
@@ -30,7 +30,11 @@ type Client struct {
 	NumCallsGetMigDeviceResources uint
 
 	ReturnedMigDeviceResources gpu.DeviceList
+	ReturnedAllowedGeometries  map[int][]gpu.Geometry
+	ReturnedMigEnabledGPUs     []int
 	ReturnedError              gpu.Error
+	// ReturnedCreateError, if not nil, is returned by CreateMigDevices instead of ReturnedError
+	ReturnedCreateError error
 
 	lockReset                 sync.Mutex
 	lockGetMigDeviceResources sync.Mutex
@@ -57,6 +61,9 @@ func (m *Client) CreateMigDevices(_ context.Context, _ mig.ProfileList) (mig.Pro
 	m.lockCreateMigResource.Lock()
 	defer m.lockCreateMigResource.Unlock()
 	m.NumCallsCreateMigResources++
+	if m.ReturnedCreateError != nil {
+		return nil, m.ReturnedCreateError
+	}
 	return nil, m.ReturnedError
 }
 
@@ -78,3 +85,11 @@ func (m *Client) GetAllocatableMigDevices(ctx context.Context) (gpu.DeviceList,
 func (m *Client) DeleteAllExcept(_ context.Context, resources gpu.DeviceList) error {
 	return m.ReturnedError
 }
+
+func (m *Client) GetAllowedGeometries(_ context.Context) (map[int][]gpu.Geometry, gpu.Error) {
+	return m.ReturnedAllowedGeometries, m.ReturnedError
+}
+
+func (m *Client) GetMigEnabledGPUs(_ context.Context) ([]int, gpu.Error) {
+	return m.ReturnedMigEnabledGPUs, m.ReturnedError
+}
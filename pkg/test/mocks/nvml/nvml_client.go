@@ -21,6 +21,8 @@ package mocks
 import (
 	gpu "github.com/nebuly-ai/nos/pkg/gpu"
 	mock "github.com/stretchr/testify/mock"
+
+	nvml "github.com/nebuly-ai/nos/pkg/gpu/nvml"
 )
 
 // Client is an autogenerated mock type for the Client type
@@ -97,6 +99,31 @@ func (_m *Client) GetGpuIndex(gpuId string) (int, gpu.Error) {
 	return r0, r1
 }
 
+// GetGpuInstanceProfiles provides a mock function with given fields: gpuIndex
+func (_m *Client) GetGpuInstanceProfiles(gpuIndex int) ([]nvml.GpuInstanceProfile, gpu.Error) {
+	ret := _m.Called(gpuIndex)
+
+	var r0 []nvml.GpuInstanceProfile
+	if rf, ok := ret.Get(0).(func(int) []nvml.GpuInstanceProfile); ok {
+		r0 = rf(gpuIndex)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]nvml.GpuInstanceProfile)
+		}
+	}
+
+	var r1 gpu.Error
+	if rf, ok := ret.Get(1).(func(int) gpu.Error); ok {
+		r1 = rf(gpuIndex)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(gpu.Error)
+		}
+	}
+
+	return r0, r1
+}
+
 // GetMigDeviceGpuIndex provides a mock function with given fields: migDeviceId
 func (_m *Client) GetMigDeviceGpuIndex(migDeviceId string) (int, gpu.Error) {
 	ret := _m.Called(migDeviceId)
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchYears is the max number of years Next looks ahead for a time matching a Schedule
+const maxSearchYears = 5
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField     = field{name: "minute", min: 0, max: 59}
+	hourField       = field{name: "hour", min: 0, max: 23}
+	dayOfMonthField = field{name: "day of month", min: 1, max: 31}
+	monthField      = field{name: "month", min: 1, max: 12, names: monthNames}
+	// day of week accepts both 0 and 7 for Sunday
+	dayOfWeekField = field{name: "day of week", min: 0, max: 7, names: dayNames}
+)
+
+// Schedule is a cron expression in the standard format, made of five fields separated by spaces:
+// minute, hour, day of month, month and day of week.
+//
+// Each field can be a "*", a value, a range ("1-5"), a step ("*/15", "1-30/10") or a
+// comma-separated list of them. Months and days of week can also be specified by their
+// three-letter English names (e.g. "jan", "mon"). The macros @yearly, @annually, @monthly,
+// @weekly, @daily, @midnight and @hourly are supported as well.
+//
+// As in the standard cron, when both day of month and day of week are restricted (e.g. not "*"),
+// a time matches the Schedule if it matches either of them.
+type Schedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+
+	dayOfMonthStar bool
+	dayOfWeekStar  bool
+}
+
+// Parse parses the cron expression provided as argument and returns the corresponding Schedule
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := macros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("invalid cron expression %q: expected 5 fields, found %d", expr, len(fields))
+	}
+
+	var s Schedule
+	var err error
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return Schedule{}, err
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return Schedule{}, err
+	}
+	if s.dayOfMonth, err = parseField(fields[2], dayOfMonthField); err != nil {
+		return Schedule{}, err
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return Schedule{}, err
+	}
+	if s.dayOfWeek, err = parseField(fields[4], dayOfWeekField); err != nil {
+		return Schedule{}, err
+	}
+	// 7 is an alias for Sunday
+	if s.dayOfWeek&(1<<7) > 0 {
+		s.dayOfWeek |= 1
+	}
+	s.dayOfMonthStar = fields[2] == "*"
+	s.dayOfWeekStar = fields[4] == "*"
+
+	return s, nil
+}
+
+// Next returns the first time strictly after t that matches the Schedule, evaluated in the location of t.
+// If no time matches the Schedule in the next years (e.g. "0 0 30 2 *"), Next returns the zero time.
+func (s Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+	maxYear := t.Year() + maxSearchYears
+
+	for t.Year() <= maxYear {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s Schedule) matchesDay(t time.Time) bool {
+	domMatch := has(s.dayOfMonth, t.Day())
+	dowMatch := has(s.dayOfWeek, int(t.Weekday()))
+	if s.dayOfMonthStar || s.dayOfWeekStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func has(bits uint64, value int) bool {
+	return bits&(1<<uint(value)) > 0
+}
+
+// parseField parses a field of a cron expression, returning a bitset with the values it matches
+func parseField(expr string, f field) (uint64, error) {
+	var result uint64
+	for _, item := range strings.Split(expr, ",") {
+		bits, err := parseItem(item, f)
+		if err != nil {
+			return 0, err
+		}
+		result |= bits
+	}
+	return result, nil
+}
+
+// parseItem parses an item of a comma-separated list of a cron field, which can be either
+// a "*", a value, a range or a step
+func parseItem(item string, f field) (uint64, error) {
+	var err error
+	rangeExpr, step := item, 1
+	if i := strings.Index(item, "/"); i >= 0 {
+		rangeExpr = item[:i]
+		if step, err = strconv.Atoi(item[i+1:]); err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step %q in %s field", item[i+1:], f.name)
+		}
+	}
+
+	start, end := f.min, f.max
+	switch {
+	case rangeExpr == "*":
+	case strings.Contains(rangeExpr, "-"):
+		bounds := strings.SplitN(rangeExpr, "-", 2)
+		if start, err = parseValue(bounds[0], f); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(bounds[1], f); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid range %q in %s field: start is greater than end", rangeExpr, f.name)
+		}
+	default:
+		if start, err = parseValue(rangeExpr, f); err != nil {
+			return 0, err
+		}
+		// A single value with a step (e.g. "5/10") means from the value to the end of the range
+		end = start
+		if strings.Contains(item, "/") {
+			end = f.max
+		}
+	}
+
+	var bits uint64
+	for v := start; v <= end; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+func parseValue(value string, f field) (int, error) {
+	if n, ok := f.names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", value, f.name)
+	}
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d] in %s field", n, f.min, f.max, f.name)
+	}
+	return n, nil
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2023 nebuly.com.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cron_test
+
+import (
+	"github.com/nebuly-ai/nos/pkg/util/cron"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expr        string
+		errExpected bool
+	}{
+		{name: "Every minute", expr: "* * * * *"},
+		{name: "Lists, ranges and steps", expr: "0,30 8-18/2 1-31 */3 1-5"},
+		{name: "Names", expr: "0 9 * jan-jun MON,fri"},
+		{name: "Macro", expr: "@daily"},
+		{name: "Sunday as 7", expr: "0 0 * * 7"},
+		{name: "Too few fields", expr: "* * * *", errExpected: true},
+		{name: "Too many fields", expr: "* * * * * *", errExpected: true},
+		{name: "Value out of range", expr: "60 * * * *", errExpected: true},
+		{name: "Invalid range", expr: "* 10-8 * * *", errExpected: true},
+		{name: "Invalid step", expr: "*/0 * * * *", errExpected: true},
+		{name: "Invalid name", expr: "* * * foo *", errExpected: true},
+		{name: "Empty", expr: "", errExpected: true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := cron.Parse(tt.expr)
+			if tt.errExpected {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	rome, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "Every minute",
+			expr:     "* * * * *",
+			from:     time.Date(2023, 1, 1, 10, 0, 30, 0, time.UTC),
+			expected: time.Date(2023, 1, 1, 10, 1, 0, 0, time.UTC),
+		},
+		{
+			name:     "Next is strictly after the provided time",
+			expr:     "0 8 * * *",
+			from:     time.Date(2023, 1, 1, 8, 0, 0, 0, time.UTC),
+			expected: time.Date(2023, 1, 2, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Weekdays only",
+			expr:     "0 8 * * mon-fri",
+			from:     time.Date(2023, 1, 6, 9, 0, 0, 0, time.UTC), // Friday
+			expected: time.Date(2023, 1, 9, 8, 0, 0, 0, time.UTC), // Monday
+		},
+		{
+			name:     "Day of month or day of week",
+			expr:     "0 0 15 * 1",
+			from:     time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC), // Tuesday
+			expected: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Steps",
+			expr:     "*/20 * * * *",
+			from:     time.Date(2023, 1, 1, 10, 41, 0, 0, time.UTC),
+			expected: time.Date(2023, 1, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Next year",
+			expr:     "@yearly",
+			from:     time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Leap day",
+			expr:     "0 0 29 2 *",
+			from:     time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Evaluated in the location of the provided time",
+			expr:     "0 20 * * *",
+			from:     time.Date(2023, 6, 1, 12, 0, 0, 0, rome),
+			expected: time.Date(2023, 6, 1, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Never matching",
+			expr:     "0 0 30 2 *",
+			from:     time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected: time.Time{},
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := cron.Parse(tt.expr)
+			assert.NoError(t, err)
+			assert.True(t, tt.expected.Equal(schedule.Next(tt.from)), "expected %s, got %s", tt.expected, schedule.Next(tt.from))
+		})
+	}
+}